@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// parseClock works out the synthetic clock of -start-time, -time-step
+// and -span: the start, as RFC3339 or a date (midnight UTC), and the
+// step between entries. -span spreads -count entries evenly over it
+// instead of giving the step. An empty start means the real clock.
+func parseClock(start string, step, span time.Duration, count int) (time.Time, time.Duration, error) {
+	if start == "" {
+		if step != 0 || span != 0 {
+			return time.Time{}, 0, errors.New("-time-step and -span need -start-time")
+		}
+		return time.Time{}, 0, nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, start)
+	if err != nil {
+		if t, err = time.Parse(time.DateOnly, start); err != nil {
+			return time.Time{}, 0, fmt.Errorf("-start-time %q is neither RFC3339 nor a date", start)
+		}
+	}
+
+	switch {
+	case step != 0 && span != 0:
+		return time.Time{}, 0, errors.New("-time-step and -span are mutually exclusive")
+	case span < 0 || step < 0:
+		return time.Time{}, 0, errors.New("-time-step and -span must not be negative")
+	case span > 0:
+		if count <= 0 {
+			return time.Time{}, 0, errors.New("-span needs a -count to divide it by")
+		}
+		step = span / time.Duration(count)
+	case step == 0:
+		step = time.Second
+	}
+	return t, step, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"log-processor/internal/logger"
+)
+
+// TestParseClock verifies generated timestamps start at -start-time and
+// advance by -time-step, or by -span divided among -count entries
+func TestParseClock(t *testing.T) {
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		start      string
+		step, span time.Duration
+		count      int
+		want       time.Time
+		wantStep   time.Duration
+	}{
+		{start: "2026-01-01", step: time.Minute, want: day, wantStep: time.Minute},
+		{start: "2026-01-01T06:30:00Z", want: day.Add(6*time.Hour + 30*time.Minute), wantStep: time.Second},
+		{start: "2026-01-01", span: 24 * time.Hour, count: 1440, want: day, wantStep: time.Minute},
+	}
+	for _, tt := range tests {
+		start, step, err := parseClock(tt.start, tt.step, tt.span, tt.count)
+		if err != nil {
+			t.Fatalf("parseClock(%q): %v", tt.start, err)
+		}
+		if !start.Equal(tt.want) || step != tt.wantStep {
+			t.Fatalf("parseClock(%q) = %v, %v; want %v, %v", tt.start, start, step, tt.want, tt.wantStep)
+		}
+
+		svc := logger.NewService("test")
+		svc.SetClock(start, step)
+		for i := 0; i < 3; i++ {
+			want := tt.want.Add(time.Duration(i) * tt.wantStep).Format(time.RFC3339Nano)
+			if got := svc.GenerateLog().Timestamp; got != want {
+				t.Fatalf("%q entry %d at %s, want %s", tt.start, i, got, want)
+			}
+		}
+	}
+
+	for _, bad := range []struct {
+		start      string
+		step, span time.Duration
+		count      int
+	}{
+		{start: "", step: time.Second},
+		{start: "yesterday"},
+		{start: "2026-01-01", step: time.Second, span: time.Hour, count: 10},
+		{start: "2026-01-01", span: time.Hour},
+		{start: "2026-01-01", step: -time.Second},
+	} {
+		if _, _, err := parseClock(bad.start, bad.step, bad.span, bad.count); err == nil {
+			t.Errorf("parseClock(%+v) succeeded, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math/rand"
+
+	"log-processor/internal/logger"
+)
+
+// duplicator stamps entries with their content hash and re-emits them
+// at a fixed rate, giving a controlled stream of duplicates for
+// exercising deduplication downstream
+type duplicator struct {
+	hash bool    // Add logger.HashField to each entry
+	rate float64 // Probability an entry is emitted twice
+	rng  *rand.Rand
+
+	duplicates int
+}
+
+// newDuplicator creates a duplicator; seed makes the choice of
+// duplicated entries reproducible
+func newDuplicator(hash bool, rate float64, seed int64) *duplicator {
+	return &duplicator{hash: hash, rate: rate, rng: rand.New(rand.NewSource(seed))}
+}
+
+// expand returns the entries to emit for a generated one: the entry
+// itself, then again as a duplicate with probability rate
+func (d *duplicator) expand(entry logger.LogEntry) []logger.LogEntry {
+	if d.hash {
+		extra := make(map[string]any, len(entry.Extra)+1)
+		for k, v := range entry.Extra {
+			extra[k] = v
+		}
+		extra[logger.HashField] = entry.ContentHash()
+		entry.Extra = extra
+	}
+
+	if d.rate > 0 && d.rng.Float64() < d.rate {
+		d.duplicates++
+		return []logger.LogEntry{entry, entry}
+	}
+	return []logger.LogEntry{entry}
+}
+
+// Duplicates returns how many entries were emitted twice
+func (d *duplicator) Duplicates() int {
+	return d.duplicates
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"log-processor/internal/logger"
+)
+
+// TestDuplicator verifies duplicates appear at the configured rate and
+// carry the same hash as the entry they repeat
+func TestDuplicator(t *testing.T) {
+	const n, rate = 20000, 0.1
+	svc := logger.NewService("test")
+	d := newDuplicator(true, rate, 1)
+
+	var emitted []logger.LogEntry
+	for i := 0; i < n; i++ {
+		emitted = append(emitted, d.expand(svc.GenerateLog())...)
+	}
+
+	dups := 0
+	for i, e := range emitted {
+		hash, ok := e.Extra[logger.HashField].(string)
+		if !ok || hash != e.ContentHash() {
+			t.Fatalf("entry %d: hash %v, want %s", i, e.Extra[logger.HashField], e.ContentHash())
+		}
+		if i > 0 && hash == emitted[i-1].Extra[logger.HashField] {
+			dups++
+			if e.FormatJSON() != emitted[i-1].FormatJSON() {
+				t.Fatalf("entry %d repeats the hash of a different entry", i)
+			}
+		}
+	}
+
+	if dups != d.Duplicates() || len(emitted) != n+dups {
+		t.Fatalf("found %d duplicates in %d entries, duplicator reports %d", dups, len(emitted), d.Duplicates())
+	}
+	// Binomial standard deviation is ~42; allow five
+	if got := float64(dups) / n; math.Abs(got-rate) > 5*math.Sqrt(rate*(1-rate)/n) {
+		t.Fatalf("duplicate rate %.4f, want %.2f", got, rate)
+	}
+}
+
+// TestDuplicatorOff verifies entries pass through untouched by default
+func TestDuplicatorOff(t *testing.T) {
+	d := newDuplicator(false, 0, 1)
+	entry := logger.LogEntry{Message: "m"}
+	out := d.expand(entry)
+	if len(out) != 1 || out[0].Extra != nil {
+		t.Fatalf("expand = %+v, want the entry unchanged", out)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"log-processor/internal/logger"
+)
+
+// parseConstantFields parses a -fields value: comma-separated key=value
+// pairs such as "env=staging,region=us-east-1". Values are strings.
+// Empty means no fields.
+func parseConstantFields(s string) (map[string]any, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	fields := make(map[string]any)
+	for _, pair := range strings.Split(s, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q is not key=value pairs", s)
+		}
+		if logger.IsKnownField(key) {
+			return nil, fmt.Errorf("%s is a built-in field", key)
+		}
+		fields[key] = value
+	}
+	return fields, nil
+}
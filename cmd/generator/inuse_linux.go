@@ -0,0 +1,63 @@
+//go:build linux
+
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// openByOthers reports which of paths another process has open or
+// mapped, found through /proc. Processes we can't inspect (e.g. other
+// users' without privileges) are not seen.
+func openByOthers(paths []string) map[string]bool {
+	wanted := make(map[string]string, len(paths)) // Absolute -> given path
+	for _, p := range paths {
+		if abs, err := filepath.Abs(p); err == nil {
+			wanted[abs] = p
+		}
+	}
+
+	inUse := make(map[string]bool)
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return inUse
+	}
+	self := os.Getpid()
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		dir := filepath.Join("/proc", proc.Name())
+
+		fds, _ := os.ReadDir(filepath.Join(dir, "fd"))
+		for _, fd := range fds {
+			target, err := os.Readlink(filepath.Join(dir, "fd", fd.Name()))
+			if p, ok := wanted[target]; err == nil && ok {
+				inUse[p] = true
+			}
+		}
+
+		maps, err := os.Open(filepath.Join(dir, "maps"))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(maps)
+		for scanner.Scan() {
+			// address perms offset dev inode path
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 6 {
+				continue
+			}
+			if p, ok := wanted[fields[5]]; ok {
+				inUse[p] = true
+			}
+		}
+		maps.Close()
+	}
+	return inUse
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"fmt"
 	"log"
@@ -12,40 +11,89 @@ import (
 	"time"
 
 	"log-processor/internal/logger"
+	"log-processor/internal/rotation"
 )
 
 func main() {
 	// Command line flags
 	interval := flag.Duration("interval", 5*time.Millisecond, "Interval between log generation")
-	format := flag.String("format", "json", "Output format: json or text")
+	format := flag.String("format", "json", "Output format: json, text, syslog or logfmt")
 	count := flag.Int("count", 0, "Number of logs to generate (0 for infinite)")
-	output := flag.String("output", "logs/app.log", "Output log file path")
+	output := flag.String("output", "logs/app.log", "Output log file path, or tcp://host:port or udp://host:port to send over the network")
 	rotate := flag.Int64("rotate-size", 1, "Rotate log file when it reaches this size in MB (0 to disable)")
+	maxTotal := flag.Int64("max-total-size", 0, "Delete the oldest rotated files once together they exceed this size in MB (0 to disable)")
+	echo := flag.Bool("echo", false, "Also print each log as text to stdout (colored on a terminal)")
+	buffer := flag.Int("buffer", 100, "Size of the buffer between generation and output writes")
+	hash := flag.Bool("hash", false, "Add a \"hash\" field computed from each entry's content")
+	dupRate := flag.Float64("dup-rate", 0, "Probability (0-1) of emitting each entry a second time, for dedup testing")
+	rotateName := flag.String("rotate-name", rotation.DefaultTemplate, "Rotated file name template using {base} and {date}")
+	rotateLayout := flag.String("rotate-layout", rotation.DefaultLayout, "Go time layout substituted for {date}")
+	constFields := flag.String("fields", "", "Constant fields added to every entry, e.g. env=staging,region=us-east-1,host=gen-01")
+	startTime := flag.String("start-time", "", "Stamp entries from this time (RFC3339 or a date) instead of the current time")
+	timeStep := flag.Duration("time-step", 0, "Time between the timestamps of entries from -start-time (default 1s)")
+	span := flag.Duration("span", 0, "Spread -count entries from -start-time evenly over this duration, instead of -time-step")
 	flag.Parse()
 
-	// Create output directory if it doesn't exist
-	dir := filepath.Dir(*output)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		log.Fatalf("Failed to create output directory: %v", err)
+	lineFormat, err := logger.ParseFormat(*format)
+	if err != nil {
+		log.Fatalf("Invalid -format: %v", err)
+	}
+
+	if *dupRate < 0 || *dupRate > 1 {
+		log.Fatalf("Invalid -dup-rate %v: must be between 0 and 1", *dupRate)
+	}
+	if *buffer < 0 {
+		log.Fatalf("Invalid -buffer %d: must not be negative", *buffer)
+	}
+	if *maxTotal < 0 {
+		log.Fatalf("Invalid -max-total-size %d: must not be negative", *maxTotal)
 	}
 
-	// Open log file
-	file, err := os.OpenFile(*output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	constant, err := parseConstantFields(*constFields)
 	if err != nil {
-		log.Fatalf("Failed to open log file: %v", err)
+		log.Fatalf("Invalid -fields: %v", err)
+	}
+
+	clockStart, clockStep, err := parseClock(*startTime, *timeStep, *span, *count)
+	if err != nil {
+		log.Fatalf("Invalid clock: %v", err)
+	}
+
+	scheme := rotation.Scheme{Template: *rotateName, Layout: *rotateLayout}
+	if err := scheme.Validate(); err != nil {
+		log.Fatalf("Invalid rotation scheme: %v", err)
 	}
-	defer file.Close()
 
-	// Use buffered writer for better performance
-	buffWriter := bufio.NewWriter(file)
-	defer buffWriter.Flush()
+	// Open the output; files are rotated, network sinks are not
+	rotateBytes := *rotate * 1024 * 1024 // Convert MB to bytes
+	sink, err := openSink(*output, scheme, rotateBytes, *maxTotal*1024*1024)
+	if err != nil {
+		log.Fatalf("Failed to open output: %v", err)
+	}
+	defer sink.Close()
+	_, isFile := sink.(*fileSink)
 
 	fmt.Println("🚀 Log Generator Started")
 	fmt.Printf("   Output: %s\n", *output)
 	fmt.Printf("   Interval: %v\n", *interval)
 	fmt.Printf("   Format: %s\n", *format)
-	if *rotate > 0 {
-		fmt.Printf("   Rotate at: %d MB\n", *rotate)
+	if *rotate > 0 && isFile {
+		fmt.Printf("   Rotate at: %d MB as %s\n", *rotate, scheme.Name(filepath.Base(*output), time.Now()))
+		for _, warning := range scheme.Warnings() {
+			fmt.Printf("   ⚠️  %s\n", warning)
+		}
+		if *maxTotal > 0 {
+			fmt.Printf("   Rotated files capped at: %d MB\n", *maxTotal)
+		}
+	}
+	if *dupRate > 0 {
+		fmt.Printf("   Duplicate rate: %g\n", *dupRate)
+	}
+	if *constFields != "" {
+		fmt.Printf("   Fields: %s\n", *constFields)
+	}
+	if !clockStart.IsZero() {
+		fmt.Printf("   Timestamps: from %s every %v\n", clockStart.Format(time.RFC3339), clockStep)
 	}
 	if *count > 0 {
 		fmt.Printf("   Count: %d\n", *count)
@@ -54,8 +102,12 @@ func main() {
 	}
 	fmt.Println("---")
 
+	color := logger.ColorEnabled(os.Stdout)
+
 	// Create logging service
 	svc := logger.NewService("log-generator")
+	svc.SetConstantFields(constant)
+	svc.SetClock(clockStart, clockStep)
 
 	// Setup graceful shutdown
 	done := make(chan struct{})
@@ -69,74 +121,79 @@ func main() {
 	}()
 
 	// Generate logs
-	logChan := make(chan logger.LogEntry, 100)
+	logChan := make(chan logger.LogEntry, *buffer)
 	go svc.GenerateLogs(*interval, logChan, done)
 
+	dups := newDuplicator(*hash, *dupRate, time.Now().UnixNano())
 	generated := 0
-	rotateBytes := *rotate * 1024 * 1024 // Convert MB to bytes
-	var currentSize int64
-
-	// Get current file size
-	if info, err := file.Stat(); err == nil {
-		currentSize = info.Size()
+	var written int64
+
+	// finish flushes the output and reports the run
+	finish := func(summary string) {
+		sink.Flush()
+		fmt.Printf("\n✅ Generated %d logs%s to %s\n", generated, summary, *output)
+		if n := dups.Duplicates(); n > 0 {
+			fmt.Printf("🔁 %d of them duplicates\n", n)
+		}
+		printDropped(sink)
+		printBlocked(svc)
 	}
 
 	for {
 		select {
-		case entry := <-logChan:
-			var line string
-			if *format == "json" {
-				line = entry.FormatJSON()
-			} else {
-				line = entry.FormatText()
-			}
+		case generatedEntry := <-logChan:
+			for _, entry := range dups.expand(generatedEntry) {
+				line := entry.FormatAs(lineFormat)
 
-			// Write to file
-			n, err := buffWriter.WriteString(line + "\n")
-			if err != nil {
-				log.Printf("Error writing to log file: %v", err)
-				continue
-			}
-			currentSize += int64(n)
+				if err := sink.WriteLine(line); err != nil {
+					log.Printf("Error writing log: %v", err)
+					continue
+				}
+				written += int64(len(line) + 1)
 
-			generated++
+				generated++
 
-			// Flush periodically for visibility
-			if generated%100 == 0 {
-				buffWriter.Flush()
-				fmt.Printf("\r📝 Generated %d logs (%.2f MB)", generated, float64(currentSize)/(1024*1024))
-			}
+				if *echo {
+					if color {
+						fmt.Println(entry.FormatTextColored())
+					} else {
+						fmt.Println(entry.FormatText())
+					}
+				}
 
-			// Check for rotation
-			if rotateBytes > 0 && currentSize >= rotateBytes {
-				buffWriter.Flush()
-				file.Close()
-
-				// Rotate file
-				timestamp := time.Now().Format("20060102-150405")
-				rotatedName := fmt.Sprintf("%s.%s", *output, timestamp)
-				os.Rename(*output, rotatedName)
-				fmt.Printf("\n🔄 Rotated log to: %s\n", rotatedName)
-
-				// Open new file
-				file, err = os.OpenFile(*output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-				if err != nil {
-					log.Fatalf("Failed to open new log file: %v", err)
+				// Flush periodically for visibility
+				if generated%100 == 0 {
+					if err := sink.Flush(); err != nil {
+						log.Printf("Error flushing logs: %v", err)
+					}
+					if !*echo {
+						fmt.Printf("\r📝 Generated %d logs (%.2f MB written)", generated, float64(written)/(1024*1024))
+					}
 				}
-				buffWriter = bufio.NewWriter(file)
-				currentSize = 0
-			}
 
-			if *count > 0 && generated >= *count {
-				buffWriter.Flush()
-				fmt.Printf("\n✅ Generated %d logs to %s\n", generated, *output)
-				return
+				if *count > 0 && generated >= *count {
+					finish("")
+					return
+				}
 			}
 
 		case <-done:
-			buffWriter.Flush()
-			fmt.Printf("\n✅ Generated %d logs total to %s\n", generated, *output)
+			finish(" total")
 			return
 		}
 	}
 }
+
+// printDropped reports lines a network sink could not send
+func printDropped(sink Sink) {
+	if ns, ok := sink.(*NetworkSink); ok && ns.Dropped() > 0 {
+		fmt.Printf("⚠️  %d logs could not be sent\n", ns.Dropped())
+	}
+}
+
+// printBlocked reports how often generation waited on the writer
+func printBlocked(svc *logger.Service) {
+	if blocked := svc.BlockedSends(); blocked > 0 {
+		fmt.Printf("⏳ Buffer full %d times (writer fell behind; consider a larger -buffer)\n", blocked)
+	}
+}
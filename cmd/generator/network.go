@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// dialTimeout bounds each connection attempt
+	dialTimeout = 5 * time.Second
+
+	// writeTimeout bounds a TCP write, so a receiver that stops reading
+	// is treated as a failed connection rather than blocking forever
+	writeTimeout = 10 * time.Second
+
+	// maxRedials is how many times a failed TCP write reconnects and
+	// resends before the line is given up
+	maxRedials = 5
+)
+
+// NetworkSink sends log lines to a TCP or UDP endpoint, such as a
+// syslog or Fluentd forward input.
+//
+// Over TCP lines are newline-delimited and buffered. Writes block while
+// the receiver applies backpressure; a failed or timed-out write drops
+// the connection, and the line is resent on a new one, redialing with
+// backoff. Lines still buffered when a connection fails may be lost or
+// arrive cut short.
+//
+// Over UDP each line is one datagram, sent best-effort: failures are
+// counted in Dropped and never reported as errors.
+type NetworkSink struct {
+	network string
+	addr    string
+
+	conn net.Conn
+	w    *bufio.Writer // TCP only
+
+	dropped atomic.Int64
+}
+
+// NewNetworkSink connects to an endpoint given as tcp://host:port or
+// udp://host:port
+func NewNetworkSink(rawURL string) (*NetworkSink, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "tcp" && u.Scheme != "udp" {
+		return nil, fmt.Errorf("unsupported network output %q (want tcp:// or udp://)", rawURL)
+	}
+	if u.Port() == "" {
+		return nil, fmt.Errorf("network output %q has no port", rawURL)
+	}
+
+	s := &NetworkSink{network: u.Scheme, addr: u.Host}
+	if err := s.dial(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// dial opens a new connection
+func (s *NetworkSink) dial() error {
+	conn, err := net.DialTimeout(s.network, s.addr, dialTimeout)
+	if err != nil {
+		return err
+	}
+
+	s.conn = conn
+	if s.network == "tcp" {
+		s.w = bufio.NewWriter(conn)
+	}
+	return nil
+}
+
+// WriteLine sends one line
+func (s *NetworkSink) WriteLine(line string) error {
+	if s.network == "udp" {
+		if _, err := s.conn.Write([]byte(line)); err != nil {
+			s.dropped.Add(1)
+		}
+		return nil
+	}
+
+	err := s.writeTCP(line)
+	backoff := 100 * time.Millisecond
+	for attempt := 0; err != nil && attempt < maxRedials; attempt++ {
+		s.conn.Close()
+		time.Sleep(backoff)
+		backoff = min(2*backoff, 5*time.Second)
+
+		if err = s.dial(); err == nil {
+			err = s.writeTCP(line)
+		}
+	}
+	if err != nil {
+		s.dropped.Add(1)
+		return fmt.Errorf("send to %s: %w", s.addr, err)
+	}
+	return nil
+}
+
+// writeTCP buffers a line, writing through to the connection when the
+// buffer fills. bufio retries short writes until all bytes are sent or
+// the connection fails.
+func (s *NetworkSink) writeTCP(line string) error {
+	if s.w.Available() < len(line)+1 {
+		if err := s.flushTCP(); err != nil {
+			return err
+		}
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := s.w.WriteString(line); err != nil {
+		return err
+	}
+	return s.w.WriteByte('\n')
+}
+
+// flushTCP writes buffered lines under the write timeout
+func (s *NetworkSink) flushTCP() error {
+	s.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return s.w.Flush()
+}
+
+// Flush sends buffered TCP lines, reconnecting once if the connection
+// has failed. Lines lost with the old connection are not resent.
+func (s *NetworkSink) Flush() error {
+	if s.network == "udp" {
+		return nil
+	}
+
+	if err := s.flushTCP(); err != nil {
+		s.conn.Close()
+		if derr := s.dial(); derr != nil {
+			return fmt.Errorf("send to %s: %w", s.addr, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Close flushes and closes the connection
+func (s *NetworkSink) Close() error {
+	err := s.Flush()
+	if cerr := s.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Dropped returns how many lines could not be sent
+func (s *NetworkSink) Dropped() int64 {
+	return s.dropped.Load()
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestNetworkSinkTCP verifies lines arrive intact over TCP, including
+// after the receiver drops the connection
+func TestNetworkSinkTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	conns := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+		}
+	}()
+
+	sink, err := NewNetworkSink("tcp://" + ln.Addr().String())
+	if err != nil {
+		t.Fatalf("NewNetworkSink: %v", err)
+	}
+	defer sink.Close()
+
+	var sent []string
+	for i := 0; i < 500; i++ {
+		line := fmt.Sprintf(`{"level":"INFO","message":"entry %d"}`, i)
+		if err := sink.WriteLine(line); err != nil {
+			t.Fatalf("WriteLine: %v", err)
+		}
+		sent = append(sent, line)
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	first := <-conns
+	first.SetReadDeadline(time.Now().Add(5 * time.Second))
+	scanner := bufio.NewScanner(first)
+	for i, want := range sent {
+		if !scanner.Scan() {
+			t.Fatalf("line %d not received: %v", i, scanner.Err())
+		}
+		if got := scanner.Text(); got != want {
+			t.Fatalf("line %d = %q, want %q", i, got, want)
+		}
+	}
+
+	// The receiver goes away; the sink reconnects and later lines
+	// arrive on the new connection
+	first.Close()
+	var second net.Conn
+	deadline := time.After(10 * time.Second)
+	for i := 0; second == nil; i++ {
+		sink.WriteLine(fmt.Sprintf(`{"message":"after %d"}`, i))
+		sink.Flush()
+		select {
+		case second = <-conns:
+		case <-deadline:
+			t.Fatal("sink did not reconnect")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	defer second.Close()
+
+	if err := sink.WriteLine(`{"message":"final"}`); err != nil {
+		t.Fatalf("WriteLine after reconnect: %v", err)
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatalf("Flush after reconnect: %v", err)
+	}
+
+	second.SetReadDeadline(time.Now().Add(5 * time.Second))
+	scanner = bufio.NewScanner(second)
+	for scanner.Scan() {
+		if scanner.Text() == `{"message":"final"}` {
+			return
+		}
+	}
+	t.Fatalf("final line not received after reconnect: %v", scanner.Err())
+}
+
+// TestNetworkSinkUDP verifies each line is sent as one datagram
+func TestNetworkSinkUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	sink, err := NewNetworkSink("udp://" + pc.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("NewNetworkSink: %v", err)
+	}
+	defer sink.Close()
+
+	sent := []string{`{"message":"one"}`, `{"message":"two"}`, `{"message":"three"}`}
+	for _, line := range sent {
+		if err := sink.WriteLine(line); err != nil {
+			t.Fatalf("WriteLine: %v", err)
+		}
+	}
+
+	buf := make([]byte, 64*1024)
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for i, want := range sent {
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("datagram %d: %v", i, err)
+		}
+		if got := string(buf[:n]); got != want {
+			t.Fatalf("datagram %d = %q, want %q", i, got, want)
+		}
+	}
+}
+
+// TestNewNetworkSinkInvalid verifies bad endpoints are rejected
+func TestNewNetworkSinkInvalid(t *testing.T) {
+	for _, output := range []string{"http://localhost:80", "tcp://localhost", "udp://"} {
+		if _, err := NewNetworkSink(output); err == nil {
+			t.Errorf("NewNetworkSink(%q) succeeded", output)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"log-processor/internal/rotation"
+)
+
+// retention keeps the rotated files of an output within a total size
+// budget by deleting the oldest
+type retention struct {
+	output   string // Active file, never deleted
+	scheme   rotation.Scheme
+	maxBytes int64
+}
+
+// rotatedFile is a candidate for pruning
+type rotatedFile struct {
+	path string
+	size int64
+	info os.FileInfo
+}
+
+// prune deletes the oldest rotated files (by modification time, then
+// name) until the rest fit in the budget, returning the deleted paths.
+// Files another process has open, such as a processor still reading
+// one, are skipped, so the budget can be exceeded until they are
+// closed; see openByOthers.
+func (r *retention) prune() ([]string, error) {
+	dir, base := filepath.Split(r.output)
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	active, _ := os.Stat(r.output)
+	pattern := r.scheme.Glob(base)
+
+	var files []rotatedFile
+	var total int64
+	for _, entry := range entries {
+		if ok, _ := filepath.Match(pattern, entry.Name()); !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // Removed meanwhile
+		}
+		if active != nil && os.SameFile(active, info) {
+			continue
+		}
+		files = append(files, rotatedFile{path: filepath.Join(dir, entry.Name()), size: info.Size(), info: info})
+		total += info.Size()
+	}
+	if total <= r.maxBytes {
+		return nil, nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		ti, tj := files[i].info.ModTime(), files[j].info.ModTime()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return files[i].path < files[j].path
+	})
+
+	paths := make([]string, len(files))
+	for i, f := range files {
+		paths[i] = f.path
+	}
+	inUse := openByOthers(paths)
+
+	var removed []string
+	for _, f := range files {
+		if total <= r.maxBytes {
+			break
+		}
+		if inUse[f.path] {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil {
+			if os.IsNotExist(err) {
+				total -= f.size
+				continue
+			}
+			return removed, err
+		}
+		total -= f.size
+		removed = append(removed, f.path)
+	}
+	return removed, nil
+}
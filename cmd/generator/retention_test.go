@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"log-processor/internal/rotation"
+)
+
+// rotatedSize totals the rotated files of output
+func rotatedSize(t *testing.T, output string, scheme rotation.Scheme) (int64, int) {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(filepath.Dir(output), scheme.Glob(filepath.Base(output))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var total int64
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += info.Size()
+	}
+	return total, len(paths)
+}
+
+// TestRetentionBudget verifies rotated files are pruned to the budget
+// over many rotations, keeping the newest
+func TestRetentionBudget(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "app.log")
+	scheme := rotation.Scheme{Layout: "20060102-150405.000000000"}
+	const rotateBytes, budget = 1000, 3500
+
+	sink, err := newFileSink(output, scheme, rotateBytes, budget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	line := strings.Repeat("x", 99) // 100 bytes with the newline
+	for i := 0; i < 200; i++ {      // About 20 rotations
+		if err := sink.WriteLine(line); err != nil {
+			t.Fatal(err)
+		}
+		if i%10 == 9 {
+			total, n := rotatedSize(t, output, scheme)
+			if total > budget {
+				t.Fatalf("after %d lines, %d rotated files total %d bytes, over the %d budget", i+1, n, total, budget)
+			}
+		}
+	}
+
+	total, n := rotatedSize(t, output, scheme)
+	if n != 3 || total != 3000 {
+		t.Fatalf("kept %d rotated files of %d bytes, want the newest 3 (3000 bytes)", n, total)
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Fatalf("active file missing: %v", err)
+	}
+}
+
+// TestRetentionSkipsOpenFiles verifies a rotated file another process
+// has open is kept even when over budget
+func TestRetentionSkipsOpenFiles(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("open files are only detected on Linux")
+	}
+	dir := t.TempDir()
+	output := filepath.Join(dir, "app.log")
+	scheme := rotation.Default()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var paths []string
+	for i := 0; i < 3; i++ {
+		p := filepath.Join(dir, scheme.Name("app.log", base.Add(time.Duration(i)*time.Second)))
+		if err := os.WriteFile(p, make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+		at := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(p, at, at); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+	if err := os.WriteFile(output, make([]byte, 1000), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another process holds the oldest file open as its stdin
+	f, err := os.Open(paths[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	cmd := exec.Command("sleep", "30")
+	cmd.Stdin = f
+	if err := cmd.Start(); err != nil {
+		t.Skipf("can't start a process holding the file: %v", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	r := &retention{output: output, scheme: scheme, maxBytes: 150}
+	removed, err := r.prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 || removed[0] != paths[1] || removed[1] != paths[2] {
+		t.Fatalf("removed %v, want the two files not held open", removed)
+	}
+	if _, err := os.Stat(paths[0]); err != nil {
+		t.Fatalf("file held open was deleted: %v", err)
+	}
+	if _, err := os.Stat(output); err != nil {
+		t.Fatalf("active file deleted: %v", err)
+	}
+}
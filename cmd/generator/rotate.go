@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"log-processor/internal/rotation"
+)
+
+// rotateFile renames the active output file according to scheme and
+// returns the rotated path. It refuses to overwrite an existing file.
+func rotateFile(output string, scheme rotation.Scheme, now time.Time) (string, error) {
+	dir, base := filepath.Split(output)
+	rotated := filepath.Join(dir, scheme.Name(base, now))
+
+	if _, err := os.Stat(rotated); err == nil {
+		return "", fmt.Errorf("rotated file %s already exists", rotated)
+	}
+	if err := os.Rename(output, rotated); err != nil {
+		return "", err
+	}
+	return rotated, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"log-processor/internal/rotation"
+)
+
+// TestRotateFile verifies the active file is renamed per scheme
+func TestRotateFile(t *testing.T) {
+	at := time.Date(2026, 1, 2, 12, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		scheme rotation.Scheme
+		want   string
+	}{
+		{scheme: rotation.Scheme{}, want: "app.log.20260102-123045"},
+		{scheme: rotation.Scheme{Template: "app-{date}.log", Layout: "2006-01-02_150405"}, want: "app-2026-01-02_123045.log"},
+	}
+
+	for _, tt := range tests {
+		dir := t.TempDir()
+		output := filepath.Join(dir, "app.log")
+		if err := os.WriteFile(output, []byte("line\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		rotated, err := rotateFile(output, tt.scheme, at)
+		if err != nil {
+			t.Fatalf("rotateFile: %v", err)
+		}
+		if rotated != filepath.Join(dir, tt.want) {
+			t.Fatalf("rotated to %s, want %s", rotated, tt.want)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Fatalf("active file still present: %v", err)
+		}
+
+		// A second rotation in the same second must not clobber the first
+		if err := os.WriteFile(output, []byte("line\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rotateFile(output, tt.scheme, at); err == nil {
+			t.Fatal("expected error when rotated name already exists")
+		}
+	}
+}
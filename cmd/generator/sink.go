@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"log-processor/internal/rotation"
+)
+
+// Sink receives the generator's formatted log lines
+type Sink interface {
+	// WriteLine writes one log line (without a trailing newline)
+	WriteLine(line string) error
+
+	// Flush pushes out any buffered lines
+	Flush() error
+
+	// Close flushes and releases the sink
+	Close() error
+}
+
+// openSink returns a NetworkSink for tcp:// and udp:// outputs and a
+// rotating file sink otherwise. maxTotalBytes caps the rotated files'
+// total size (0 = unbounded).
+func openSink(output string, scheme rotation.Scheme, rotateBytes, maxTotalBytes int64) (Sink, error) {
+	if strings.HasPrefix(output, "tcp://") || strings.HasPrefix(output, "udp://") {
+		return NewNetworkSink(output)
+	}
+	return newFileSink(output, scheme, rotateBytes, maxTotalBytes)
+}
+
+// fileSink appends lines to a file, rotating it once it reaches
+// rotateBytes (0 disables rotation)
+type fileSink struct {
+	path        string
+	scheme      rotation.Scheme
+	rotateBytes int64
+	retention   *retention // Applied after each rotation, if set
+
+	file *os.File
+	w    *bufio.Writer
+	size int64
+}
+
+// newFileSink opens (creating if needed) the output file for appending.
+// With maxTotalBytes, the oldest rotated files are deleted after each
+// rotation to keep them within it.
+func newFileSink(path string, scheme rotation.Scheme, rotateBytes, maxTotalBytes int64) (*fileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create output directory: %w", err)
+	}
+
+	s := &fileSink{path: path, scheme: scheme, rotateBytes: rotateBytes}
+	if maxTotalBytes > 0 {
+		s.retention = &retention{output: path, scheme: scheme, maxBytes: maxTotalBytes}
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// open opens the active file and picks up its current size
+func (s *fileSink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	s.file = file
+	s.w = bufio.NewWriter(file)
+	s.size = 0
+	if info, err := file.Stat(); err == nil {
+		s.size = info.Size()
+	}
+	return nil
+}
+
+// WriteLine appends a line, rotating the file when it is full
+func (s *fileSink) WriteLine(line string) error {
+	n, err := s.w.WriteString(line + "\n")
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+
+	if s.rotateBytes > 0 && s.size >= s.rotateBytes {
+		return s.rotate()
+	}
+	return nil
+}
+
+// rotate renames the full file aside and starts a new one
+func (s *fileSink) rotate() error {
+	s.w.Flush()
+	s.file.Close()
+
+	rotatedName, err := rotateFile(s.path, s.scheme, time.Now())
+	if err != nil {
+		log.Printf("Error rotating log file: %v", err)
+	} else {
+		fmt.Printf("\n🔄 Rotated log to: %s\n", rotatedName)
+	}
+
+	if err := s.open(); err != nil {
+		return err
+	}
+	if s.retention != nil {
+		removed, err := s.retention.prune()
+		for _, path := range removed {
+			fmt.Printf("🗑️  Deleted %s to stay within the size budget\n", path)
+		}
+		if err != nil {
+			log.Printf("Error pruning rotated logs: %v", err)
+		}
+	}
+	return nil
+}
+
+// Flush writes buffered lines to the file
+func (s *fileSink) Flush() error {
+	return s.w.Flush()
+}
+
+// Close flushes and closes the file
+func (s *fileSink) Close() error {
+	err := s.w.Flush()
+	if cerr := s.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"log-processor/internal/logger"
+)
+
+// runConvert implements the "convert" subcommand
+func runConvert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	from := fs.String("from", "json", "Input format: json, text, syslog or logfmt")
+	to := fs.String("to", "text", "Output format: json, text, syslog or logfmt")
+	input := fs.String("in", "", "Input file (default stdin)")
+	output := fs.String("out", "", "Output file (default stdout)")
+	fields := fs.String("fields", "", "Comma-separated fields to keep, e.g. timestamp,level,message (default all)")
+	rejects := fs.String("rejects", "", "Write unparseable lines here instead of passing them through")
+	fs.Parse(args)
+
+	fromFormat, err := logger.ParseFormat(*from)
+	if err != nil {
+		return err
+	}
+	toFormat, err := logger.ParseFormat(*to)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	converter := logger.Converter{
+		From:   fromFormat,
+		To:     toFormat,
+		Color:  logger.ColorEnabled(w),
+		Fields: logger.ParseFields(*fields),
+	}
+	if *rejects != "" {
+		f, err := os.Create(*rejects)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		converter.Errors = f
+	}
+
+	if err := converter.Convert(r, w); err != nil {
+		return fmt.Errorf("convert: %w", err)
+	}
+	return nil
+}
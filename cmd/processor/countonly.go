@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"log-processor/internal/processor"
+)
+
+// runCountOnly counts the records of each segment without parsing or
+// processing them, committing offsets past them, and prints the counts
+// and their total to out
+func runCountOnly(proc *processor.Processor, out io.Writer) error {
+	report, err := proc.CountSegments(context.Background())
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(report.BySegment))
+	for name := range report.BySegment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%d\n", name, report.BySegment[name])
+	}
+	tw.Flush()
+	fmt.Fprintf(out, "Total: %d records in %d segments\n", report.Records, report.Segments)
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"log-processor/internal/processor"
+)
+
+// TestRunCountOnly verifies per-segment counts and the total are
+// printed, and that a second count finds nothing new
+func TestRunCountOnly(t *testing.T) {
+	cfg := processor.Config{
+		LogsDir:      t.TempDir(),
+		LogPattern:   "app.log",
+		OffsetsDir:   t.TempDir(),
+		WorkerCount:  1,
+		ScanInterval: time.Second,
+	}
+	for name, content := range map[string]string{
+		"app.log.20260101-000000": "{}\n{}\n{}\n",
+		"app.log.20260101-010000": "{}\n{}",
+	} {
+		if err := os.WriteFile(filepath.Join(cfg.LogsDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	count := func() string {
+		t.Helper()
+		proc, err := processor.NewProcessor(cfg, func(*processor.LogRecord) error {
+			t.Error("process func called")
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out bytes.Buffer
+		if err := runCountOnly(proc, &out); err != nil {
+			t.Fatalf("runCountOnly: %v", err)
+		}
+		return out.String()
+	}
+
+	out := count()
+	for _, want := range []string{"app.log.20260101-000000  3\n", "app.log.20260101-010000  2\n", "Total: 5 records in 2 segments\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if out := count(); !strings.Contains(out, "Total: 0 records in 2 segments") {
+		t.Errorf("second count found records again:\n%s", out)
+	}
+}
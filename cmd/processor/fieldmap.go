@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"log-processor/internal/logger"
+)
+
+// parseFieldMap parses a -field-map value: a preset name ("logrus" or
+// "zap") or comma-separated source=target pairs such as
+// "ts=timestamp,msg=message". Empty means no mapping.
+func parseFieldMap(s string) (map[string]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if preset, ok := logger.FieldMapPresets[s]; ok {
+		return preset, nil
+	}
+
+	m := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || from == "" || !logger.IsKnownField(to) {
+			return nil, fmt.Errorf("%q is not a preset (logrus, zap) or source=field pairs", s)
+		}
+		m[from] = to
+	}
+	return m, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"log-processor/internal/logger"
+)
+
+// TestParseFieldMap verifies presets, explicit pairs and bad mappings
+func TestParseFieldMap(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    map[string]string
+		wantErr bool
+	}{
+		{in: "", want: nil},
+		{in: "zap", want: logger.ZapFieldMap},
+		{in: "logrus", want: logger.LogrusFieldMap},
+		{in: "ts=timestamp, msg=message", want: map[string]string{"ts": "timestamp", "msg": "message"}},
+		{in: "ts", wantErr: true},
+		{in: "ts=when", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseFieldMap(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseFieldMap(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseFieldMap(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -5,36 +5,233 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"log-processor/internal/logger"
 	"log-processor/internal/processor"
+	"log-processor/internal/rotation"
 )
 
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "convert":
+			if err := runConvert(os.Args[2:]); err != nil {
+				log.Fatalf("Convert failed: %v", err)
+			}
+			return
+		case "query":
+			if err := runQuery(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Query failed: %v", err)
+			}
+			return
+		case "diff-offsets":
+			if err := runDiffOffsets(os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("Diff failed: %v", err)
+			}
+			return
+		}
+	}
+
 	// Command line flags
 	logsDir := flag.String("logs-dir", "logs", "Directory containing log files")
 	pattern := flag.String("pattern", "app.log", "Base log file pattern")
+	exactFile := flag.String("exact-file", "", "Process just this file as a single segment instead of the rotated files of -pattern")
 	offsetsDir := flag.String("offsets-dir", "offsets", "Directory for offset files")
-	workers := flag.Int("workers", 2, "Number of parallel workers")
+	verifyOffsets := flag.Bool("verify-offsets", false, "Fingerprint segments with their offsets so a different file with the same name is not resumed from a stale offset")
+	workers := flag.String("workers", "2", "Number of parallel workers, or 0/auto for one per CPU (GOMAXPROCS)")
+	scanInterval := flag.Duration("scan-interval", time.Second, "How often to scan for new segments")
+	scanJitter := flag.Duration("scan-jitter", 0, "Randomize each scan interval by up to this much")
+	inputFormat := flag.String("input-format", "json", "Record format of the logs: json, text, syslog, logfmt, wrapped for one JSON document per file, or auto to detect it per segment")
+	wrappedPath := flag.String("wrapped-path", "", "Dot-separated keys leading to the array of records in a wrapped document, e.g. logs")
+	fieldMap := flag.String("field-map", "", "Map JSON field names from other loggers: logrus, zap, or pairs like ts=timestamp,msg=message")
+	resync := flag.Bool("resync-corrupt", false, "Skip corrupt regions of JSON segments as one dead-lettered record, resuming at the next valid record")
+	sanitizeUTF8 := flag.Bool("sanitize-utf8", false, "Replace invalid UTF-8 in records with U+FFFD before parsing")
+	dropUTF8 := flag.Bool("drop-invalid-utf8", false, "Drop invalid UTF-8 from records before parsing instead of replacing it")
+	useMmap := flag.Bool("mmap", false, "Memory-map rotated segments instead of using buffered reads")
+	prefetch := flag.Bool("prefetch", false, "Advise the kernel to read each segment ahead sequentially into the page cache (Linux)")
+	follow := flag.Bool("follow", false, "Also tail the active log file, handing it off on rotation")
+	deleteDone := flag.Bool("delete-after-complete", false, "Delete segments and their offsets once fully processed (destructive)")
+	deleteGrace := flag.Duration("delete-grace", time.Minute, "How long to keep a completed segment before deleting it")
+	validate := flag.Bool("validate", false, "Count entries missing required fields (timestamp, level, service, message) as errors")
+	validateOnly := flag.Bool("validate-only", false, "Check every segment for malformed records (implies -validate), print a JSON summary and exit 1 if over -max-malformed; nothing is processed")
+	maxMalformed := flag.Int64("max-malformed", 0, "Malformed records -validate-only tolerates before failing")
+	countOnly := flag.Bool("count-only", false, "Count the records of each segment from its offset without parsing or processing them, commit offsets past them, print the counts and exit")
+	schemaPath := flag.String("schema", "", "JSON Schema file each record must satisfy; violations count as errors")
+	haltOnSchema := flag.Bool("halt-on-schema", false, "Stop at the first record violating -schema instead of skipping it")
+	partitionBy := flag.String("partition-by", "", "Write processed records as NDJSON to one file per value of this field, e.g. service")
+	partitionDir := flag.String("partition-dir", "out", "Directory for -partition-by output files")
+	partitionMaxOpen := flag.Int("partition-max-open", 64, "Most -partition-by files kept open; the least recently written is closed beyond it")
+	measureLatency := flag.Bool("measure-latency", false, "Time each record's processing and report p50/p95/p99/max latency")
+	commitTarget := flag.Duration("commit-target", 0, "Adapt records between offset commits to commit about this often (0 = every 100 records)")
+	autoParser := flag.Bool("auto-parser", false, "Benchmark the JSON backends on a sample of the input and use the fastest")
+	parser := flag.String("parser", "", "JSON backend to decode with, e.g. minio/simdjson-go (default goccy/go-json)")
+	logLevel := flag.String("log-level", "warn", "Operational log level written to stderr: debug, info, warn or error")
+	from := flag.String("from", "", "Only process records at or after this time: RFC3339 or relative like -1h")
+	to := flag.String("to", "", "Only process records at or before this time: RFC3339 or relative like -10m")
+	exclude := flag.String("exclude-segments", "", "Comma-separated segment names or globs never to track or process")
+	include := flag.String("include-segments", "", "Comma-separated segment names or globs; only matching segments are processed")
+	fromLatest := flag.Bool("resume-from-latest", false, "On the first run against -offsets-dir, skip the segments already present and process only new data")
+	maxRetries := flag.Int("max-segment-retries", 0, "Give up on a failing segment for this run after this many retries (0 = retry forever)")
+	openRetries := flag.Int("open-retries", 3, "Retry opening a segment this many times with backoff before the attempt fails")
+	maxOpenFiles := flag.Int("max-open-files", 0, "Most segment files read at once across workers (with -follow, plus the active file); workers wait beyond it (0 = no limit)")
+	segmentDeadline := flag.Duration("segment-deadline", 0, "Hand a segment back to the queue after this long, resuming it later, so other segments get a turn (0 = no limit)")
+	maxCommitFailures := flag.Int("max-commit-failures", 0, "Stop after this many consecutive failed offset commits (0 = never)")
+	runReports := flag.Int("run-reports", 10, "Keep a report of each of the last N runs in -offsets-dir (0 = none)")
+	limit := flag.Int64("limit", 0, "Stop after processing this many records (0 = no limit)")
+	stopTimeout := flag.Duration("stop-timeout", 30*time.Second, "How long shutdown waits for workers before giving up (0 = forever)")
+	rotateName := flag.String("rotate-name", rotation.DefaultTemplate, "Rotated file name template using {base} and {date}")
+	rotateLayout := flag.String("rotate-layout", rotation.DefaultLayout, "Go time layout substituted for {date}")
+	configPath := flag.String("config", "", "JSON file of flag settings, e.g. {\"workers\": 4}; flags and LOG_PROCESSOR_* variables override it")
+	exportPath := flag.String("export-offsets", "", "Write all offsets in -offsets-dir to this file (- for stdout) as one JSON document, then exit")
+	importPath := flag.String("import-offsets", "", "Merge offsets from an -export-offsets file (- for stdin) into -offsets-dir, keeping the further offset per segment, then exit")
+	printCfg := flag.Bool("print-config", false, "Print the effective configuration after merging flags, environment and -config, then exit")
 	flag.Parse()
 
+	if *configPath == "" {
+		*configPath = os.Getenv(envName("config"))
+	}
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if err := applySources(flag.CommandLine, *configPath, os.Getenv); err != nil {
+		log.Fatalf("Invalid settings: %v", err)
+	}
+
+	// A LevelVar so a SIGHUP can change the level
+	var level slog.LevelVar
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		log.Fatalf("Invalid -log-level: %v", err)
+	}
+
+	workerCount, err := parseWorkers(*workers)
+	if err != nil {
+		log.Fatalf("Invalid -workers: %v", err)
+	}
+
+	fields, err := parseFieldMap(*fieldMap)
+	if err != nil {
+		log.Fatalf("Invalid -field-map: %v", err)
+	}
+
+	now := time.Now()
+	timeFrom, err := parseTimeBound(*from, now)
+	if err != nil {
+		log.Fatalf("Invalid -from: %v", err)
+	}
+	timeTo, err := parseTimeBound(*to, now)
+	if err != nil {
+		log.Fatalf("Invalid -to: %v", err)
+	}
+
+	// Create processor configuration
+	cfg := processor.Config{
+		LogsDir:       *logsDir,
+		LogPattern:    *pattern,
+		ExactFile:     *exactFile,
+		OffsetsDir:    *offsetsDir,
+		WorkerCount:   workerCount,
+		ScanInterval:  *scanInterval,
+		ScanJitter:    *scanJitter,
+		Rotation:      rotation.Scheme{Template: *rotateName, Layout: *rotateLayout},
+		Follow:        *follow,
+		InputFormat:   logger.Format(*inputFormat),
+		WrappedPath:   *wrappedPath,
+		FieldMap:      fields,
+		ResyncCorrupt: *resync,
+		SanitizeUTF8:  *sanitizeUTF8,
+		UseMmap:       *useMmap,
+		Prefetch:      *prefetch,
+
+		DropInvalidUTF8:       *dropUTF8,
+		DeleteAfterComplete:   *deleteDone,
+		VerifyOffsetIdentity:  *verifyOffsets,
+		DeleteGrace:           *deleteGrace,
+		StopTimeout:           *stopTimeout,
+		MaxRecords:            *limit,
+		MaxCommitFailures:     *maxCommitFailures,
+		MaxSegmentRetries:     *maxRetries,
+		OpenRetries:           *openRetries,
+		SegmentDeadline:       *segmentDeadline,
+		MaxOpenFiles:          *maxOpenFiles,
+		RunReports:            *runReports,
+		TimeFrom:              timeFrom,
+		TimeTo:                timeTo,
+		ExcludeSegments:       splitList(*exclude),
+		IncludeSegments:       splitList(*include),
+		ResumeFromLatest:      *fromLatest,
+		ValidateEntries:       *validate,
+		SchemaPath:            *schemaPath,
+		HaltOnSchemaViolation: *haltOnSchema,
+		CommitTarget:          *commitTarget,
+		MeasureLatency:        *measureLatency,
+		AutoSelectParser:      *autoParser,
+		ParserBackend:         *parser,
+		Logger:                slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: &level})),
+	}
+
+	if *printCfg {
+		printConfig(os.Stdout, cfg)
+		return
+	}
+
+	if *exportPath != "" {
+		if err := exportOffsets(*offsetsDir, *exportPath, os.Stdout); err != nil {
+			log.Fatalf("Failed to export offsets: %v", err)
+		}
+		return
+	}
+	if *importPath != "" {
+		if err := importOffsets(*offsetsDir, *importPath, os.Stdin); err != nil {
+			log.Fatalf("Failed to import offsets: %v", err)
+		}
+		return
+	}
+
+	if *validateOnly {
+		cfg.ValidateEntries = true
+		proc, err := processor.NewProcessor(cfg, func(*processor.LogRecord) error { return nil })
+		if err != nil {
+			log.Printf("Failed to create processor: %v", err)
+			os.Exit(exitScanError)
+		}
+		os.Exit(runValidateOnly(proc, *maxMalformed, os.Stdout, os.Stderr))
+	}
+
+	if *countOnly {
+		proc, err := processor.NewProcessor(cfg, func(*processor.LogRecord) error { return nil })
+		if err != nil {
+			log.Fatalf("Failed to create processor: %v", err)
+		}
+		if err := runCountOnly(proc, os.Stdout); err != nil {
+			log.Fatalf("Count failed: %v", err)
+		}
+		return
+	}
+
 	fmt.Println("Log Processor Started")
 	fmt.Printf("Logs Dir: %s\n", *logsDir)
-	fmt.Printf("Pattern: %s\n", *pattern)
+	if *exactFile != "" {
+		fmt.Printf("File: %s\n", *exactFile)
+	} else {
+		fmt.Printf("Pattern: %s\n", *pattern)
+	}
 	fmt.Printf("Offsets Dir: %s\n", *offsetsDir)
-	fmt.Printf("Workers: %d\n", *workers)
+	fmt.Printf("Workers: %d\n", workerCount)
 	fmt.Println("---")
 
-	// Create processor configuration
-	cfg := processor.Config{
-		LogsDir:      *logsDir,
-		LogPattern:   *pattern,
-		OffsetsDir:   *offsetsDir,
-		WorkerCount:  *workers,
-		ScanInterval: time.Second,
+	var partitions *processor.PartitionWriter
+	if *partitionBy != "" {
+		partitions, err = processor.NewPartitionWriter(*partitionDir, *partitionBy, *partitionMaxOpen)
+		if err != nil {
+			log.Fatalf("Invalid -partition-by: %v", err)
+		}
+		fmt.Printf("Output: %s, partitioned by %s\n", *partitionDir, *partitionBy)
 	}
 
 	// Example process function - just count by level
@@ -53,6 +250,9 @@ func main() {
 			fmt.Printf("Processed: %d records", totalCount)
 		}
 
+		if partitions != nil {
+			return partitions.Write(record)
+		}
 		return nil
 	}
 
@@ -73,24 +273,78 @@ func main() {
 		cancel()
 	}()
 
+	// Apply changes to the live settings of -config on SIGHUP
+	reload, err := newReloader(flag.CommandLine, *configPath, explicit, os.Getenv, &level, cfg.Logger)
+	if err != nil {
+		log.Fatalf("Invalid settings: %v", err)
+	}
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	go func() {
+		live := cfg
+		for range hupChan {
+			next, err := reload.reload(proc, live)
+			if err != nil {
+				cfg.Logger.Error("reload failed", "error", err)
+				continue
+			}
+			live = next
+		}
+	}()
+
 	// Start processing
 	if err := proc.Start(ctx); err != nil {
 		log.Fatalf("Failed to start processor: %v", err)
 	}
+	if name := proc.SelectedParser(); name != "" {
+		fmt.Printf("JSON backend: %s (fastest on sample)\n", name)
+	}
 
-	// Wait for context cancellation
-	<-ctx.Done()
+	// Wait for a signal, or the processor stopping itself at -limit
+	<-proc.Done()
 
 	// Stop processor
-	proc.Stop()
+	if err := proc.Stop(); err != nil {
+		log.Printf("Stop: %v", err)
+	}
+	if partitions != nil {
+		if err := partitions.Close(); err != nil {
+			log.Printf("Partition output: %v", err)
+		}
+	}
 
 	// Print final stats
 	processed, errors, segStats := proc.Stats()
 	fmt.Println("\n\nFinal Statistics")
 	fmt.Printf("Total Processed: %d\n", processed)
 	fmt.Printf("Errors: %d\n", errors)
+	if n := proc.CorruptRegions(); n > 0 {
+		fmt.Printf("Corrupt regions skipped: %d\n", n)
+	}
+	if n := proc.SanitizedRecords(); n > 0 {
+		fmt.Printf("Records with invalid UTF-8 sanitized: %d\n", n)
+	}
+	if n := proc.CommitErrors(); n > 0 {
+		fmt.Printf("Offset commit failures: %d (progress may be reprocessed on restart)\n", n)
+	}
+	if n := proc.SchemaViolations(); n > 0 {
+		fmt.Printf("Schema violations: %d\n", n)
+	}
+	if l := proc.Latency(); l.Count > 0 {
+		fmt.Printf("Processing latency - p50: %v, p95: %v, p99: %v, max: %v\n", l.P50, l.P95, l.P99, l.Max)
+	}
 	fmt.Printf("Segments - Total: %d, Pending: %d, Processing: %d, Complete: %d\n",
 		segStats[0], segStats[1], segStats[2], segStats[3])
+	if failed := proc.FailedSegments(); len(failed) > 0 {
+		fmt.Printf("Segments failed after %d retries: %s\n", *maxRetries, strings.Join(failed, ", "))
+	}
+
+	for name, stats := range proc.SegmentStats() {
+		if stats.ParseFailed > 0 {
+			fmt.Printf("Parse failures in %s: %d (%.1f%%)\n", name, stats.ParseFailed, 100*stats.FailureRate())
+		}
+	}
 
 	fmt.Println("\nLog Levels:")
 	for level, count := range levelCounts {
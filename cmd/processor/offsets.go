@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	json "github.com/goccy/go-json"
+
+	"log-processor/internal/processor"
+)
+
+// exportOffsets writes the offsets in dir as one JSON document to path,
+// or to out if path is "-"
+func exportOffsets(dir, path string, out io.Writer) error {
+	om, err := processor.NewOffsetManager(dir)
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		return om.Export(out)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := om.Export(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// importOffsets merges an -export-offsets document from path, or from
+// in if path is "-", into the offsets in dir
+func importOffsets(dir, path string, in io.Reader) error {
+	om, err := processor.NewOffsetManager(dir)
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		return om.Import(in)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return om.Import(f)
+}
+
+// runDiffOffsets implements the diff-offsets subcommand, comparing two
+// -export-offsets documents segment by segment
+func runDiffOffsets(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("diff-offsets", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "Print the diff as a JSON array instead of a table")
+	changed := fs.Bool("changed", false, "Leave out segments whose offsets are unchanged")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: processor diff-offsets [-json] [-changed] before.json after.json")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("want two offset exports, got %d", fs.NArg())
+	}
+
+	before, err := readOffsetExport(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	after, err := readOffsetExport(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	diffs := processor.DiffOffsets(before, after)
+	if *changed {
+		kept := diffs[:0]
+		for _, d := range diffs {
+			if d.Change != processor.OffsetUnchanged {
+				kept = append(kept, d)
+			}
+		}
+		diffs = kept
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(diffs)
+	}
+	printOffsetDiffs(out, diffs)
+	return nil
+}
+
+// readOffsetExport reads an -export-offsets document from path
+func readOffsetExport(path string) (map[string]processor.OffsetData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	offsets, err := processor.ReadOffsetExport(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return offsets, nil
+}
+
+// printOffsetDiffs writes diffs as a table, marking replaced files
+func printOffsetDiffs(w io.Writer, diffs []processor.OffsetDiff) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "SEGMENT\tCHANGE\tBEFORE\tAFTER\tBYTES\tLINES")
+	for _, d := range diffs {
+		change := string(d.Change)
+		if d.Replaced {
+			change += " (replaced)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%+d\t%+d\n", d.Segment, change, d.Before, d.After, d.Bytes, d.Lines)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	json "github.com/goccy/go-json"
+
+	"log-processor/internal/processor"
+)
+
+// TestExportImportOffsets verifies offsets move between directories
+// through a file and through stdin/stdout
+func TestExportImportOffsets(t *testing.T) {
+	src := t.TempDir()
+	om, err := processor.NewOffsetManager(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := om.CommitOffset("app.log.20260101-000000", 128, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "offsets.json")
+	if err := exportOffsets(src, file, nil); err != nil {
+		t.Fatalf("export to file: %v", err)
+	}
+	viaFile := t.TempDir()
+	if err := importOffsets(viaFile, file, nil); err != nil {
+		t.Fatalf("import from file: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := exportOffsets(src, "-", &buf); err != nil {
+		t.Fatalf("export to stdout: %v", err)
+	}
+	viaStdin := t.TempDir()
+	if err := importOffsets(viaStdin, "-", &buf); err != nil {
+		t.Fatalf("import from stdin: %v", err)
+	}
+
+	for _, dir := range []string{viaFile, viaStdin} {
+		om, err := processor.NewOffsetManager(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offset, lines := om.GetOffset("app.log.20260101-000000"); offset != 128 || lines != 4 {
+			t.Errorf("%s: imported offset %d/%d, want 128/4", dir, offset, lines)
+		}
+	}
+}
+
+// TestDiffOffsetsCommand verifies the table and JSON output of
+// diff-offsets, with -changed leaving out unchanged segments
+func TestDiffOffsetsCommand(t *testing.T) {
+	export := func(offsets map[string]int64) string {
+		t.Helper()
+		dir := t.TempDir()
+		om, err := processor.NewOffsetManager(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, offset := range offsets {
+			if err := om.CommitOffset(name, offset, offset/10); err != nil {
+				t.Fatal(err)
+			}
+		}
+		path := filepath.Join(t.TempDir(), "offsets.json")
+		if err := exportOffsets(dir, path, nil); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	before := export(map[string]int64{"app.log.1": 100, "app.log.2": 50})
+	after := export(map[string]int64{"app.log.1": 300, "app.log.2": 50, "app.log.3": 20})
+
+	var table bytes.Buffer
+	if err := runDiffOffsets([]string{"-changed", before, after}, &table); err != nil {
+		t.Fatalf("diff-offsets: %v", err)
+	}
+	out := table.String()
+	for _, want := range []string{"app.log.1  advanced  100     300    +200   +20", "app.log.3  added"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("table missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "app.log.2") {
+		t.Errorf("-changed kept an unchanged segment:\n%s", out)
+	}
+
+	var doc bytes.Buffer
+	if err := runDiffOffsets([]string{"-json", before, after}, &doc); err != nil {
+		t.Fatalf("diff-offsets -json: %v", err)
+	}
+	var diffs []processor.OffsetDiff
+	if err := json.Unmarshal(doc.Bytes(), &diffs); err != nil {
+		t.Fatalf("decode %s: %v", doc.String(), err)
+	}
+	if len(diffs) != 3 || diffs[1].Change != processor.OffsetUnchanged {
+		t.Errorf("JSON diff = %+v", diffs)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"log-processor/internal/logger"
+	"log-processor/internal/processor"
+	"log-processor/internal/rotation"
+)
+
+// runQuery implements the "query" subcommand: an ad-hoc filter over the
+// segments, printing either the matching records or their counts
+func runQuery(args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("query", flag.ExitOnError)
+	logsDir := fs.String("logs-dir", "logs", "Directory containing log files")
+	pattern := fs.String("pattern", "app.log", "Base log file pattern")
+	offsetsDir := fs.String("offsets-dir", "offsets", "Directory holding segment indexes, used to narrow level and service filters")
+	inputFormat := fs.String("input-format", "json", "Record format of the logs: json, text, syslog, logfmt or auto")
+	where := fs.String("where", "", `Filter expression, e.g. "level=ERROR AND service=payment-service" (default all records)`)
+	countBy := fs.String("count-by", "", "Count matching records by this field instead of printing them")
+	window := fs.Duration("window", 0, "Count per event-time window of this size, e.g. 5m")
+	rotateName := fs.String("rotate-name", rotation.DefaultTemplate, "Rotated file name template using {base} and {date}")
+	rotateLayout := fs.String("rotate-layout", rotation.DefaultLayout, "Go time layout substituted for {date}")
+	fs.Parse(args)
+
+	filter, err := processor.ParseFilter(*where)
+	if err != nil {
+		return fmt.Errorf("-where: %w", err)
+	}
+
+	cfg := processor.Config{
+		LogsDir:      *logsDir,
+		LogPattern:   *pattern,
+		OffsetsDir:   *offsetsDir,
+		WorkerCount:  1,
+		ScanInterval: time.Second,
+		Rotation:     rotation.Scheme{Template: *rotateName, Layout: *rotateLayout},
+		InputFormat:  logger.Format(*inputFormat),
+	}
+	proc, err := processor.NewProcessor(cfg, func(*processor.LogRecord) error { return nil })
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+
+	// key groups a record by -count-by, with records lacking the field
+	// counted under "-"
+	key := func(record *processor.LogRecord) (string, bool) {
+		if *countBy == "" {
+			return "total", true
+		}
+		if v, ok := record.Entry.Field(*countBy); ok {
+			return fmt.Sprint(v), true
+		}
+		return "-", true
+	}
+
+	var matched int64
+	switch {
+	case *window > 0:
+		agg := processor.NewWindowedAggregator(*window, key, func(r processor.WindowResult) {
+			fmt.Fprintf(w, "%s - %s\n", r.Start.Format(time.RFC3339), r.End.Format(time.RFC3339))
+			printCounts(w, r.Counts)
+		})
+		err = proc.Filter(context.Background(), filter, func(record *processor.LogRecord) error {
+			matched++
+			agg.Add(record)
+			return nil
+		})
+		agg.Flush()
+		if n := agg.Untimed(); n > 0 {
+			fmt.Fprintf(w, "Without a timestamp: %d\n", n)
+		}
+
+	case *countBy != "":
+		counts := make(map[string]int64)
+		err = proc.Filter(context.Background(), filter, func(record *processor.LogRecord) error {
+			matched++
+			k, _ := key(record)
+			counts[k]++
+			return nil
+		})
+		printCounts(w, counts)
+
+	default:
+		err = proc.Filter(context.Background(), filter, func(record *processor.LogRecord) error {
+			matched++
+			data, err := logger.Marshal(record.Entry)
+			if err != nil {
+				return err
+			}
+			w.Write(data)
+			return w.WriteByte('\n')
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Matched: %d\n", matched)
+	return nil
+}
+
+// printCounts prints counts largest first, then by key
+func printCounts(w io.Writer, counts map[string]int64) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %-30s %d\n", k, counts[k])
+	}
+}
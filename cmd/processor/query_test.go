@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunQuery runs queries end to end over a fixture of two segments
+func TestRunQuery(t *testing.T) {
+	logsDir := t.TempDir()
+	fixture := map[string][]string{
+		"app.log.20260101-000000": {
+			`{"timestamp":"2026-01-01T00:01:00Z","level":"ERROR","service":"payment-service","message":"card declined"}`,
+			`{"timestamp":"2026-01-01T00:02:00Z","level":"INFO","service":"payment-service","message":"paid"}`,
+			`{"timestamp":"2026-01-01T00:03:00Z","level":"ERROR","service":"auth-service","message":"bad token"}`,
+		},
+		"app.log.20260101-001000": {
+			`{"timestamp":"2026-01-01T00:11:00Z","level":"ERROR","service":"payment-service","message":"timeout"}`,
+			`{"timestamp":"2026-01-01T00:12:00Z","level":"WARNING","service":"order-service","message":"slow","duration_ms":900}`,
+			`not json`,
+		},
+	}
+	for name, lines := range fixture {
+		if err := os.WriteFile(filepath.Join(logsDir, name), []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	query := func(args ...string) string {
+		t.Helper()
+		var out bytes.Buffer
+		args = append([]string{"-logs-dir", logsDir, "-offsets-dir", t.TempDir()}, args...)
+		if err := runQuery(args, &out); err != nil {
+			t.Fatalf("runQuery %v: %v", args, err)
+		}
+		return out.String()
+	}
+
+	out := query("-where", "level=ERROR AND service=payment-service", "-count-by", "service")
+	if !strings.Contains(out, "payment-service") || !strings.Contains(out, "Matched: 2") || strings.Contains(out, "auth-service") {
+		t.Errorf("count by service:\n%s", out)
+	}
+
+	out = query("-where", "level=error OR duration_ms>500", "-count-by", "service")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(strings.TrimSpace(lines[0]), "payment-service") ||
+		!strings.HasSuffix(lines[0], " 2") || lines[3] != "Matched: 4" {
+		t.Errorf("counts not ordered largest first:\n%s", out)
+	}
+
+	out = query("-where", `message~"time"`)
+	if strings.Count(out, "\n") != 1 || !strings.Contains(out, `"message":"timeout"`) {
+		t.Errorf("record output:\n%s", out)
+	}
+
+	out = query("-where", "level=ERROR", "-count-by", "service", "-window", "10m")
+	if !strings.Contains(out, "2026-01-01T00:00:00Z - 2026-01-01T00:10:00Z") ||
+		!strings.Contains(out, "2026-01-01T00:10:00Z - 2026-01-01T00:20:00Z") || !strings.Contains(out, "Matched: 3") {
+		t.Errorf("windowed counts:\n%s", out)
+	}
+
+	if err := runQuery([]string{"-logs-dir", logsDir, "-where", "level="}, &bytes.Buffer{}); err == nil {
+		t.Error("invalid -where accepted")
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"time"
+
+	"log-processor/internal/processor"
+)
+
+// liveSettings are the flags a SIGHUP applies from -config to the
+// running processor; changes to any other setting need a restart
+var liveSettings = map[string]bool{
+	"workers":       true,
+	"scan-interval": true,
+	"scan-jitter":   true,
+	"log-level":     true,
+}
+
+// reloader re-reads the -config file on SIGHUP
+type reloader struct {
+	fs     *flag.FlagSet
+	path   string
+	pinned map[string]bool // Set on the command line or in the environment, so the file doesn't apply
+	last   map[string]any  // Settings as of the last read of the file
+	level  *slog.LevelVar
+	log    *slog.Logger
+}
+
+// newReloader records the file's settings as they were applied at
+// startup. Flags in explicit, and those set by the environment, keep
+// their values across reloads.
+func newReloader(fs *flag.FlagSet, path string, explicit map[string]bool, getenv func(string) string, level *slog.LevelVar, log *slog.Logger) (*reloader, error) {
+	r := &reloader{fs: fs, path: path, pinned: make(map[string]bool), level: level, log: log}
+	fs.VisitAll(func(f *flag.Flag) {
+		if explicit[f.Name] || getenv(envName(f.Name)) != "" {
+			r.pinned[f.Name] = true
+		}
+	})
+	if path != "" {
+		var err error
+		if r.last, err = readSettings(fs, path); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// reload re-reads the file and applies the live settings that changed
+// to cfg and the processor, returning the updated config. A live
+// setting removed from the file returns to its default. Other settings
+// that changed are logged as ignored.
+func (r *reloader) reload(proc *processor.Processor, cfg processor.Config) (processor.Config, error) {
+	if r.path == "" {
+		return cfg, fmt.Errorf("no -config file to reload")
+	}
+	file, err := readSettings(r.fs, r.path)
+	if err != nil {
+		return cfg, err
+	}
+
+	names := make([]string, 0, len(file)+len(r.last))
+	for name := range file {
+		names = append(names, name)
+	}
+	for name := range r.last {
+		if _, ok := file[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	next := cfg
+	level := r.level.Level()
+	var ignored []string
+	for _, name := range names {
+		v, ok := file[name]
+		old, had := r.last[name]
+		if r.pinned[name] || ok == had && reflect.DeepEqual(v, old) {
+			continue
+		}
+		if !liveSettings[name] {
+			ignored = append(ignored, name)
+			continue
+		}
+
+		s := r.fs.Lookup(name).DefValue
+		if ok {
+			s = settingString(v)
+		}
+		switch name {
+		case "workers":
+			next.WorkerCount, err = parseWorkers(s)
+		case "scan-interval":
+			next.ScanInterval, err = time.ParseDuration(s)
+		case "scan-jitter":
+			next.ScanJitter, err = time.ParseDuration(s)
+		case "log-level":
+			err = level.UnmarshalText([]byte(s))
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("%s: %s: %w", r.path, name, err)
+		}
+	}
+
+	notApplied, err := proc.Reload(next)
+	if err != nil {
+		return cfg, err
+	}
+	r.level.Set(level)
+	r.last = file
+
+	ignored = append(ignored, notApplied...)
+	if len(ignored) > 0 {
+		r.log.Warn("changed settings need a restart; ignored", "settings", ignored)
+	}
+	return next, nil
+}
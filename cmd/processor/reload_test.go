@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"log-processor/internal/processor"
+)
+
+// TestReloader verifies a reload applies changed live settings from
+// the config file, keeps pinned ones and reports the rest as ignored
+func TestReloader(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("workers", "2", "")
+	fs.Duration("scan-jitter", 0, "")
+	fs.String("log-level", "warn", "")
+	fs.String("logs-dir", "logs", "")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"workers": 2, "scan-jitter": "10ms", "logs-dir": "a"}`)
+
+	var out bytes.Buffer
+	var level slog.LevelVar
+	level.Set(slog.LevelWarn)
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: &level}))
+
+	cfg := processor.Config{
+		LogsDir:      t.TempDir(),
+		LogPattern:   "app.log",
+		OffsetsDir:   t.TempDir(),
+		WorkerCount:  2,
+		ScanInterval: time.Second,
+		Logger:       logger,
+	}
+	proc, err := processor.NewProcessor(cfg, func(*processor.LogRecord) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{"LOG_PROCESSOR_SCAN_JITTER": "5ms"}
+	r, err := newReloader(fs, path, nil, func(k string) string { return env[k] }, &level, logger)
+	if err != nil {
+		t.Fatalf("newReloader: %v", err)
+	}
+
+	write(`{"workers": 3, "scan-jitter": "20ms", "logs-dir": "b", "log-level": "debug"}`)
+	cfg, err = r.reload(proc, cfg)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if cfg.WorkerCount != 3 {
+		t.Errorf("WorkerCount = %d, want 3", cfg.WorkerCount)
+	}
+	if cfg.ScanJitter != 0 {
+		t.Errorf("ScanJitter = %v, want the environment's to stand", cfg.ScanJitter)
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Errorf("log level = %v, want debug", level.Level())
+	}
+	if got := len(proc.Assignments()); got != 3 {
+		t.Errorf("processor has %d workers, want 3", got)
+	}
+	if !strings.Contains(out.String(), "settings=[logs-dir]") {
+		t.Errorf("log %q doesn't report logs-dir as ignored", out.String())
+	}
+
+	// Removing a live setting restores its default
+	write(`{"logs-dir": "b", "log-level": "debug"}`)
+	if cfg, err = r.reload(proc, cfg); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if cfg.WorkerCount != 2 {
+		t.Errorf("WorkerCount = %d, want the default 2", cfg.WorkerCount)
+	}
+
+	write(`{"workers": "many"}`)
+	if _, err := r.reload(proc, cfg); err == nil {
+		t.Error("expected an invalid worker count to fail the reload")
+	}
+}
+
+// TestReloaderScanInterval verifies a reload applies a changed scan
+// interval and rejects one the processor can't use
+func TestReloaderScanInterval(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Duration("scan-interval", time.Second, "")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{}`)
+
+	var out bytes.Buffer
+	var level slog.LevelVar
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: &level}))
+
+	cfg := processor.Config{
+		LogsDir:      t.TempDir(),
+		LogPattern:   "app.log",
+		OffsetsDir:   t.TempDir(),
+		WorkerCount:  1,
+		ScanInterval: time.Second,
+		Logger:       logger,
+	}
+	proc, err := processor.NewProcessor(cfg, func(*processor.LogRecord) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := newReloader(fs, path, nil, func(string) string { return "" }, &level, logger)
+	if err != nil {
+		t.Fatalf("newReloader: %v", err)
+	}
+
+	write(`{"scan-interval": "250ms"}`)
+	if cfg, err = r.reload(proc, cfg); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if cfg.ScanInterval != 250*time.Millisecond {
+		t.Errorf("ScanInterval = %v, want 250ms", cfg.ScanInterval)
+	}
+	if !strings.Contains(out.String(), "scan_interval=250ms") {
+		t.Errorf("log %q doesn't show the processor rescheduled at 250ms", out.String())
+	}
+	if strings.Contains(out.String(), "need a restart") {
+		t.Errorf("scan-interval reported as needing a restart: %q", out.String())
+	}
+
+	write(`{"scan-interval": "0s"}`)
+	if _, err := r.reload(proc, cfg); err == nil {
+		t.Error("expected a zero scan interval to fail the reload")
+	}
+}
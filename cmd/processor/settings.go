@@ -0,0 +1,156 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	json "github.com/goccy/go-json"
+)
+
+// envPrefix prefixes the environment variable for each flag, e.g.
+// LOG_PROCESSOR_LOGS_DIR for -logs-dir
+const envPrefix = "LOG_PROCESSOR_"
+
+// envName returns the environment variable that sets a flag
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// applySources fills in flags not given on the command line, first
+// from the environment and then from a JSON config file (if path is
+// not empty) mapping flag names to values, e.g. {"workers": 4,
+// "follow": true}. Command-line flags take precedence over the
+// environment, which takes precedence over the file.
+func applySources(fs *flag.FlagSet, path string, getenv func(string) string) error {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+
+	var file map[string]any
+	if path != "" {
+		var err error
+		if file, err = readSettings(fs, path); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if err != nil || explicit[f.Name] {
+			return
+		}
+
+		if v := getenv(envName(f.Name)); v != "" {
+			if serr := fs.Set(f.Name, v); serr != nil {
+				err = fmt.Errorf("%s: %w", envName(f.Name), serr)
+			}
+			return
+		}
+
+		if v, ok := file[f.Name]; ok {
+			if serr := fs.Set(f.Name, settingString(v)); serr != nil {
+				err = fmt.Errorf("%s: %s: %w", path, f.Name, serr)
+			}
+		}
+	})
+	return err
+}
+
+// readSettings reads a JSON config file of flag settings, rejecting
+// names that aren't flags of fs
+func readSettings(fs *flag.FlagSet, path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file map[string]any
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for name := range file {
+		if fs.Lookup(name) == nil {
+			return nil, fmt.Errorf("%s: unknown setting %q", path, name)
+		}
+	}
+	return file, nil
+}
+
+// settingString renders a config file value as flag text: strings as
+// they are, numbers and booleans as their JSON
+func settingString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// printConfig writes the settable fields of a config as a table, one
+// field per line in declaration order. Hooks, sources and other values
+// that aren't plain settings (funcs, interfaces, pointers, channels)
+// are left out, as are maps of them.
+func printConfig(w io.Writer, cfg any) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	defer tw.Flush()
+
+	v := reflect.ValueOf(cfg)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() || !printable(field.Type) {
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\n", field.Name, formatSetting(v.Field(i)))
+	}
+}
+
+// printable reports whether a config field holds a plain setting
+func printable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Func, reflect.Interface, reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return false
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return printable(t.Elem())
+	}
+	return true
+}
+
+// formatSetting renders a setting value, quoting strings so empty
+// values stay visible
+func formatSetting(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = fmt.Sprintf("%v=%s", k, formatSetting(v.MapIndex(k)))
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	if t, ok := v.Interface().(time.Time); ok && t.IsZero() {
+		return "none"
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%+v", v.Interface())
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"log-processor/internal/processor"
+)
+
+// TestApplySources verifies flags override the environment, which
+// overrides the config file, which overrides defaults
+func TestApplySources(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	logsDir := fs.String("logs-dir", "logs", "")
+	workers := fs.String("workers", "2", "")
+	follow := fs.Bool("follow", false, "")
+	grace := fs.Duration("delete-grace", time.Minute, "")
+	pattern := fs.String("pattern", "app.log", "")
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := `{"logs-dir": "/from/file", "workers": 8, "follow": true, "delete-grace": "5m"}`
+	if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{
+		"LOG_PROCESSOR_WORKERS":      "6",
+		"LOG_PROCESSOR_DELETE_GRACE": "10m",
+	}
+
+	if err := fs.Parse([]string{"-delete-grace", "30s"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := applySources(fs, path, func(k string) string { return env[k] }); err != nil {
+		t.Fatalf("applySources: %v", err)
+	}
+
+	if *grace != 30*time.Second {
+		t.Errorf("delete-grace = %v, want the flag's 30s", *grace)
+	}
+	if *workers != "6" {
+		t.Errorf("workers = %q, want the environment's 6", *workers)
+	}
+	if *logsDir != "/from/file" || !*follow {
+		t.Errorf("logs-dir = %q, follow = %v; want the file's values", *logsDir, *follow)
+	}
+	if *pattern != "app.log" {
+		t.Errorf("pattern = %q, want the default", *pattern)
+	}
+
+	// The effective config shows the merged values
+	var out bytes.Buffer
+	printConfig(&out, processor.Config{LogsDir: *logsDir, Follow: *follow, DeleteGrace: *grace,
+		DeadLetter: func(*processor.LogRecord, error) {}})
+	for _, want := range []string{`LogsDir `, `"/from/file"`, "DeleteGrace ", "30s", "Follow ", "true", "TimeFrom ", "none"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("printed config lacks %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "DeadLetter") || strings.Contains(out.String(), "Logger") {
+		t.Errorf("printed config includes hooks:\n%s", out.String())
+	}
+}
+
+// TestApplySourcesErrors verifies bad settings are reported
+func TestApplySourcesErrors(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"unknown.json": `{"no-such-flag": 1}`,
+		"invalid.json": `{"follow": "maybe"}`,
+		"broken.json":  `{`,
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.Bool("follow", false, "")
+
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := applySources(fs, path, func(string) string { return "" }); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// parseTimeBound parses a -from/-to value: an RFC3339 timestamp, or a
+// duration relative to now such as "-1h" (an hour ago) or "-30m".
+// Empty means unbounded and yields the zero time.
+func parseTimeBound(s string, now time.Time) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if d, err := time.ParseDuration(s); err == nil {
+			return now.Add(d), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is neither an RFC3339 timestamp nor a relative duration like -1h", s)
+}
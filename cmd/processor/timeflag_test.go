@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseTimeBound verifies absolute and relative time bounds
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{in: "", want: time.Time{}},
+		{in: "2026-02-28T09:30:00Z", want: time.Date(2026, 2, 28, 9, 30, 0, 0, time.UTC)},
+		{in: "2026-02-28T09:30:00.5+02:00", want: time.Date(2026, 2, 28, 7, 30, 0, 500_000_000, time.UTC)},
+		{in: "-1h", want: now.Add(-time.Hour)},
+		{in: "-1h30m", want: now.Add(-90 * time.Minute)},
+		{in: "+15m", want: now.Add(15 * time.Minute)},
+		{in: "1h", wantErr: true},
+		{in: "yesterday", wantErr: true},
+		{in: "2026-02-28", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTimeBound(tt.in, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTimeBound(%q) = %v, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseTimeBound(%q): %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTimeBound(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	json "github.com/goccy/go-json"
+
+	"log-processor/internal/processor"
+)
+
+// Exit codes of -validate-only
+const (
+	exitValid     = 0 // Malformed records within -max-malformed
+	exitMalformed = 1 // More malformed records than -max-malformed
+	exitScanError = 2 // The segments could not be read
+)
+
+// validateSamples is how many malformed records the summary lists
+const validateSamples = 100
+
+// validateSummary is the JSON summary -validate-only writes to stdout
+type validateSummary struct {
+	processor.ValidationReport
+	MaxMalformed int64  `json:"max_malformed"`
+	Passed       bool   `json:"passed"`
+	Error        string `json:"error,omitempty"`
+}
+
+// runValidateOnly scans the segments without processing them, writes
+// a JSON summary to out and malformed records to errOut, and returns
+// the exit code
+func runValidateOnly(proc *processor.Processor, maxMalformed int64, out, errOut io.Writer) int {
+	report, err := proc.ValidateSegments(context.Background(), validateSamples)
+	summary := validateSummary{ValidationReport: report, MaxMalformed: maxMalformed}
+
+	code := exitValid
+	switch {
+	case err != nil:
+		summary.Error = err.Error()
+		code = exitScanError
+		fmt.Fprintf(errOut, "Validation failed: %v\n", err)
+	case report.Malformed > maxMalformed:
+		code = exitMalformed
+	}
+	summary.Passed = code == exitValid
+
+	for _, m := range report.Samples {
+		fmt.Fprintf(errOut, "%s:%d: %s\n", m.Segment, m.Line, m.Reason)
+	}
+	if n := report.Malformed - int64(len(report.Samples)); n > 0 {
+		fmt.Fprintf(errOut, "... and %d more\n", n)
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	enc.Encode(summary)
+	return code
+}
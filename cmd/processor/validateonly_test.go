@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	json "github.com/goccy/go-json"
+
+	"log-processor/internal/processor"
+)
+
+// TestRunValidateOnly verifies the exit codes and summary over a clean
+// and a dirty fixture, and that offsets are left untouched
+func TestRunValidateOnly(t *testing.T) {
+	valid := `{"timestamp":"2026-01-01T00:00:00Z","level":"INFO","service":"api","message":"ok"}`
+
+	run := func(maxMalformed int64, lines ...string) (int, validateSummary, string, string) {
+		t.Helper()
+
+		cfg := processor.Config{
+			LogsDir:         t.TempDir(),
+			LogPattern:      "app.log",
+			OffsetsDir:      t.TempDir(),
+			WorkerCount:     1,
+			ScanInterval:    time.Second,
+			ValidateEntries: true,
+		}
+		content := strings.Join(lines, "\n") + "\n"
+		if err := os.WriteFile(filepath.Join(cfg.LogsDir, "app.log.20260101-000000"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		proc, err := processor.NewProcessor(cfg, func(*processor.LogRecord) error {
+			t.Error("process func called")
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("NewProcessor: %v", err)
+		}
+
+		var out, errOut bytes.Buffer
+		code := runValidateOnly(proc, maxMalformed, &out, &errOut)
+
+		var summary validateSummary
+		if err := json.Unmarshal(out.Bytes(), &summary); err != nil {
+			t.Fatalf("summary is not JSON: %v\n%s", err, out.String())
+		}
+		if offsets, _ := filepath.Glob(filepath.Join(cfg.OffsetsDir, "*")); len(offsets) != 0 {
+			t.Errorf("validation wrote %v", offsets)
+		}
+		return code, summary, out.String(), errOut.String()
+	}
+
+	code, summary, _, _ := run(0, valid, valid)
+	if code != exitValid || !summary.Passed || summary.Records != 2 || summary.Malformed != 0 {
+		t.Errorf("clean fixture: exit %d, summary %+v", code, summary)
+	}
+
+	dirty := []string{valid, `{"level":"INFO","message":"no timestamp"}`, `not json`, valid}
+	code, summary, _, errOut := run(0, dirty...)
+	if code != exitMalformed || summary.Passed {
+		t.Errorf("dirty fixture: exit %d, passed %v", code, summary.Passed)
+	}
+	if summary.Records != 4 || summary.Malformed != 2 || summary.ParseErrors != 1 || summary.InvalidEntries != 1 {
+		t.Errorf("dirty fixture summary %+v", summary.ValidationReport)
+	}
+	if summary.BySegment["app.log.20260101-000000"] != 2 || len(summary.Samples) != 2 ||
+		summary.Samples[0].Line != 2 || summary.Samples[1].Line != 3 {
+		t.Errorf("dirty fixture detail %+v", summary.ValidationReport)
+	}
+	if !strings.Contains(errOut, "app.log.20260101-000000:3:") {
+		t.Errorf("malformed lines not reported:\n%s", errOut)
+	}
+
+	// Within the threshold
+	if code, summary, _, _ := run(2, dirty...); code != exitValid || !summary.Passed {
+		t.Errorf("dirty fixture under -max-malformed 2: exit %d", code)
+	}
+}
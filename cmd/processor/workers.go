@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+)
+
+// parseWorkers parses a -workers value. "auto" or 0 sizes the pool to
+// GOMAXPROCS, since processing is mostly CPU-bound JSON decoding.
+func parseWorkers(s string) (int, error) {
+	if s == "auto" {
+		return runtime.GOMAXPROCS(0), nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a worker count or \"auto\"", s)
+	}
+	if n == 0 {
+		return runtime.GOMAXPROCS(0), nil
+	}
+	return n, nil
+}
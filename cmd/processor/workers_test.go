@@ -0,0 +1,42 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+)
+
+// TestParseWorkers verifies explicit counts and the GOMAXPROCS default
+func TestParseWorkers(t *testing.T) {
+	procs := runtime.GOMAXPROCS(0)
+
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{in: "4", want: 4},
+		{in: "0", want: procs},
+		{in: "auto", want: procs},
+		{in: "-1", wantErr: true},
+		{in: "many", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseWorkers(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseWorkers(%q) = %d, want error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("parseWorkers(%q) = %d, %v; want %d", tt.in, got, err, tt.want)
+		}
+	}
+
+	// The auto value follows GOMAXPROCS as it changes
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(3))
+	if got, _ := parseWorkers("auto"); got != 3 {
+		t.Errorf("parseWorkers(auto) with GOMAXPROCS=3 = %d", got)
+	}
+}
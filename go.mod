@@ -6,18 +6,20 @@ require (
 	github.com/bytedance/sonic v1.14.2
 	github.com/goccy/go-json v0.10.5
 	github.com/json-iterator/go v1.1.12
+	github.com/klauspost/compress v1.15.15
 	github.com/minio/simdjson-go v0.4.5
+	github.com/pierrec/lz4/v4 v4.1.30
+	golang.org/x/sync v0.16.0
+	golang.org/x/sys v0.22.0
 )
 
 require (
 	github.com/bytedance/gopkg v0.1.3 // indirect
 	github.com/bytedance/sonic/loader v0.4.0 // indirect
 	github.com/cloudwego/base64x v0.1.6 // indirect
-	github.com/klauspost/compress v1.15.15 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.9 // indirect
 	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
 	github.com/modern-go/reflect2 v1.0.2 // indirect
 	github.com/twitchyliquid64/golang-asm v0.15.1 // indirect
 	golang.org/x/arch v0.0.0-20210923205945-b76863e36670 // indirect
-	golang.org/x/sys v0.22.0 // indirect
 )
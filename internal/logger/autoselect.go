@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"math"
+	"time"
+)
+
+// Supporter is implemented by backends that only run well on some
+// platforms (e.g. JIT-based ones). Backends without it are assumed to
+// be supported everywhere.
+type Supporter interface {
+	Supported() bool
+}
+
+// Supported reports whether b can be used on this platform
+func Supported(b Backend) bool {
+	s, ok := b.(Supporter)
+	return !ok || s.Supported()
+}
+
+// MeasureUnmarshal times rounds passes of b decoding every line of
+// sample into a LogEntry. A backend that fails (or panics) on any line
+// is reported as infinitely slow, so it is never preferred.
+func MeasureUnmarshal(b Backend, sample [][]byte, rounds int) time.Duration {
+	start := time.Now()
+	for i := 0; i < rounds; i++ {
+		for _, line := range sample {
+			// Decode the known fields directly: LogEntry's own
+			// UnmarshalJSON would go through the selected backend
+			var fields entryFields
+			if err := decode(b, line, &fields); err != nil {
+				return time.Duration(math.MaxInt64)
+			}
+		}
+	}
+	return time.Since(start)
+}
+
+// Fastest returns the supported registered backend that measure
+// reports as quickest on sample. Ties keep the earlier (default first)
+// backend.
+func Fastest(sample [][]byte, measure func(Backend, [][]byte) time.Duration) Backend {
+	var best Backend
+	bestTime := time.Duration(math.MaxInt64)
+	for _, b := range Backends() {
+		if !Supported(b) {
+			continue
+		}
+		if d := measure(b, sample); best == nil || d < bestTime {
+			best, bestTime = b, d
+		}
+	}
+	return best
+}
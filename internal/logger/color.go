@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"io"
+	"os"
+)
+
+// ANSI escape sequences used for colored text output
+const (
+	ansiReset   = "\x1b[0m"
+	ansiRed     = "\x1b[31m"
+	ansiYellow  = "\x1b[33m"
+	ansiGreen   = "\x1b[32m"
+	ansiGray    = "\x1b[90m"
+	ansiBoldRed = "\x1b[1;31m"
+)
+
+// levelColors maps each level to the color of its text lines
+var levelColors = map[LogLevel]string{
+	DEBUG:   ansiGray,
+	INFO:    ansiGreen,
+	WARNING: ansiYellow,
+	ERROR:   ansiRed,
+	FATAL:   ansiBoldRed,
+}
+
+// FormatTextColored is FormatText wrapped in an ANSI color for the
+// entry's level. Unknown levels are left uncolored.
+func (e LogEntry) FormatTextColored() string {
+	text := e.FormatText()
+	if color, ok := levelColors[e.Level]; ok {
+		return color + text + ansiReset
+	}
+	return text
+}
+
+// ColorEnabled reports whether colored output should be written to w:
+// w must be a terminal (a character device, such as os.Stdout when not
+// piped) and the NO_COLOR environment variable must be unset or empty.
+func ColorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+
+	f, ok := w.(interface{ Stat() (os.FileInfo, error) })
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"bytes"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeTerminal is a writer that reports itself as a character device
+type fakeTerminal struct {
+	bytes.Buffer
+}
+
+func (t *fakeTerminal) Stat() (os.FileInfo, error) { return terminalInfo{}, nil }
+
+type terminalInfo struct{}
+
+func (terminalInfo) Name() string       { return "tty" }
+func (terminalInfo) Size() int64        { return 0 }
+func (terminalInfo) Mode() fs.FileMode  { return fs.ModeDevice | fs.ModeCharDevice }
+func (terminalInfo) ModTime() time.Time { return time.Time{} }
+func (terminalInfo) IsDir() bool        { return false }
+func (terminalInfo) Sys() any           { return nil }
+
+// TestColorEnabled verifies color is used only for terminals without NO_COLOR
+func TestColorEnabled(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	if !ColorEnabled(&fakeTerminal{}) {
+		t.Error("expected color for a terminal")
+	}
+	if ColorEnabled(&bytes.Buffer{}) {
+		t.Error("expected no color for a buffer")
+	}
+
+	pipe, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pipe.Close()
+	if ColorEnabled(pipe) {
+		t.Error("expected no color for a regular file")
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if ColorEnabled(&fakeTerminal{}) {
+		t.Error("expected NO_COLOR to disable color")
+	}
+}
+
+// TestConvertColored verifies text output carries level colors only
+// when enabled
+func TestConvertColored(t *testing.T) {
+	input := `{"level":"ERROR","service":"svc","message":"boom"}` + "\n" +
+		`{"level":"WARNING","service":"svc","message":"hmm"}` + "\n"
+
+	for _, tt := range []struct {
+		name string
+		env  string
+		want bool
+	}{
+		{name: "terminal", want: true},
+		{name: "NO_COLOR", env: "1", want: false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.env)
+
+			out := &fakeTerminal{}
+			c := Converter{From: JSON, To: Text, Color: ColorEnabled(out)}
+			if err := c.Convert(strings.NewReader(input), out); err != nil {
+				t.Fatal(err)
+			}
+
+			got := out.String()
+			hasColor := strings.Contains(got, ansiRed+"[] ERROR") && strings.Contains(got, ansiYellow)
+			if hasColor != tt.want {
+				t.Fatalf("colored = %v, want %v: %q", hasColor, tt.want, got)
+			}
+			if !tt.want && strings.Contains(got, "\x1b[") {
+				t.Fatalf("unexpected escape codes: %q", got)
+			}
+		})
+	}
+}
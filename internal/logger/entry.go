@@ -0,0 +1,283 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// entryFields is LogEntry without its JSON methods, so the selected
+// backend can encode the known fields without recursing
+type entryFields LogEntry
+
+// knownFields are the JSON names of LogEntry's struct fields
+var knownFields = map[string]bool{
+	"timestamp":   true,
+	"level":       true,
+	"service":     true,
+	"message":     true,
+	"request_id":  true,
+	"user_id":     true,
+	"duration_ms": true,
+}
+
+// IsKnownField reports whether name is one of LogEntry's struct fields
+func IsKnownField(name string) bool {
+	return knownFields[name]
+}
+
+// MarshalJSON encodes the known fields followed by any Extra fields.
+// Extra keys that collide with known fields are ignored.
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	data, err := Marshal(entryFields(e))
+	if err != nil || len(e.Extra) == 0 {
+		return data, err
+	}
+
+	extra := make(map[string]any, len(e.Extra))
+	for k, v := range e.Extra {
+		if !knownFields[k] {
+			extra[k] = v
+		}
+	}
+	if len(extra) == 0 {
+		return data, nil
+	}
+
+	extraData, err := Marshal(extra)
+	if err != nil {
+		return nil, err
+	}
+
+	// Splice {"known":...} and {"extra":...} into one object
+	out := make([]byte, 0, len(data)+len(extraData))
+	out = append(out, data[:len(data)-1]...)
+	if len(data) > 2 {
+		out = append(out, ',')
+	}
+	out = append(out, extraData[1:]...)
+	return out, nil
+}
+
+// UnmarshalJSON decodes the known fields and collects any others into
+// Extra. The second pass for Extra only runs when the object has more
+// keys than populated known fields, keeping the common case fast.
+func (e *LogEntry) UnmarshalJSON(data []byte) error {
+	var fields entryFields
+	if err := Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*e = LogEntry(fields)
+	e.Extra = nil
+
+	if countTopLevelKeys(data) <= e.populatedFields() {
+		return nil
+	}
+
+	var all map[string]json.RawMessage
+	if err := Unmarshal(data, &all); err != nil {
+		return err
+	}
+	for k, raw := range all {
+		if knownFields[k] {
+			continue
+		}
+		var v any
+		if err := Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if e.Extra == nil {
+			e.Extra = make(map[string]any)
+		}
+		e.Extra[k] = v
+	}
+	return nil
+}
+
+// populatedFields counts known fields holding a non-zero value
+func (e *LogEntry) populatedFields() int {
+	n := 0
+	for _, set := range []bool{
+		e.Timestamp != "", e.Level != "", e.Service != "", e.Message != "",
+		e.RequestID != "", e.UserID != "", e.Duration != 0,
+	} {
+		if set {
+			n++
+		}
+	}
+	return n
+}
+
+// countTopLevelKeys counts the members of a JSON object without
+// decoding it
+func countTopLevelKeys(data []byte) int {
+	data = bytes.TrimSpace(data)
+	if len(data) < 2 || data[0] != '{' {
+		return 0
+	}
+
+	keys, depth := 0, 0
+	inString, escaped, empty := false, false, true
+	for _, c := range data[1 : len(data)-1] {
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			empty = false
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			keys++
+		}
+	}
+	if empty {
+		return 0
+	}
+	return keys + 1
+}
+
+// Field returns the value of a field by its JSON name, checking the
+// known fields first and then Extra. Empty known fields are reported
+// as absent, matching their omission from JSON output.
+func (e LogEntry) Field(name string) (any, bool) {
+	switch name {
+	case "timestamp":
+		return e.Timestamp, e.Timestamp != ""
+	case "level":
+		return string(e.Level), e.Level != ""
+	case "service":
+		return e.Service, e.Service != ""
+	case "message":
+		return e.Message, e.Message != ""
+	case "request_id":
+		return e.RequestID, e.RequestID != ""
+	case "user_id":
+		return e.UserID, e.UserID != ""
+	case "duration_ms":
+		return e.Duration, e.Duration != 0
+	}
+	v, ok := e.Extra[name]
+	return v, ok
+}
+
+// Valid reports whether l is one of the known log levels or a custom
+// level registered with Service.RegisterLevel
+func (l LogLevel) Valid() bool {
+	switch l {
+	case DEBUG, INFO, WARNING, ERROR, FATAL:
+		return true
+	}
+	_, ok := customSeverity(l)
+	return ok
+}
+
+// Validate checks that the required fields are present and well formed:
+// an RFC 3339 timestamp, a known level, and a service and message. All
+// violations are reported together.
+func (e LogEntry) Validate() error {
+	var errs []error
+
+	if e.Timestamp == "" {
+		errs = append(errs, errors.New("missing timestamp"))
+	} else if _, err := time.Parse(time.RFC3339Nano, e.Timestamp); err != nil {
+		errs = append(errs, fmt.Errorf("invalid timestamp %q", e.Timestamp))
+	}
+
+	if e.Level == "" {
+		errs = append(errs, errors.New("missing level"))
+	} else if !e.Level.Valid() {
+		errs = append(errs, fmt.Errorf("unknown level %q", e.Level))
+	}
+
+	if e.Service == "" {
+		errs = append(errs, errors.New("missing service"))
+	}
+	if e.Message == "" {
+		errs = append(errs, errors.New("missing message"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// SetField sets a field by its JSON name. Known fields require a value
+// of their type (a string, or an integer for duration_ms); any other
+// name is stored in Extra.
+func (e *LogEntry) SetField(name string, value any) error {
+	if !knownFields[name] {
+		if e.Extra == nil {
+			e.Extra = make(map[string]any)
+		}
+		e.Extra[name] = value
+		return nil
+	}
+
+	if name == "duration_ms" {
+		switch v := value.(type) {
+		case int:
+			e.Duration = v
+		case int64:
+			e.Duration = int(v)
+		case float64:
+			if v != float64(int(v)) {
+				return fmt.Errorf("field %s: %v is not an integer", name, v)
+			}
+			e.Duration = int(v)
+		default:
+			return fmt.Errorf("field %s: want an integer, got %T", name, value)
+		}
+		return nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("field %s: want a string, got %T", name, value)
+	}
+	switch name {
+	case "timestamp":
+		e.Timestamp = s
+	case "level":
+		e.Level = LogLevel(s)
+	case "service":
+		e.Service = s
+	case "message":
+		e.Message = s
+	case "request_id":
+		e.RequestID = s
+	case "user_id":
+		e.UserID = s
+	}
+	return nil
+}
+
+// DeleteField clears a known field or removes an Extra key
+func (e *LogEntry) DeleteField(name string) {
+	switch name {
+	case "timestamp":
+		e.Timestamp = ""
+	case "level":
+		e.Level = ""
+	case "service":
+		e.Service = ""
+	case "message":
+		e.Message = ""
+	case "request_id":
+		e.RequestID = ""
+	case "user_id":
+		e.UserID = ""
+	case "duration_ms":
+		e.Duration = 0
+	default:
+		delete(e.Extra, name)
+	}
+}
@@ -0,0 +1,83 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidate verifies required-field checks on log entries
+func TestValidate(t *testing.T) {
+	valid := LogEntry{
+		Timestamp: "2026-01-02T12:30:45.123456789Z",
+		Level:     INFO,
+		Service:   "api-gateway",
+		Message:   "Request completed",
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*LogEntry)
+		want   []string
+	}{
+		{name: "valid", modify: func(*LogEntry) {}},
+		{name: "missing level", modify: func(e *LogEntry) { e.Level = "" }, want: []string{"missing level"}},
+		{name: "unknown level", modify: func(e *LogEntry) { e.Level = "TRACE" }, want: []string{`unknown level "TRACE"`}},
+		{name: "bad timestamp", modify: func(e *LogEntry) { e.Timestamp = "yesterday" }, want: []string{`invalid timestamp "yesterday"`}},
+		{name: "aggregated", modify: func(e *LogEntry) { *e = LogEntry{} }, want: []string{
+			"missing timestamp", "missing level", "missing service", "missing message",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := valid
+			tt.modify(&e)
+
+			err := e.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+
+	// Generated entries are always valid
+	svc := NewService("test")
+	for i := 0; i < 100; i++ {
+		if err := svc.GenerateLog().Validate(); err != nil {
+			t.Fatalf("generated entry invalid: %v", err)
+		}
+	}
+}
+
+// TestContentHash verifies the hash covers content, not Extra order or
+// an existing hash field
+func TestContentHash(t *testing.T) {
+	a := LogEntry{Timestamp: "2026-01-02T12:30:45Z", Level: INFO, Service: "svc", Message: "m",
+		Extra: map[string]any{"x": 1, "y": map[string]any{"b": 2, "a": 1}}}
+	b := a
+	b.Extra = map[string]any{"y": map[string]any{"a": 1, "b": 2}, "x": 1, HashField: "stale"}
+
+	if a.ContentHash() != b.ContentHash() {
+		t.Fatalf("equal content hashed differently: %s vs %s", a.ContentHash(), b.ContentHash())
+	}
+	if len(a.ContentHash()) != 16 {
+		t.Fatalf("hash %q, want 16 hex digits", a.ContentHash())
+	}
+
+	c := a
+	c.Message = "other"
+	if a.ContentHash() == c.ContentHash() {
+		t.Fatal("different messages hashed equally")
+	}
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// LogrusFieldMap maps the JSON field names of logrus's JSONFormatter
+// to LogEntry's (logrus already uses "level")
+var LogrusFieldMap = map[string]string{
+	"time": "timestamp",
+	"msg":  "message",
+	"lvl":  "level",
+}
+
+// ZapFieldMap maps the field names of zap's production JSON encoder to
+// LogEntry's. The named logger, if any, becomes the service.
+var ZapFieldMap = map[string]string{
+	"ts":     "timestamp",
+	"msg":    "message",
+	"logger": "service",
+}
+
+// FieldMapPresets names the built-in field maps
+var FieldMapPresets = map[string]map[string]string{
+	"logrus": LogrusFieldMap,
+	"zap":    ZapFieldMap,
+}
+
+// MapFields renames fields from other logging libraries: each Extra key
+// in m is moved to the field it maps to, replacing any value there. So
+// that the result validates, a numeric timestamp (Unix seconds, as zap
+// writes) becomes RFC 3339 and the level is normalized to a LogLevel,
+// e.g. "info" to INFO and "warn" to WARNING. A value that doesn't fit
+// its target field is left in Extra.
+func (e *LogEntry) MapFields(m map[string]string) {
+	for from, to := range m {
+		value, ok := e.Extra[from]
+		if !ok || from == to {
+			continue
+		}
+		if secs, isNum := value.(float64); isNum && to == "timestamp" {
+			value = time.UnixMicro(int64(math.Round(secs * 1e6))).UTC().Format(time.RFC3339Nano)
+		}
+		if e.SetField(to, value) == nil {
+			delete(e.Extra, from)
+		}
+	}
+	if len(e.Extra) == 0 {
+		e.Extra = nil
+	}
+
+	if e.Level != "" {
+		e.Level = NormalizeLevel(string(e.Level))
+	}
+}
+
+// NormalizeLevel maps level names used by common logging libraries to
+// a LogLevel, ignoring case. Unrecognized names are upper-cased.
+func NormalizeLevel(name string) LogLevel {
+	switch level := strings.ToUpper(name); level {
+	case "TRACE":
+		return DEBUG
+	case "WARN":
+		return WARNING
+	case "DPANIC", "PANIC", "CRITICAL":
+		return FATAL
+	default:
+		return LogLevel(level)
+	}
+}
@@ -0,0 +1,72 @@
+package logger
+
+import "testing"
+
+// TestMapFields verifies zap and logrus lines populate LogEntry through
+// their presets
+func TestMapFields(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		m    map[string]string
+		want LogEntry
+	}{
+		{
+			name: "zap",
+			line: `{"level":"warn","ts":1767225600.5,"logger":"api","caller":"main.go:12","msg":"slow request","request_id":"r1"}`,
+			m:    ZapFieldMap,
+			want: LogEntry{
+				Timestamp: "2026-01-01T00:00:00.5Z",
+				Level:     WARNING,
+				Service:   "api",
+				Message:   "slow request",
+				RequestID: "r1",
+				Extra:     map[string]any{"caller": "main.go:12"},
+			},
+		},
+		{
+			name: "logrus",
+			line: `{"level":"info","msg":"user logged in","time":"2026-01-01T10:00:00+01:00","service":"auth","user_id":"u7"}`,
+			m:    LogrusFieldMap,
+			want: LogEntry{
+				Timestamp: "2026-01-01T10:00:00+01:00",
+				Level:     INFO,
+				Service:   "auth",
+				Message:   "user logged in",
+				UserID:    "u7",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e LogEntry
+			if err := Unmarshal([]byte(tt.line), &e); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			e.MapFields(tt.m)
+
+			got, _ := Marshal(e)
+			want, _ := Marshal(tt.want)
+			if string(got) != string(want) {
+				t.Errorf("got  %s\nwant %s", got, want)
+			}
+			if err := e.Validate(); err != nil {
+				t.Errorf("Validate: %v", err)
+			}
+		})
+	}
+}
+
+// TestNormalizeLevel verifies library level names map to LogLevels
+func TestNormalizeLevel(t *testing.T) {
+	for name, want := range map[string]LogLevel{
+		"debug": DEBUG, "trace": DEBUG, "info": INFO, "warn": WARNING,
+		"warning": WARNING, "error": ERROR, "dpanic": FATAL, "fatal": FATAL,
+		"notice": "NOTICE",
+	} {
+		if got := NormalizeLevel(name); got != want {
+			t.Errorf("NormalizeLevel(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"sort"
+	"strings"
+)
+
+// fieldOrder lists the known fields in their JSON output order
+var fieldOrder = []string{
+	"timestamp", "level", "service", "message", "request_id", "user_id", "duration_ms",
+}
+
+// FieldFilter is a whitelist of field names (JSON names, including
+// Extra keys) to keep on output. A nil filter keeps everything.
+type FieldFilter map[string]bool
+
+// ParseFields parses a comma-separated field list. An empty list
+// yields a nil filter.
+func ParseFields(list string) FieldFilter {
+	var f FieldFilter
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			if f == nil {
+				f = make(FieldFilter)
+			}
+			f[name] = true
+		}
+	}
+	return f
+}
+
+// Apply returns a copy of e with fields outside the filter cleared.
+// Known fields are zeroed, so in JSON the required ones still appear
+// empty; use FormatJSON to omit them entirely.
+func (f FieldFilter) Apply(e LogEntry) LogEntry {
+	if f == nil {
+		return e
+	}
+
+	out := LogEntry{}
+	if f["timestamp"] {
+		out.Timestamp = e.Timestamp
+	}
+	if f["level"] {
+		out.Level = e.Level
+	}
+	if f["service"] {
+		out.Service = e.Service
+	}
+	if f["message"] {
+		out.Message = e.Message
+	}
+	if f["request_id"] {
+		out.RequestID = e.RequestID
+	}
+	if f["user_id"] {
+		out.UserID = e.UserID
+	}
+	if f["duration_ms"] {
+		out.Duration = e.Duration
+	}
+	for k, v := range e.Extra {
+		if f[k] && !knownFields[k] {
+			if out.Extra == nil {
+				out.Extra = make(map[string]any)
+			}
+			out.Extra[k] = v
+		}
+	}
+	return out
+}
+
+// FormatJSON renders only the whitelisted fields that are set, known
+// fields first in their usual order, then Extra keys sorted by name
+func (f FieldFilter) FormatJSON(e LogEntry) string {
+	if f == nil {
+		return e.FormatJSON()
+	}
+
+	var names []string
+	for _, name := range fieldOrder {
+		if f[name] {
+			names = append(names, name)
+		}
+	}
+	var extra []string
+	for k := range e.Extra {
+		if f[k] && !knownFields[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	names = append(names, extra...)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	first := true
+	for _, name := range names {
+		v, ok := e.Field(name)
+		if !ok {
+			continue
+		}
+		key, err := Marshal(name)
+		if err != nil {
+			continue
+		}
+		value, err := Marshal(v)
+		if err != nil {
+			continue
+		}
+
+		if !first {
+			b.WriteByte(',')
+		}
+		first = false
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// FormatAs renders the whitelisted fields of e in the given format
+func (f FieldFilter) FormatAs(e LogEntry, format Format) string {
+	switch format {
+	case Text:
+		return f.Apply(e).FormatText()
+	case Syslog:
+		return f.Apply(e).FormatSyslog()
+	case Logfmt:
+		return f.Apply(e).FormatLogfmt()
+	}
+	return f.FormatJSON(e)
+}
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestFieldFilter verifies only whitelisted fields are emitted, from
+// both the known fields and Extra
+func TestFieldFilter(t *testing.T) {
+	entry := LogEntry{
+		Timestamp: "2026-01-01T00:00:00Z",
+		Level:     INFO,
+		Service:   "auth-service",
+		Message:   "User logged in",
+		UserID:    "user-1234",
+		Duration:  12,
+		Extra:     map[string]any{"client_ip": "10.0.0.1", "country": "GB"},
+	}
+
+	f := ParseFields(" level, message ,country")
+
+	got := f.FormatJSON(entry)
+	want := `{"level":"INFO","message":"User logged in","country":"GB"}`
+	if got != want {
+		t.Fatalf("FormatJSON() = %s, want %s", got, want)
+	}
+
+	// The dropped values must not survive a round trip
+	parsed, err := ParseJSON([]byte(got))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.UserID != "" || parsed.Timestamp != "" || parsed.Extra["client_ip"] != nil {
+		t.Fatalf("dropped fields reappeared: %+v", parsed)
+	}
+
+	text := f.FormatAs(entry, Text)
+	for _, dropped := range []string{"user-1234", "auth-service", "2026-01-01"} {
+		if strings.Contains(text, dropped) {
+			t.Fatalf("text output %q contains dropped value %q", text, dropped)
+		}
+	}
+
+	// No filter keeps everything
+	if got := FieldFilter(nil).FormatJSON(entry); got != entry.FormatJSON() {
+		t.Fatalf("nil filter changed output: %s", got)
+	}
+}
+
+// TestConvertFields verifies the converter applies the whitelist
+func TestConvertFields(t *testing.T) {
+	input := `{"timestamp":"2026-01-01T00:00:00Z","level":"ERROR","service":"svc","message":"boom","user_id":"u1","trace":"abc"}` + "\n"
+
+	var out strings.Builder
+	c := Converter{From: JSON, To: JSON, Fields: ParseFields("message,trace")}
+	if err := c.Convert(strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(out.String()); got != `{"message":"boom","trace":"abc"}` {
+		t.Fatalf("converted = %s", got)
+	}
+}
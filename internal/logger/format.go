@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Format identifies a serialized log entry representation
+type Format string
+
+const (
+	JSON   Format = "json"   // One JSON object per line (FormatJSON)
+	Text   Format = "text"   // Human-readable line (FormatText)
+	Syslog Format = "syslog" // RFC 5424 syslog message (FormatSyslog)
+	Logfmt Format = "logfmt" // key=value pairs (FormatLogfmt)
+)
+
+// ErrUnknownFormat is returned for unsupported format names
+var ErrUnknownFormat = errors.New("unknown log format")
+
+// ParseFormat converts a format name (e.g. from a flag) to a Format
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case JSON, Text, Syslog, Logfmt:
+		return f, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownFormat, name)
+}
+
+// ParseJSON parses a line produced by FormatJSON
+func ParseJSON(line []byte) (LogEntry, error) {
+	var entry LogEntry
+	if err := Unmarshal(line, &entry); err != nil {
+		return LogEntry{}, err
+	}
+	return entry, nil
+}
+
+// ParseText parses a line produced by FormatText
+func ParseText(line string) (LogEntry, error) {
+	if !strings.HasPrefix(line, "[") {
+		return LogEntry{}, fmt.Errorf("text log line must start with '[': %q", line)
+	}
+
+	end := strings.Index(line, "] ")
+	if end < 0 {
+		return LogEntry{}, fmt.Errorf("text log line missing timestamp: %q", line)
+	}
+
+	// The message is last, so it may itself contain the separator
+	fields := strings.SplitN(line[end+2:], " | ", 4)
+	if len(fields) != 4 {
+		return LogEntry{}, fmt.Errorf("text log line has %d fields, want 4: %q", len(fields), line)
+	}
+
+	return LogEntry{
+		Timestamp: line[1:end],
+		Level:     LogLevel(fields[0]),
+		Service:   fields[1],
+		RequestID: fields[2],
+		Message:   fields[3],
+	}, nil
+}
+
+// Parse parses a single line in the given format
+func Parse(line []byte, f Format) (LogEntry, error) {
+	switch f {
+	case JSON:
+		return ParseJSON(line)
+	case Text:
+		return ParseText(string(line))
+	case Syslog:
+		return ParseSyslog(string(line))
+	case Logfmt:
+		return ParseLogfmt(string(line))
+	}
+	return LogEntry{}, fmt.Errorf("%w: %q", ErrUnknownFormat, f)
+}
+
+// FormatAs renders the entry in the given format
+func (e LogEntry) FormatAs(f Format) string {
+	switch f {
+	case Text:
+		return e.FormatText()
+	case Syslog:
+		return e.FormatSyslog()
+	case Logfmt:
+		return e.FormatLogfmt()
+	}
+	return e.FormatJSON()
+}
+
+// Converter streams log lines from one format to another
+type Converter struct {
+	From Format
+	To   Format
+
+	// Errors receives lines that fail to parse. If nil, such lines are
+	// passed through to the output unchanged.
+	Errors io.Writer
+
+	// Color colorizes text output by level (see ColorEnabled)
+	Color bool
+
+	// Fields, if set, limits output to the whitelisted fields
+	Fields FieldFilter
+}
+
+// Convert reads lines from r in c.From format and writes them to w in c.To format
+func (c Converter) Convert(r io.Reader, w io.Writer) error {
+	if _, err := ParseFormat(string(c.From)); err != nil {
+		return err
+	}
+	if _, err := ParseFormat(string(c.To)); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	out := bufio.NewWriter(w)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		text := string(line)
+		if entry, err := Parse(line, c.From); err == nil {
+			switch {
+			case c.Color && c.To == Text:
+				text = c.Fields.Apply(entry).FormatTextColored()
+			default:
+				text = c.Fields.FormatAs(entry, c.To)
+			}
+		} else if c.Errors != nil {
+			if _, err := fmt.Fprintln(c.Errors, text); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := out.WriteString(text + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	return out.Flush()
+}
+
+// Convert streams lines from r in format from to w in format to.
+// Lines that fail to parse are copied through unchanged.
+func Convert(r io.Reader, w io.Writer, from, to Format) error {
+	return Converter{From: from, To: to}.Convert(r, w)
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestConvertRoundTrip converts a mixed fixture JSON -> text -> JSON
+func TestConvertRoundTrip(t *testing.T) {
+	entries := []LogEntry{
+		{Timestamp: "2026-01-01T16:38:14.328717Z", Level: INFO, Service: "payment-service", Message: "File uploaded", RequestID: "req-53b55783"},
+		{Timestamp: "2026-01-01T16:38:14.828588Z", Level: ERROR, Service: "user-service", Message: "Service | timeout", RequestID: "req-3b33af3e"},
+	}
+	garbage := "this is not a log line"
+
+	var input strings.Builder
+	input.WriteString(entries[0].FormatJSON() + "\n")
+	input.WriteString(garbage + "\n")
+	input.WriteString("\n")
+	input.WriteString(entries[1].FormatJSON() + "\n")
+
+	// JSON -> text, passing the bad line through
+	var text bytes.Buffer
+	if err := Convert(strings.NewReader(input.String()), &text, JSON, Text); err != nil {
+		t.Fatalf("Convert to text: %v", err)
+	}
+	wantText := entries[0].FormatText() + "\n" + garbage + "\n" + entries[1].FormatText() + "\n"
+	if text.String() != wantText {
+		t.Fatalf("text output:\ngot  %q\nwant %q", text.String(), wantText)
+	}
+
+	// text -> JSON, routing the bad line to the error writer
+	var jsonOut, rejected bytes.Buffer
+	c := Converter{From: Text, To: JSON, Errors: &rejected}
+	if err := c.Convert(&text, &jsonOut); err != nil {
+		t.Fatalf("Convert to JSON: %v", err)
+	}
+	wantJSON := entries[0].FormatJSON() + "\n" + entries[1].FormatJSON() + "\n"
+	if jsonOut.String() != wantJSON {
+		t.Fatalf("JSON output:\ngot  %q\nwant %q", jsonOut.String(), wantJSON)
+	}
+	if rejected.String() != garbage+"\n" {
+		t.Fatalf("rejected output: got %q", rejected.String())
+	}
+}
+
+// TestConvertUnknownFormat rejects unsupported formats up front
+func TestConvertUnknownFormat(t *testing.T) {
+	err := Convert(strings.NewReader(""), &bytes.Buffer{}, JSON, Format("xml"))
+	if err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+}
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+)
+
+// HashField is the Extra key conventionally holding ContentHash. It is
+// left out of the hash itself, so an entry can carry its own.
+const HashField = "hash"
+
+// ContentHash returns a stable 16-hex-digit hash of the entry's content:
+// its populated known fields and Extra values (except HashField).
+// Equal entries hash equally whatever order Extra was built in, so the
+// hash identifies duplicates across runs and processes.
+func (e LogEntry) ContentHash() string {
+	h := sha256.New()
+	write := func(name string, value any) {
+		// encoding/json sorts nested map keys, keeping this stable
+		// whichever backend Marshal uses
+		data, err := json.Marshal(value)
+		if err != nil {
+			data = []byte("?")
+		}
+		h.Write([]byte(name))
+		h.Write([]byte{'='})
+		h.Write(data)
+		h.Write([]byte{'\n'})
+	}
+
+	for _, name := range fieldOrder {
+		if v, ok := e.Field(name); ok {
+			write(name, v)
+		}
+	}
+
+	keys := make([]string, 0, len(e.Extra))
+	for k := range e.Extra {
+		if !knownFields[k] && k != HashField {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		write(k, e.Extra[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	gojson "github.com/goccy/go-json"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// Marshaler encodes a value as JSON
+type Marshaler interface {
+	Marshal(v any) ([]byte, error)
+}
+
+// Unmarshaler decodes JSON into a value
+type Unmarshaler interface {
+	Unmarshal(data []byte, v any) error
+}
+
+// Backend is a JSON implementation usable in both directions
+type Backend interface {
+	Marshaler
+	Unmarshaler
+	Name() string
+}
+
+// Built-in backends. GoJSON is the default.
+var (
+	GoJSON   Backend = gojsonBackend{}
+	StdJSON  Backend = stdBackend{}
+	Jsoniter Backend = jsoniterBackend{}
+)
+
+type gojsonBackend struct{}
+
+func (gojsonBackend) Name() string                       { return "goccy/go-json" }
+func (gojsonBackend) Marshal(v any) ([]byte, error)      { return gojson.Marshal(v) }
+func (gojsonBackend) Unmarshal(data []byte, v any) error { return gojson.Unmarshal(data, v) }
+
+type stdBackend struct{}
+
+func (stdBackend) Name() string                       { return "encoding/json" }
+func (stdBackend) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (stdBackend) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+var jsoniterConfig = jsoniter.ConfigCompatibleWithStandardLibrary
+
+type jsoniterBackend struct{}
+
+func (jsoniterBackend) Name() string                       { return "json-iterator/go" }
+func (jsoniterBackend) Marshal(v any) ([]byte, error)      { return jsoniterConfig.Marshal(v) }
+func (jsoniterBackend) Unmarshal(data []byte, v any) error { return jsoniterConfig.Unmarshal(data, v) }
+
+// Boxes give atomic.Pointer a fixed type regardless of implementation
+type marshalerBox struct{ Marshaler }
+type unmarshalerBox struct{ Unmarshaler }
+
+var (
+	marshaler   atomic.Pointer[marshalerBox]
+	unmarshaler atomic.Pointer[unmarshalerBox]
+)
+
+var (
+	backends   = []Backend{GoJSON, StdJSON, Jsoniter}
+	backendsMu sync.RWMutex
+)
+
+func init() {
+	SetBackend(GoJSON)
+}
+
+// RegisterBackend makes b available via Backends and BackendByName,
+// replacing any registered backend with the same name
+func RegisterBackend(b Backend) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	for i, existing := range backends {
+		if existing.Name() == b.Name() {
+			backends[i] = b
+			return
+		}
+	}
+	backends = append(backends, b)
+}
+
+// UnregisterBackend removes the registered backend with the given name,
+// if any, mainly for tests that register their own
+func UnregisterBackend(name string) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	for i, b := range backends {
+		if b.Name() == name {
+			backends = append(backends[:i:i], backends[i+1:]...)
+			return
+		}
+	}
+}
+
+// Backends returns all registered backends, default first
+func Backends() []Backend {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	return append([]Backend(nil), backends...)
+}
+
+// BackendByName returns the registered backend with the given name
+func BackendByName(name string) (Backend, bool) {
+	for _, b := range Backends() {
+		if b.Name() == name {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// SetMarshaler selects the encoder used by FormatJSON and Marshal
+func SetMarshaler(m Marshaler) {
+	marshaler.Store(&marshalerBox{m})
+}
+
+// SetUnmarshaler selects the decoder used by ParseJSON, Unmarshal and
+// the processor's LogReader
+func SetUnmarshaler(u Unmarshaler) {
+	unmarshaler.Store(&unmarshalerBox{u})
+}
+
+// SetBackend selects b for both encoding and decoding
+func SetBackend(b Backend) {
+	SetMarshaler(b)
+	SetUnmarshaler(b)
+}
+
+// Marshal encodes v with the selected marshaler
+func Marshal(v any) ([]byte, error) {
+	return marshaler.Load().Marshal(v)
+}
+
+// DecodePanicError is returned by Unmarshal when the decoder panicked
+// rather than returning an error, as some backends have on certain
+// malformed input
+type DecodePanicError struct {
+	Value any // Recovered panic value
+}
+
+// Error implements the error interface
+func (e *DecodePanicError) Error() string {
+	return fmt.Sprintf("json decoder panicked: %v", e.Value)
+}
+
+// Unmarshal decodes data into v with the selected unmarshaler. Since
+// data is often untrusted, a panic in the decoder is recovered and
+// returned as a *DecodePanicError.
+func Unmarshal(data []byte, v any) error {
+	return decode(unmarshaler.Load(), data, v)
+}
+
+// decode runs u, returning a panic as a *DecodePanicError
+func decode(u Unmarshaler, data []byte, v any) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &DecodePanicError{Value: r}
+		}
+	}()
+	return u.Unmarshal(data, v)
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+
+	gojson "github.com/goccy/go-json"
+	"github.com/minio/simdjson-go"
+)
+
+// SimdJSON decodes LogEntry fields with simdjson-go, which needs AVX2
+// and CLMUL. simdjson-go has no encoder and only builds a tape, so
+// Marshal and decoding into anything other than the known fields go
+// through goccy/go-json.
+var SimdJSON Backend = simdjsonBackend{}
+
+func init() {
+	RegisterBackend(SimdJSON)
+}
+
+// simdParsed reuses tapes between parses
+var simdParsed = sync.Pool{
+	New: func() any { return (*simdjson.ParsedJson)(nil) },
+}
+
+type simdjsonBackend struct{}
+
+func (simdjsonBackend) Name() string                  { return "minio/simdjson-go" }
+func (simdjsonBackend) Marshal(v any) ([]byte, error) { return gojson.Marshal(v) }
+
+// Supported reports whether the CPU has the instructions simdjson-go needs
+func (simdjsonBackend) Supported() bool {
+	return simdjson.SupportedCPU()
+}
+
+func (simdjsonBackend) Unmarshal(data []byte, v any) error {
+	fields, ok := v.(*entryFields)
+	if !ok {
+		return gojson.Unmarshal(data, v)
+	}
+
+	reuse := simdParsed.Get().(*simdjson.ParsedJson)
+	pj, err := simdjson.Parse(data, reuse)
+	if err != nil {
+		return err
+	}
+	defer simdParsed.Put(pj)
+
+	iter := pj.Iter()
+	iter.Advance()
+	typ, root, err := iter.Root(nil)
+	if err != nil {
+		return err
+	}
+	if typ != simdjson.TypeObject {
+		return fmt.Errorf("cannot decode JSON %s into a log entry", typ)
+	}
+	obj, err := root.Object(nil)
+	if err != nil {
+		return err
+	}
+	return decodeSimdFields(obj, fields)
+}
+
+// decodeSimdFields sets the known fields of obj. Other keys are left
+// for LogEntry.UnmarshalJSON's second pass, and nulls leave a field as
+// it was, as with encoding/json.
+func decodeSimdFields(obj *simdjson.Object, fields *entryFields) error {
+	var elem simdjson.Iter
+	for {
+		name, typ, err := obj.NextElementBytes(&elem)
+		if err != nil {
+			return err
+		}
+		if typ == simdjson.TypeNone {
+			return nil
+		}
+		if typ == simdjson.TypeNull {
+			continue
+		}
+
+		var dst *string
+		switch string(name) {
+		case "timestamp":
+			dst = &fields.Timestamp
+		case "level":
+			s, err := elem.String()
+			if err != nil {
+				return fmt.Errorf("level: %w", err)
+			}
+			fields.Level = LogLevel(s)
+			continue
+		case "service":
+			dst = &fields.Service
+		case "message":
+			dst = &fields.Message
+		case "request_id":
+			dst = &fields.RequestID
+		case "user_id":
+			dst = &fields.UserID
+		case "duration_ms":
+			n, err := elem.Int()
+			if err != nil {
+				return fmt.Errorf("duration_ms: %w", err)
+			}
+			fields.Duration = int(n)
+			continue
+		default:
+			continue
+		}
+
+		s, err := elem.String()
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*dst = s
+	}
+}
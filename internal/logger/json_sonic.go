@@ -0,0 +1,30 @@
+//go:build sonic
+
+package logger
+
+import (
+	"runtime"
+
+	"github.com/bytedance/sonic"
+)
+
+// sonic only compiles on the Go versions it has been ported to, so it
+// is opt-in: build with -tags sonic to register it.
+func init() {
+	RegisterBackend(sonicBackend{})
+}
+
+// ConfigStd matches encoding/json output (HTML escaping, sorted maps)
+var sonicAPI = sonic.ConfigStd
+
+type sonicBackend struct{}
+
+func (sonicBackend) Name() string                       { return "bytedance/sonic" }
+func (sonicBackend) Marshal(v any) ([]byte, error)      { return sonicAPI.Marshal(v) }
+func (sonicBackend) Unmarshal(data []byte, v any) error { return sonicAPI.Unmarshal(data, v) }
+
+// Supported reports whether sonic's JIT runs on this architecture;
+// elsewhere it falls back to a slower generic path
+func (sonicBackend) Supported() bool {
+	return runtime.GOARCH == "amd64" || runtime.GOARCH == "arm64"
+}
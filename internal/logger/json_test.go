@@ -0,0 +1,159 @@
+package logger
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestBackendsRoundTrip verifies every backend produces identical output
+func TestBackendsRoundTrip(t *testing.T) {
+	defer SetBackend(GoJSON)
+
+	entries := append([]LogEntry{
+		{Timestamp: "2026-01-01T00:00:00Z", Level: ERROR, Service: "svc", Message: `quote " and <html> & unicode ✓`},
+		{Timestamp: "2026-01-01T00:00:00Z", Level: INFO, Service: "svc", Message: "extra",
+			Extra: map[string]any{"client_ip": "81.2.69.142", "asn": float64(20712), "tags": []any{"a", true}}},
+	}, sampleLogs...)
+
+	SetBackend(GoJSON)
+	want := make([]string, len(entries))
+	for i, e := range entries {
+		want[i] = e.FormatJSON()
+	}
+
+	for _, backend := range Backends() {
+		t.Run(backend.Name(), func(t *testing.T) {
+			SetBackend(backend)
+
+			for i, e := range entries {
+				line := e.FormatJSON()
+				if line != want[i] {
+					t.Fatalf("FormatJSON mismatch:\ngot  %s\nwant %s", line, want[i])
+				}
+
+				parsed, err := ParseJSON([]byte(line))
+				if err != nil {
+					t.Fatalf("ParseJSON: %v", err)
+				}
+				if !reflect.DeepEqual(parsed, e) {
+					t.Fatalf("round trip mismatch: got %+v, want %+v", parsed, e)
+				}
+			}
+		})
+	}
+
+	if _, ok := BackendByName("encoding/json"); !ok {
+		t.Fatal("expected encoding/json to be registered")
+	}
+}
+
+// unsupportedBackend is a backend that claims not to run here
+type unsupportedBackend struct{ Backend }
+
+func (unsupportedBackend) Name() string    { return "unsupported" }
+func (unsupportedBackend) Supported() bool { return false }
+
+// TestFastest verifies selection by measurement, skipping unsupported
+// backends and ones that fail on the sample
+func TestFastest(t *testing.T) {
+	sample := [][]byte{[]byte(`{"level":"INFO","message":"a"}`)}
+
+	if d := MeasureUnmarshal(StdJSON, sample, 2); d <= 0 || d == time.Duration(math.MaxInt64) {
+		t.Fatalf("MeasureUnmarshal() = %v", d)
+	}
+	if d := MeasureUnmarshal(StdJSON, [][]byte{[]byte(`{`)}, 1); d != time.Duration(math.MaxInt64) {
+		t.Fatalf("failing backend measured %v, want max", d)
+	}
+
+	unsupported := unsupportedBackend{GoJSON}
+	RegisterBackend(unsupported)
+	t.Cleanup(func() { UnregisterBackend(unsupported.Name()) })
+
+	best := Fastest(sample, func(b Backend, _ [][]byte) time.Duration {
+		switch b.Name() {
+		case "unsupported":
+			return 0
+		case StdJSON.Name():
+			return time.Millisecond
+		}
+		return time.Second
+	})
+	if best != StdJSON {
+		t.Fatalf("Fastest() = %s, want %s", best.Name(), StdJSON.Name())
+	}
+}
+
+// panickyBackend is a decoder that panics on input containing "boom",
+// standing in for a backend bug tripped by malformed input
+type panickyBackend struct{ Backend }
+
+func (b panickyBackend) Unmarshal(data []byte, v any) error {
+	if strings.Contains(string(data), "boom") {
+		var m map[string]int
+		m["boom"]++ // nil map write
+	}
+	return b.Backend.Unmarshal(data, v)
+}
+
+// TestUnmarshalRecoversPanic verifies a panicking decoder yields an
+// error instead of crashing the caller
+func TestUnmarshalRecoversPanic(t *testing.T) {
+	SetUnmarshaler(panickyBackend{GoJSON})
+	defer SetBackend(GoJSON)
+
+	_, err := ParseJSON([]byte(`{"message":"boom"}`))
+	var panicErr *DecodePanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("ParseJSON = %v, want a *DecodePanicError", err)
+	}
+
+	entry, err := ParseJSON([]byte(`{"message":"fine"}`))
+	if err != nil || entry.Message != "fine" {
+		t.Fatalf("ParseJSON after a panic = %+v, %v", entry, err)
+	}
+}
+
+// renamedBackend is a backend registered under a name of its own
+type renamedBackend struct {
+	Backend
+	name string
+}
+
+func (b renamedBackend) Name() string { return b.name }
+
+// TestUnregisterBackend verifies a registered backend can be removed
+// again, leaving the others registered
+func TestUnregisterBackend(t *testing.T) {
+	before := len(Backends())
+	RegisterBackend(renamedBackend{GoJSON, "temporary"})
+	UnregisterBackend("temporary")
+
+	if _, ok := BackendByName("temporary"); ok {
+		t.Fatal("unregistered backend still found")
+	}
+	if got := len(Backends()); got != before {
+		t.Fatalf("%d backends registered, want %d", got, before)
+	}
+}
+
+// TestRegisterBackendReplaces verifies registering a name again replaces
+// the earlier backend rather than adding a second one
+func TestRegisterBackendReplaces(t *testing.T) {
+	first := renamedBackend{GoJSON, "replaced"}
+	second := renamedBackend{StdJSON, "replaced"}
+	RegisterBackend(first)
+	t.Cleanup(func() { UnregisterBackend("replaced") })
+	before := len(Backends())
+	RegisterBackend(second)
+
+	if got := len(Backends()); got != before {
+		t.Fatalf("%d backends registered after re-registering, want %d", got, before)
+	}
+	if b, ok := BackendByName("replaced"); !ok || b != second {
+		t.Fatalf("BackendByName() = %v, %v; want the second registration", b, ok)
+	}
+}
@@ -0,0 +1,142 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// logfmtOrder is the order known fields are written in by FormatLogfmt
+var logfmtOrder = []string{"timestamp", "level", "service", "message", "request_id", "user_id", "duration_ms"}
+
+// FormatLogfmt renders the entry as a logfmt line of key=value pairs:
+// the known fields that are set, then Extra sorted by key. Values with
+// spaces, quotes, '=' or control characters are quoted.
+func (e LogEntry) FormatLogfmt() string {
+	var b strings.Builder
+	write := func(key, value string) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(key)
+		b.WriteByte('=')
+		b.WriteString(logfmtValue(value))
+	}
+
+	for _, key := range logfmtOrder {
+		if value, ok := e.Field(key); ok {
+			write(key, fmt.Sprint(value))
+		}
+	}
+
+	keys := make([]string, 0, len(e.Extra))
+	for key := range e.Extra {
+		if !knownFields[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, ok := e.Extra[key].(string)
+		if !ok {
+			data, err := Marshal(e.Extra[key])
+			if err != nil {
+				continue
+			}
+			value = string(data)
+		}
+		write(key, value)
+	}
+	return b.String()
+}
+
+// logfmtValue quotes a value if it can't be written bare
+func logfmtValue(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, c := range s {
+		if c <= ' ' || c == '=' || c == '"' || c == '\\' || c == 0x7f {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// ParseLogfmt parses a logfmt line, such as one produced by
+// FormatLogfmt. Every token must be a key=value pair; keys other than
+// the known fields are kept in Extra as strings.
+func ParseLogfmt(line string) (LogEntry, error) {
+	var entry LogEntry
+	rest := strings.TrimSpace(line)
+	if rest == "" {
+		return LogEntry{}, fmt.Errorf("empty logfmt line")
+	}
+
+	for rest != "" {
+		end := 0
+		for end < len(rest) && isLogfmtKeyByte(rest[end]) {
+			end++
+		}
+		if end == 0 || end == len(rest) || rest[end] != '=' {
+			return LogEntry{}, fmt.Errorf("logfmt line needs key=value pairs: %q", line)
+		}
+		key := rest[:end]
+		rest = rest[end+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			n := quotedLen(rest)
+			if n < 0 {
+				return LogEntry{}, fmt.Errorf("logfmt value of %s has no closing quote: %q", key, line)
+			}
+			unquoted, err := strconv.Unquote(rest[:n])
+			if err != nil {
+				return LogEntry{}, fmt.Errorf("logfmt value of %s: %w", key, err)
+			}
+			value, rest = unquoted, rest[n:]
+			if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+				return LogEntry{}, fmt.Errorf("logfmt value of %s runs on after its quote: %q", key, line)
+			}
+		} else {
+			n := strings.IndexAny(rest, " \t")
+			if n < 0 {
+				n = len(rest)
+			}
+			value, rest = rest[:n], rest[n:]
+		}
+		rest = strings.TrimLeft(rest, " \t")
+
+		if key == "duration_ms" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return LogEntry{}, fmt.Errorf("logfmt duration_ms: %w", err)
+			}
+			entry.Duration = n
+			continue
+		}
+		entry.SetField(key, value)
+	}
+	return entry, nil
+}
+
+// isLogfmtKeyByte reports whether c may appear in a logfmt key
+func isLogfmtKeyByte(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '/' ||
+		'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+// quotedLen returns the length of the quoted string at the start of s,
+// quotes included, or -1 if it isn't closed
+func quotedLen(s string) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return -1
+}
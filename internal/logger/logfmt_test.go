@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestLogfmtRoundTrip verifies FormatLogfmt output parses back to the
+// same entry, with quoting where needed
+func TestLogfmtRoundTrip(t *testing.T) {
+	entry := LogEntry{
+		Timestamp: "2026-01-02T10:00:00Z",
+		Level:     ERROR,
+		Service:   "payment-service",
+		Message:   `charge "failed" for a=b`,
+		RequestID: "req-1",
+		Duration:  42,
+		Extra:     map[string]any{"region": "eu west", "attempt": "3"},
+	}
+
+	line := entry.FormatLogfmt()
+	want := `timestamp=2026-01-02T10:00:00Z level=ERROR service=payment-service message="charge \"failed\" for a=b" request_id=req-1 duration_ms=42 attempt=3 region="eu west"`
+	if line != want {
+		t.Fatalf("FormatLogfmt:\n got %s\nwant %s", line, want)
+	}
+
+	parsed, err := ParseLogfmt(line)
+	if err != nil {
+		t.Fatalf("ParseLogfmt: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, entry) {
+		t.Fatalf("round trip:\n got %+v\nwant %+v", parsed, entry)
+	}
+}
+
+// TestParseLogfmtRejects verifies lines that aren't key=value pairs fail
+func TestParseLogfmtRejects(t *testing.T) {
+	for _, line := range []string{
+		"",
+		"plain text message",
+		`{"level":"INFO"}`,
+		"[2026-01-02T10:00:00Z] INFO | svc | req | msg",
+		`level=INFO message="unterminated`,
+		`message="quoted"trailing`,
+		"duration_ms=soon",
+	} {
+		if _, err := ParseLogfmt(line); err == nil {
+			t.Errorf("ParseLogfmt(%q) succeeded, want an error", line)
+		}
+	}
+}
@@ -1,11 +1,11 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
 	"math/rand"
+	"sync/atomic"
 	"time"
-
-	json "github.com/goccy/go-json"
 )
 
 // LogLevel represents the severity of a log entry
@@ -28,6 +28,10 @@ type LogEntry struct {
 	RequestID string   `json:"request_id,omitempty"`
 	UserID    string   `json:"user_id,omitempty"`
 	Duration  int      `json:"duration_ms,omitempty"`
+
+	// Extra holds any additional top-level fields. They are inlined
+	// alongside the known fields when marshaled to JSON.
+	Extra map[string]any `json:"-"`
 }
 
 // Service generates logs for testing purposes
@@ -35,6 +39,16 @@ type Service struct {
 	serviceName string
 	services    []string
 	messages    map[LogLevel][]string
+	levels      []LogLevel // Levels in selection order, with their weights
+	weights     []int
+	constant    map[string]any // Extra fields added to every entry
+
+	// Synthetic clock set by SetClock; zero clockStart means time.Now
+	clockStart time.Time
+	clockStep  time.Duration
+	clockTicks atomic.Int64
+
+	blockedSends atomic.Int64 // Sends that found the output channel full
 }
 
 // NewService creates a new logging service
@@ -49,9 +63,82 @@ func NewService(serviceName string) *Service {
 			ERROR:   {"Database connection failed", "Authentication failed", "Invalid input", "Service timeout"},
 			FATAL:   {"Out of memory", "Disk full", "Critical service unavailable", "Configuration error"},
 		},
+		levels:  []LogLevel{DEBUG, INFO, WARNING, ERROR, FATAL},
+		weights: []int{15, 150, 20, 10, 2}, // Weighted distribution
 	}
 }
 
+// RegisterLevel adds a custom level (e.g. TRACE or NOTICE) to the
+// generated mix, or replaces the messages and weight of an existing
+// one. Levels are picked in proportion to their weights; the built-in
+// ones are DEBUG 15, INFO 150, WARNING 20, ERROR 10 and FATAL 2, and a
+// weight of 0 stops a level being generated. The severity (0-7, see
+// LogLevel.Severity) is registered for the level package-wide, which
+// also makes it Valid; built-in levels keep theirs. RegisterLevel must
+// not be called while the service is generating.
+func (s *Service) RegisterLevel(level LogLevel, severity int, messages []string, weight int) error {
+	if level == "" {
+		return errors.New("level name is empty")
+	}
+	if weight < 0 {
+		return fmt.Errorf("level %s: weight must not be negative", level)
+	}
+	if weight > 0 && len(messages) == 0 {
+		return fmt.Errorf("level %s: no messages", level)
+	}
+	if err := registerSeverity(level, severity); err != nil {
+		return err
+	}
+
+	s.messages[level] = append([]string(nil), messages...)
+	for i, l := range s.levels {
+		if l == level {
+			s.weights[i] = weight
+			return nil
+		}
+	}
+	s.levels = append(s.levels, level)
+	s.weights = append(s.weights, weight)
+	return nil
+}
+
+// SetConstantFields adds fields to the Extra of every generated entry,
+// e.g. env, region and host to resemble a particular deployment. Extra
+// fields already set on a GenerateFrom base take precedence, and names
+// of LogEntry's own fields are left out. nil clears them.
+// SetConstantFields must not be called while the service is generating.
+func (s *Service) SetConstantFields(fields map[string]any) {
+	s.constant = nil
+	for k, v := range fields {
+		if IsKnownField(k) {
+			continue
+		}
+		if s.constant == nil {
+			s.constant = make(map[string]any, len(fields))
+		}
+		s.constant[k] = v
+	}
+}
+
+// SetClock stamps generated entries with synthetic times instead of the
+// current time: the first gets start and each one after it step later,
+// e.g. to produce a day of historical logs for backfill tests. A zero
+// start restores the current time. SetClock must not be called while
+// the service is generating.
+func (s *Service) SetClock(start time.Time, step time.Duration) {
+	s.clockStart, s.clockStep = start, step
+	s.clockTicks.Store(0)
+}
+
+// now returns the timestamp for the next generated entry
+func (s *Service) now() time.Time {
+	if s.clockStart.IsZero() {
+		return time.Now()
+	}
+	tick := s.clockTicks.Add(1) - 1
+	return s.clockStart.Add(time.Duration(tick) * s.clockStep)
+}
+
 // generateRequestID creates a random request ID
 func generateRequestID() string {
 	const chars = "abcdef0123456789"
@@ -69,49 +156,81 @@ func generateUserID() string {
 
 // GenerateLog creates a random log entry
 func (s *Service) GenerateLog() LogEntry {
-	levels := []LogLevel{DEBUG, INFO, INFO, INFO, WARNING, ERROR, FATAL}
-	weights := []int{15, 50, 50, 50, 20, 10, 2} // Weighted distribution
+	return s.GenerateFrom(LogEntry{})
+}
 
-	// Weighted random selection
-	totalWeight := 0
-	for _, w := range weights {
-		totalWeight += w
-	}
-	r := rand.Intn(totalWeight)
-	cumulative := 0
-	selectedLevel := INFO
-	for i, w := range weights {
-		cumulative += w
-		if r < cumulative {
-			selectedLevel = levels[i]
-			break
+// GenerateFrom generates an entry shaped by base: fields set in base are
+// kept as they are, and only the empty ones are generated, so a stream
+// can vary in just the fields left out (e.g. a fixed service and
+// message with a fresh timestamp and request ID each time). A generated
+// message matches the entry's level if the generator has messages for
+// it. Extra is copied, so entries don't share base's map.
+func (s *Service) GenerateFrom(base LogEntry) LogEntry {
+	entry := base
+	if base.Extra != nil || s.constant != nil {
+		entry.Extra = make(map[string]any, len(base.Extra)+len(s.constant))
+		for k, v := range s.constant {
+			entry.Extra[k] = v
+		}
+		for k, v := range base.Extra {
+			entry.Extra[k] = v
 		}
 	}
 
-	messages := s.messages[selectedLevel]
-	message := messages[rand.Intn(len(messages))]
-	service := s.services[rand.Intn(len(s.services))]
-
-	entry := LogEntry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
-		Level:     selectedLevel,
-		Service:   service,
-		Message:   message,
-		RequestID: generateRequestID(),
+	if entry.Timestamp == "" {
+		entry.Timestamp = s.now().UTC().Format(time.RFC3339Nano)
+	}
+	if entry.Level == "" {
+		entry.Level = s.randomLevel()
+	}
+	if entry.Service == "" {
+		entry.Service = s.services[rand.Intn(len(s.services))]
+	}
+	if entry.Message == "" {
+		messages := s.messages[entry.Level]
+		if len(messages) == 0 {
+			messages = s.messages[INFO]
+		}
+		entry.Message = messages[rand.Intn(len(messages))]
+	}
+	if entry.RequestID == "" {
+		entry.RequestID = generateRequestID()
 	}
 
 	// Add optional fields based on context
-	if rand.Float32() > 0.3 {
+	if entry.UserID == "" && rand.Float32() > 0.3 {
 		entry.UserID = generateUserID()
 	}
-	if selectedLevel == INFO || selectedLevel == WARNING {
+	if entry.Duration == 0 && (entry.Level == INFO || entry.Level == WARNING) {
 		entry.Duration = rand.Intn(5000) + 1
 	}
 
 	return entry
 }
 
-// GenerateLogs continuously generates logs at the specified interval
+// randomLevel picks a level in proportion to the registered weights
+func (s *Service) randomLevel() LogLevel {
+	totalWeight := 0
+	for _, w := range s.weights {
+		totalWeight += w
+	}
+	if totalWeight == 0 {
+		return INFO
+	}
+	r := rand.Intn(totalWeight)
+	cumulative := 0
+	for i, w := range s.weights {
+		cumulative += w
+		if r < cumulative {
+			return s.levels[i]
+		}
+	}
+	return INFO
+}
+
+// GenerateLogs continuously generates logs at the specified interval.
+// When output is full the send blocks (applying back-pressure) and the
+// event is counted in BlockedSends.
 func (s *Service) GenerateLogs(interval time.Duration, output chan<- LogEntry, done <-chan struct{}) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
@@ -119,16 +238,42 @@ func (s *Service) GenerateLogs(interval time.Duration, output chan<- LogEntry, d
 	for {
 		select {
 		case <-ticker.C:
-			output <- s.GenerateLog()
+			if !s.send(s.GenerateLog(), output, done) {
+				return
+			}
 		case <-done:
 			return
 		}
 	}
 }
 
+// send delivers entry to output, counting a blocked send if the channel
+// is full. It returns false if done closes first.
+func (s *Service) send(entry LogEntry, output chan<- LogEntry, done <-chan struct{}) bool {
+	select {
+	case output <- entry:
+		return true
+	default:
+	}
+
+	s.blockedSends.Add(1)
+	select {
+	case output <- entry:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// BlockedSends returns how many generated entries had to wait because
+// the consumer (e.g. the file writer) was not keeping up
+func (s *Service) BlockedSends() int64 {
+	return s.blockedSends.Load()
+}
+
 // FormatJSON converts a log entry to JSON string
 func (e LogEntry) FormatJSON() string {
-	data, _ := json.Marshal(e)
+	data, _ := Marshal(e)
 	return string(data)
 }
 
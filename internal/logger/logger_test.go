@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// TestGenerateLogsCountsBlockedSends verifies a slow consumer is reported
+func TestGenerateLogsCountsBlockedSends(t *testing.T) {
+	svc := NewService("test")
+	output := make(chan LogEntry, 1)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		svc.GenerateLogs(time.Millisecond, output, done)
+		close(finished)
+	}()
+
+	// Drain slowly so the single-slot buffer stays full
+	for i := 0; i < 5; i++ {
+		<-output
+		time.Sleep(20 * time.Millisecond)
+	}
+	close(done)
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("GenerateLogs did not return after done while blocked")
+	}
+
+	if blocked := svc.BlockedSends(); blocked == 0 {
+		t.Fatal("expected blocked sends with a slow consumer")
+	}
+}
+
+// TestGenerateFrom verifies fields set in the base are kept and only the
+// empty ones are generated
+func TestGenerateFrom(t *testing.T) {
+	svc := NewService("test")
+	base := LogEntry{
+		Level:   ERROR,
+		Service: "billing",
+		Message: "card declined",
+		UserID:  "user-1",
+		Extra:   map[string]any{"region": "eu"},
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		e := svc.GenerateFrom(base)
+		if e.Level != ERROR || e.Service != "billing" || e.Message != "card declined" || e.UserID != "user-1" {
+			t.Fatalf("base fields changed: %+v", e)
+		}
+		if e.Extra["region"] != "eu" {
+			t.Fatalf("Extra not kept: %v", e.Extra)
+		}
+		if err := e.Validate(); err != nil {
+			t.Fatalf("generated entry invalid: %v", err)
+		}
+		if e.RequestID == "" {
+			t.Fatal("request ID not generated")
+		}
+		if e.Duration != 0 {
+			t.Fatalf("duration generated for an ERROR entry: %d", e.Duration)
+		}
+		seen[e.RequestID] = true
+
+		e.Extra["region"] = "us"
+	}
+	if len(seen) < 2 {
+		t.Error("request ID does not vary between entries")
+	}
+	if base.Extra["region"] != "eu" {
+		t.Error("base Extra modified through a generated entry")
+	}
+
+	// A set timestamp is kept as well
+	fixed := LogEntry{Timestamp: "2026-01-01T00:00:00Z", Level: WARNING}
+	if e := svc.GenerateFrom(fixed); e.Timestamp != fixed.Timestamp || e.Level != WARNING || e.Message == "" {
+		t.Errorf("GenerateFrom(%+v) = %+v", fixed, e)
+	}
+}
+
+// TestSetConstantFields verifies constant fields reach every generated
+// entry's JSON, without overriding a base's Extra or known fields
+func TestSetConstantFields(t *testing.T) {
+	svc := NewService("test")
+	svc.SetConstantFields(map[string]any{"env": "staging", "region": "us-east-1", "host": "gen-01", "service": "ignored"})
+
+	for i := 0; i < 20; i++ {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(svc.GenerateLog().FormatJSON()), &fields); err != nil {
+			t.Fatal(err)
+		}
+		if fields["env"] != "staging" || fields["region"] != "us-east-1" || fields["host"] != "gen-01" {
+			t.Fatalf("constant fields missing from %v", fields)
+		}
+		if fields["service"] == "ignored" {
+			t.Fatal("constant field overrode the service")
+		}
+	}
+
+	e := svc.GenerateFrom(LogEntry{Extra: map[string]any{"region": "eu-west-1"}})
+	if e.Extra["region"] != "eu-west-1" || e.Extra["env"] != "staging" {
+		t.Fatalf("base Extra should win over constant fields: %v", e.Extra)
+	}
+
+	svc.SetConstantFields(nil)
+	if e := svc.GenerateLog(); e.Extra != nil {
+		t.Fatalf("Extra after clearing constant fields = %v", e.Extra)
+	}
+}
+
+// TestSetClock verifies generated timestamps start at the clock's start
+// and advance by its step, leaving a base's timestamp alone
+func TestSetClock(t *testing.T) {
+	svc := NewService("test")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc.SetClock(start, 90*time.Second)
+
+	for i := 0; i < 5; i++ {
+		want := start.Add(time.Duration(i) * 90 * time.Second).Format(time.RFC3339Nano)
+		if got := svc.GenerateLog().Timestamp; got != want {
+			t.Fatalf("entry %d timestamp = %s, want %s", i, got, want)
+		}
+	}
+	if e := svc.GenerateFrom(LogEntry{Timestamp: "fixed"}); e.Timestamp != "fixed" {
+		t.Fatalf("base timestamp replaced with %s", e.Timestamp)
+	}
+
+	svc.SetClock(time.Time{}, 0)
+	ts, err := time.Parse(time.RFC3339Nano, svc.GenerateLog().Timestamp)
+	if err != nil || time.Since(ts) > time.Minute {
+		t.Fatalf("timestamp after clearing the clock = %v, %v; want now", ts, err)
+	}
+}
+
+// TestRegisterLevel verifies a custom level is generated with its own
+// messages and severity
+func TestRegisterLevel(t *testing.T) {
+	const trace LogLevel = "TRACE"
+	t.Cleanup(func() {
+		customMu.Lock()
+		defer customMu.Unlock()
+		delete(customSeverities, trace)
+	})
+
+	svc := NewService("test")
+	if err := svc.RegisterLevel(trace, 7, []string{"Entering handler"}, 1000); err != nil {
+		t.Fatalf("RegisterLevel: %v", err)
+	}
+
+	traced := 0
+	for i := 0; i < 200; i++ {
+		e := svc.GenerateLog()
+		if e.Level != trace {
+			continue
+		}
+		traced++
+		if e.Message != "Entering handler" {
+			t.Fatalf("TRACE entry with message %q", e.Message)
+		}
+		if err := e.Validate(); err != nil {
+			t.Fatalf("TRACE entry invalid: %v", err)
+		}
+	}
+	if traced < 100 {
+		t.Fatalf("%d of 200 entries were TRACE at weight 1000 of 1197", traced)
+	}
+	if got := trace.Severity(); got != 7 {
+		t.Errorf("TRACE severity = %d, want 7", got)
+	}
+
+	// A weight of 0 stops the level being generated
+	if err := svc.RegisterLevel(trace, 7, nil, 0); err != nil {
+		t.Fatalf("RegisterLevel: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		if e := svc.GenerateLog(); e.Level == trace {
+			t.Fatal("TRACE generated at weight 0")
+		}
+	}
+
+	for _, bad := range []struct {
+		severity, weight int
+		messages         []string
+	}{
+		{severity: 8, weight: 1, messages: []string{"x"}},
+		{severity: 7, weight: -1, messages: []string{"x"}},
+		{severity: 7, weight: 1},
+	} {
+		if err := svc.RegisterLevel("NOTICE", bad.severity, bad.messages, bad.weight); err == nil {
+			t.Errorf("RegisterLevel(%+v) succeeded", bad)
+		}
+	}
+}
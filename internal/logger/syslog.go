@@ -0,0 +1,332 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// syslogFacility is the facility of formatted entries (user-level)
+	syslogFacility = 1
+
+	// syslogSDID names the structured-data element holding the entry's
+	// other fields. 32473 is the enterprise number reserved for
+	// documentation (RFC 5612).
+	syslogSDID = "log@32473"
+
+	// syslogTimeLayout is RFC 3339 limited to the microsecond precision
+	// RFC 5424 allows
+	syslogTimeLayout = "2006-01-02T15:04:05.999999Z07:00"
+)
+
+// severities maps levels to RFC 5424 severities, most severe first
+var severities = map[LogLevel]int{
+	FATAL:   2, // Critical
+	ERROR:   3, // Error
+	WARNING: 4, // Warning
+	INFO:    6, // Informational
+	DEBUG:   7, // Debug
+}
+
+// customSeverities holds the severities of levels registered with
+// Service.RegisterLevel
+var (
+	customMu         sync.RWMutex
+	customSeverities = map[LogLevel]int{}
+)
+
+// registerSeverity records a custom level's severity
+func registerSeverity(level LogLevel, severity int) error {
+	if severity < 0 || severity > 7 {
+		return fmt.Errorf("level %s: severity %d is not between 0 and 7", level, severity)
+	}
+	if _, builtin := severities[level]; builtin {
+		return nil
+	}
+
+	customMu.Lock()
+	defer customMu.Unlock()
+	customSeverities[level] = severity
+	return nil
+}
+
+// customSeverity returns the severity of a registered custom level
+func customSeverity(level LogLevel) (int, bool) {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	s, ok := customSeverities[level]
+	return s, ok
+}
+
+// Severity returns the level's RFC 5424 severity, from 0 (emergency)
+// to 7 (debug). Custom levels have the severity they were registered
+// with; other unknown levels are 5 (notice).
+func (l LogLevel) Severity() int {
+	if s, ok := severities[l]; ok {
+		return s
+	}
+	if s, ok := customSeverity(l); ok {
+		return s
+	}
+	return 5
+}
+
+// LevelForSeverity maps an RFC 5424 severity back to a level. The
+// severities above critical count as FATAL, and notice as INFO.
+func LevelForSeverity(severity int) LogLevel {
+	switch {
+	case severity <= 2:
+		return FATAL
+	case severity == 3:
+		return ERROR
+	case severity == 4:
+		return WARNING
+	case severity <= 6:
+		return INFO
+	}
+	return DEBUG
+}
+
+// FormatSyslog renders the entry as an RFC 5424 syslog message: level
+// becomes the severity, service the APP-NAME and message the MSG. The
+// remaining fields, including Extra, are parameters of a single
+// structured-data element; non-string Extra values are JSON-encoded.
+func (e LogEntry) FormatSyslog() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s - %s - - ",
+		syslogFacility*8+e.Level.Severity(), syslogTimestamp(e.Timestamp), syslogHeader(e.Service, 48))
+
+	params := e.syslogParams()
+	if len(params) == 0 {
+		b.WriteByte('-')
+	} else {
+		b.WriteString("[" + syslogSDID)
+		for _, p := range params {
+			b.WriteString(" " + p[0] + `="` + escapeSDValue(p[1]) + `"`)
+		}
+		b.WriteByte(']')
+	}
+
+	if e.Message != "" {
+		b.WriteString(" " + e.Message)
+	}
+	return b.String()
+}
+
+// syslogParams lists the structured-data parameters of an entry, known
+// fields first and then Extra keys in sorted order
+func (e LogEntry) syslogParams() [][2]string {
+	var params [][2]string
+	if e.RequestID != "" {
+		params = append(params, [2]string{"request_id", e.RequestID})
+	}
+	if e.UserID != "" {
+		params = append(params, [2]string{"user_id", e.UserID})
+	}
+	if e.Duration != 0 {
+		params = append(params, [2]string{"duration_ms", strconv.Itoa(e.Duration)})
+	}
+
+	keys := make([]string, 0, len(e.Extra))
+	for k := range e.Extra {
+		if validSDName(k) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		value, ok := e.Extra[k].(string)
+		if !ok {
+			data, err := Marshal(e.Extra[k])
+			if err != nil {
+				continue
+			}
+			value = string(data)
+		}
+		params = append(params, [2]string{k, value})
+	}
+	return params
+}
+
+// syslogTimestamp converts an RFC 3339 timestamp to syslog precision,
+// or the nil value if it doesn't parse
+func syslogTimestamp(ts string) string {
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return "-"
+	}
+	return t.Format(syslogTimeLayout)
+}
+
+// syslogHeader makes a header field valid: printable ASCII without
+// spaces, at most max characters, and "-" when empty
+func syslogHeader(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+
+	b := []byte(s)
+	for i, c := range b {
+		if c <= ' ' || c > '~' {
+			b[i] = '_'
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
+
+// validSDName reports whether a key can be a structured-data parameter
+// name: 1 to 32 printable ASCII characters other than '=', ' ', ']'
+// and '"'
+func validSDName(name string) bool {
+	if name == "" || len(name) > 32 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c <= ' ' || c > '~' || c == '=' || c == ']' || c == '"' {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeSDValue escapes the characters RFC 5424 requires in parameter
+// values
+func escapeSDValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(v)
+}
+
+// ParseSyslog parses an RFC 5424 syslog message, such as one produced
+// by FormatSyslog. Parameters of any structured-data element fill the
+// known fields they name and otherwise become string Extra values.
+func ParseSyslog(line string) (LogEntry, error) {
+	rest, ok := strings.CutPrefix(line, "<")
+	if !ok {
+		return LogEntry{}, fmt.Errorf("syslog line must start with '<': %q", line)
+	}
+	end := strings.IndexByte(rest, '>')
+	if end < 1 || end > 3 {
+		return LogEntry{}, fmt.Errorf("syslog line has a malformed priority: %q", line)
+	}
+	pri, err := strconv.Atoi(rest[:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return LogEntry{}, fmt.Errorf("syslog line has a malformed priority: %q", line)
+	}
+	rest, ok = strings.CutPrefix(rest[end+1:], "1 ")
+	if !ok {
+		return LogEntry{}, fmt.Errorf("syslog line is not RFC 5424 (version 1): %q", line)
+	}
+
+	// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID
+	header := strings.SplitN(rest, " ", 6)
+	if len(header) != 6 {
+		return LogEntry{}, fmt.Errorf("syslog line has a truncated header: %q", line)
+	}
+
+	entry := LogEntry{Level: LevelForSeverity(pri % 8)}
+	if header[0] != "-" {
+		entry.Timestamp = header[0]
+	}
+	if header[2] != "-" {
+		entry.Service = header[2]
+	}
+
+	rest = header[5]
+	if r, ok := strings.CutPrefix(rest, "-"); ok {
+		rest = r
+	} else {
+		if rest, err = entry.parseStructuredData(rest); err != nil {
+			return LogEntry{}, fmt.Errorf("%w: %q", err, line)
+		}
+	}
+
+	if msg, ok := strings.CutPrefix(rest, " "); ok {
+		entry.Message = strings.TrimPrefix(msg, "\uFEFF") // Optional BOM
+	} else if rest != "" {
+		return LogEntry{}, fmt.Errorf("syslog line has trailing data after structured data: %q", line)
+	}
+	return entry, nil
+}
+
+// errBadStructuredData is returned for malformed structured data
+var errBadStructuredData = errors.New("syslog line has malformed structured data")
+
+// parseStructuredData consumes one or more SD elements from s into the
+// entry and returns what follows them
+func (e *LogEntry) parseStructuredData(s string) (string, error) {
+	for strings.HasPrefix(s, "[") {
+		s = s[1:]
+
+		// SD-ID
+		i := strings.IndexAny(s, " ]")
+		if i < 1 {
+			return "", errBadStructuredData
+		}
+		s = s[i:]
+
+		for strings.HasPrefix(s, " ") {
+			eq := strings.Index(s, `="`)
+			if eq < 2 {
+				return "", errBadStructuredData
+			}
+			name := s[1:eq]
+			s = s[eq+2:]
+
+			var value strings.Builder
+			closed := false
+			for j := 0; j < len(s); j++ {
+				c := s[j]
+				if c == '\\' && j+1 < len(s) && strings.IndexByte(`\"]`, s[j+1]) >= 0 {
+					value.WriteByte(s[j+1])
+					j++
+					continue
+				}
+				if c == '"' {
+					s = s[j+1:]
+					closed = true
+					break
+				}
+				value.WriteByte(c)
+			}
+			if !closed {
+				return "", errBadStructuredData
+			}
+			e.setSyslogParam(name, value.String())
+		}
+
+		var ok bool
+		if s, ok = strings.CutPrefix(s, "]"); !ok {
+			return "", errBadStructuredData
+		}
+	}
+	return s, nil
+}
+
+// setSyslogParam stores a structured-data parameter on the entry
+func (e *LogEntry) setSyslogParam(name, value string) {
+	switch name {
+	case "request_id":
+		e.RequestID = value
+	case "user_id":
+		e.UserID = value
+	case "duration_ms":
+		if d, err := strconv.Atoi(value); err == nil {
+			e.Duration = d
+			return
+		}
+		fallthrough
+	default:
+		if e.Extra == nil {
+			e.Extra = make(map[string]any)
+		}
+		e.Extra[name] = value
+	}
+}
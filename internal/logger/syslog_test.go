@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestSyslogRoundTrip verifies entries survive FormatSyslog/ParseSyslog
+func TestSyslogRoundTrip(t *testing.T) {
+	entries := []LogEntry{
+		{Timestamp: "2026-01-01T16:38:14.328717Z", Level: INFO, Service: "payment-service", Message: "File uploaded", RequestID: "req-53b55783"},
+		{Timestamp: "2026-01-01T16:38:14.8+02:00", Level: FATAL, Service: "auth-service", Message: `quote " bracket ] backslash \ done`,
+			UserID: "user-7", Duration: 250, Extra: map[string]any{"region": "eu-west-1", "note": `a "b" [c]`}},
+		{Level: DEBUG, Service: "worker", Message: ""},
+		{Timestamp: "2026-01-01T00:00:00Z", Level: WARNING, Message: "no service"},
+	}
+
+	for _, want := range entries {
+		line := want.FormatSyslog()
+		got, err := ParseSyslog(line)
+		if err != nil {
+			t.Fatalf("ParseSyslog(%q): %v", line, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("round trip of %q:\ngot  %+v\nwant %+v", line, got, want)
+		}
+
+		// The generic format plumbing agrees
+		if viaFormat, err := Parse([]byte(want.FormatAs(Syslog)), Syslog); err != nil || !reflect.DeepEqual(viaFormat, want) {
+			t.Errorf("Parse(FormatAs(Syslog)) = %+v, %v", viaFormat, err)
+		}
+	}
+}
+
+// TestSyslogFormat checks the exact encoding of an entry
+func TestSyslogFormat(t *testing.T) {
+	e := LogEntry{
+		Timestamp: "2026-01-01T16:38:14.123456789Z",
+		Level:     ERROR,
+		Service:   "my service",
+		Message:   "boom",
+		RequestID: "req-1",
+		Extra:     map[string]any{"attempt": 3, "bad name": "skipped"},
+	}
+	want := `<11>1 2026-01-01T16:38:14.123456Z - my_service - - [log@32473 request_id="req-1" attempt="3"] boom`
+	if got := e.FormatSyslog(); got != want {
+		t.Fatalf("FormatSyslog() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestSyslogSeverity verifies the level/severity mapping both ways
+func TestSyslogSeverity(t *testing.T) {
+	for level, severity := range map[LogLevel]int{FATAL: 2, ERROR: 3, WARNING: 4, INFO: 6, DEBUG: 7, "TRACE": 5} {
+		if got := level.Severity(); got != severity {
+			t.Errorf("%s.Severity() = %d, want %d", level, got, severity)
+		}
+	}
+
+	// More severe levels have lower severity numbers
+	order := []LogLevel{FATAL, ERROR, WARNING, INFO, DEBUG}
+	for i := 1; i < len(order); i++ {
+		if order[i-1].Severity() >= order[i].Severity() {
+			t.Errorf("%s is not more severe than %s", order[i-1], order[i])
+		}
+	}
+
+	want := []LogLevel{FATAL, FATAL, FATAL, ERROR, WARNING, INFO, INFO, DEBUG}
+	for severity, level := range want {
+		if got := LevelForSeverity(severity); got != level {
+			t.Errorf("LevelForSeverity(%d) = %s, want %s", severity, got, level)
+		}
+	}
+}
+
+// TestParseSyslogForeign parses messages from other syslog producers
+func TestParseSyslogForeign(t *testing.T) {
+	// RFC 5424 section 6.5, example 3 (with a BOM before the message)
+	line := `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high"] ` + "\uFEFF" + `An application event log entry...`
+	got, err := ParseSyslog(line)
+	if err != nil {
+		t.Fatalf("ParseSyslog: %v", err)
+	}
+	want := LogEntry{
+		Timestamp: "2003-10-11T22:14:15.003Z",
+		Level:     INFO, // Notice
+		Service:   "evntslog",
+		Message:   "An application event log entry...",
+		Extra:     map[string]any{"iut": "3", "eventSource": "Application", "eventID": "1011", "class": "high"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseSyslog() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	for _, bad := range []string{
+		"",
+		"not syslog",
+		"<999>1 - - - - - -",
+		"<13> 2003-10-11T22:14:15.003Z host app - - -",
+		"<13>1 2003-10-11T22:14:15.003Z host",
+		`<13>1 - - - - - [id a="unterminated] msg`,
+		`<13>1 - - - - - [id a="1"`,
+	} {
+		if _, err := ParseSyslog(bad); err == nil {
+			t.Errorf("ParseSyslog(%q) succeeded", bad)
+		}
+	}
+
+	if !strings.HasPrefix(LogEntry{Level: INFO}.FormatSyslog(), "<14>1 - - - - - -") {
+		t.Errorf("empty entry: %q", LogEntry{Level: INFO}.FormatSyslog())
+	}
+}
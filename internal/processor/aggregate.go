@@ -0,0 +1,181 @@
+package processor
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// WindowKeyFunc picks the aggregation key of a record (e.g. its
+// service), or reports false to leave the record out
+type WindowKeyFunc func(record *LogRecord) (key string, ok bool)
+
+// WindowResult is the per-key record count of one closed window
+type WindowResult struct {
+	Start  time.Time // Inclusive
+	End    time.Time // Exclusive
+	Counts map[string]int64
+}
+
+// WindowEmitFunc receives each window once it closes
+type WindowEmitFunc func(WindowResult)
+
+// WindowedAggregator counts records per key over event-time windows,
+// using each record's timestamp rather than when it is processed.
+// Windows are tumbling by default, or sliding with SetSlide.
+//
+// The watermark trails the latest timestamp seen by the allowed
+// lateness (SetAllowedLateness, default 0). A window closes, and is
+// emitted, once the watermark reaches its end; records arriving for a
+// closed window are dropped and counted by Late. Since the watermark
+// only moves with new records, call Flush at the end of the input to
+// emit the windows still open.
+//
+// Add is safe for concurrent use, e.g. from a ProcessFunc with several
+// workers, but workers interleave records so the allowed lateness
+// should cover how far apart they read.
+type WindowedAggregator struct {
+	size     time.Duration
+	slide    time.Duration
+	lateness time.Duration
+	key      WindowKeyFunc
+	emit     WindowEmitFunc
+
+	mu        sync.Mutex
+	windows   map[int64]map[string]int64 // Open windows by start (Unix nanoseconds)
+	maxEvent  time.Time                  // Latest timestamp seen
+	watermark time.Time                  // Windows ending at or before this are closed
+	late      int64
+	untimed   int64
+}
+
+// NewWindowedAggregator creates an aggregator with tumbling windows of
+// the given size. Each closed window with at least one record is
+// passed to emit, in order of start time; emit runs with the
+// aggregator locked and must not call back into it.
+func NewWindowedAggregator(size time.Duration, key WindowKeyFunc, emit WindowEmitFunc) *WindowedAggregator {
+	if size <= 0 {
+		panic("processor: window size must be positive")
+	}
+
+	return &WindowedAggregator{
+		size:    size,
+		slide:   size,
+		key:     key,
+		emit:    emit,
+		windows: make(map[int64]map[string]int64),
+	}
+}
+
+// SetSlide makes windows sliding: a new window starts every slide, so
+// each record counts towards size/slide windows. Slide must be
+// positive and no larger than the window size.
+func (a *WindowedAggregator) SetSlide(slide time.Duration) {
+	if slide <= 0 || slide > a.size {
+		panic("processor: window slide must be positive and at most the window size")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.slide = slide
+}
+
+// SetAllowedLateness sets how far behind the latest timestamp a record
+// may be and still be counted
+func (a *WindowedAggregator) SetAllowedLateness(d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.lateness = max(d, 0)
+}
+
+// Add counts a record in every window containing its timestamp, then
+// emits the windows the advanced watermark closes. Records without a
+// parseable RFC3339 timestamp are skipped and counted by Untimed.
+func (a *WindowedAggregator) Add(record *LogRecord) {
+	key, ok := a.key(record)
+	if !ok {
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	ts, err := time.Parse(time.RFC3339Nano, record.Entry.Timestamp)
+	if err != nil {
+		a.untimed++
+		return
+	}
+
+	counted := false
+	for start := ts.Truncate(a.slide); start.Add(a.size).After(ts); start = start.Add(-a.slide) {
+		if !a.watermark.IsZero() && !start.Add(a.size).After(a.watermark) {
+			break // This and earlier windows are closed
+		}
+		w := a.windows[start.UnixNano()]
+		if w == nil {
+			w = make(map[string]int64)
+			a.windows[start.UnixNano()] = w
+		}
+		w[key]++
+		counted = true
+	}
+	if !counted {
+		a.late++
+		return
+	}
+
+	if ts.After(a.maxEvent) {
+		a.maxEvent = ts
+		if watermark := ts.Add(-a.lateness); watermark.After(a.watermark) {
+			a.watermark = watermark
+			a.emitClosed(false)
+		}
+	}
+}
+
+// Flush emits every open window, e.g. once the input is exhausted.
+// Windows are closed for good: later records for them count as late.
+func (a *WindowedAggregator) Flush() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.emitClosed(true)
+	if a.maxEvent.After(a.watermark) {
+		// Close every window that could have held a record seen so far
+		a.watermark = a.maxEvent.Truncate(a.slide).Add(a.size)
+	}
+}
+
+// emitClosed emits and forgets the windows ending at or before the
+// watermark, or all of them
+func (a *WindowedAggregator) emitClosed(all bool) {
+	starts := make([]int64, 0, len(a.windows))
+	for start := range a.windows {
+		end := time.Unix(0, start).Add(a.size)
+		if all || !end.After(a.watermark) {
+			starts = append(starts, start)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	for _, start := range starts {
+		begin := time.Unix(0, start).UTC()
+		a.emit(WindowResult{Start: begin, End: begin.Add(a.size), Counts: a.windows[start]})
+		delete(a.windows, start)
+	}
+}
+
+// Late returns how many records arrived after their windows closed
+func (a *WindowedAggregator) Late() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.late
+}
+
+// Untimed returns how many records were skipped for lacking a
+// parseable timestamp
+func (a *WindowedAggregator) Untimed() int64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.untimed
+}
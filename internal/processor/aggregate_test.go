@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"log-processor/internal/logger"
+)
+
+// timedRecord builds a record with the given timestamp, level and service
+func timedRecord(ts string, level logger.LogLevel, service string) *LogRecord {
+	return &LogRecord{Entry: logger.LogEntry{Timestamp: ts, Level: level, Service: service}}
+}
+
+// errorsByService keys ERROR records by service
+func errorsByService(r *LogRecord) (string, bool) {
+	return r.Entry.Service, r.Entry.Level == logger.ERROR
+}
+
+// TestWindowedAggregatorTumbling verifies per-window counts, that a
+// window is emitted only once the watermark passes its end, and that
+// records later than the allowed lateness are dropped
+func TestWindowedAggregatorTumbling(t *testing.T) {
+	var emitted []WindowResult
+	agg := NewWindowedAggregator(time.Minute, errorsByService, func(w WindowResult) {
+		emitted = append(emitted, w)
+	})
+	agg.SetAllowedLateness(10 * time.Second)
+
+	steps := []struct {
+		record *LogRecord
+		emits  int // Windows emitted so far
+	}{
+		{timedRecord("2026-01-01T00:00:10Z", logger.ERROR, "api"), 0},
+		{timedRecord("2026-01-01T00:00:30Z", logger.ERROR, "db"), 0},
+		{timedRecord("2026-01-01T00:00:50Z", logger.INFO, "api"), 0},
+		{timedRecord("2026-01-01T00:01:05Z", logger.ERROR, "api"), 0}, // Watermark 00:00:55
+		{timedRecord("2026-01-01T00:00:58Z", logger.ERROR, "api"), 0}, // Out of order, within lateness
+		{timedRecord("2026-01-01T00:01:12Z", logger.ERROR, "db"), 1},  // Watermark 00:01:02 closes the first window
+		{timedRecord("2026-01-01T00:00:59Z", logger.ERROR, "api"), 1}, // Too late
+		{timedRecord("not a time", logger.ERROR, "api"), 1},
+	}
+	for i, step := range steps {
+		agg.Add(step.record)
+		if len(emitted) != step.emits {
+			t.Fatalf("after record %d: %d windows emitted, want %d", i, len(emitted), step.emits)
+		}
+	}
+
+	agg.Flush()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	want := []WindowResult{
+		{Start: base, End: base.Add(time.Minute), Counts: map[string]int64{"api": 2, "db": 1}},
+		{Start: base.Add(time.Minute), End: base.Add(2 * time.Minute), Counts: map[string]int64{"api": 1, "db": 1}},
+	}
+	if !reflect.DeepEqual(emitted, want) {
+		t.Fatalf("emitted %+v, want %+v", emitted, want)
+	}
+	if agg.Late() != 1 || agg.Untimed() != 1 {
+		t.Fatalf("late=%d untimed=%d, want 1 and 1", agg.Late(), agg.Untimed())
+	}
+}
+
+// TestWindowedAggregatorSliding verifies a record counts in every
+// overlapping window
+func TestWindowedAggregatorSliding(t *testing.T) {
+	var emitted []WindowResult
+	agg := NewWindowedAggregator(time.Minute, errorsByService, func(w WindowResult) {
+		emitted = append(emitted, w)
+	})
+	agg.SetSlide(30 * time.Second)
+
+	agg.Add(timedRecord("2026-01-01T00:00:10Z", logger.ERROR, "api"))
+	agg.Add(timedRecord("2026-01-01T00:00:40Z", logger.ERROR, "api"))
+	agg.Flush()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var got []string
+	for _, w := range emitted {
+		got = append(got, fmt.Sprintf("%s=%d", w.Start.Sub(base), w.Counts["api"]))
+	}
+	want := []string{"-30s=1", "0s=2", "30s=1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("windows %q, want %q", got, want)
+	}
+}
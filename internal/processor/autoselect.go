@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"sort"
+	"time"
+
+	"log-processor/internal/logger"
+)
+
+// Bounds on the AutoSelectParser benchmark
+const (
+	parserSampleLines  = 200
+	parserSampleRounds = 5
+)
+
+// measureParser times one backend on the sample; tests replace it to
+// make selection deterministic
+var measureParser = func(b logger.Backend, sample [][]byte) time.Duration {
+	return logger.MeasureUnmarshal(b, sample, parserSampleRounds)
+}
+
+// selectParser benchmarks the backends on a sample of the input and
+// selects the fastest for decoding. Without any input to sample, the
+// current backend is kept.
+func (p *Processor) selectParser() {
+	sample := p.sampleLines(parserSampleLines)
+	if len(sample) == 0 {
+		return
+	}
+
+	if b := logger.Fastest(sample, measureParser); b != nil {
+		logger.SetUnmarshaler(b)
+		p.selectedParser = b.Name()
+		p.log.Info("selected JSON backend", "backend", b.Name(), "sample", len(sample))
+	}
+}
+
+// sampleLines reads up to n parseable lines from the oldest segments.
+// Stream segments are skipped, since reading them would consume data.
+func (p *Processor) sampleLines(n int) [][]byte {
+	infos, err := p.source.List()
+	if err != nil {
+		return nil
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	var sample [][]byte
+	for _, info := range infos {
+		if info.Stream {
+			continue
+		}
+
+		rc, err := p.source.Open(info.Name, 0)
+		if err != nil {
+			continue
+		}
+		reader := NewLogReaderFrom(rc, info.Path, 0, p.cfg.readerOptions())
+		for len(sample) < n {
+			record, err := reader.Read()
+			if err != nil {
+				break
+			}
+			if record.ParseErr == nil {
+				sample = append(sample, trimDelimiter(record.Raw, p.cfg.delimiter()))
+			}
+		}
+		reader.Close()
+
+		if len(sample) >= n {
+			break
+		}
+	}
+	return sample
+}
+
+// SelectedParser returns the JSON backend chosen by AutoSelectParser,
+// or "" if no selection was made
+func (p *Processor) SelectedParser() string {
+	return p.selectedParser
+}
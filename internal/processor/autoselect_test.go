@@ -0,0 +1,200 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"log-processor/internal/logger"
+)
+
+// countingBackend wraps a backend, counting decodes
+type countingBackend struct {
+	logger.Backend
+	name    string // Defaults to "counting"
+	decodes atomic.Int64
+}
+
+func (c *countingBackend) Name() string {
+	if c.name == "" {
+		return "counting"
+	}
+	return c.name
+}
+
+func (c *countingBackend) Unmarshal(data []byte, v any) error {
+	c.decodes.Add(1)
+	return c.Backend.Unmarshal(data, v)
+}
+
+// TestAutoSelectParser verifies the fastest backend is selected and
+// then used to decode records
+func TestAutoSelectParser(t *testing.T) {
+	defer logger.SetBackend(logger.GoJSON)
+
+	fastest := &countingBackend{Backend: logger.GoJSON}
+	logger.RegisterBackend(fastest)
+	t.Cleanup(func() { logger.UnregisterBackend(fastest.Name()) })
+
+	// Deterministic benchmark in which the counting backend wins
+	defer func(orig func(logger.Backend, [][]byte) time.Duration) { measureParser = orig }(measureParser)
+	var sampled int
+	measureParser = func(b logger.Backend, sample [][]byte) time.Duration {
+		sampled = len(sample)
+		if b == fastest {
+			return time.Millisecond
+		}
+		return time.Second
+	}
+
+	cfg := newTestConfig(t, 1)
+	cfg.AutoSelectParser = true
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"level":"INFO","message":"a"}`, `not json`, `{"level":"INFO","message":"b"}`,
+	)
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	if got := proc.SelectedParser(); got != "counting" {
+		t.Fatalf("SelectedParser() = %q, want counting", got)
+	}
+	if sampled != 2 {
+		t.Fatalf("benchmark sampled %d lines, want the 2 parseable ones", sampled)
+	}
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+	if fastest.decodes.Load() < 3 {
+		t.Fatalf("selected backend decoded %d records, want all 3", fastest.decodes.Load())
+	}
+}
+
+// TestParserBackend verifies a named backend decodes the records and
+// that unknown names are rejected
+func TestParserBackend(t *testing.T) {
+	defer logger.SetBackend(logger.GoJSON)
+
+	named := &countingBackend{Backend: logger.GoJSON, name: "named"}
+	logger.RegisterBackend(named)
+	t.Cleanup(func() { logger.UnregisterBackend(named.Name()) })
+
+	cfg := newTestConfig(t, 1)
+	cfg.ParserBackend = "named"
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"level":"INFO","message":"a"}`, `{"level":"INFO","message":"b"}`,
+	)
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+	if named.decodes.Load() < 2 {
+		t.Fatalf("named backend decoded %d records, want both", named.decodes.Load())
+	}
+
+	cfg.ParserBackend = "no-such-backend"
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected an unknown ParserBackend to fail validation")
+	}
+}
+
+// BenchmarkReaderBackends measures the whole read path (file I/O, line
+// scanning and parsing) of a generated 50k-record segment with each
+// supported JSON backend, reporting records/s and allocations per
+// record. Build with -tags sonic to include sonic.
+func BenchmarkReaderBackends(b *testing.B) {
+	defer logger.SetBackend(logger.GoJSON)
+
+	const records = 50000
+	path := filepath.Join(b.TempDir(), "app.log.20260101-000000")
+	f, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	svc := logger.NewService("bench")
+	var size int64
+	for i := 0; i < records; i++ {
+		n, _ := w.WriteString(svc.GenerateLog().FormatJSON() + "\n")
+		size += int64(n)
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	f.Close()
+
+	for _, backend := range logger.Backends() {
+		if !logger.Supported(backend) {
+			continue
+		}
+		b.Run(backend.Name(), func(b *testing.B) {
+			logger.SetUnmarshaler(backend)
+			b.SetBytes(size)
+			b.ReportAllocs()
+
+			var before, after runtime.MemStats
+			runtime.ReadMemStats(&before)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if n := readRecords(b, path); n != records {
+					b.Fatalf("read %d records, want %d", n, records)
+				}
+			}
+			b.StopTimer()
+			runtime.ReadMemStats(&after)
+
+			total := float64(b.N) * records
+			b.ReportMetric(total/b.Elapsed().Seconds(), "records/s")
+			b.ReportMetric(float64(after.Mallocs-before.Mallocs)/total, "allocs/record")
+		})
+	}
+}
+
+// readRecords reads a segment through a LogReader, failing on records
+// that don't parse, and returns how many it read
+func readRecords(b *testing.B, path string) int {
+	reader, err := NewLogReaderWithOptions(path, 0, DefaultReaderOptions())
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer reader.Close()
+
+	n := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return n
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+		if record.ParseErr != nil {
+			b.Fatalf("line %d: %v", record.LineNumber, record.ParseErr)
+		}
+		n++
+	}
+}
@@ -0,0 +1,78 @@
+package processor
+
+import (
+	"hash/maphash"
+	"math"
+	"strconv"
+	"sync/atomic"
+)
+
+const (
+	// defaultExpectedRecords sizes the duplicate filter when
+	// Config.ExpectedRecords is unset
+	defaultExpectedRecords = 1_000_000
+
+	// duplicateFalsePositiveRate is the target chance that a record
+	// never delivered is taken for a duplicate (and dropped) once the
+	// filter holds its expected number of records
+	duplicateFalsePositiveRate = 1e-6
+)
+
+// bloomFilter is a fixed-size, concurrency-safe Bloom filter. It never
+// reports a false negative; false positives grow past the expected
+// count it was sized for.
+type bloomFilter struct {
+	bits  []atomic.Uint64
+	m     uint64 // Number of bits
+	k     int    // Hashes per key
+	seeds [2]maphash.Seed
+}
+
+// newBloomFilter sizes a filter for n keys at false-positive rate fp
+func newBloomFilter(n int, fp float64) *bloomFilter {
+	m := uint64(math.Ceil(-float64(n) * math.Log(fp) / (math.Ln2 * math.Ln2)))
+	m = max(m, 64)
+	k := int(math.Round(float64(m) / float64(n) * math.Ln2))
+	k = max(k, 1)
+
+	return &bloomFilter{
+		bits:  make([]atomic.Uint64, (m+63)/64),
+		m:     m,
+		k:     k,
+		seeds: [2]maphash.Seed{maphash.MakeSeed(), maphash.MakeSeed()},
+	}
+}
+
+// locations derives the key's k bit positions by double hashing
+func (b *bloomFilter) locations(key string, fn func(bit uint64) bool) {
+	h1 := maphash.String(b.seeds[0], key)
+	h2 := maphash.String(b.seeds[1], key) | 1
+	for i := 0; i < b.k; i++ {
+		if !fn((h1 + uint64(i)*h2) % b.m) {
+			return
+		}
+	}
+}
+
+// add inserts a key
+func (b *bloomFilter) add(key string) {
+	b.locations(key, func(bit uint64) bool {
+		b.bits[bit/64].Or(1 << (bit % 64))
+		return true
+	})
+}
+
+// contains reports whether a key may have been added
+func (b *bloomFilter) contains(key string) bool {
+	found := true
+	b.locations(key, func(bit uint64) bool {
+		found = b.bits[bit/64].Load()&(1<<(bit%64)) != 0
+		return found
+	})
+	return found
+}
+
+// recordKey identifies a record within a run by segment and position
+func recordKey(segment string, record *LogRecord) string {
+	return segment + ":" + strconv.FormatInt(record.Offset, 10)
+}
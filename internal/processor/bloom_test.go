@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestBloomFilter verifies there are no false negatives and false
+// positives stay rare at the sized count
+func TestBloomFilter(t *testing.T) {
+	const n = 10000
+	b := newBloomFilter(n, 1e-4)
+
+	for i := 0; i < n; i++ {
+		b.add(fmt.Sprintf("seg:%d", i))
+	}
+	for i := 0; i < n; i++ {
+		if !b.contains(fmt.Sprintf("seg:%d", i)) {
+			t.Fatalf("seg:%d added but not found", i)
+		}
+	}
+
+	var falsePositives int
+	for i := n; i < 11*n; i++ {
+		if b.contains(fmt.Sprintf("seg:%d", i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 50 { // ~10 expected
+		t.Fatalf("%d false positives in %d lookups", falsePositives, 10*n)
+	}
+}
+
+// TestSuppressDuplicates verifies records are not redelivered when a
+// segment is retried from an earlier offset in the same run
+func TestSuppressDuplicates(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.SuppressDuplicates = true
+	cfg.ExpectedRecords = 1000
+
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf(`{"message":"line %d"}`, i))
+	}
+	const name = "app.log.20260101-000000"
+	writeSegment(t, cfg.LogsDir, name, lines...)
+
+	var mu sync.Mutex
+	delivered := make(map[string]int)
+	proc, err := NewProcessor(cfg, func(record *LogRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered[record.Entry.Message]++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	complete := func() bool {
+		_, _, stats := proc.Stats()
+		return stats[3] == 1
+	}
+	waitFor(t, 2*time.Second, complete)
+
+	// Simulate a retry: rewind the segment and requeue it
+	if err := proc.offsetMgr.ForceCommitOffset(name, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	proc.segmentMgr.ReleaseSegment(name)
+	waitFor(t, 2*time.Second, func() bool {
+		return proc.Duplicates() == int64(len(lines)) && complete()
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(delivered) != len(lines) {
+		t.Fatalf("delivered %d distinct records, want %d", len(delivered), len(lines))
+	}
+	for msg, n := range delivered {
+		if n != 1 {
+			t.Errorf("%q delivered %d times", msg, n)
+		}
+	}
+	if processed, _, _ := proc.Stats(); processed != int64(len(lines)) {
+		t.Errorf("processed = %d, want %d", processed, len(lines))
+	}
+}
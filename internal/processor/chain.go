@@ -0,0 +1,196 @@
+package processor
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"log-processor/internal/rotation"
+)
+
+// ChainPosition is a position in a rotation chain: a byte offset within
+// a segment. The active file's segment name is the base pattern itself.
+type ChainPosition struct {
+	Segment string `json:"segment"`
+	Offset  int64  `json:"offset"`
+}
+
+// ChainReader reads a log's whole rotation chain as one ordered stream:
+// the rotated segments oldest first (in name order, as the processor
+// schedules them), then the active file. At the end of a segment it
+// moves on to the next, and at the end of the active file Read returns
+// io.EOF until more is written, so callers poll to follow the log.
+//
+// Rotation is handled transparently. When the active file is renamed,
+// the reader drains it under its rotated name before moving on to the
+// rotated segments after it and the new active file, so no record is
+// skipped or read twice. Position can be saved and passed to
+// NewChainReader to resume later.
+type ChainReader struct {
+	source *FileSource
+	base   string
+	active string // Active file path
+	opts   ReaderOptions
+
+	pos    ChainPosition
+	reader *LogReader
+	file   os.FileInfo // Identity of the open active file, nil otherwise
+}
+
+// NewChainReader creates a reader for the rotation chain of base in
+// dir, starting at from. The zero position starts at the oldest
+// segment; a segment that no longer exists (e.g. deleted after
+// archiving) resumes at the next one after it. A position in the active
+// file is only meaningful while that file has not been rotated.
+func NewChainReader(dir, base string, scheme rotation.Scheme, from ChainPosition, opts ReaderOptions) *ChainReader {
+	return &ChainReader{
+		source: NewSchemeFileSource(dir, base, scheme),
+		base:   base,
+		active: filepath.Join(dir, base),
+		opts:   opts,
+		pos:    from,
+	}
+}
+
+// Read returns the next record of the chain, or io.EOF once the active
+// file has been read to its end
+func (c *ChainReader) Read() (*LogRecord, error) {
+	for {
+		if c.reader == nil {
+			if err := c.open(); err != nil {
+				return nil, err
+			}
+		}
+
+		record, err := c.reader.Read()
+		if err == nil {
+			c.pos.Offset = c.reader.Offset()
+			return record, nil
+		}
+		if err != io.EOF {
+			return nil, err
+		}
+
+		if c.file != nil {
+			// At the end of the active file: continue only if it has
+			// been rotated, finishing it under its new name
+			name, ok := c.rotatedName()
+			if !ok {
+				return nil, io.EOF
+			}
+			c.pos.Segment, c.file = name, nil
+			c.reader.ReleasePartial()
+			continue
+		}
+
+		// At the end of a rotated segment
+		next, err := c.next(c.pos.Segment)
+		if err != nil {
+			return nil, err
+		}
+		c.reader.Close()
+		c.reader = nil
+		c.pos = ChainPosition{Segment: next}
+	}
+}
+
+// open opens the segment at the current position, moving past one that
+// no longer exists
+func (c *ChainReader) open() error {
+	if c.pos.Segment == "" {
+		first, err := c.next("")
+		if err != nil {
+			return err
+		}
+		c.pos = ChainPosition{Segment: first}
+	}
+
+	if c.pos.Segment == c.base {
+		file, err := openSegment(c.active, c.pos.Offset)
+		if os.IsNotExist(err) {
+			return io.EOF // Not created yet, or mid-rotation
+		}
+		if err != nil {
+			return err
+		}
+		info, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return err
+		}
+
+		opts := c.opts
+		opts.HoldPartial = true
+		c.reader = NewLogReaderFrom(file, c.active, c.pos.Offset, opts)
+		c.file = info
+		return nil
+	}
+
+	rc, err := c.source.Open(c.pos.Segment, c.pos.Offset)
+	if os.IsNotExist(err) {
+		next, err := c.next(c.pos.Segment)
+		if err != nil {
+			return err
+		}
+		c.pos = ChainPosition{Segment: next}
+		return c.open()
+	}
+	if err != nil {
+		return err
+	}
+	c.reader = NewLogReaderFrom(rc, filepath.Join(c.source.dir, c.pos.Segment), c.pos.Offset, c.opts)
+	return nil
+}
+
+// next returns the segment following after in the chain: the oldest
+// rotated segment named after it, or else the active file
+func (c *ChainReader) next(after string) (string, error) {
+	infos, err := c.source.List()
+	if err != nil {
+		return "", err
+	}
+
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	for _, info := range infos {
+		if after == "" || info.Name > after {
+			return info.Name, nil
+		}
+	}
+	return c.base, nil
+}
+
+// rotatedName finds the rotated segment the open active file was
+// renamed to, if it has been rotated
+func (c *ChainReader) rotatedName() (string, bool) {
+	if stat, err := os.Stat(c.active); err == nil && os.SameFile(c.file, stat) {
+		return "", false
+	}
+
+	infos, err := c.source.List()
+	if err != nil {
+		return "", false
+	}
+	for _, info := range infos {
+		stat, err := os.Stat(info.Path)
+		if err == nil && os.SameFile(c.file, stat) {
+			return info.Name, true
+		}
+	}
+	return "", false
+}
+
+// Position returns the position after the last record read
+func (c *ChainReader) Position() ChainPosition {
+	return c.pos
+}
+
+// Close closes the open segment
+func (c *ChainReader) Close() error {
+	if c.reader == nil {
+		return nil
+	}
+	err := c.reader.Close()
+	c.reader = nil
+	return err
+}
@@ -0,0 +1,92 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"log-processor/internal/rotation"
+)
+
+// readChain reads records until io.EOF, returning their messages
+func readChain(t *testing.T, c *ChainReader) []string {
+	t.Helper()
+
+	var messages []string
+	for {
+		record, err := c.Read()
+		if err == io.EOF {
+			return messages
+		}
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		messages = append(messages, record.Entry.Message)
+	}
+}
+
+// TestChainReader verifies three rotated segments and the active file
+// read as one ordered, gapless stream that follows a rotation and
+// resumes from a saved position
+func TestChainReader(t *testing.T) {
+	dir := t.TempDir()
+	var want []string
+	for i := 0; i < 3; i++ {
+		a, b := fmt.Sprintf("seg%d-a", i), fmt.Sprintf("seg%d-b", i)
+		appendLines(t, filepath.Join(dir, fmt.Sprintf("app.log.20260101-00000%d", i)), a, b)
+		want = append(want, a, b)
+	}
+	active := filepath.Join(dir, "app.log")
+	appendLines(t, active, "active-a", "active-b")
+	want = append(want, "active-a", "active-b")
+
+	c := NewChainReader(dir, "app.log", rotation.Default(), ChainPosition{}, DefaultReaderOptions())
+	defer c.Close()
+
+	got := readChain(t, c)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("read %v, want %v", got, want)
+	}
+	if pos := c.Position(); pos.Segment != "app.log" {
+		t.Fatalf("position %+v after reading everything, want the active file", pos)
+	}
+
+	// More is written, then the file rotates with a record still unread
+	appendLines(t, active, "active-c")
+	if got := readChain(t, c); fmt.Sprint(got) != "[active-c]" {
+		t.Fatalf("after append read %v", got)
+	}
+	appendLines(t, active, "active-d")
+	if err := os.Rename(active, filepath.Join(dir, "app.log.20260101-000003")); err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, active, "new-a")
+
+	if got := readChain(t, c); fmt.Sprint(got) != "[active-d new-a]" {
+		t.Fatalf("across rotation read %v, want [active-d new-a]", got)
+	}
+	saved := c.Position()
+	if saved.Segment != "app.log" {
+		t.Fatalf("position %+v after rotation, want the new active file", saved)
+	}
+
+	// A new reader resumes where the first stopped
+	appendLines(t, active, "new-b")
+	resumed := NewChainReader(dir, "app.log", rotation.Default(), saved, DefaultReaderOptions())
+	defer resumed.Close()
+	if got := readChain(t, resumed); fmt.Sprint(got) != "[new-b]" {
+		t.Fatalf("resumed read %v, want [new-b]", got)
+	}
+
+	// Resuming in a deleted segment moves on to the next
+	if err := os.Remove(filepath.Join(dir, "app.log.20260101-000001")); err != nil {
+		t.Fatal(err)
+	}
+	from := NewChainReader(dir, "app.log", rotation.Default(), ChainPosition{Segment: "app.log.20260101-000001", Offset: 5}, DefaultReaderOptions())
+	defer from.Close()
+	if got := readChain(t, from); len(got) != 8 || got[0] != "seg2-a" {
+		t.Fatalf("from a deleted segment read %v", got)
+	}
+}
@@ -0,0 +1,161 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkpointSuffix ends the names of checkpoint files in OffsetsDir
+const checkpointSuffix = ".checkpoint.json"
+
+// CheckpointID identifies a checkpoint taken by Processor.Checkpoint.
+// IDs begin with the time the checkpoint was taken, so they sort in the
+// order they were taken.
+type CheckpointID string
+
+// barrier pauses record delivery for a checkpoint. Workers hold it for
+// reading while delivering a segment's records, and pass it between
+// records; a checkpoint holds it for writing once every worker has
+// committed its progress and let go. Stream segments, which have no
+// offsets to checkpoint and whose reads can block indefinitely, don't
+// take part.
+type barrier struct {
+	mu      sync.RWMutex
+	pending atomic.Bool
+
+	errMu sync.Mutex
+	err   error // First commit failure while passing the pending checkpoint
+}
+
+// pass lets a pending checkpoint through: it commits the caller's
+// progress, then waits for the checkpoint to be taken. The caller must
+// hold b.mu for reading.
+func (b *barrier) pass(commit func() error) {
+	if !b.pending.Load() {
+		return
+	}
+	if err := commit(); err != nil {
+		b.errMu.Lock()
+		if b.err == nil {
+			b.err = err
+		}
+		b.errMu.Unlock()
+	}
+	b.mu.RUnlock()
+	b.mu.RLock()
+}
+
+// Checkpoint briefly pauses record delivery on every worker, has each
+// commit the offset after the last record it delivered, and saves the
+// committed offsets of every segment as a checkpoint in OffsetsDir: a
+// consistent point across workers, e.g. to align with a downstream
+// transaction, that RestoreCheckpoint can return to. Workers finish the
+// record in hand first, however long that takes; CheckpointContext
+// bounds the wait. It must not be called from the process func.
+func (p *Processor) Checkpoint() (CheckpointID, error) {
+	return p.CheckpointContext(context.Background())
+}
+
+// CheckpointContext is Checkpoint, giving up and returning ctx's error
+// if ctx ends before every worker has paused, so a process func stuck
+// on a record can't hold up the caller indefinitely
+func (p *Processor) CheckpointContext(ctx context.Context) (CheckpointID, error) {
+	p.checkpointMu.Lock()
+	b := &p.barrier
+	b.pending.Store(true)
+	locked := make(chan struct{})
+	go func() {
+		b.mu.Lock()
+		close(locked)
+	}()
+	select {
+	case <-locked:
+	case <-ctx.Done():
+		// Let go of the barrier as soon as it is taken, and only then
+		// allow another checkpoint
+		go func() {
+			<-locked
+			b.pending.Store(false)
+			b.errMu.Lock()
+			b.err = nil
+			b.errMu.Unlock()
+			b.mu.Unlock()
+			p.checkpointMu.Unlock()
+		}()
+		return "", fmt.Errorf("checkpoint: %w", ctx.Err())
+	}
+	b.pending.Store(false)
+	defer p.checkpointMu.Unlock()
+	defer b.mu.Unlock()
+
+	b.errMu.Lock()
+	err := b.err
+	b.err = nil
+	b.errMu.Unlock()
+	if err != nil {
+		return "", fmt.Errorf("checkpoint: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := p.offsetMgr.Export(&buf); err != nil {
+		return "", fmt.Errorf("checkpoint: %w", err)
+	}
+	random := make([]byte, 4)
+	rand.Read(random)
+	id := CheckpointID(time.Now().UTC().Format("20060102T150405.000Z") + "-" + hex.EncodeToString(random))
+	if err := writeFileAtomic(p.checkpointPath(id), buf.Bytes(), 0644); err != nil {
+		return "", fmt.Errorf("checkpoint: %w", err)
+	}
+	p.log.Info("checkpoint taken", "checkpoint", id)
+	return id, nil
+}
+
+// RestoreCheckpoint sets every segment's offset back (or forward) to
+// where it was at a checkpoint, so the next Start delivers the records
+// after it again. Segments found since start from the beginning, and
+// segments moved back start a new epoch (see LogRecord.Epoch). The
+// processor must be stopped.
+func (p *Processor) RestoreCheckpoint(id CheckpointID) error {
+	if p.running.Load() {
+		return errors.New("restore checkpoint: processor is running")
+	}
+	if id == "" || strings.ContainsAny(string(id), `/\`) || id == ".." {
+		return fmt.Errorf("restore checkpoint: invalid checkpoint %q", id)
+	}
+
+	file, err := os.Open(p.checkpointPath(id))
+	if err != nil {
+		return fmt.Errorf("restore checkpoint: %w", err)
+	}
+	defer file.Close()
+	snapshot, err := ReadOffsetExport(file)
+	if err != nil {
+		return fmt.Errorf("restore checkpoint %s: %w", id, err)
+	}
+
+	changed, err := p.offsetMgr.Restore(snapshot)
+	for _, name := range changed {
+		// Rescanned with its restored offset on the next Start
+		p.segmentMgr.RemoveComplete(name)
+	}
+	if err != nil {
+		return fmt.Errorf("restore checkpoint %s: %w", id, err)
+	}
+	p.log.Info("checkpoint restored", "checkpoint", id, "segments", len(changed))
+	return nil
+}
+
+// checkpointPath returns the file a checkpoint is saved in
+func (p *Processor) checkpointPath(id CheckpointID) string {
+	return filepath.Join(p.cfg.OffsetsDir, "checkpoint-"+string(id)+checkpointSuffix)
+}
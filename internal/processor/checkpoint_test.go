@@ -0,0 +1,149 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestCheckpoint verifies a checkpoint taken mid-processing holds every
+// worker's progress up to the last record delivered, durably, and that
+// restoring it rewinds the offsets to that point
+func TestCheckpoint(t *testing.T) {
+	const segments, records = 3, 400
+	cfg := newTestConfig(t, 3)
+	line := `{"level":"INFO","message":"m"}`
+	lines := make([]string, records)
+	for i := range lines {
+		lines[i] = line
+	}
+	for i := range segments {
+		writeSegment(t, cfg.LogsDir, fmt.Sprintf("app.log.20260101-%02d0000", i), lines...)
+	}
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error {
+		time.Sleep(100 * time.Microsecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 5*time.Second, func() bool {
+		processed, _, _ := proc.Stats()
+		return processed >= 150
+	})
+	before, _, _ := proc.Stats()
+	id, err := proc.Checkpoint()
+	if err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	after, _, _ := proc.Stats()
+
+	file, err := os.Open(proc.checkpointPath(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := ReadOffsetExport(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("read checkpoint: %v", err)
+	}
+
+	// Every record delivered when the barrier was taken is in it, each
+	// segment's offset just past its last one
+	var total int64
+	for name, data := range snapshot {
+		if data.Offset != data.LinesProcessed*int64(len(line)+1) {
+			t.Errorf("%s: checkpoint offset %d doesn't follow its %d records", name, data.Offset, data.LinesProcessed)
+		}
+		total += data.LinesProcessed
+	}
+	if total < before || total > after {
+		t.Fatalf("checkpoint holds %d records, want between %d and %d", total, before, after)
+	}
+	if total == int64(segments*records) {
+		t.Fatal("processing finished before the checkpoint; nothing was in flight")
+	}
+
+	waitFor(t, 10*time.Second, func() bool {
+		_, _, stats := proc.Stats()
+		return stats[3] == segments
+	})
+	if err := proc.RestoreCheckpoint(id); err == nil {
+		t.Fatal("expected restoring a running processor to fail")
+	}
+	proc.Stop()
+
+	if err := proc.RestoreCheckpoint(id); err != nil {
+		t.Fatalf("RestoreCheckpoint: %v", err)
+	}
+	reloaded, err := NewOffsetManager(cfg.OffsetsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range snapshot {
+		if offset, lines := reloaded.GetOffset(name); offset != data.Offset || lines != data.LinesProcessed {
+			t.Errorf("%s: restored offset %d/%d, want %d/%d", name, offset, lines, data.Offset, data.LinesProcessed)
+		}
+		if epoch := reloaded.GetEpoch(name); epoch != 1 {
+			t.Errorf("%s: epoch after restore = %d, want 1", name, epoch)
+		}
+	}
+
+	// The next run picks up from the checkpoint
+	resumed, err := ProcessOnce(context.Background(), cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("ProcessOnce: %v", err)
+	}
+	if want := int64(segments*records) - total; resumed.Processed != want {
+		t.Errorf("next run processed %d records, want the %d after the checkpoint", resumed.Processed, want)
+	}
+}
+
+// TestCheckpointTimeout verifies CheckpointContext gives up when its ctx ends
+// while a worker is still delivering a record, and that delivery then
+// carries on and a later checkpoint succeeds
+func TestCheckpointTimeout(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"message":"a"}`, `{"message":"b"}`)
+
+	entered := make(chan struct{}, 2)
+	unblock := make(chan struct{})
+	proc, err := NewProcessor(cfg, func(*LogRecord) error {
+		entered <- struct{}{}
+		<-unblock
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+	<-entered
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := proc.CheckpointContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Checkpoint with a stuck worker = %v, want DeadlineExceeded", err)
+	}
+
+	close(unblock)
+	waitFor(t, 2*time.Second, func() bool {
+		processed, _, _ := proc.Stats()
+		return processed == 2
+	})
+	if _, err := proc.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package processor
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Codec decompresses segments whose names end in its extension. Such
+// segments can't be seeked, so they resume by skipping the lines
+// committed for them (see ResumeSkipLines), and offsets count
+// decompressed bytes.
+type Codec interface {
+	Ext() string // Segment name suffix, e.g. ".zst"
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Built-in codecs
+var (
+	Gzip Codec = gzipCodec{}
+	Zstd Codec = zstdCodec{}
+	Lz4  Codec = lz4Codec{}
+)
+
+type gzipCodec struct{}
+
+func (gzipCodec) Ext() string { return ".gz" }
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+type zstdCodec struct{}
+
+func (zstdCodec) Ext() string { return ".zst" }
+func (zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+type lz4Codec struct{}
+
+func (lz4Codec) Ext() string { return ".lz4" }
+func (lz4Codec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(lz4.NewReader(r)), nil
+}
+
+var (
+	codecs   = []Codec{Gzip, Zstd, Lz4}
+	codecsMu sync.RWMutex
+)
+
+// RegisterCodec makes FileSource decompress segments ending in c's
+// extension, e.g. a bzip2 codec for ".bz2". It replaces any codec
+// already registered for the extension.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	for i, existing := range codecs {
+		if existing.Ext() == c.Ext() {
+			codecs[i] = c
+			return
+		}
+	}
+	codecs = append(codecs, c)
+}
+
+// codecFor returns the codec for a segment name, or nil if the segment
+// isn't compressed
+func codecFor(name string) Codec {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	for _, c := range codecs {
+		if strings.HasSuffix(name, c.Ext()) {
+			return c
+		}
+	}
+	return nil
+}
+
+// isCompressed reports whether a segment name denotes a compressed file
+func isCompressed(name string) bool {
+	return codecFor(name) != nil
+}
+
+// decompressReadCloser closes both the decompressor and the underlying
+// file
+type decompressReadCloser struct {
+	io.ReadCloser
+	file *os.File
+}
+
+// Close closes the decompressor and the file
+func (d *decompressReadCloser) Close() error {
+	err := d.ReadCloser.Close()
+	if ferr := d.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
+
+// openCompressed opens a compressed file, discarding offset
+// decompressed bytes
+func openCompressed(path string, c Codec, offset int64) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	zr, err := c.NewReader(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	rc := &decompressReadCloser{ReadCloser: zr, file: file}
+
+	if offset > 0 {
+		if _, err := io.CopyN(io.Discard, zr, offset); err != nil {
+			rc.Close()
+			return nil, err
+		}
+	}
+	return rc, nil
+}
@@ -0,0 +1,195 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// xorCodec is a stand-in registered codec that flips every bit
+type xorCodec struct{}
+
+func (xorCodec) Ext() string { return ".xor" }
+func (xorCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return io.NopCloser(xorReader{r}), nil
+}
+
+type xorReader struct{ r io.Reader }
+
+func (x xorReader) Read(p []byte) (int, error) {
+	n, err := x.r.Read(p)
+	for i := range p[:n] {
+		p[i] ^= 0xFF
+	}
+	return n, err
+}
+
+// compressZstd returns data as a zstd frame
+func compressZstd(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// compressLz4 returns data as an lz4 frame
+func compressLz4(t *testing.T, data []byte) []byte {
+	var buf bytes.Buffer
+	zw := lz4.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestCompressedSegments verifies zstd, lz4 and registered codecs' segments
+// are decompressed and resume by line
+func TestCompressedSegments(t *testing.T) {
+	codecsMu.RLock()
+	saved := slices.Clone(codecs)
+	codecsMu.RUnlock()
+	t.Cleanup(func() {
+		codecsMu.Lock()
+		codecs = saved
+		codecsMu.Unlock()
+	})
+	RegisterCodec(xorCodec{})
+
+	tests := []struct {
+		ext      string
+		compress func(t *testing.T, data []byte) []byte
+	}{
+		{".zst", compressZstd},
+		{".lz4", compressLz4},
+		{".xor", func(t *testing.T, data []byte) []byte {
+			out, _ := io.ReadAll(xorReader{bytes.NewReader(data)})
+			return out
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			if codecFor("x"+tt.ext) == nil {
+				t.Fatalf("no codec for %s", tt.ext)
+			}
+
+			dir := t.TempDir()
+			var ndjson bytes.Buffer
+			for i := 1; i <= 3; i++ {
+				fmt.Fprintf(&ndjson, `{"level":"INFO","message":"m%d"}`+"\n", i)
+			}
+			name := "app.log.20260101-000000" + tt.ext
+			if err := os.WriteFile(filepath.Join(dir, name), tt.compress(t, ndjson.Bytes()), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			infos, err := NewFileSource(dir, "app.log").List()
+			if err != nil || len(infos) != 1 || infos[0].Resume != ResumeSkipLines {
+				t.Fatalf("List() = %+v, %v; want one line-skip segment", infos, err)
+			}
+
+			var seen []string
+			if _, err := ProcessDir(context.Background(), dir, "app.log", func(r *LogRecord) error {
+				seen = append(seen, r.Entry.Message)
+				return nil
+			}); err != nil {
+				t.Fatalf("ProcessDir: %v", err)
+			}
+			if got := strings.Join(seen, ","); got != "m1,m2,m3" {
+				t.Fatalf("processed %s, want m1,m2,m3", got)
+			}
+		})
+	}
+}
+
+// TestCompressedResumeFromLineCheckpoint verifies zstd and lz4 segments
+// resume by skipping committed lines, as gzip ones do, and are not
+// reprocessed once complete
+func TestCompressedResumeFromLineCheckpoint(t *testing.T) {
+	tests := []struct {
+		ext      string
+		compress func(t *testing.T, data []byte) []byte
+	}{
+		{".zst", compressZstd},
+		{".lz4", compressLz4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			cfg := newTestConfig(t, 1)
+			name := "app.log.20260101-000000" + tt.ext
+
+			var ndjson bytes.Buffer
+			for _, msg := range []string{"one", "two", "", "three", "four"} {
+				if msg == "" {
+					ndjson.WriteString("\n") // Blank lines count toward the checkpoint
+					continue
+				}
+				fmt.Fprintf(&ndjson, `{"message":%q}`+"\n", msg)
+			}
+			if err := os.WriteFile(filepath.Join(cfg.LogsDir, name), tt.compress(t, ndjson.Bytes()), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			// A previous run got through "two" and the blank line
+			offsetMgr, err := NewOffsetManager(cfg.OffsetsDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := offsetMgr.CommitLine(name, 0, 2, 3); err != nil {
+				t.Fatal(err)
+			}
+
+			run := func() []string {
+				var mu sync.Mutex
+				var seen []string
+				proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+					mu.Lock()
+					seen = append(seen, r.Entry.Message)
+					mu.Unlock()
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("NewProcessor: %v", err)
+				}
+				if err := proc.Start(context.Background()); err != nil {
+					t.Fatalf("Start: %v", err)
+				}
+				waitFor(t, 2*time.Second, func() bool {
+					_, _, segStats := proc.Stats()
+					return segStats[3] == 1
+				})
+				proc.Stop()
+
+				mu.Lock()
+				defer mu.Unlock()
+				return seen
+			}
+
+			if got := run(); strings.Join(got, ",") != "three,four" {
+				t.Fatalf("resumed run processed %q, want three,four", got)
+			}
+			if got := run(); len(got) != 0 {
+				t.Fatalf("completed segment reprocessed: %q", got)
+			}
+		})
+	}
+}
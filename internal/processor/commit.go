@@ -0,0 +1,88 @@
+package processor
+
+import "time"
+
+// Commit pacing defaults
+const (
+	defaultCommitRecords    = 100   // Fixed interval without CommitTarget
+	defaultMaxCommitRecords = 10000 // Adaptive upper bound
+)
+
+// commitPacer decides when a reader's offset should be committed. With
+// a target latency it adapts the number of records between commits to
+// the observed rate, so that at any throughput a commit happens about
+// once per target: often when slow (bounding lost work), rarely when
+// fast (bounding offset I/O).
+type commitPacer struct {
+	min, max  int64
+	target    time.Duration // 0 = fixed interval of min records
+	threshold int64         // Records between commits currently in effect
+	pending   int64         // Records since the last commit
+	last      time.Time     // Time of the last commit
+	now       func() time.Time
+	report    func(threshold int64)
+}
+
+// newCommitPacer creates a pacer from the processor's commit settings
+func (p *Processor) newCommitPacer() *commitPacer {
+	cfg := p.cfg
+	c := &commitPacer{
+		min:    int64(cfg.MinCommitRecords),
+		max:    int64(cfg.MaxCommitRecords),
+		target: cfg.CommitTarget,
+		now:    time.Now,
+		report: func(threshold int64) { p.commitInterval.Store(threshold) },
+	}
+
+	if c.target <= 0 {
+		c.min = defaultCommitRecords
+		c.max = defaultCommitRecords
+	}
+	if c.min <= 0 {
+		c.min = 1
+	}
+	if c.max <= 0 {
+		c.max = defaultMaxCommitRecords
+	}
+	if c.max < c.min {
+		c.max = c.min
+	}
+
+	c.threshold = c.min
+	c.last = c.now()
+	return c
+}
+
+// record counts one record and reports whether a commit is due. When
+// it is, the caller must commit; the pacer assumes it did.
+func (c *commitPacer) record() bool {
+	c.pending++
+	if c.target <= 0 {
+		if c.pending < c.threshold {
+			return false
+		}
+		c.pending = 0
+		return true
+	}
+
+	now := c.now()
+	elapsed := now.Sub(c.last)
+	if c.pending < c.threshold && elapsed < c.target {
+		return false
+	}
+
+	// Aim the next commit at one target's worth of records
+	next := c.max
+	if elapsed > 0 {
+		rate := float64(c.pending) / elapsed.Seconds()
+		if want := rate * c.target.Seconds(); want < float64(c.max) {
+			next = int64(want)
+		}
+	}
+	c.threshold = min(max(next, c.min), c.max)
+	c.report(c.threshold)
+
+	c.pending = 0
+	c.last = now
+	return true
+}
@@ -0,0 +1,79 @@
+package processor
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCommitPacerAdapts verifies the commit interval grows with high
+// throughput, shrinks with low throughput, and stays within bounds
+func TestCommitPacerAdapts(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.CommitTarget = time.Second
+	cfg.MinCommitRecords = 10
+	cfg.MaxCommitRecords = 5000
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := time.Unix(0, 0)
+	pacer := proc.newCommitPacer()
+	pacer.now = func() time.Time { return clock }
+	pacer.last = clock
+
+	// drive feeds n records spaced by gap and returns the commit count
+	drive := func(n int, gap time.Duration) int {
+		commits := 0
+		for i := 0; i < n; i++ {
+			clock = clock.Add(gap)
+			if pacer.record() {
+				commits++
+			}
+			if th := proc.CommitInterval(); th < 10 || th > 5000 {
+				t.Fatalf("commit interval %d outside [10, 5000]", th)
+			}
+		}
+		return commits
+	}
+
+	// 1000 records/s: about one commit per second of records
+	drive(5000, time.Millisecond)
+	if got := proc.CommitInterval(); got < 900 || got > 1100 {
+		t.Fatalf("at 1000/s interval = %d, want ~1000", got)
+	}
+
+	// 100000 records/s: capped at the maximum
+	drive(20000, 10*time.Microsecond)
+	if got := proc.CommitInterval(); got != 5000 {
+		t.Fatalf("at 100000/s interval = %d, want max 5000", got)
+	}
+
+	// 2 records/s: the time target forces commits, down to the minimum
+	if commits := drive(20, 500*time.Millisecond); commits < 9 {
+		t.Fatalf("at 2/s only %d commits over 10s", commits)
+	}
+	if got := proc.CommitInterval(); got != 10 {
+		t.Fatalf("at 2/s interval = %d, want min 10", got)
+	}
+}
+
+// TestCommitPacerFixed verifies the default fixed interval
+func TestCommitPacerFixed(t *testing.T) {
+	proc, err := NewProcessor(newTestConfig(t, 1), func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pacer := proc.newCommitPacer()
+	commits := 0
+	for i := 0; i < 1000; i++ {
+		if pacer.record() {
+			commits++
+		}
+	}
+	if commits != 10 || proc.CommitInterval() != 100 {
+		t.Fatalf("commits=%d interval=%d, want 10 and 100", commits, proc.CommitInterval())
+	}
+}
@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// CountReport summarizes a CountSegments pass
+type CountReport struct {
+	Segments int   `json:"segments"`
+	Records  int64 `json:"records"`
+
+	// BySegment counts the records of each segment counted
+	BySegment map[string]int64 `json:"by_segment"`
+}
+
+// CountSegments counts the records of every segment from its committed
+// offset without parsing them or calling the process func, then
+// commits each segment as fully processed, so a later run resumes
+// after what was counted. Records are counted by delimiter, so unlike
+// CountRecords blank lines count too. Streams are skipped since they
+// can't be resumed; ExcludeSegments, IncludeSegments and TimeFrom
+// select segments as in a run, but TimeFrom and TimeTo don't filter
+// records. It is meant for a processor that hasn't been started.
+func (p *Processor) CountSegments(ctx context.Context) (CountReport, error) {
+	report := CountReport{BySegment: make(map[string]int64)}
+	if p.cfg.InputFormat == Wrapped {
+		return report, errors.New("wrapped segments can't be counted by delimiter")
+	}
+
+	infos, err := p.source.List()
+	if err != nil {
+		return report, err
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	delim := p.cfg.delimiter()
+	for _, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if info.Stream || p.cfg.skipSegment(info) {
+			continue
+		}
+
+		records, err := p.countSegment(info, delim)
+		if err != nil {
+			return report, err
+		}
+		report.Segments++
+		report.Records += records
+		report.BySegment[info.Name] = records
+	}
+	return report, nil
+}
+
+// countSegment counts and commits the records of a segment past its
+// resume point
+func (p *Processor) countSegment(info SegmentInfo, delim byte) (int64, error) {
+	var offset int64
+	if info.Resume == ResumeSeek {
+		offset, _ = p.offsetMgr.GetOffset(info.Name)
+	}
+	rc, err := p.source.Open(info.Name, offset)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+
+	records, n, err := countDelimited(rc, delim)
+	if err != nil {
+		return 0, err
+	}
+
+	if info.Resume == ResumeSkipLines {
+		// Counted from the start, past the lines already consumed
+		line := p.offsetMgr.GetLine(info.Name)
+		total := records
+		records = max(total-line, 0)
+		return records, p.offsetMgr.CommitLine(info.Name, info.Size, records, max(total, line))
+	}
+	return records, p.offsetMgr.CommitOffset(info.Name, offset+n, records)
+}
@@ -0,0 +1,69 @@
+package processor
+
+import "time"
+
+// scheduleDelete arranges for a completed segment to be removed once
+// the grace period has passed. Segments rediscovered by every scan are
+// reported repeatedly, so an existing schedule is kept.
+func (p *Processor) scheduleDelete(name string) {
+	p.deletionsMu.Lock()
+	defer p.deletionsMu.Unlock()
+
+	if _, ok := p.deletions[name]; ok {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(p.cfg.DeleteGrace, func() {
+		p.deletionsMu.Lock()
+		if p.deletions[name] != timer {
+			p.deletionsMu.Unlock()
+			return // Cancelled
+		}
+		delete(p.deletions, name)
+		p.deletionsMu.Unlock()
+
+		p.deleteSegment(name)
+	})
+	p.deletions[name] = timer
+}
+
+// deleteSegment removes a segment and then its offset, unless it was
+// requeued since completing. The offset goes last so a failed removal
+// leaves the segment recorded as complete rather than reprocessed.
+func (p *Processor) deleteSegment(name string) {
+	if !p.segmentMgr.RemoveComplete(name) {
+		return
+	}
+
+	remover, ok := p.source.(SegmentRemover)
+	if !ok {
+		return
+	}
+	if err := remover.Remove(name); err != nil {
+		p.log.Error("delete segment failed", "segment", name, "error", err)
+		p.errors.Add(1)
+		return
+	}
+	if err := p.removeIndex(name); err != nil {
+		p.log.Error("delete index failed", "segment", name, "error", err)
+		p.errors.Add(1)
+	}
+	if err := p.offsetMgr.DeleteOffset(name); err != nil {
+		p.log.Error("delete offset failed", "segment", name, "error", err)
+		p.errors.Add(1)
+	}
+	p.log.Info("segment deleted", "segment", name)
+}
+
+// cancelDeletes stops all pending deletions. Segments still on disk are
+// rediscovered as complete, and rescheduled, on the next start.
+func (p *Processor) cancelDeletes() {
+	p.deletionsMu.Lock()
+	defer p.deletionsMu.Unlock()
+
+	for name, t := range p.deletions {
+		t.Stop()
+		delete(p.deletions, name)
+	}
+}
@@ -0,0 +1,88 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestDeleteAfterComplete verifies a completed segment and its offset
+// are removed only after the grace period
+func TestDeleteAfterComplete(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.DeleteAfterComplete = true
+	cfg.DeleteGrace = 300 * time.Millisecond
+	path := writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", `{"message":"a"}`)
+	offsetPath := filepath.Join(cfg.OffsetsDir, "app.log.20260101-000000.offset.json")
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	// Still within the grace period
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("segment deleted before grace: %v", err)
+	}
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, err := os.Stat(path)
+		return os.IsNotExist(err)
+	})
+	waitFor(t, time.Second, func() bool {
+		_, err := os.Stat(offsetPath)
+		return os.IsNotExist(err)
+	})
+
+	// Neither is rediscovered
+	time.Sleep(3 * cfg.ScanInterval)
+	if total, _, _, _ := proc.segmentMgr.GetStats(); total != 0 {
+		t.Fatalf("%d segments tracked after deletion, want 0", total)
+	}
+}
+
+// TestDeleteSkipsRequeued verifies a segment requeued during the grace
+// period survives
+func TestDeleteSkipsRequeued(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.DeleteAfterComplete = true
+	cfg.DeleteGrace = 50 * time.Millisecond
+
+	src := NewMemorySource()
+	src.Put("app.log.1", []byte(`{"message":"a"}`+"\n"))
+	cfg.Source = src
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	sm := proc.segmentMgr
+	if err := sm.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if !sm.ClaimSegment("app.log.1", 0) {
+		t.Fatal("claim failed")
+	}
+	sm.MarkComplete("app.log.1")
+	sm.ReleaseSegment("app.log.1")
+
+	time.Sleep(4 * cfg.DeleteGrace)
+
+	if infos, _ := src.List(); len(infos) != 1 {
+		t.Fatalf("requeued segment was deleted")
+	}
+	if seg := sm.GetSegment("app.log.1"); seg == nil || seg.State != SegmentPending {
+		t.Fatalf("requeued segment no longer pending: %+v", seg)
+	}
+}
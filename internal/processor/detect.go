@@ -0,0 +1,82 @@
+package processor
+
+import (
+	"bytes"
+
+	"log-processor/internal/logger"
+)
+
+// Auto is the InputFormat that detects each segment's format from its
+// first non-blank line (see DetectFormat). Lines that don't parse in
+// the detected format are retried in the format they look like, so
+// mixed files still parse.
+const Auto logger.Format = "auto"
+
+// DetectFormat guesses the format of a line: '{' starts JSON, or a
+// pretty-printed Wrapped document if the line opens a structure it
+// doesn't close; '[' starts a Wrapped array if a record or nothing
+// follows, and otherwise a Text timestamp; '<' starts a syslog
+// priority; and key=value pairs are logfmt. ',' and ']' continue a
+// Wrapped array, as when resuming inside one. Anything else is taken
+// to be JSON.
+func DetectFormat(line []byte) logger.Format {
+	line = bytes.TrimSpace(bytes.TrimPrefix(line, utf8BOM))
+	if len(line) == 0 {
+		return logger.JSON
+	}
+
+	switch line[0] {
+	case '{':
+		if bytes.IndexByte([]byte("{[,"), line[len(line)-1]) >= 0 {
+			return Wrapped
+		}
+		return logger.JSON
+	case '[':
+		rest := bytes.TrimSpace(line[1:])
+		if len(rest) == 0 || rest[0] == '{' || rest[0] == ']' {
+			return Wrapped
+		}
+		return logger.Text
+	case '<':
+		return logger.Syslog
+	case ',', ']':
+		return Wrapped
+	}
+	if _, err := logger.ParseLogfmt(string(line)); err == nil {
+		return logger.Logfmt
+	}
+	return logger.JSON
+}
+
+// detect settles an Auto format from the first non-blank line ahead,
+// without consuming it. It reports false if there is no input yet.
+func (lr *LogReader) detect() bool {
+	var ahead []byte
+	if lr.mapped != nil {
+		ahead = lr.mapped.data[lr.mapped.pos:]
+	} else if _, err := lr.reader.Peek(1); err == nil {
+		ahead, _ = lr.reader.Peek(lr.reader.Buffered())
+	}
+	if len(ahead) == 0 {
+		return false
+	}
+
+	// Within what's buffered, which may end mid-line
+	line := ahead
+	for len(line) > 0 {
+		next := line
+		if i := bytes.IndexByte(line, lr.opts.Delimiter); i >= 0 {
+			next, line = line[:i], line[i+1:]
+		} else {
+			line = nil
+		}
+		if len(bytes.TrimSpace(bytes.TrimPrefix(next, utf8BOM))) > 0 {
+			line = next
+			break
+		}
+	}
+
+	lr.auto = true
+	lr.opts.Format = DetectFormat(line)
+	return true
+}
@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"log-processor/internal/logger"
+)
+
+// TestReaderAutoFormat verifies each format is detected and parsed with
+// InputFormat Auto, and that lines of another format still parse
+func TestReaderAutoFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		path    string
+		format  logger.Format
+		want    []string
+	}{
+		{
+			name:    "ndjson",
+			content: "\n" + `{"level":"INFO","message":"a"}` + "\n" + `{"level":"INFO","message":"b"}` + "\n",
+			format:  logger.JSON,
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "text",
+			content: "[2026-01-02T10:00:00Z] INFO | api | req-1 | a\n[2026-01-02T10:00:01Z] WARNING | api | req-2 | b\n",
+			format:  logger.Text,
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "logfmt",
+			content: "level=INFO message=a\nlevel=ERROR message=\"b c\" duration_ms=5\n",
+			format:  logger.Logfmt,
+			want:    []string{"a", "b c"},
+		},
+		{
+			name:    "syslog",
+			content: "<14>1 2026-01-02T10:00:00Z host api - - - a\n",
+			format:  logger.Syslog,
+			want:    []string{"a"},
+		},
+		{
+			name:    "array",
+			content: `[{"message":"a"},` + "\n" + `{"message":"b"}]`,
+			format:  Wrapped,
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "pretty wrapper",
+			content: "{\n  \"logs\": [\n    {\"message\": \"a\"}\n  ]\n}\n",
+			path:    "logs",
+			format:  Wrapped,
+			want:    []string{"a"},
+		},
+		{
+			name: "mixed",
+			content: `{"level":"INFO","message":"a"}` + "\n" +
+				"level=INFO message=b\n" +
+				"[2026-01-02T10:00:00Z] INFO | api | req-1 | c\n" +
+				`{"level":"INFO","message":"d"}` + "\n",
+			format: logger.JSON,
+			want:   []string{"a", "b", "c", "d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log.1")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			opts := DefaultReaderOptions()
+			opts.Format = Auto
+			opts.WrappedPath = tt.path
+			reader, err := NewLogReaderWithOptions(path, 0, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+
+			var got []string
+			for {
+				record, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Read: %v", err)
+				}
+				if record.ParseErr != nil {
+					t.Fatalf("record %q: %v", record.Raw, record.ParseErr)
+				}
+				got = append(got, record.Entry.Message)
+			}
+			if reader.opts.Format != tt.format {
+				t.Errorf("detected %q, want %q", reader.opts.Format, tt.format)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("messages = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
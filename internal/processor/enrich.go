@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// EnricherFunc looks up extra fields for a key (e.g. geo/ASN data for
+// an IP address). A nil map with a nil error means nothing was found.
+type EnricherFunc func(key string) (map[string]any, error)
+
+// Enrich returns a Transform that looks up the string value of field
+// and merges the result into Entry.Extra. Existing fields are not
+// overwritten. Up to cacheSize lookups are cached (LRU); 0 disables
+// caching. Records without the field pass through unchanged.
+func Enrich(field string, fn EnricherFunc, cacheSize int) Transform {
+	cache := newLookupCache(cacheSize)
+
+	return func(record *LogRecord) error {
+		v, ok := record.Entry.Field(field)
+		if !ok {
+			return nil
+		}
+		key, ok := v.(string)
+		if !ok || key == "" {
+			return nil
+		}
+
+		fields, ok := cache.get(key)
+		if !ok {
+			var err error
+			fields, err = fn(key)
+			if err != nil {
+				return fmt.Errorf("enrich %s=%q: %w", field, key, err)
+			}
+			cache.put(key, fields)
+		}
+
+		for k, v := range fields {
+			if _, exists := record.Entry.Field(k); exists {
+				continue
+			}
+			if record.Entry.Extra == nil {
+				record.Entry.Extra = make(map[string]any, len(fields))
+			}
+			record.Entry.Extra[k] = v
+		}
+		return nil
+	}
+}
+
+// lookupCache is a fixed-size LRU cache of enrichment results
+type lookupCache struct {
+	size    int
+	order   *list.List // Front is most recently used
+	entries map[string]*list.Element
+	mu      sync.Mutex
+}
+
+type lookupEntry struct {
+	key    string
+	fields map[string]any
+}
+
+func newLookupCache(size int) *lookupCache {
+	return &lookupCache{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (c *lookupCache) get(key string) (map[string]any, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(*lookupEntry).fields, true
+}
+
+func (c *lookupCache) put(key string, fields map[string]any) {
+	if c.size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value.(*lookupEntry).fields = fields
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lookupEntry{key: key, fields: fields})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lookupEntry).key)
+	}
+}
@@ -0,0 +1,208 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"log-processor/internal/logger"
+)
+
+func ExampleEnrich() {
+	// A stub standing in for a MaxMind (or similar) database lookup
+	lookup := func(ip string) (map[string]any, error) {
+		if strings.HasPrefix(ip, "81.2.") {
+			return map[string]any{"country": "GB", "asn": 20712}, nil
+		}
+		return nil, nil
+	}
+
+	enrich := Enrich("client_ip", lookup, 1024)
+
+	var entry logger.LogEntry
+	_ = logger.Unmarshal([]byte(`{"level":"INFO","message":"login","client_ip":"81.2.69.142"}`), &entry)
+	record := &LogRecord{Entry: entry}
+	if err := enrich(record); err != nil {
+		panic(err)
+	}
+
+	data, _ := logger.Marshal(record.Entry)
+	fmt.Println(string(data))
+	// Output: {"timestamp":"","level":"INFO","service":"","message":"login","asn":20712,"client_ip":"81.2.69.142","country":"GB"}
+}
+
+// TestEnrichCachesLookups verifies repeated keys hit the cache
+func TestEnrichCachesLookups(t *testing.T) {
+	calls := 0
+	enrich := Enrich("client_ip", func(ip string) (map[string]any, error) {
+		calls++
+		return map[string]any{"country": "GB"}, nil
+	}, 1)
+
+	for _, ip := range []string{"a", "a", "b", "a"} {
+		record := &LogRecord{Entry: logger.LogEntry{Extra: map[string]any{"client_ip": ip}}}
+		if err := enrich(record); err != nil {
+			t.Fatal(err)
+		}
+		if record.Entry.Extra["country"] != "GB" {
+			t.Fatalf("%s: not enriched: %v", ip, record.Entry.Extra)
+		}
+	}
+
+	// "a" is evicted by "b" with a cache of one
+	if calls != 3 {
+		t.Fatalf("lookups = %d, want 3", calls)
+	}
+}
+
+// TestProcessorTransform verifies transformed fields reach the callback
+// and a failing transform is counted and dead-lettered
+func TestProcessorTransform(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"level":"INFO","message":"ok","client_ip":"10.0.0.1"}`,
+		`{"level":"INFO","message":"bad","client_ip":"10.0.0.2"}`,
+	)
+
+	cfg.Transform = Enrich("client_ip", func(ip string) (map[string]any, error) {
+		if ip == "10.0.0.2" {
+			return nil, fmt.Errorf("lookup failed")
+		}
+		return map[string]any{"country": "ZZ"}, nil
+	}, 16)
+
+	var mu sync.Mutex
+	var output []string
+	var dead []string
+	cfg.DeadLetter = func(record *LogRecord, err error) {
+		mu.Lock()
+		dead = append(dead, record.Entry.Message)
+		mu.Unlock()
+	}
+
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		output = append(output, r.Entry.FormatJSON())
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		processed, errs, _ := proc.Stats()
+		return processed+errs == 2
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(output) != 1 || !strings.Contains(output[0], `"country":"ZZ"`) || !strings.Contains(output[0], `"client_ip":"10.0.0.1"`) {
+		t.Fatalf("enriched output = %q", output)
+	}
+	if len(dead) != 1 || dead[0] != "bad" {
+		t.Fatalf("dead letters = %q", dead)
+	}
+}
+
+// TestTransformPipeline verifies Transforms run in order after
+// Transform, and that a dropping or failing stage ends the pipeline
+// with the record filtered or dead-lettered
+func TestTransformPipeline(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"level":"INFO","message":"a"}`,
+		`{"level":"INFO","message":"drop"}`,
+		`{"level":"INFO","message":"fail"}`,
+		`{"level":"INFO","message":"b"}`,
+	)
+
+	var mu sync.Mutex
+	var lastStage, dead []string
+	stage := func(name string) Transform {
+		return func(r *LogRecord) error {
+			trail, _ := r.Entry.Extra["stages"].(string)
+			r.Entry.SetField("stages", trail+name)
+			return nil
+		}
+	}
+	cfg.Transform = stage("t")
+	cfg.Transforms = []Transform{
+		stage("1"),
+		func(r *LogRecord) error {
+			if r.Entry.Message == "drop" {
+				return ErrDrop
+			}
+			return nil
+		},
+		func(r *LogRecord) error {
+			if r.Entry.Message == "fail" {
+				return errors.New("stage failed")
+			}
+			return nil
+		},
+		stage("2"),
+		func(r *LogRecord) error {
+			mu.Lock()
+			lastStage = append(lastStage, r.Entry.Message)
+			mu.Unlock()
+			return nil
+		},
+	}
+	cfg.DeadLetter = func(record *LogRecord, err error) {
+		mu.Lock()
+		dead = append(dead, record.Entry.Message)
+		mu.Unlock()
+	}
+
+	var output []string
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		output = append(output, r.Entry.Message+":"+r.Entry.Extra["stages"].(string))
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	processed, errs, _ := proc.Stats()
+	if processed != 2 || errs != 1 || proc.Filtered() != 1 {
+		t.Fatalf("processed %d, errors %d, filtered %d; want 2, 1, 1", processed, errs, proc.Filtered())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(output, []string{"a:t12", "b:t12"}) {
+		t.Fatalf("output = %q, want stages applied in order", output)
+	}
+	if !reflect.DeepEqual(lastStage, []string{"a", "b"}) {
+		t.Fatalf("last stage saw %q, want only the records that got through", lastStage)
+	}
+	if !reflect.DeepEqual(dead, []string{"fail"}) {
+		t.Fatalf("dead letters = %q, want the failed record only", dead)
+	}
+
+	cfg.Transforms = []Transform{nil}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected a nil stage to fail validation")
+	}
+}
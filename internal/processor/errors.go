@@ -0,0 +1,164 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrStopTimeout is returned by Stop when workers are still running
+// after Config.StopTimeout
+var ErrStopTimeout = errors.New("workers still running after stop timeout")
+
+// ErrStopped is returned by WaitForIdle when the processor is not
+// running or stops before becoming idle
+var ErrStopped = errors.New("processor stopped")
+
+// ErrNotSeekable is returned by LogReader.SeekToLine when the reader
+// would have to move backwards over a source that can't seek, such as
+// a gzip segment or a stream
+var ErrNotSeekable = errors.New("segment is not seekable")
+
+// ErrDrop is returned by a Transform to remove a record: it is not
+// processed, and is counted by Processor.Filtered rather than as an
+// error
+var ErrDrop = errors.New("record dropped")
+
+// ErrOffsetRegression is returned by OffsetManager.CommitOffset for a
+// commit behind the segment's stored offset
+var ErrOffsetRegression = errors.New("offset commit behind stored offset")
+
+// PanicError is returned in place of a panic recovered from a ProcessFunc
+type PanicError struct {
+	Value any    // Value passed to panic
+	Stack []byte // Stack trace captured at recovery
+}
+
+// Error implements the error interface
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("process func panicked: %v", e.Value)
+}
+
+// SchemaError reports a record that does not satisfy Config.SchemaPath.
+// Err lists every violation found.
+type SchemaError struct {
+	Err error
+}
+
+// Error implements the error interface
+func (e *SchemaError) Error() string {
+	return "schema violation: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *SchemaError) Unwrap() error {
+	return e.Err
+}
+
+// CorruptRegionError is the ParseErr of a record standing for a run of
+// corrupt input skipped by ReaderOptions.Resync
+type CorruptRegionError struct {
+	Lines int64 // Lines (or line fragments) skipped
+	Bytes int64 // Bytes skipped
+}
+
+// Error implements the error interface
+func (e *CorruptRegionError) Error() string {
+	return fmt.Sprintf("skipped corrupt region of %d lines (%d bytes)", e.Lines, e.Bytes)
+}
+
+// TransientError marks a process func error as retryable, e.g. a
+// downstream timeout. See Transient.
+type TransientError struct {
+	Err error
+}
+
+// Error implements the error interface
+func (e *TransientError) Error() string {
+	return "transient: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *TransientError) Unwrap() error {
+	return e.Err
+}
+
+// PermanentError marks a process func error as not worth retrying, e.g.
+// a record the downstream rejects. See Permanent.
+type PermanentError struct {
+	Err error
+}
+
+// Error implements the error interface
+func (e *PermanentError) Error() string {
+	return "permanent: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+// FatalError marks a process func error as one the processor can't go
+// on after, e.g. a downstream that is gone for good. See Fatal.
+type FatalError struct {
+	Err error
+}
+
+// Error implements the error interface
+func (e *FatalError) Error() string {
+	return "fatal: " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *FatalError) Unwrap() error {
+	return e.Err
+}
+
+// Transient wraps err so the processor retries the record later: its
+// segment is released at that record and reclaimed after
+// Config.RetryDelay, within Config.MaxSegmentRetries. Records of
+// streams and the followed active file are not retried. Transient(nil)
+// is nil.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &TransientError{Err: err}
+}
+
+// Permanent wraps err so the processor dead-letters the record and
+// moves on. Permanent(nil) is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &PermanentError{Err: err}
+}
+
+// Fatal wraps err so the processor stops: the record is left for the
+// next run, the other workers are cancelled, and Processor.Run (or
+// ProcessOnce) returns the error. Fatal(nil) is nil.
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &FatalError{Err: err}
+}
+
+// IsTransient reports whether err was marked with Transient
+func IsTransient(err error) bool {
+	var te *TransientError
+	return errors.As(err, &te)
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var pe *PermanentError
+	return errors.As(err, &pe)
+}
+
+// IsFatal reports whether err was marked with Fatal
+func IsFatal(err error) bool {
+	var fe *FatalError
+	return errors.As(err, &fe)
+}
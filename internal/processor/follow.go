@@ -0,0 +1,427 @@
+package processor
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// follower tails the active log file (LogsDir/LogPattern). When the
+// file is rotated it drains the renamed file, records it as a completed
+// segment, and starts following the newly created active file from
+// offset zero, so no record is lost or delivered twice. The active
+// offset carries a fingerprint of the file's head, so a rotation while
+// the processor was stopped is recognized on the next Start (see
+// reclaim).
+type follower struct {
+	w    *worker
+	path string // Active file path
+	name string // Offset key for the active file
+
+	current os.FileInfo // Identity of the file being read
+	mu      sync.Mutex
+
+	watcher changeWatcher // Nil when polling
+}
+
+// newFollower creates a follower for the processor's active file
+func newFollower(p *Processor) *follower {
+	return &follower{
+		w:    p.newWorker(),
+		path: filepath.Join(p.cfg.LogsDir, p.cfg.LogPattern),
+		name: p.cfg.LogPattern,
+	}
+}
+
+// owns reports whether info is the file currently being followed. The
+// segment manager uses it to avoid tracking a just-rotated file before
+// the follower has finished reading it.
+func (f *follower) owns(info SegmentInfo) bool {
+	f.mu.Lock()
+	current := f.current
+	f.mu.Unlock()
+
+	if current == nil {
+		return false
+	}
+	stat, err := os.Stat(info.Path)
+	return err == nil && os.SameFile(current, stat)
+}
+
+// run follows the active file until the processor stops, returning
+// only a Fatal error from the process func
+func (f *follower) run() error {
+	p := f.w.processor
+
+	if w, err := watchFile(f.path); err == nil {
+		f.watcher = w
+		defer w.Close()
+	} else {
+		p.log.Debug("change notifications unavailable; polling the active file", "error", err)
+	}
+
+	for {
+		if err := p.files.acquire(p.ctx); err != nil {
+			return nil // Stopping
+		}
+		file, info, ok := f.open()
+		if !ok {
+			p.files.release()
+			return nil
+		}
+
+		rotated, err := f.follow(file, info)
+		p.files.release()
+		if err != nil || !rotated {
+			return err
+		}
+	}
+}
+
+// reclaim recognizes a rotation that happened while the processor was
+// stopped: if the active offset's fingerprint doesn't match the file
+// now at the active path, the offset is moved to the rotated segment
+// whose head does match, and the new active file starts from zero. It
+// runs before the first scan, so the rotated segment is resumed rather
+// than read again from the start.
+func (f *follower) reclaim() error {
+	p := f.w.processor
+	stored, ok := p.offsetMgr.stored(f.name)
+	if !ok || stored.Fingerprint == "" {
+		return nil
+	}
+
+	fingerprint := sourceFingerprint(p.source)
+	n := fingerprintLength(stored.Fingerprint)
+	if current, err := fingerprint(f.name, n); err == nil && current == stored.Fingerprint {
+		return nil // Still the same file
+	}
+
+	infos, err := p.source.List()
+	if err != nil {
+		return err
+	}
+	// Most recent rotation first
+	for i := len(infos) - 1; i >= 0; i-- {
+		name := infos[i].Name
+		if head, err := fingerprint(name, n); err == nil && head == stored.Fingerprint {
+			p.log.Info("active file rotated while stopped", "segment", name, "offset", stored.Offset)
+			return p.offsetMgr.relocate(f.name, name)
+		}
+	}
+	p.log.Warn("active file replaced while stopped and its rotated segment not found; starting it afresh",
+		"path", f.path, "offset", stored.Offset)
+	return p.offsetMgr.DeleteOffset(f.name)
+}
+
+// open waits for the active file to exist and opens it
+func (f *follower) open() (*os.File, os.FileInfo, bool) {
+	for {
+		file, err := os.Open(f.path)
+		if err == nil {
+			info, err := file.Stat()
+			if err == nil {
+				return file, info, true
+			}
+			file.Close()
+		}
+
+		if !f.wait() {
+			return nil, nil, false
+		}
+	}
+}
+
+// wait blocks until the active file may have changed, returning false
+// if the processor stops first. With a change watcher that is when it
+// signals (or, as a safety net, watchedFollowInterval passes);
+// otherwise after FollowInterval.
+func (f *follower) wait() bool {
+	p := f.w.processor
+
+	var changes <-chan struct{}
+	interval := p.cfg.followInterval()
+	if f.watcher != nil && !f.watcher.Failed() {
+		changes = f.watcher.Changes()
+		interval = max(interval, watchedFollowInterval)
+	}
+
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	select {
+	case <-p.ctx.Done():
+		return false
+	case <-changes:
+	case <-timer.C:
+	}
+	return true
+}
+
+// follow reads the open active file until it is rotated (returning
+// true) or the processor stops (returning false, with the error if a
+// Fatal one stopped it)
+func (f *follower) follow(file *os.File, info os.FileInfo) (bool, error) {
+	p := f.w.processor
+
+	// Resume from the committed offset unless the file has since been
+	// replaced by a smaller one
+	startOffset, linesProcessed := p.offsetMgr.GetOffset(f.name)
+	committed := startOffset
+	if startOffset > info.Size() {
+		startOffset, linesProcessed = 0, 0
+	}
+	if startOffset > 0 {
+		if _, err := file.Seek(startOffset, io.SeekStart); err != nil {
+			startOffset, linesProcessed = 0, 0
+			file.Seek(0, io.SeekStart)
+		}
+	}
+	if startOffset < committed {
+		// Starting over: move the stored offset back so later commits
+		// from the new file aren't refused as regressions
+		f.w.resetOffset(f.name)
+	}
+	epoch := p.offsetMgr.GetEpoch(f.name)
+
+	// Fingerprint commits from the open file, which stays the one read
+	// even once the active path refers to its successor
+	p.offsetMgr.setIdentity(f.name, func(n int64) (string, error) {
+		return hashHead(io.NewSectionReader(file, 0, n), n)
+	})
+
+	f.mu.Lock()
+	f.current = info
+	f.mu.Unlock()
+
+	opts := p.cfg.segmentReaderOptions(f.name)
+	opts.HoldPartial = true
+	reader := NewLogReaderFrom(file, f.path, startOffset, opts)
+	defer file.Close() // Shared by the readers of any retries
+
+	var lastTimestamp time.Time
+	var ordinal int64
+	pacer := p.newCommitPacer()
+
+	// Checkpoints pass between records, and while waiting for more. A
+	// stream at the active path, whose reads may block indefinitely,
+	// stays out of them.
+	stream := isStream(info)
+	if !stream {
+		p.barrier.mu.RLock()
+		defer p.barrier.mu.RUnlock()
+	} else {
+		// Interrupt a read waiting on the stream's writer on stop
+		stop := context.AfterFunc(p.ctx, func() { file.Close() })
+		defer stop()
+	}
+	for {
+		if !stream {
+			p.barrier.pass(func() error { return f.w.commitOffset(f.name, reader.Offset(), linesProcessed) })
+		}
+
+		select {
+		case <-p.ctx.Done():
+			f.w.commitOffset(f.name, reader.Offset(), linesProcessed)
+			return false, nil
+		default:
+		}
+
+		start := reader.Offset()
+		record, err := reader.Read()
+		if err == nil {
+			if !p.cfg.inWindow(record) {
+				if pacer.record() {
+					f.w.commitOffset(f.name, reader.Offset(), linesProcessed)
+				}
+				continue
+			}
+			if !p.admit() {
+				// Leave this record for the next run
+				f.w.commitOffset(f.name, start, linesProcessed)
+				return false, nil
+			}
+			if p.cfg.CheckMonotonic {
+				f.w.checkMonotonic(f.name, record, &lastTimestamp)
+			}
+			ordinal++
+			record.Segment, record.Ordinal, record.Epoch = f.name, ordinal, epoch
+			result, err := f.w.deliver(f.name, record, !stream)
+			switch result {
+			case delivered:
+				linesProcessed++
+			case halted:
+				f.w.commitOffset(f.name, start, linesProcessed)
+				p.halt(f.name, record, err)
+				return false, nil
+			case fatal:
+				f.w.commitOffset(f.name, start, linesProcessed)
+				return false, err
+			case retried:
+				// Read the record again once the retry delay has passed
+				f.w.commitOffset(f.name, start, linesProcessed)
+				ordinal--
+				if reader = f.retry(file, reader, start, err); reader == nil {
+					return false, nil
+				}
+				continue
+			}
+			if pacer.record() {
+				f.w.commitOffset(f.name, reader.Offset(), linesProcessed)
+			}
+			continue
+		}
+		if err != io.EOF && p.ctx.Err() == nil {
+			p.errors.Add(1)
+		}
+
+		// Caught up; persist progress and check for rotation
+		f.w.commitOffset(f.name, reader.Offset(), linesProcessed)
+
+		if f.rotated(info) {
+			err := f.handoff(file, reader, linesProcessed, &lastTimestamp, ordinal, epoch)
+			return err == nil, err
+		}
+
+		if !stream {
+			p.barrier.mu.RUnlock()
+		}
+		more := f.wait()
+		if !stream {
+			p.barrier.mu.RLock()
+		}
+		if !more {
+			return false, nil
+		}
+	}
+}
+
+// retry waits RetryDelay after a transient error from the record at
+// start, then returns a new reader over file positioned back at it, to
+// deliver it again. It returns nil if the processor stops first. The
+// caller holds the checkpoint barrier, which is let go meanwhile.
+func (f *follower) retry(file *os.File, reader *LogReader, start int64, err error) *LogReader {
+	p := f.w.processor
+	p.log.Warn("transient error; retrying",
+		"segment", f.name, "offset", start, "retry_in", p.cfg.retryDelay(), "error", err)
+
+	timer := time.NewTimer(p.cfg.retryDelay())
+	defer timer.Stop()
+	p.barrier.mu.RUnlock()
+	defer p.barrier.mu.RLock()
+	select {
+	case <-p.ctx.Done():
+		return nil
+	case <-timer.C:
+	}
+
+	if _, err := file.Seek(start, io.SeekStart); err != nil {
+		p.log.Error("seek to retry failed", "segment", f.name, "offset", start, "error", err)
+		return nil
+	}
+	next := NewLogReaderFrom(file, f.path, start, reader.opts)
+	next.lineNumber = reader.lineNumber - 1
+	return next
+}
+
+// rotated reports whether the active path now refers to a different file
+func (f *follower) rotated(current os.FileInfo) bool {
+	stat, err := os.Stat(f.path)
+	if err != nil {
+		return os.IsNotExist(err)
+	}
+	return !os.SameFile(current, stat)
+}
+
+// handoff drains the rotated file, records it as a completed segment
+// under its new name, and resets the active file's offset. A Fatal
+// error stops the drain there and is returned.
+func (f *follower) handoff(file *os.File, reader *LogReader, linesProcessed int64, lastTimestamp *time.Time, ordinal, epoch int64) error {
+	p := f.w.processor
+	var stopErr error
+
+	// The rotated file can no longer grow, so anything written just
+	// before the rename (including an unterminated last line) is final
+	reader.ReleasePartial()
+	offset := reader.Offset()
+	retry := !isStream(f.current)
+	for {
+		start := reader.Offset()
+		record, err := reader.Read()
+		if err != nil {
+			break
+		}
+		if !p.cfg.inWindow(record) {
+			offset = reader.Offset()
+			continue
+		}
+		if !p.admit() {
+			break // The rest is left for the next run
+		}
+		if p.cfg.CheckMonotonic {
+			f.w.checkMonotonic(f.name, record, lastTimestamp)
+		}
+		ordinal++
+		record.Segment, record.Ordinal, record.Epoch = f.name, ordinal, epoch
+		result, err := f.w.deliver(f.name, record, retry)
+		if result == halted {
+			p.halt(f.name, record, err)
+			break
+		}
+		if result == fatal {
+			stopErr = err
+			break
+		}
+		if result == retried {
+			ordinal--
+			if reader = f.retry(file, reader, start, err); reader == nil {
+				break // Left for the next run
+			}
+			reader.ReleasePartial()
+			continue
+		}
+		if result == delivered {
+			linesProcessed++
+		}
+		offset = reader.Offset()
+	}
+
+	f.mu.Lock()
+	current := f.current
+	f.mu.Unlock()
+
+	if name, ok := f.rotatedName(current); ok {
+		f.w.commitOffset(name, offset, linesProcessed)
+		p.log.Info("active file rotated", "segment", name, "offset", offset)
+	} else {
+		p.log.Warn("rotated active file not found among segments", "path", f.path)
+	}
+	// The new active file is a different file, not a replay of this one
+	p.offsetMgr.setIdentity(f.name, nil)
+	f.w.clearOffset(f.name)
+
+	f.mu.Lock()
+	f.current = nil
+	f.mu.Unlock()
+
+	_ = p.segmentMgr.Scan()
+	return stopErr
+}
+
+// rotatedName finds the segment name the followed file was renamed to
+func (f *follower) rotatedName(current os.FileInfo) (string, bool) {
+	infos, err := f.w.processor.source.List()
+	if err != nil {
+		return "", false
+	}
+	for _, info := range infos {
+		stat, err := os.Stat(info.Path)
+		if err == nil && os.SameFile(current, stat) {
+			return info.Name, true
+		}
+	}
+	return "", false
+}
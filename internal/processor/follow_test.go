@@ -0,0 +1,252 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// appendLines appends JSON records with the given messages to path
+func appendLines(t *testing.T, path string, messages ...string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	for _, msg := range messages {
+		if _, err := fmt.Fprintf(f, `{"message":%q}`+"\n", msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestFollowRotationHandoff rotates the active file mid-follow and
+// verifies every record is delivered exactly once, with no rotation
+// counted as a replay (Epoch stays 0)
+func TestFollowRotationHandoff(t *testing.T) {
+	cfg := newTestConfig(t, 2)
+	cfg.Follow = true
+	cfg.FollowInterval = 20 * time.Millisecond
+	cfg.ScanInterval = 10 * time.Millisecond
+
+	active := filepath.Join(cfg.LogsDir, "app.log")
+	appendLines(t, active, "a1", "a2")
+
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	total := 0
+	var epochs []int64
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+		counts[r.Entry.Message]++
+		total++
+		if r.Epoch != 0 {
+			epochs = append(epochs, r.Epoch)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	seen := func(n int) func() bool {
+		return func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return total >= n
+		}
+	}
+
+	waitFor(t, 2*time.Second, seen(2))
+
+	// Write a partial line, then finish it, to exercise held-back records
+	f, err := os.OpenFile(active, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"message":`)
+	time.Sleep(50 * time.Millisecond)
+	f.WriteString(`"a3"}` + "\n")
+	f.Close()
+	appendLines(t, active, "a4")
+
+	// Rotate, with a final record written just before the rename
+	appendLines(t, active, "a5")
+	rotated := filepath.Join(cfg.LogsDir, "app.log.20260101-000000")
+	if err := os.Rename(active, rotated); err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, active, "b1", "b2")
+
+	waitFor(t, 3*time.Second, seen(7))
+	waitFor(t, 2*time.Second, func() bool {
+		seg := proc.segmentMgr.GetSegment("app.log.20260101-000000")
+		return seg != nil && seg.State == SegmentComplete
+	})
+
+	// Give any erroneous reprocessing of the rotated file time to show up
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, msg := range []string{"a1", "a2", "a3", "a4", "a5", "b1", "b2"} {
+		if counts[msg] != 1 {
+			t.Errorf("record %s delivered %d times, want 1", msg, counts[msg])
+		}
+	}
+	if total != 7 {
+		t.Errorf("delivered %d records, want 7", total)
+	}
+	if len(epochs) != 0 {
+		t.Errorf("records delivered with epochs %v, want 0", epochs)
+	}
+	if epoch := proc.offsetMgr.GetEpoch("app.log"); epoch != 0 {
+		t.Errorf("active file epoch after rotation = %d, want 0", epoch)
+	}
+
+	info, err := os.Stat(rotated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset, _ := proc.offsetMgr.GetOffset("app.log.20260101-000000"); offset != info.Size() {
+		t.Errorf("rotated segment offset %d, want %d", offset, info.Size())
+	}
+}
+
+// TestFollowRestartAfterRotation rotates the active file while the
+// processor is stopped, replacing it with a larger one, and verifies the
+// restart resumes the rotated file where it left off and reads the new
+// one from the start: nothing lost, nothing delivered twice
+func TestFollowRestartAfterRotation(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.Follow = true
+	cfg.FollowInterval = 20 * time.Millisecond
+	cfg.ScanInterval = 10 * time.Millisecond
+
+	active := filepath.Join(cfg.LogsDir, "app.log")
+	appendLines(t, active, "old1", "old2", "old3", "old4", "old5")
+
+	var mu sync.Mutex
+	counts := make(map[string]int)
+	total := 0
+	run := func(want int) {
+		t.Helper()
+		proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+			mu.Lock()
+			defer mu.Unlock()
+			counts[r.Entry.Message]++
+			total++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("NewProcessor: %v", err)
+		}
+		if err := proc.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		waitFor(t, 2*time.Second, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return total >= want
+		})
+		time.Sleep(100 * time.Millisecond) // Let any duplicates arrive
+		if err := proc.Stop(); err != nil {
+			t.Fatalf("Stop: %v", err)
+		}
+	}
+
+	run(5)
+
+	// Rotate while stopped: one more record lands in the old file first,
+	// and the new active file is larger than the old offset
+	appendLines(t, active, "old6")
+	if err := os.Rename(active, filepath.Join(cfg.LogsDir, "app.log.20260101-000000")); err != nil {
+		t.Fatal(err)
+	}
+	var fresh []string
+	for i := 1; i <= 10; i++ {
+		fresh = append(fresh, fmt.Sprintf("new%d", i))
+	}
+	appendLines(t, active, fresh...)
+
+	run(16)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if total != 16 {
+		t.Errorf("delivered %d records, want 16", total)
+	}
+	for _, msg := range append([]string{"old1", "old2", "old3", "old4", "old5", "old6"}, fresh...) {
+		if counts[msg] != 1 {
+			t.Errorf("%q delivered %d times, want once", msg, counts[msg])
+		}
+	}
+}
+
+// TestFollowTransientRetry verifies a record of the active file that
+// fails transiently is delivered again after RetryDelay, in order,
+// rather than lost
+func TestFollowTransientRetry(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.Follow = true
+	cfg.FollowInterval = 20 * time.Millisecond
+	cfg.RetryDelay = 20 * time.Millisecond
+
+	active := filepath.Join(cfg.LogsDir, "app.log")
+	appendLines(t, active, "ok", "flaky", "done")
+
+	var mu sync.Mutex
+	var seen []string
+	attempts := 0
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.Entry.Message == "flaky" {
+			if attempts++; attempts <= 2 {
+				return Transient(errors.New("downstream unavailable"))
+			}
+		}
+		seen = append(seen, r.Entry.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(seen) >= 3
+	})
+	time.Sleep(50 * time.Millisecond) // Let any duplicates arrive
+
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"ok", "flaky", "done"}; !slices.Equal(seen, want) {
+		t.Errorf("delivered %q, want %q", seen, want)
+	}
+	if attempts != 3 {
+		t.Errorf("flaky record attempted %d times, want 3", attempts)
+	}
+	if processed, errs, _ := proc.Stats(); processed != 3 || errs != 2 {
+		t.Errorf("Stats() processed=%d errors=%d; want 3 and 2", processed, errs)
+	}
+}
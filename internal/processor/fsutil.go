@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// globDir returns the paths of entries in dir whose base name matches
+// pattern. Unlike filepath.Glob on a joined path, metacharacters in dir
+// itself are never interpreted, which matters for directories such as
+// "logs[1]" and for Windows paths where '\' cannot escape them.
+func globDir(dir, pattern string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var paths []string
+	for _, entry := range entries {
+		matched, err := filepath.Match(pattern, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if matched {
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// writeFileAtomic writes data to a temp file beside filename, syncs it,
+// and renames it into place so readers never observe a partial write
+func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmpFile := filename + ".tmp"
+
+	f, err := os.OpenFile(tmpFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+
+	if err := replaceFile(tmpFile, filename); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+
+	return syncDir(filepath.Dir(filename))
+}
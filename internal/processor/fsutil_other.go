@@ -0,0 +1,21 @@
+//go:build !windows
+
+package processor
+
+import "os"
+
+// replaceFile atomically renames src over dst
+func replaceFile(src, dst string) error {
+	return os.Rename(src, dst)
+}
+
+// syncDir flushes directory metadata so a completed rename survives a crash
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
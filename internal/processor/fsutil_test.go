@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWriteFileAtomicOverwrite verifies repeated writes replace the
+// destination in place (the case that breaks naive renames on Windows)
+func TestWriteFileAtomicOverwrite(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "seg.offset.json")
+
+	for _, content := range []string{"first", "second", "third"} {
+		if err := writeFileAtomic(filename, []byte(content), 0644); err != nil {
+			t.Fatalf("writeFileAtomic(%q): %v", content, err)
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Fatalf("got %q, want %q", data, content)
+		}
+	}
+
+	if _, err := os.Stat(filename + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("temp file left behind: %v", err)
+	}
+}
+
+// TestOffsetsInMetacharDir verifies offsets and segments are found in
+// directories whose names contain glob metacharacters
+func TestOffsetsInMetacharDir(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "logs[1]")
+	if err := os.MkdirAll(base, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	om, err := NewOffsetManager(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := om.CommitOffset("app.log.1", 10, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := om.CommitOffset("app.log.1", 20, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewOffsetManager(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset, lines := reloaded.GetOffset("app.log.1"); offset != 20 || lines != 2 {
+		t.Fatalf("reloaded offset=%d lines=%d, want 20 and 2", offset, lines)
+	}
+
+	writeSegment(t, base, "app.log.20260101-000000", `{"message":"x"}`)
+	infos, err := NewFileSource(base, "app.log").List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Name != "app.log.20260101-000000" {
+		t.Fatalf("unexpected segments: %+v", infos)
+	}
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package processor
+
+import "os"
+
+// replaceFile renames src over dst. os.Rename replaces existing files
+// on Windows, but can fail transiently if dst is briefly held open (for
+// example by a virus scanner), so fall back to remove-then-rename.
+func replaceFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+// syncDir is a no-op: Windows does not support fsync on directories
+func syncDir(dir string) error {
+	return nil
+}
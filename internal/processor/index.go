@@ -0,0 +1,299 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	json "github.com/goccy/go-json"
+
+	"log-processor/internal/logger"
+)
+
+// fingerprintBytes is how much of a segment's head is hashed
+const fingerprintBytes = 4096
+
+// Filter selects records by level and service, and optionally a query
+// expression (see ParseFilter). Empty fields match anything.
+type Filter struct {
+	Level   logger.LogLevel
+	Service string
+	Where   Expr
+}
+
+// Match reports whether an entry passes the filter
+func (f Filter) Match(e logger.LogEntry) bool {
+	return f.matchKey(e.Level, e.Service) && (f.Where == nil || f.Where.Match(e))
+}
+
+func (f Filter) matchKey(level logger.LogLevel, service string) bool {
+	return (f.Level == "" || f.Level == level) &&
+		(f.Service == "" || f.Service == service)
+}
+
+// segmentIndex maps (level, service) to the start offsets of matching
+// records in one segment, and holds a sparse line index of it. It is
+// only valid for the segment contents described by Fingerprint.
+type segmentIndex struct {
+	Segment     string                                 `json:"segment"`
+	Fingerprint string                                 `json:"fingerprint"`
+	Offsets     map[logger.LogLevel]map[string][]int64 `json:"offsets"`
+	Lines       *LineIndex                             `json:"lines,omitempty"`
+}
+
+// newSegmentIndex creates an empty index for a segment, taking a line
+// mark every lineStride lines
+func newSegmentIndex(segment string, lineStride int64) *segmentIndex {
+	return &segmentIndex{
+		Segment: segment,
+		Offsets: make(map[logger.LogLevel]map[string][]int64),
+		Lines:   newLineIndex(lineStride),
+	}
+}
+
+// add records that a record read from offset has the entry's keys.
+// consumed is the number of lines before offset, so any blank lines
+// skipped ahead of the record are marked rather than the record's own
+// line. Unparseable records are not indexed by key, only by line.
+func (idx *segmentIndex) add(record *LogRecord, offset, consumed int64) {
+	idx.Lines.add(consumed+1, offset)
+	if record.ParseErr != nil {
+		return
+	}
+
+	level, service := record.Entry.Level, record.Entry.Service
+	if idx.Offsets[level] == nil {
+		idx.Offsets[level] = make(map[string][]int64)
+	}
+	idx.Offsets[level][service] = append(idx.Offsets[level][service], offset)
+}
+
+// lookup returns the sorted start offsets of records matching f
+func (idx *segmentIndex) lookup(f Filter) []int64 {
+	var offsets []int64
+	for level, services := range idx.Offsets {
+		for service, offs := range services {
+			if f.matchKey(level, service) {
+				offsets = append(offsets, offs...)
+			}
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	return offsets
+}
+
+// indexPath returns the sidecar index file for a segment
+func (p *Processor) indexPath(segment string) string {
+	return filepath.Join(p.cfg.OffsetsDir, segment+".index.json")
+}
+
+// saveIndex persists a segment index stamped with the fingerprint
+func (p *Processor) saveIndex(idx *segmentIndex, fingerprint string) error {
+	idx.Fingerprint = fingerprint
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p.indexPath(idx.Segment), data, 0644)
+}
+
+// loadIndex reads a segment's index, returning nil if there is none or
+// it was built for different segment contents
+func (p *Processor) loadIndex(segment, fingerprint string) *segmentIndex {
+	data, err := os.ReadFile(p.indexPath(segment))
+	if err != nil {
+		return nil
+	}
+
+	var idx segmentIndex
+	if err := json.Unmarshal(data, &idx); err != nil {
+		return nil // Corrupted; rebuilt by the next full pass
+	}
+	if idx.Segment != segment || idx.Fingerprint != fingerprint {
+		return nil
+	}
+	return &idx
+}
+
+// removeIndex deletes a segment's index, if any
+func (p *Processor) removeIndex(segment string) error {
+	err := os.Remove(p.indexPath(segment))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// fingerprint identifies a segment's contents by its size and a hash
+// of its first bytes, so a replaced or rewritten segment is detected
+func (p *Processor) fingerprint(name string, size int64) (string, error) {
+	rc, err := p.source.Open(name, 0)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	h := sha256.New()
+	if _, err := io.CopyN(h, rc, fingerprintBytes); err != nil && err != io.EOF {
+		return "", err
+	}
+	return strconv.FormatInt(size, 10) + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Filter invokes fn for every record matching f in every segment
+// currently available, in segment order. Like Each it is a read-only
+// scan from offset zero. Segments with a valid index (see BuildIndex)
+// are read only at the indexed offsets; others are scanned in full,
+// building their index on the way when BuildIndex is set.
+func (p *Processor) Filter(ctx context.Context, f Filter, fn ProcessFunc) error {
+	infos, err := p.source.List()
+	if err != nil {
+		return err
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	for _, info := range infos {
+		if err := p.filterSegment(ctx, info, f, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterSegment invokes fn for the matching records of one segment
+func (p *Processor) filterSegment(ctx context.Context, info SegmentInfo, f Filter, fn ProcessFunc) error {
+	if info.Stream {
+		return p.eachInSegment(ctx, info, matching(f, fn))
+	}
+
+	fingerprint, err := p.fingerprint(info.Name, info.Size)
+	if err != nil {
+		return err
+	}
+
+	if idx := p.loadIndex(info.Name, fingerprint); idx != nil {
+		return p.readIndexed(ctx, info, idx.lookup(f), matching(f, fn))
+	}
+
+	if !p.cfg.BuildIndex {
+		return p.eachInSegment(ctx, info, matching(f, fn))
+	}
+
+	// Full pass, indexing every record as it goes
+	idx := newSegmentIndex(info.Name, p.cfg.LineIndexStride)
+	rc, err := p.source.Open(info.Name, 0)
+	if err != nil {
+		return err
+	}
+	reader := NewLogReaderFrom(rc, info.Path, 0, p.cfg.segmentReaderOptions(info.Name))
+	defer reader.Close()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		start, consumed := reader.Offset(), reader.LineNumber()
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		idx.add(record, start, consumed)
+		if record.ParseErr == nil && f.Match(record.Entry) {
+			if err := fn(record); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := p.saveIndex(idx, fingerprint); err != nil {
+		return fmt.Errorf("save index for %s: %w", info.Name, err)
+	}
+	return nil
+}
+
+// readIndexed reads the single record at each of the ascending offsets,
+// opening the segment once and moving forward through it
+func (p *Processor) readIndexed(ctx context.Context, info SegmentInfo, offsets []int64, fn ProcessFunc) error {
+	if len(offsets) == 0 {
+		return nil
+	}
+	rc, err := p.source.Open(info.Name, offsets[0])
+	if err != nil {
+		return err
+	}
+	reader := NewLogReaderFrom(rc, info.Path, offsets[0], p.cfg.segmentReaderOptions(info.Name))
+	defer reader.Close()
+
+	for _, offset := range offsets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := reader.skipTo(offset)
+		var record *LogRecord
+		if err == nil {
+			record, err = reader.Read()
+		}
+		if err != nil {
+			return fmt.Errorf("read indexed record at %s:%d: %w", info.Name, offset, err)
+		}
+
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipTo moves the reader forward to offset, seeking when the segment
+// allows and discarding the bytes in between otherwise
+func (lr *LogReader) skipTo(offset int64) error {
+	lr.unpeek()
+	pos := lr.offset
+	if lr.pending != nil {
+		pos = lr.pendingOffset // Read up to the end of the held record
+	}
+	gap := offset - pos
+	if gap < 0 {
+		return fmt.Errorf("offset %d is behind the reader at %d", offset, pos)
+	}
+
+	seeker, ok := lr.file.(io.Seeker)
+	if !ok || gap <= int64(lr.reader.Buffered()) {
+		if _, err := io.CopyN(io.Discard, lr.reader, gap); err != nil {
+			return err
+		}
+	} else {
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+		lr.reader.Reset(lr.file)
+	}
+	lr.partial, lr.pending = nil, nil
+	lr.offset = offset
+	return nil
+}
+
+// matching wraps fn so it only sees parsed records passing f
+func matching(f Filter, fn ProcessFunc) ProcessFunc {
+	return func(record *LogRecord) error {
+		if record.ParseErr != nil || !f.Match(record.Entry) {
+			return nil
+		}
+		return fn(record)
+	}
+}
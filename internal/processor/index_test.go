@@ -0,0 +1,174 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"log-processor/internal/logger"
+)
+
+// filterMessages runs a filter and returns the matching messages
+func filterMessages(t *testing.T, proc *Processor, f Filter) []string {
+	t.Helper()
+
+	var messages []string
+	err := proc.Filter(context.Background(), f, func(r *LogRecord) error {
+		messages = append(messages, r.Entry.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Filter: %v", err)
+	}
+	return messages
+}
+
+// TestFilterIndex verifies processing builds an index that Filter
+// consults, and that changing the segment invalidates it
+func TestFilterIndex(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.BuildIndex = true
+
+	// Pad past the fingerprinted head so a later in-place edit is
+	// invisible to the fingerprint
+	var lines []string
+	for i := 0; len(strings.Join(lines, "\n")) < 2*fingerprintBytes; i++ {
+		lines = append(lines, fmt.Sprintf(`{"level":"INFO","service":"api","message":"pad%04d"}`, i))
+	}
+	lines = append(lines,
+		`{"level":"ERROR","service":"payment-service","message":"hit1"}`,
+		`{"level":"ERROR","service":"auth-service","message":"miss"}`,
+		`{"level":"ERROR","service":"payment-service","message":"hit2"}`,
+		`{"level":"INFO","service":"payment-service","message":"late"}`,
+	)
+	path := writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", lines...)
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+	proc.Stop()
+
+	if _, err := os.Stat(filepath.Join(cfg.OffsetsDir, "app.log.20260101-000000.index.json")); err != nil {
+		t.Fatalf("index not written: %v", err)
+	}
+
+	query := Filter{Level: logger.ERROR, Service: "payment-service"}
+	if got := filterMessages(t, proc, query); strings.Join(got, ",") != "hit1,hit2" {
+		t.Fatalf("filtered = %q", got)
+	}
+
+	// Rewrite the last record in place, keeping the size and head. The
+	// index still matches, so only the indexed offsets are read.
+	data, _ := os.ReadFile(path)
+	edited := strings.Replace(string(data),
+		`{"level":"INFO","service":"payment-service","message":"late"}`,
+		`{"level":"ERROR","service":"payment-service","message":"lat"}`, 1)
+	if len(edited) != len(data) || edited == string(data) {
+		t.Fatal("test edit must change the record but not the size")
+	}
+	if err := os.WriteFile(path, []byte(edited), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := filterMessages(t, proc, query); strings.Join(got, ",") != "hit1,hit2" {
+		t.Fatalf("filtered with valid index = %q", got)
+	}
+
+	// Appending changes the fingerprint, forcing a full scan (and a
+	// rebuilt index that includes the new record)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"level":"ERROR","service":"payment-service","message":"hit3"}` + "\n")
+	f.Close()
+
+	for i := 0; i < 2; i++ {
+		got := filterMessages(t, proc, query)
+		if strings.Join(got, ",") != "hit1,hit2,lat,hit3" {
+			t.Fatalf("pass %d after change: filtered = %q", i, got)
+		}
+	}
+}
+
+// openCounter counts the segments opened through a source
+type openCounter struct {
+	SegmentSource
+	opens atomic.Int64
+}
+
+func (s *openCounter) Open(name string, offset int64) (io.ReadCloser, error) {
+	s.opens.Add(1)
+	return s.SegmentSource.Open(name, offset)
+}
+
+// TestFilterIndexOpensOnce verifies an indexed segment's records are read
+// through one open however many of them match, whether the reader seeks
+// (files) or reads past the records between matches (memory)
+func TestFilterIndexOpensOnce(t *testing.T) {
+	var lines, want []string
+	for i := range 500 {
+		level, service := "INFO", "api"
+		if i%7 == 3 {
+			level, service = "ERROR", "payment-service"
+			want = append(want, fmt.Sprintf("m%03d", i))
+		}
+		lines = append(lines, fmt.Sprintf(`{"level":%q,"service":%q,"message":"m%03d"}`, level, service, i))
+	}
+
+	cfg := newTestConfig(t, 1)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", lines...)
+	memory := NewMemorySource()
+	memory.Put("app.log.20260101-000000", []byte(strings.Join(lines, "\n")+"\n"))
+
+	sources := map[string]SegmentSource{
+		"file":   NewFileSource(cfg.LogsDir, cfg.LogPattern),
+		"memory": memory,
+	}
+	for name, source := range sources {
+		t.Run(name, func(t *testing.T) {
+			cfg := cfg
+			cfg.OffsetsDir = t.TempDir()
+			cfg.BuildIndex = true
+			src := &openCounter{SegmentSource: source}
+			cfg.Source = src
+
+			proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+			if err != nil {
+				t.Fatalf("NewProcessor: %v", err)
+			}
+			if err := proc.Start(context.Background()); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			waitFor(t, 2*time.Second, func() bool {
+				_, _, segStats := proc.Stats()
+				return segStats[3] == 1
+			})
+			proc.Stop()
+
+			src.opens.Store(0)
+			got := filterMessages(t, proc, Filter{Level: logger.ERROR, Service: "payment-service"})
+			if !slices.Equal(got, want) {
+				t.Fatalf("filtered %d records %q, want %d", len(got), got, len(want))
+			}
+			// Once to fingerprint the segment, once to read its records
+			if n := src.opens.Load(); n != 2 {
+				t.Errorf("segment opened %d times, want 2", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package processor
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// latencySubBuckets splits each power of two into this many buckets,
+// bounding a reported percentile's error to 1/8 (12.5%)
+const latencySubBuckets = 8
+
+// LatencyStats summarizes how long the process func took per record
+// (see Config.MeasureLatency). Percentiles are upper bounds accurate to
+// within 12.5%.
+type LatencyStats struct {
+	Count int64
+	P50   time.Duration
+	P95   time.Duration
+	P99   time.Duration
+	Max   time.Duration
+}
+
+// latencyHistogram is a lock-free log-linear histogram of durations
+type latencyHistogram struct {
+	buckets [64 * latencySubBuckets]atomic.Int64
+	count   atomic.Int64
+	max     atomic.Int64
+}
+
+// latencyBucket returns the bucket of a duration in nanoseconds. Values
+// below 8 have their own buckets; above that, each power of two has
+// latencySubBuckets buckets selected by the three bits after the
+// leading one.
+func latencyBucket(ns int64) int {
+	v := uint64(max(ns, 0))
+	e := bits.Len64(v)
+	if e <= 3 {
+		return int(v)
+	}
+	mantissa := (v >> (e - 4)) & (latencySubBuckets - 1)
+	return (e-3)*latencySubBuckets + int(mantissa)
+}
+
+// latencyBucketMax returns the largest value in a bucket
+func latencyBucketMax(i int) int64 {
+	if i < latencySubBuckets {
+		return int64(i)
+	}
+	e, mantissa := i/latencySubBuckets+3, uint64(i%latencySubBuckets)
+	upper := (latencySubBuckets+mantissa+1)<<(e-4) - 1
+	return int64(min(upper, math.MaxInt64))
+}
+
+// record adds one observation
+func (h *latencyHistogram) record(d time.Duration) {
+	ns := int64(d)
+	h.buckets[latencyBucket(ns)].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.max.Load()
+		if ns <= old || h.max.CompareAndSwap(old, ns) {
+			return
+		}
+	}
+}
+
+// stats computes the percentiles. Observations recorded concurrently
+// may or may not be included.
+func (h *latencyHistogram) stats() LatencyStats {
+	var counts [len(h.buckets)]int64
+	var total int64
+	for i := range h.buckets {
+		counts[i] = h.buckets[i].Load()
+		total += counts[i]
+	}
+
+	s := LatencyStats{Count: total, Max: time.Duration(h.max.Load())}
+	if total == 0 {
+		return s
+	}
+
+	quantile := func(q float64) time.Duration {
+		rank := int64(math.Ceil(q * float64(total)))
+		var seen int64
+		for i, n := range counts {
+			if seen += n; seen >= rank {
+				return min(time.Duration(latencyBucketMax(i)), s.Max)
+			}
+		}
+		return s.Max
+	}
+	s.P50, s.P95, s.P99 = quantile(0.50), quantile(0.95), quantile(0.99)
+	return s
+}
@@ -0,0 +1,65 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestLatencyBuckets verifies every value falls in a bucket whose bound
+// is within 12.5% above it
+func TestLatencyBuckets(t *testing.T) {
+	for _, ns := range []int64{0, 1, 7, 8, 9, 15, 16, 100, 999, 1e6, 20e6, 3e9, 1 << 62} {
+		upper := latencyBucketMax(latencyBucket(ns))
+		if upper < ns || float64(upper-ns) > float64(ns)/8 {
+			t.Errorf("%d ns: bucket bound %d", ns, upper)
+		}
+	}
+}
+
+// TestMeasureLatency verifies a callback's known duration is reported
+func TestMeasureLatency(t *testing.T) {
+	const sleep = 20 * time.Millisecond
+
+	cfg := newTestConfig(t, 1)
+	cfg.MeasureLatency = true
+	var lines []string
+	for i := 0; i < 10; i++ {
+		lines = append(lines, fmt.Sprintf(`{"message":"m%d"}`, i))
+	}
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", lines...)
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error {
+		time.Sleep(sleep)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if got := proc.Latency(); got.Count != 0 {
+		t.Fatalf("latency before processing = %+v", got)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := proc.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle: %v", err)
+	}
+	proc.Stop()
+
+	got := proc.Latency()
+	if got.Count != 10 {
+		t.Fatalf("Count = %d, want 10", got.Count)
+	}
+	for name, d := range map[string]time.Duration{"p50": got.P50, "p95": got.P95, "p99": got.P99, "max": got.Max} {
+		if d < sleep || d > 4*sleep {
+			t.Errorf("%s = %v, want about %v", name, d, sleep)
+		}
+	}
+	if got.P50 > got.P99 || got.P99 > got.Max {
+		t.Errorf("percentiles out of order: %+v", got)
+	}
+}
@@ -0,0 +1,105 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	json "github.com/goccy/go-json"
+)
+
+// latestMarker is written to OffsetsDir once ResumeFromLatest has
+// seeded it, so later runs don't skip the backlog that built up while
+// the processor was down
+const latestMarker = "resume-from-latest.json"
+
+// latestSeed is the content of latestMarker
+type latestSeed struct {
+	SeededAt time.Time `json:"seeded_at"`
+	Segments int       `json:"segments"`
+}
+
+// seedLatest implements Config.ResumeFromLatest: on the first run
+// against an empty OffsetsDir, every segment present is committed at
+// its size, and with Follow the active file at the end of its last
+// complete record, so that only data written from now on is processed
+func (p *Processor) seedLatest() error {
+	if !p.cfg.ResumeFromLatest {
+		return nil
+	}
+	marker := filepath.Join(p.cfg.OffsetsDir, latestMarker)
+	if _, err := os.Stat(marker); err == nil || !os.IsNotExist(err) {
+		return err
+	}
+	if len(p.offsetMgr.GetAllOffsets()) > 0 {
+		p.log.Info("offsets already stored; not resuming from latest")
+		return nil
+	}
+
+	infos, err := p.source.List()
+	if err != nil {
+		return err
+	}
+	seed := latestSeed{SeededAt: time.Now().UTC()}
+	for _, info := range infos {
+		if info.Stream {
+			continue
+		}
+		if err := p.offsetMgr.CommitOffset(info.Name, info.Size, 0); err != nil {
+			return err
+		}
+		seed.Segments++
+	}
+
+	if p.cfg.Follow {
+		path := filepath.Join(p.cfg.LogsDir, p.cfg.LogPattern)
+		end, err := lastRecordEnd(path, p.cfg.delimiter())
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if end > 0 {
+			if err := p.offsetMgr.CommitOffset(p.cfg.LogPattern, end, 0); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(seed, "", "  ")
+	if err != nil {
+		return err
+	}
+	p.log.Info("resuming from latest; existing segments skipped", "segments", seed.Segments)
+	return writeFileAtomic(marker, data, 0644)
+}
+
+// lastRecordEnd returns the offset just past the last delimiter in the
+// file at path, so a record still being written isn't cut in two
+func lastRecordEnd(path string, delim byte) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	buf := make([]byte, 64<<10)
+	for end := info.Size(); end > 0; {
+		start := max(end-int64(len(buf)), 0)
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+		if i := bytes.LastIndexByte(chunk, delim); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+		end = start
+	}
+	return 0, nil
+}
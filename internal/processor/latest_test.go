@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestResumeFromLatest verifies segments present on the first run are
+// skipped while newer ones are processed, and that the seeding isn't
+// repeated on a restart
+func TestResumeFromLatest(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.ResumeFromLatest = true
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", `{"level":"INFO","message":"old-1"}`)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-010000", `{"level":"INFO","message":"old-2"}`)
+
+	var mu sync.Mutex
+	var seen []string
+	run := func(segments int) {
+		t.Helper()
+		proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+			mu.Lock()
+			seen = append(seen, r.Entry.Message)
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("NewProcessor: %v", err)
+		}
+		if err := proc.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		defer proc.Stop()
+
+		if segments > 0 {
+			writeSegment(t, cfg.LogsDir, "app.log.20260101-020000", `{"level":"INFO","message":"new"}`)
+		}
+		waitFor(t, 2*time.Second, func() bool {
+			_, _, segStats := proc.Stats()
+			return segStats[0] == segments && segStats[3] == segments
+		})
+	}
+
+	run(3)
+	mu.Lock()
+	if len(seen) != 1 || seen[0] != "new" {
+		t.Fatalf("first run processed %q, want just the new segment", seen)
+	}
+	seen = nil
+	mu.Unlock()
+	if _, err := os.Stat(filepath.Join(cfg.OffsetsDir, latestMarker)); err != nil {
+		t.Fatalf("seed marker: %v", err)
+	}
+
+	// Written while the processor was down: part of the backlog a
+	// restart must not skip
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-030000", `{"level":"INFO","message":"while down"}`)
+	run(4)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 1 || seen[0] != "while down" {
+		t.Fatalf("restart processed %q, want the segment written while down", seen)
+	}
+}
+
+// TestLastRecordEnd verifies the active file is seeded before a record
+// still being written
+func TestLastRecordEnd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	content := `{"message":"a"}` + "\n" + `{"message":"b"}` + "\n" + `{"message":"par`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	end, err := lastRecordEnd(path, '\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := int64(len(content) - len(`{"message":"par`)); end != want {
+		t.Fatalf("lastRecordEnd = %d, want %d", end, want)
+	}
+}
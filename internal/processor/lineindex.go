@@ -0,0 +1,133 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// defaultLineIndexStride is how many lines apart line marks are taken
+// when Config.LineIndexStride is unset
+const defaultLineIndexStride = 1000
+
+// LineMark records where a line starts in a segment
+type LineMark struct {
+	Line   int64 `json:"l"` // 1-based line number, counting blank lines
+	Offset int64 `json:"o"` // Byte offset where the line starts
+}
+
+// LineIndex is a sparse line number to byte offset index holding a mark
+// about every Stride lines, so a line can be reached by seeking to the
+// nearest earlier mark and scanning forward
+type LineIndex struct {
+	Stride int64      `json:"stride"`
+	Marks  []LineMark `json:"marks"`
+}
+
+// newLineIndex creates an empty index taking a mark every stride lines
+func newLineIndex(stride int64) *LineIndex {
+	if stride <= 0 {
+		stride = defaultLineIndexStride
+	}
+	return &LineIndex{Stride: stride}
+}
+
+// add notes that line starts at offset. Lines must be added in order
+// from the start of the segment.
+func (li *LineIndex) add(line, offset int64) {
+	if n := len(li.Marks); n > 0 && line < li.Marks[n-1].Line+li.Stride {
+		return
+	}
+	li.Marks = append(li.Marks, LineMark{Line: line, Offset: offset})
+}
+
+// Nearest returns the last mark at or before line n, or the start of
+// the segment if there is none
+func (li *LineIndex) Nearest(n int64) LineMark {
+	i := sort.Search(len(li.Marks), func(i int) bool { return li.Marks[i].Line > n })
+	if i == 0 {
+		return LineMark{Line: 1, Offset: 0}
+	}
+	return li.Marks[i-1]
+}
+
+// SetLineIndex gives the reader a line index for SeekToLine. The index
+// must have been built from the same segment contents.
+func (lr *LogReader) SetLineIndex(idx *LineIndex) {
+	lr.lines = idx
+}
+
+// SeekToLine positions the reader so the next Read returns line n (or
+// the first non-blank line after it). It seeks to the nearest indexed
+// line at or before n, if any, and scans forward from there. Line
+// numbers count from where the reader was opened, so it must have been
+// opened at the start of the segment. A reader over a source that
+// can't seek can only move forward.
+func (lr *LogReader) SeekToLine(n int64) error {
+	if n < 1 {
+		return fmt.Errorf("line %d out of range", n)
+	}
+
+	lr.unpeek()
+	mark := LineMark{Line: 1, Offset: 0}
+	if lr.lines != nil {
+		mark = lr.lines.Nearest(n)
+	}
+
+	// Scanning forward from the current position beats seeking back to
+	// an earlier mark
+	if seeker, ok := lr.file.(io.Seeker); ok && (lr.lineNumber >= n || mark.Line > lr.lineNumber+1) {
+		if _, err := seeker.Seek(mark.Offset, io.SeekStart); err != nil {
+			return err
+		}
+		lr.reader.Reset(lr.file)
+		lr.partial = nil
+		lr.offset = mark.Offset
+		lr.lineNumber = mark.Line - 1
+	} else if lr.lineNumber >= n {
+		return ErrNotSeekable
+	}
+
+	return lr.SkipLines(n - 1)
+}
+
+// OpenAtLine opens a reader on a segment positioned at line n, seeking
+// via the segment's line index (see BuildIndex) when a valid one exists
+// and scanning from the start otherwise
+func (p *Processor) OpenAtLine(segment string, n int64) (*LogReader, error) {
+	infos, err := p.source.List()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range infos {
+		if info.Name != segment {
+			continue
+		}
+
+		var lines *LineIndex
+		if !info.Stream {
+			fingerprint, err := p.fingerprint(info.Name, info.Size)
+			if err != nil {
+				return nil, err
+			}
+			if idx := p.loadIndex(info.Name, fingerprint); idx != nil {
+				lines = idx.Lines
+			}
+		}
+
+		rc, err := p.source.Open(info.Name, 0)
+		if err != nil {
+			return nil, err
+		}
+		reader := NewLogReaderFrom(rc, info.Path, 0, p.cfg.readerOptions())
+		reader.SetLineIndex(lines)
+		if err := reader.SeekToLine(n); err != nil && err != io.EOF {
+			reader.Close()
+			return nil, err
+		}
+		return reader, nil
+	}
+	return nil, fmt.Errorf("segment %s: %w", segment, os.ErrNotExist)
+}
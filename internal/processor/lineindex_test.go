@@ -0,0 +1,86 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestSeekToLine verifies processing builds a sparse line index and
+// OpenAtLine/SeekToLine land on the requested line, with or without it
+func TestSeekToLine(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.BuildIndex = true
+	cfg.LineIndexStride = 10
+
+	// Blank lines count as lines, so line numbers stay file-relative
+	var lines []string
+	for i := 1; i <= 95; i++ {
+		if i%7 == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, fmt.Sprintf(`{"message":"line %d"}`, i))
+	}
+	const name = "app.log.20260101-000000"
+	writeSegment(t, cfg.LogsDir, name, lines...)
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+
+	// Before processing there is no index; the segment is scanned
+	reader, err := proc.OpenAtLine(name, 45)
+	if err != nil {
+		t.Fatalf("OpenAtLine without index: %v", err)
+	}
+	if record, err := reader.Read(); err != nil || record.Entry.Message != "line 45" {
+		t.Fatalf("Read() = %+v, %v; want line 45", record, err)
+	}
+	reader.Close()
+
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+	proc.Stop()
+
+	fingerprint, err := proc.fingerprint(name, proc.segmentMgr.GetSegment(name).Size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idx := proc.loadIndex(name, fingerprint)
+	if idx == nil || idx.Lines == nil || len(idx.Lines.Marks) < 9 {
+		t.Fatalf("line index not built: %+v", idx)
+	}
+
+	reader, err = proc.OpenAtLine(name, 1)
+	if err != nil {
+		t.Fatalf("OpenAtLine: %v", err)
+	}
+	defer reader.Close()
+
+	// Forward, backward, onto a blank line and onto a mark
+	for _, n := range []int64{88, 45, 3, 50, 14, 95, 1} {
+		if err := reader.SeekToLine(n); err != nil {
+			t.Fatalf("SeekToLine(%d): %v", n, err)
+		}
+		record, err := reader.Read()
+		if err != nil {
+			t.Fatalf("Read after SeekToLine(%d): %v", n, err)
+		}
+
+		want := n
+		if want%7 == 0 {
+			want++ // Blank; the next record is returned
+		}
+		if record.LineNumber != want || record.Entry.Message != fmt.Sprintf("line %d", want) {
+			t.Fatalf("SeekToLine(%d) read line %d %q, want line %d", n, record.LineNumber, record.Entry.Message, want)
+		}
+	}
+}
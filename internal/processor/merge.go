@@ -0,0 +1,213 @@
+package processor
+
+import (
+	"container/heap"
+	"fmt"
+	"io"
+	"time"
+)
+
+// UntimedPolicy says how MergeReader orders records without a parseable
+// RFC 3339 timestamp (including records that failed to parse)
+type UntimedPolicy int
+
+const (
+	// UntimedPrevious orders a record at the timestamp of the record
+	// before it in its segment, keeping it with its neighbours; at the
+	// start of a segment it sorts first
+	UntimedPrevious UntimedPolicy = iota
+
+	// UntimedDrop skips the record, counting it in Dropped
+	UntimedDrop
+
+	// UntimedError makes Read fail at the record
+	UntimedError
+)
+
+// MergeOptions configures a MergeReader
+type MergeOptions struct {
+	Untimed UntimedPolicy
+
+	// Offsets, if set, resumes each segment from its committed offset,
+	// and Commit persists the position reached in each
+	Offsets *OffsetManager
+}
+
+// MergeReader merges segments that are each in timestamp order into a
+// single stream in global timestamp order, e.g. to interleave the logs
+// of several services into one consolidated view. It holds one record
+// per segment in a min-heap, so memory does not grow with segment size.
+// Records with equal timestamps come in the order segments were given.
+//
+// Records are returned with Segment set. A segment's position only
+// advances past records that Read has returned, so committing (see
+// MergeOptions.Offsets) never skips a record still waiting in the heap.
+type MergeReader struct {
+	opts MergeOptions
+	segs []*mergeSegment
+	heap mergeHeap
+
+	dropped int64
+	err     error // From reading ahead, returned by the next Read
+}
+
+// mergeSegment is one input of a MergeReader
+type mergeSegment struct {
+	name   string
+	index  int
+	reader *LogReader
+	last   time.Time // Timestamp of the last record read
+
+	offset    int64 // After the last record returned
+	lines     int64 // Records returned, including earlier runs
+	committed int64 // Offset last committed
+}
+
+// mergeItem is a record waiting in the heap
+type mergeItem struct {
+	record *LogRecord
+	ts     time.Time
+	seg    *mergeSegment
+}
+
+// NewMergeReader opens the named segments of source for merging, each
+// read with readerOpts
+func NewMergeReader(source SegmentSource, segments []string, readerOpts ReaderOptions, opts MergeOptions) (*MergeReader, error) {
+	m := &MergeReader{opts: opts}
+	for i, name := range segments {
+		seg := &mergeSegment{name: name, index: i}
+		if opts.Offsets != nil {
+			seg.offset, seg.lines = opts.Offsets.GetOffset(name)
+			seg.committed = seg.offset
+		}
+
+		rc, err := source.Open(name, seg.offset)
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		seg.reader = NewLogReaderFrom(rc, name, seg.offset, readerOpts)
+		m.segs = append(m.segs, seg)
+	}
+
+	for _, seg := range m.segs {
+		if err := m.fill(seg); err != nil {
+			m.Close()
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// fill reads the next record of seg into the heap, if it has one
+func (m *MergeReader) fill(seg *mergeSegment) error {
+	for {
+		record, err := seg.reader.Read()
+		if err == io.EOF {
+			// Nothing of seg is waiting, so its end (past any dropped
+			// records) has been reached
+			seg.offset = seg.reader.Offset()
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", seg.name, err)
+		}
+
+		ts, err := time.Parse(time.RFC3339Nano, record.Entry.Timestamp)
+		if err != nil || record.ParseErr != nil {
+			switch m.opts.Untimed {
+			case UntimedDrop:
+				m.dropped++
+				continue
+			case UntimedError:
+				return fmt.Errorf("%s:%d: record has no timestamp to merge by", seg.name, record.LineNumber)
+			}
+			ts = seg.last
+		}
+		seg.last = ts
+
+		record.Segment = seg.name
+		heap.Push(&m.heap, mergeItem{record: record, ts: ts, seg: seg})
+		return nil
+	}
+}
+
+// Read returns the earliest record not yet returned, or io.EOF once
+// every segment has been read to its end
+func (m *MergeReader) Read() (*LogRecord, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if m.heap.Len() == 0 {
+		return nil, io.EOF
+	}
+
+	item := heap.Pop(&m.heap).(mergeItem)
+	item.seg.offset = item.record.Offset
+	item.seg.lines++
+	m.err = m.fill(item.seg)
+	return item.record, nil
+}
+
+// Commit persists the position of every segment that has advanced
+// since the last commit. It does nothing without MergeOptions.Offsets.
+func (m *MergeReader) Commit() error {
+	if m.opts.Offsets == nil {
+		return nil
+	}
+	for _, seg := range m.segs {
+		if seg.offset == seg.committed {
+			continue
+		}
+		if err := m.opts.Offsets.CommitOffset(seg.name, seg.offset, seg.lines); err != nil {
+			return err
+		}
+		seg.committed = seg.offset
+	}
+	return nil
+}
+
+// Positions returns the offset after the last record returned from each
+// segment
+func (m *MergeReader) Positions() map[string]int64 {
+	positions := make(map[string]int64, len(m.segs))
+	for _, seg := range m.segs {
+		positions[seg.name] = seg.offset
+	}
+	return positions
+}
+
+// Dropped returns how many records UntimedDrop skipped
+func (m *MergeReader) Dropped() int64 {
+	return m.dropped
+}
+
+// Close closes every segment
+func (m *MergeReader) Close() error {
+	var first error
+	for _, seg := range m.segs {
+		if err := seg.reader.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// mergeHeap orders waiting records by timestamp, then segment order
+type mergeHeap []mergeItem
+
+func (h mergeHeap) Len() int { return len(h) }
+func (h mergeHeap) Less(i, j int) bool {
+	if !h[i].ts.Equal(h[j].ts) {
+		return h[i].ts.Before(h[j].ts)
+	}
+	return h[i].seg.index < h[j].seg.index
+}
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *mergeHeap) Push(x any)   { *h = append(*h, x.(mergeItem)) }
+func (h *mergeHeap) Pop() any {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,102 @@
+package processor
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readMerge reads records until io.EOF, returning "message@segment"
+func readMerge(t *testing.T, m *MergeReader, limit int) []string {
+	t.Helper()
+
+	var got []string
+	for limit < 0 || len(got) < limit {
+		record, err := m.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		got = append(got, record.Entry.Message+"@"+record.Segment)
+	}
+	return got
+}
+
+// TestMergeReader verifies three time-sorted segments merge into one
+// globally sorted stream that resumes from committed offsets
+func TestMergeReader(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	line := func(sec int, msg string) string {
+		return fmt.Sprintf(`{"timestamp":%q,"message":%q}`, base.Add(time.Duration(sec)*time.Second).Format(time.RFC3339Nano), msg)
+	}
+
+	src := NewMemorySource()
+	src.Put("api", []byte(strings.Join([]string{line(1, "a1"), line(4, "a4"), line(7, "a7"), line(9, "a9")}, "\n")+"\n"))
+	src.Put("db", []byte(strings.Join([]string{line(2, "d2"), line(4, "d4"), `{"message":"untimed"}`, line(8, "d8")}, "\n")+"\n"))
+	src.Put("web", []byte(strings.Join([]string{line(0, "w0"), line(3, "w3"), line(10, "w10")}, "\n")+"\n"))
+	segments := []string{"api", "db", "web"}
+
+	want := []string{
+		"w0@web", "a1@api", "d2@db", "w3@web", "a4@api", "d4@db", "untimed@db",
+		"a7@api", "d8@db", "a9@api", "w10@web",
+	}
+
+	m, err := NewMergeReader(src, segments, DefaultReaderOptions(), MergeOptions{})
+	if err != nil {
+		t.Fatalf("NewMergeReader: %v", err)
+	}
+	if got := readMerge(t, m, -1); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged\n%v\nwant\n%v", got, want)
+	}
+	m.Close()
+
+	m, _ = NewMergeReader(src, segments, DefaultReaderOptions(), MergeOptions{Untimed: UntimedDrop})
+	if got := readMerge(t, m, -1); len(got) != len(want)-1 || m.Dropped() != 1 {
+		t.Errorf("UntimedDrop read %d records, dropped %d", len(got), m.Dropped())
+	}
+	m.Close()
+
+	m, err = NewMergeReader(src, segments, DefaultReaderOptions(), MergeOptions{Untimed: UntimedError})
+	if err != nil {
+		t.Fatalf("NewMergeReader: %v", err)
+	}
+	readMerge(t, m, 6)
+	if _, err := m.Read(); err == nil || !strings.Contains(err.Error(), "db:3") {
+		t.Errorf("UntimedError: Read = %v, want an error at db:3", err)
+	}
+	m.Close()
+
+	// Commit part way, then resume without repeating or skipping
+	offsets, err := NewOffsetManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewOffsetManager: %v", err)
+	}
+	m, _ = NewMergeReader(src, segments, DefaultReaderOptions(), MergeOptions{Offsets: offsets})
+	first := readMerge(t, m, 5)
+	if err := m.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	m.Close()
+
+	m, _ = NewMergeReader(src, segments, DefaultReaderOptions(), MergeOptions{Offsets: offsets})
+	rest := readMerge(t, m, -1)
+	if err := m.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	m.Close()
+	if got := append(first, rest...); !reflect.DeepEqual(got, want) {
+		t.Errorf("resumed\n%v\nwant\n%v", got, want)
+	}
+	for _, name := range segments {
+		data, _ := src.Open(name, 0)
+		all, _ := io.ReadAll(data)
+		if offset, _ := offsets.GetOffset(name); offset != int64(len(all)) {
+			t.Errorf("%s committed at %d, want its end %d", name, offset, len(all))
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// mappedFile is a memory-mapped segment. LogReader scans it for
+// delimiters directly rather than reading it through a bufio.Reader,
+// copying out only each line; it also implements io.ReadSeekCloser for
+// other uses.
+type mappedFile struct {
+	data  []byte
+	pos   int64
+	unmap func() error
+}
+
+// next returns the bytes up to and including the next delimiter, like
+// bufio.Reader.ReadBytes. The line is copied, so records stay valid
+// after the mapping is closed.
+func (m *mappedFile) next(delim byte) ([]byte, error) {
+	rest := m.data[m.pos:]
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+
+	n, err := bytes.IndexByte(rest, delim)+1, error(nil)
+	if n == 0 {
+		n, err = len(rest), io.EOF
+	}
+	m.pos += int64(n)
+	return append([]byte(nil), rest[:n]...), err
+}
+
+// Read copies from the current position
+func (m *mappedFile) Read(p []byte) (int, error) {
+	if m.pos >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[m.pos:])
+	m.pos += int64(n)
+	return n, nil
+}
+
+// Seek moves the position within the mapping
+func (m *mappedFile) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += m.pos
+	case io.SeekEnd:
+		offset += int64(len(m.data))
+	}
+	if offset < 0 {
+		return 0, errors.New("mmap: negative position")
+	}
+	m.pos = min(offset, int64(len(m.data)))
+	return m.pos, nil
+}
+
+// Close unmaps the file. Bytes returned by next remain valid.
+func (m *mappedFile) Close() error {
+	if m.unmap == nil {
+		return nil
+	}
+	err := m.unmap()
+	m.data, m.unmap = nil, nil
+	return err
+}
+
+// openMapped maps a regular file positioned at offset, for segments that
+// no longer grow
+func openMapped(path string, offset int64) (*mappedFile, error) {
+	m, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.Seek(offset, io.SeekStart); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package processor
+
+import "errors"
+
+// mapFile is unavailable without mmap; callers fall back to buffered
+// reads
+func mapFile(path string) (*mappedFile, error) {
+	return nil, errors.New("mmap not supported on this platform")
+}
@@ -0,0 +1,150 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// readAll reads every record from a segment, opened mapped or buffered
+func readAll(t testing.TB, path string, offset int64, mapped bool) []LogRecord {
+	t.Helper()
+
+	var rc io.ReadCloser
+	var err error
+	if mapped {
+		rc, err = openMapped(path, offset)
+	} else {
+		rc, err = openSegment(path, offset)
+	}
+	if err != nil {
+		t.Fatalf("open (mapped=%v): %v", mapped, err)
+	}
+	reader := NewLogReaderFrom(rc, path, offset, DefaultReaderOptions())
+	defer reader.Close()
+
+	var records []LogRecord
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		records = append(records, *record)
+	}
+}
+
+// TestMmapReader verifies a mapped segment yields the same records and
+// offsets as buffered reads, from the start and from an offset, and
+// that records outlive the mapping
+func TestMmapReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1")
+	content := `{"message":"one"}` + "\n\n" + `{"message":"two"}` + "\r\n" + `not json` + "\n" + `{"message":"last"}`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, offset := range []int64{0, 18} {
+		want := readAll(t, path, offset, false)
+		got := readAll(t, path, offset, true)
+		if len(got) != len(want) {
+			t.Fatalf("offset %d: mapped read %d records, buffered %d", offset, len(got), len(want))
+		}
+		for i := range want {
+			if !reflect.DeepEqual(got[i].Entry, want[i].Entry) || got[i].Offset != want[i].Offset ||
+				got[i].LineNumber != want[i].LineNumber || string(got[i].Raw) != string(want[i].Raw) {
+				t.Errorf("offset %d record %d: mapped %+v, buffered %+v", offset, i, got[i], want[i])
+			}
+		}
+	}
+
+	// The processor reads rotated segments through the mapping
+	cfg := newTestConfig(t, 1)
+	cfg.UseMmap = true
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", `{"message":"a"}`, `{"message":"b"}`)
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitFor(t, 2*time.Second, func() bool {
+		processed, _, segStats := proc.Stats()
+		return processed == 2 && segStats[3] == 1
+	})
+	proc.Stop()
+
+	// An empty file maps to no records
+	empty := filepath.Join(t.TempDir(), "app.log.2")
+	if err := os.WriteFile(empty, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if records := readAll(t, empty, 0, true); len(records) != 0 {
+		t.Fatalf("empty mapped file read %d records", len(records))
+	}
+}
+
+// BenchmarkSegmentRead compares reading a 100MB segment memory-mapped
+// and through a read buffer
+func BenchmarkSegmentRead(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "app.log.1")
+	f, err := os.Create(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	w := bufio.NewWriter(f)
+	var size int64
+	for i := 0; size < 100<<20; i++ {
+		n, _ := fmt.Fprintf(w, `{"timestamp":"2026-01-01T00:00:00Z","level":"INFO","service":"api","message":"request %d handled","request_id":"req-%d"}`+"\n", i, i)
+		size += int64(n)
+	}
+	if err := w.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	f.Close()
+
+	for _, mapped := range []bool{false, true} {
+		name := "buffered"
+		if mapped {
+			name = "mmap"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				readAll(b, path, 0, mapped)
+			}
+		})
+	}
+}
+
+// TestMmapSkipsExactFile verifies the file of an ExactFile source,
+// which may still be written to, is read buffered even with UseMmap
+func TestMmapSkipsExactFile(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.UseMmap = true
+	cfg.ExactFile = filepath.Join(cfg.LogsDir, "app.log")
+	writeSegment(t, cfg.LogsDir, "app.log", `{"message":"a"}`)
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+
+	seg := &Segment{Name: "app.log", Path: cfg.ExactFile}
+	rc, err := proc.openSegment(seg, 0)
+	if err != nil {
+		t.Fatalf("openSegment: %v", err)
+	}
+	defer rc.Close()
+	if _, mapped := rc.(*mappedFile); mapped {
+		t.Fatal("exact file was memory-mapped")
+	}
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package processor
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// mapFile maps a whole regular file read-only
+func mapFile(path string) (*mappedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() // The mapping outlives the descriptor
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, errors.New("mmap: not a regular file")
+	}
+	if info.Size() == 0 {
+		return &mappedFile{}, nil // Empty mappings are invalid
+	}
+	if int64(int(info.Size())) != info.Size() {
+		return nil, errors.New("mmap: file too large")
+	}
+
+	data, err := syscall.Mmap(int(f.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	return &mappedFile{data: data, unmap: func() error { return syscall.Munmap(data) }}, nil
+}
@@ -1,8 +1,15 @@
 package processor
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,14 +21,42 @@ type OffsetData struct {
 	Segment        string    `json:"segment"`
 	Offset         int64     `json:"offset"`
 	LinesProcessed int64     `json:"lines_processed"`
+	Line           int64     `json:"line,omitempty"` // Lines consumed, for ResumeSkipLines
 	LastUpdated    time.Time `json:"last_updated"`
+
+	// Fingerprint identifies the file the offset was committed for (see
+	// OffsetManager.SetFingerprint)
+	Fingerprint string `json:"fingerprint,omitempty"`
+
+	// Epoch counts the deliberate rewinds of the segment: it starts at 0
+	// and goes up by one each time ForceCommitOffset moves the offset
+	// back, so records delivered again after a replay can be told apart
+	// from the first delivery (see LogRecord.Epoch)
+	Epoch int64 `json:"epoch,omitempty"`
 }
 
-// OffsetManager manages offsets for log segments
+// FingerprintFunc hashes the first n bytes of a segment, reporting the
+// number of bytes actually hashed as part of the result
+type FingerprintFunc func(segment string, n int64) (string, error)
+
+// OffsetManager manages offsets for log segments. Every commit is
+// written durably (temp file, fsync, rename) before it becomes visible
+// through GetOffset, so the offsets loaded after a crash are never
+// ahead of what reached disk. Records processed after a segment's last
+// commit are processed again on restart; the size of that window is
+// the commit interval (see Config.CommitTarget).
 type OffsetManager struct {
 	offsetDir string
 	offsets   map[string]*OffsetData
 	mu        sync.RWMutex
+
+	fingerprint FingerprintFunc
+	verified    map[string]bool // Offsets whose fingerprint matched
+
+	// identities fingerprint the file a segment's offsets belong to,
+	// overriding fingerprint for names that may point at another file
+	// by the time of a commit (see setIdentity)
+	identities map[string]func(n int64) (string, error)
 }
 
 // NewOffsetManager creates a new offset manager
@@ -31,8 +66,10 @@ func NewOffsetManager(offsetDir string) (*OffsetManager, error) {
 	}
 
 	om := &OffsetManager{
-		offsetDir: offsetDir,
-		offsets:   make(map[string]*OffsetData),
+		offsetDir:  offsetDir,
+		offsets:    make(map[string]*OffsetData),
+		verified:   make(map[string]bool),
+		identities: make(map[string]func(n int64) (string, error)),
 	}
 
 	// Load existing offsets
@@ -43,9 +80,18 @@ func NewOffsetManager(offsetDir string) (*OffsetManager, error) {
 	return om, nil
 }
 
-// loadAll loads all offset files from disk
+// loadAll loads all offset files from disk. Temp files left by a crash
+// mid-write were never renamed into place, so they are discarded.
 func (om *OffsetManager) loadAll() error {
-	files, err := filepath.Glob(filepath.Join(om.offsetDir, "*.offset.json"))
+	stale, err := globDir(om.offsetDir, "*.offset.json.tmp")
+	if err != nil {
+		return err
+	}
+	for _, file := range stale {
+		os.Remove(file)
+	}
+
+	files, err := globDir(om.offsetDir, "*.offset.json")
 	if err != nil {
 		return err
 	}
@@ -67,33 +113,277 @@ func (om *OffsetManager) loadAll() error {
 	return nil
 }
 
-// GetOffset returns the last committed offset for a segment
-func (om *OffsetManager) GetOffset(segment string) (int64, int64) {
+// SetFingerprint makes offsets carry the identity of the file they were
+// committed for: a hash of the segment's head, up to the committed
+// offset. Before a stored offset is trusted, the head of the file now
+// under that name is hashed and compared, and on a mismatch (a
+// different file with the same name, e.g. after the logs directory
+// changed) the offset is discarded and the segment starts afresh. Files
+// that can't be read are given the benefit of the doubt.
+func (om *OffsetManager) SetFingerprint(fn FingerprintFunc) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	om.fingerprint = fn
+}
+
+// lookup returns a segment's offset data if it is present and belongs
+// to the file now under the segment's name
+func (om *OffsetManager) lookup(segment string) (OffsetData, bool) {
 	om.mu.RLock()
-	defer om.mu.RUnlock()
+	data, ok := om.offsets[segment]
+	trusted := !ok || om.fingerprint == nil || data.Fingerprint == "" || om.verified[segment]
+	var out OffsetData
+	if ok {
+		out = *data
+	}
+	om.mu.RUnlock()
+	if trusted {
+		return out, ok
+	}
 
-	if data, ok := om.offsets[segment]; ok {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	data, ok = om.offsets[segment]
+	if !ok || om.verified[segment] || data.Fingerprint == "" {
+		if ok {
+			out = *data
+		}
+		return out, ok
+	}
+
+	fingerprint, err := om.fingerprint(segment, fingerprintLength(data.Fingerprint))
+	if err == nil && fingerprint != data.Fingerprint {
+		delete(om.offsets, segment) // A different file: start afresh
+		return OffsetData{}, false
+	}
+	if err == nil {
+		om.verified[segment] = true
+	}
+	return *data, true
+}
+
+// setIdentity makes commits of segment carry fingerprints from fn, which
+// hashes the head of the file being read (e.g. through its open handle)
+// rather than whatever the name refers to when the commit is made. A
+// nil fn removes it.
+func (om *OffsetManager) setIdentity(segment string, fn func(n int64) (string, error)) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+	if fn == nil {
+		delete(om.identities, segment)
+		return
+	}
+	om.identities[segment] = fn
+}
+
+// identify returns the fingerprint for a commit at offset, reusing the
+// previous one once the hashed head is complete
+func (om *OffsetManager) identify(segment string, offset int64) string {
+	n := min(offset, fingerprintBytes)
+	identity := om.identities[segment]
+	if identity == nil && om.fingerprint != nil {
+		identity = func(n int64) (string, error) { return om.fingerprint(segment, n) }
+	}
+	if identity == nil || n <= 0 {
+		return ""
+	}
+	if prev, ok := om.offsets[segment]; ok && prev.Fingerprint != "" && fingerprintLength(prev.Fingerprint) == n {
+		return prev.Fingerprint
+	}
+	fingerprint, err := identity(n)
+	if err != nil {
+		return ""
+	}
+	return fingerprint
+}
+
+// fingerprintLength returns how many bytes a fingerprint covers
+func fingerprintLength(fingerprint string) int64 {
+	n, _, _ := strings.Cut(fingerprint, ":")
+	length, _ := strconv.ParseInt(n, 10, 64)
+	return length
+}
+
+// sourceFingerprint hashes segment heads read from a source
+func sourceFingerprint(source SegmentSource) FingerprintFunc {
+	return func(segment string, n int64) (string, error) {
+		rc, err := source.Open(segment, 0)
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		return hashHead(rc, n)
+	}
+}
+
+// hashHead fingerprints the first n bytes of r
+func hashHead(r io.Reader, n int64) (string, error) {
+	h := sha256.New()
+	read, err := io.CopyN(h, r, n)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strconv.FormatInt(read, 10) + ":" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// GetEpoch returns the number of times a segment's offset has been
+// deliberately rewound (see OffsetData.Epoch)
+func (om *OffsetManager) GetEpoch(segment string) int64 {
+	if data, ok := om.lookup(segment); ok {
+		return data.Epoch
+	}
+	return 0
+}
+
+// GetOffset returns the last committed offset for a segment
+func (om *OffsetManager) GetOffset(segment string) (int64, int64) {
+	if data, ok := om.lookup(segment); ok {
 		return data.Offset, data.LinesProcessed
 	}
 	return 0, 0
 }
 
-// CommitOffset saves the offset for a segment
+// CommitOffset saves the offset for a segment. Offsets only move
+// forward: a commit behind the stored offset, e.g. from a stale worker,
+// fails with ErrOffsetRegression and leaves the stored offset as it was.
 func (om *OffsetManager) CommitOffset(segment string, offset int64, linesProcessed int64) error {
+	return om.commitOffset(segment, offset, linesProcessed, false)
+}
+
+// ForceCommitOffset saves the offset for a segment even if it is behind
+// the stored one, for deliberate resets such as replaying a segment or
+// starting over on a truncated file. Moving the offset back starts a
+// new epoch for the segment.
+func (om *OffsetManager) ForceCommitOffset(segment string, offset int64, linesProcessed int64) error {
+	return om.commitOffset(segment, offset, linesProcessed, true)
+}
+
+// commitOffset saves the offset for a segment, refusing to move it
+// backwards unless forced
+func (om *OffsetManager) commitOffset(segment string, offset, linesProcessed int64, force bool) error {
 	om.mu.Lock()
 	defer om.mu.Unlock()
 
+	var epoch int64
+	if stored, ok := om.offsets[segment]; ok {
+		if offset < stored.Offset && !force {
+			return fmt.Errorf("%w: %s at %d, stored %d", ErrOffsetRegression, segment, offset, stored.Offset)
+		}
+		epoch = stored.Epoch
+		if force && (offset < stored.Offset || offset == stored.Offset && stored.Line > 0) {
+			epoch++ // A rewind, including of a line checkpoint
+		}
+	}
+
 	data := &OffsetData{
 		Segment:        segment,
 		Offset:         offset,
 		LinesProcessed: linesProcessed,
 		LastUpdated:    time.Now().UTC(),
+		Fingerprint:    om.identify(segment, offset),
+		Epoch:          epoch,
 	}
 
+	// Persist to disk before publishing, so memory is never ahead
+	if err := om.persist(segment, data); err != nil {
+		return err
+	}
 	om.offsets[segment] = data
+	om.verified[segment] = true
+	return nil
+}
 
-	// Persist to disk
-	return om.persist(segment, data)
+// GetLine returns the line checkpoint of a ResumeSkipLines segment
+func (om *OffsetManager) GetLine(segment string) int64 {
+	if data, ok := om.lookup(segment); ok {
+		return data.Line
+	}
+	return 0
+}
+
+// CommitLine saves a line checkpoint for a segment whose byte offsets
+// can't be seeked to (e.g. gzip). offset should be 0 until the segment
+// is finished, then its size, so IsComplete stays meaningful.
+func (om *OffsetManager) CommitLine(segment string, offset, linesProcessed, line int64) error {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	data := &OffsetData{
+		Segment:        segment,
+		Offset:         offset,
+		LinesProcessed: linesProcessed,
+		Line:           line,
+		LastUpdated:    time.Now().UTC(),
+		Fingerprint:    om.identify(segment, offset),
+	}
+	if stored, ok := om.offsets[segment]; ok {
+		data.Epoch = stored.Epoch
+	}
+
+	if err := om.persist(segment, data); err != nil {
+		return err
+	}
+	om.offsets[segment] = data
+	om.verified[segment] = true
+	return nil
+}
+
+// stored returns a segment's offset data as stored, without checking
+// its fingerprint against the file now under the name
+func (om *OffsetManager) stored(segment string) (OffsetData, bool) {
+	om.mu.RLock()
+	defer om.mu.RUnlock()
+	if data, ok := om.offsets[segment]; ok {
+		return *data, true
+	}
+	return OffsetData{}, false
+}
+
+// relocate moves a segment's offset to the name its file was renamed
+// to, unless that name's offset is already at or past it, and forgets
+// the old name
+func (om *OffsetManager) relocate(from, to string) error {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	data, ok := om.offsets[from]
+	if !ok {
+		return nil
+	}
+	if stored, ok := om.offsets[to]; !ok || stored.Offset < data.Offset {
+		moved := *data
+		moved.Segment = to
+		moved.LastUpdated = time.Now().UTC()
+		if err := om.persist(to, &moved); err != nil {
+			return err
+		}
+		om.offsets[to] = &moved
+		delete(om.verified, to)
+	}
+
+	delete(om.offsets, from)
+	delete(om.verified, from)
+	err := os.Remove(filepath.Join(om.offsetDir, from+".offset.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteOffset forgets a segment's offset and removes its offset file
+func (om *OffsetManager) DeleteOffset(segment string) error {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	delete(om.offsets, segment)
+	delete(om.verified, segment)
+
+	err := os.Remove(filepath.Join(om.offsetDir, segment+".offset.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
 }
 
 // persist writes offset data to disk
@@ -106,20 +396,12 @@ func (om *OffsetManager) persist(segment string, data *OffsetData) error {
 	}
 
 	// Write to temp file first, then rename (atomic)
-	tmpFile := filename + ".tmp"
-	if err := os.WriteFile(tmpFile, jsonData, 0644); err != nil {
-		return err
-	}
-
-	return os.Rename(tmpFile, filename)
+	return writeFileAtomic(filename, jsonData, 0644)
 }
 
 // IsComplete checks if a segment has been fully processed
 func (om *OffsetManager) IsComplete(segment string, fileSize int64) bool {
-	om.mu.RLock()
-	defer om.mu.RUnlock()
-
-	if data, ok := om.offsets[segment]; ok {
+	if data, ok := om.lookup(segment); ok {
 		return data.Offset >= fileSize
 	}
 	return false
@@ -136,3 +418,146 @@ func (om *OffsetManager) GetAllOffsets() map[string]OffsetData {
 	}
 	return result
 }
+
+// offsetExportVersion is the format version of Export documents
+const offsetExportVersion = 1
+
+// offsetExport is the document written by Export
+type offsetExport struct {
+	Version    int          `json:"version"`
+	ExportedAt time.Time    `json:"exported_at"`
+	Offsets    []OffsetData `json:"offsets"`
+}
+
+// Export writes every tracked offset to w as a single JSON document,
+// e.g. to back up progress or move it to another machine with Import
+func (om *OffsetManager) Export(w io.Writer) error {
+	all := om.GetAllOffsets()
+	doc := offsetExport{
+		Version:    offsetExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Offsets:    make([]OffsetData, 0, len(all)),
+	}
+	for _, data := range all {
+		doc.Offsets = append(doc.Offsets, data)
+	}
+	sort.Slice(doc.Offsets, func(i, j int) bool {
+		return doc.Offsets[i].Segment < doc.Offsets[j].Segment
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// Import merges offsets written by Export. Like commits, offsets only
+// move forward: a segment already at or past the imported offset keeps
+// its own (for line checkpoints, the further line wins at equal
+// offsets), and epochs never go back. Imported offsets are persisted as
+// they are applied and their fingerprints are verified against the
+// local files on first use, as after a restart.
+func (om *OffsetManager) Import(r io.Reader) error {
+	doc, err := decodeOffsetExport(r)
+	if err != nil {
+		return err
+	}
+
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	for _, data := range doc.Offsets {
+		if stored, ok := om.offsets[data.Segment]; ok {
+			if stored.Offset > data.Offset || (stored.Offset == data.Offset && stored.Line >= data.Line) {
+				continue
+			}
+			data.Epoch = max(data.Epoch, stored.Epoch)
+		}
+		if err := om.persist(data.Segment, &data); err != nil {
+			return err
+		}
+		om.offsets[data.Segment] = &data
+		delete(om.verified, data.Segment)
+	}
+	return nil
+}
+
+// Restore sets the offsets to snapshot, e.g. one taken with Export:
+// unlike Import, offsets may move backwards, and tracked segments the
+// snapshot doesn't have go back to the start. Segments moved back start
+// a new epoch. It returns the segments whose offsets changed.
+func (om *OffsetManager) Restore(snapshot map[string]OffsetData) ([]string, error) {
+	om.mu.Lock()
+	defer om.mu.Unlock()
+
+	names := make([]string, 0, len(snapshot)+len(om.offsets))
+	for name := range snapshot {
+		names = append(names, name)
+	}
+	for name := range om.offsets {
+		if _, ok := snapshot[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changed []string
+	for _, name := range names {
+		data, ok := snapshot[name]
+		if !ok {
+			data = OffsetData{Segment: name}
+		}
+		data.Segment = name
+		if stored, ok := om.offsets[name]; ok {
+			if stored.Offset == data.Offset && stored.Line == data.Line && stored.LinesProcessed == data.LinesProcessed {
+				continue
+			}
+			data.Epoch = max(data.Epoch, stored.Epoch)
+			if data.Offset < stored.Offset || data.Offset == stored.Offset && data.Line < stored.Line {
+				data.Epoch++
+			}
+		}
+		data.LastUpdated = time.Now().UTC()
+
+		if err := om.persist(name, &data); err != nil {
+			return changed, err
+		}
+		om.offsets[name] = &data
+		delete(om.verified, name)
+		changed = append(changed, name)
+	}
+	return changed, nil
+}
+
+// decodeOffsetExport reads and checks a document written by Export
+func decodeOffsetExport(r io.Reader) (offsetExport, error) {
+	var doc offsetExport
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return doc, fmt.Errorf("decode offsets: %w", err)
+	}
+	if doc.Version != offsetExportVersion {
+		return doc, fmt.Errorf("unsupported offsets export version %d", doc.Version)
+	}
+	for _, data := range doc.Offsets {
+		if data.Segment == "" || data.Segment != filepath.Base(data.Segment) || data.Segment == ".." {
+			return doc, fmt.Errorf("invalid segment name %q in offsets export", data.Segment)
+		}
+		if data.Offset < 0 || data.LinesProcessed < 0 || data.Line < 0 {
+			return doc, fmt.Errorf("%s: negative offset in offsets export", data.Segment)
+		}
+	}
+	return doc, nil
+}
+
+// ReadOffsetExport reads a document written by Export into a map of
+// offsets by segment, as returned by GetAllOffsets
+func ReadOffsetExport(r io.Reader) (map[string]OffsetData, error) {
+	doc, err := decodeOffsetExport(r)
+	if err != nil {
+		return nil, err
+	}
+	offsets := make(map[string]OffsetData, len(doc.Offsets))
+	for _, data := range doc.Offsets {
+		offsets[data.Segment] = data
+	}
+	return offsets, nil
+}
@@ -0,0 +1,359 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestOffsetsNeverAheadOfDisk verifies a failed write leaves the last
+// durable offset in effect and that a restart recovers exactly it
+func TestOffsetsNeverAheadOfDisk(t *testing.T) {
+	dir := t.TempDir()
+	const seg = "app.log.20260101-000000"
+
+	om, err := NewOffsetManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := om.CommitOffset(seg, 100, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Block the next write: the temp file path is taken by a directory
+	tmp := filepath.Join(dir, seg+".offset.json.tmp")
+	if err := os.Mkdir(tmp, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := om.CommitOffset(seg, 500, 50); err == nil {
+		t.Fatal("expected the blocked commit to fail")
+	}
+	if offset, lines := om.GetOffset(seg); offset != 100 || lines != 10 {
+		t.Fatalf("in-memory offset after failed commit = %d/%d, want 100/10", offset, lines)
+	}
+	os.Remove(tmp)
+
+	// Crash mid-write: a half-written temp file never renamed into place
+	if err := os.WriteFile(tmp, []byte(`{"segment":"`+seg+`","offset":9`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered, err := NewOffsetManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset, lines := recovered.GetOffset(seg); offset != 100 || lines != 10 {
+		t.Fatalf("recovered offset = %d/%d, want last durable 100/10", offset, lines)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Fatal("stale temp file not cleaned up on load")
+	}
+}
+
+// makeReadOnly makes dir unwritable. Root ignores directory
+// permissions, so there the directory is replaced by a file instead.
+func makeReadOnly(t *testing.T, dir string) {
+	t.Helper()
+
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(dir, 0755) })
+
+	probe := filepath.Join(dir, "probe")
+	if err := os.WriteFile(probe, nil, 0644); err != nil {
+		return // Permissions are enforced
+	}
+	os.Remove(probe)
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(dir, nil, 0444); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCommitErrors verifies failed commits to a read-only offsets
+// directory are counted, reported to OnCommitError, and stop the
+// processor after MaxCommitFailures in a row
+func TestCommitErrors(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.MaxCommitFailures = 2
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", `{"message":"a"}`)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000001", `{"message":"b"}`)
+
+	var mu sync.Mutex
+	failed := make(map[string]bool)
+	cfg.OnCommitError = func(segment string, err error) {
+		mu.Lock()
+		failed[segment] = true
+		mu.Unlock()
+	}
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	makeReadOnly(t, cfg.OffsetsDir)
+
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	select {
+	case <-proc.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("processor kept running despite failing commits")
+	}
+	proc.Stop()
+
+	if n := proc.CommitErrors(); n != 2 {
+		t.Fatalf("CommitErrors = %d, want 2", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !failed["app.log.20260101-000000"] || !failed["app.log.20260101-000001"] {
+		t.Fatalf("OnCommitError saw %v, want both segments", failed)
+	}
+}
+
+// TestVerifyOffsetIdentity verifies an offset is only resumed from for
+// the file it was committed for, when two files share a basename
+func TestVerifyOffsetIdentity(t *testing.T) {
+	offsets := t.TempDir()
+	const name = "app.log.20260101-000000"
+
+	run := func(dir string) []string {
+		t.Helper()
+		cfg := newTestConfig(t, 1)
+		cfg.LogsDir, cfg.OffsetsDir = dir, offsets
+		cfg.VerifyOffsetIdentity = true
+
+		var mu sync.Mutex
+		var seen []string
+		proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+			mu.Lock()
+			defer mu.Unlock()
+			seen = append(seen, r.Entry.Message)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("NewProcessor: %v", err)
+		}
+		if err := proc.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := proc.WaitForIdle(ctx); err != nil {
+			t.Fatalf("WaitForIdle: %v", err)
+		}
+		proc.Stop()
+
+		mu.Lock()
+		defer mu.Unlock()
+		return seen
+	}
+
+	first, second := t.TempDir(), t.TempDir()
+	writeSegment(t, first, name, `{"message":"a1"}`, `{"message":"a2"}`, `{"message":"a3"}`)
+	writeSegment(t, second, name, `{"message":"b1"}`, `{"message":"b2"}`)
+
+	if got := strings.Join(run(first), ","); got != "a1,a2,a3" {
+		t.Fatalf("first directory processed %s", got)
+	}
+	// Shorter than the stored offset, so it would otherwise be
+	// considered complete
+	if got := strings.Join(run(second), ","); got != "b1,b2" {
+		t.Fatalf("same-named file in another directory processed %q, want b1,b2", got)
+	}
+
+	// Each file resumes from its own offset once it grows
+	appendLines(t, filepath.Join(second, name), "b3")
+	if got := strings.Join(run(second), ","); got != "b3" {
+		t.Fatalf("grown file resumed with %q, want b3", got)
+	}
+}
+
+// TestOffsetCommitMonotonic verifies a commit behind the stored offset
+// is refused, in memory and on disk, while a forced one goes through
+func TestOffsetCommitMonotonic(t *testing.T) {
+	dir := t.TempDir()
+	const seg = "app.log.20260101-000000"
+
+	om, err := NewOffsetManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := om.CommitOffset(seg, 500, 50); err != nil {
+		t.Fatal(err)
+	}
+	if err := om.CommitOffset(seg, 500, 50); err != nil {
+		t.Fatalf("repeated commit: %v", err)
+	}
+
+	if err := om.CommitOffset(seg, 100, 10); !errors.Is(err, ErrOffsetRegression) {
+		t.Fatalf("backward commit error = %v, want ErrOffsetRegression", err)
+	}
+	if offset, lines := om.GetOffset(seg); offset != 500 || lines != 50 {
+		t.Fatalf("offset after backward commit = %d/%d, want 500/50", offset, lines)
+	}
+	reloaded, err := NewOffsetManager(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset, _ := reloaded.GetOffset(seg); offset != 500 {
+		t.Fatalf("persisted offset after backward commit = %d, want 500", offset)
+	}
+
+	if err := om.ForceCommitOffset(seg, 0, 0); err != nil {
+		t.Fatalf("ForceCommitOffset: %v", err)
+	}
+	if offset, lines := om.GetOffset(seg); offset != 0 || lines != 0 {
+		t.Fatalf("offset after forced commit = %d/%d, want 0/0", offset, lines)
+	}
+	if err := om.CommitOffset(seg, 100, 10); err != nil {
+		t.Fatalf("commit after reset: %v", err)
+	}
+}
+
+// TestOffsetExportImport verifies offsets survive an export into a
+// fresh manager and that importing only moves offsets forward
+func TestOffsetExportImport(t *testing.T) {
+	src, err := NewOffsetManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits := map[string][2]int64{
+		"app.log.20260101-000000": {1000, 10},
+		"app.log.20260101-000001": {2500, 25},
+		"app.log.20260101-000002": {40, 1},
+	}
+	for seg, c := range commits {
+		if err := src.CommitOffset(seg, c[0], c[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := src.CommitLine("app.log.20260101-000003.gz", 0, 7, 7); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+
+	dstDir := t.TempDir()
+	dst, err := NewOffsetManager(dstDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Already further along here, so kept
+	if err := dst.CommitOffset("app.log.20260101-000002", 90, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Import(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+
+	for seg, c := range commits {
+		want := c
+		if seg == "app.log.20260101-000002" {
+			want = [2]int64{90, 2}
+		}
+		if offset, lines := dst.GetOffset(seg); offset != want[0] || lines != want[1] {
+			t.Errorf("%s imported as %d/%d, want %d/%d", seg, offset, lines, want[0], want[1])
+		}
+	}
+	if line := dst.GetLine("app.log.20260101-000003.gz"); line != 7 {
+		t.Errorf("line checkpoint imported as %d, want 7", line)
+	}
+
+	// Imported offsets are persisted
+	reloaded, err := NewOffsetManager(dstDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset, _ := reloaded.GetOffset("app.log.20260101-000001"); offset != 2500 {
+		t.Errorf("reloaded imported offset = %d, want 2500", offset)
+	}
+
+	bad := `{"version":1,"offsets":[{"segment":"../escape","offset":1}]}`
+	if err := dst.Import(strings.NewReader(bad)); err == nil {
+		t.Error("expected a segment name with a path to be rejected")
+	}
+}
+
+// TestReplayBumpsEpoch verifies replaying a segment starts a new epoch
+// that the callback sees on the redelivered records and that survives a
+// restart, while ordinary commits keep the epoch as it was
+func TestReplayBumpsEpoch(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	const name = "app.log.20260101-000000"
+	writeSegment(t, cfg.LogsDir, name, `{"level":"INFO","message":"a"}`, `{"level":"INFO","message":"b"}`)
+
+	var mu sync.Mutex
+	var epochs []int64
+	proc, err := NewProcessor(cfg, func(record *LogRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+		epochs = append(epochs, record.Epoch)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	complete := func() bool {
+		_, _, stats := proc.Stats()
+		return stats[3] == 1
+	}
+	waitFor(t, 2*time.Second, complete)
+
+	if err := proc.offsetMgr.ForceCommitOffset(name, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	proc.segmentMgr.ReleaseSegment(name)
+	waitFor(t, 2*time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(epochs) == 4 && complete()
+	})
+
+	mu.Lock()
+	got := slices.Clone(epochs)
+	mu.Unlock()
+	if want := []int64{0, 0, 1, 1}; !slices.Equal(got, want) {
+		t.Fatalf("epochs delivered = %v, want %v", got, want)
+	}
+
+	reloaded, err := NewOffsetManager(cfg.OffsetsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if epoch := reloaded.GetEpoch(name); epoch != 1 {
+		t.Errorf("persisted epoch = %d, want 1", epoch)
+	}
+
+	// Forcing a commit that doesn't move the offset back isn't a replay
+	offset, lines := reloaded.GetOffset(name)
+	if err := reloaded.ForceCommitOffset(name, offset, lines); err != nil {
+		t.Fatal(err)
+	}
+	if epoch := reloaded.GetEpoch(name); epoch != 1 {
+		t.Errorf("epoch after a forced commit in place = %d, want 1", epoch)
+	}
+}
@@ -0,0 +1,82 @@
+package processor
+
+import "sort"
+
+// OffsetChange classifies how a segment's offset changed between two
+// snapshots
+type OffsetChange string
+
+const (
+	OffsetAdvanced  OffsetChange = "advanced"
+	OffsetRegressed OffsetChange = "regressed"
+	OffsetAdded     OffsetChange = "added"
+	OffsetRemoved   OffsetChange = "removed"
+	OffsetUnchanged OffsetChange = "unchanged"
+)
+
+// OffsetDiff is the change in one segment's offset between two
+// snapshots. Bytes and Lines are after minus before, so negative for a
+// regression; a segment missing from a snapshot counts as at 0. Lines
+// compares the line checkpoint of ResumeSkipLines segments and
+// LinesProcessed otherwise.
+type OffsetDiff struct {
+	Segment string       `json:"segment"`
+	Change  OffsetChange `json:"change"`
+	Before  int64        `json:"before"`
+	After   int64        `json:"after"`
+	Bytes   int64        `json:"bytes"`
+	Lines   int64        `json:"lines"`
+
+	// Replaced is set when both snapshots fingerprinted the segment and
+	// the fingerprints differ: a different file under the same name
+	Replaced bool `json:"replaced,omitempty"`
+}
+
+// DiffOffsets compares two offset snapshots, such as those returned by
+// GetAllOffsets or ReadOffsetExport, returning a diff for every segment
+// in either one, sorted by segment. An offset that moved back, or a
+// line checkpoint that did at the same offset, is a regression.
+func DiffOffsets(before, after map[string]OffsetData) []OffsetDiff {
+	segments := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		segments = append(segments, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			segments = append(segments, name)
+		}
+	}
+	sort.Strings(segments)
+
+	diffs := make([]OffsetDiff, 0, len(segments))
+	for _, name := range segments {
+		b, hadBefore := before[name]
+		a, hasAfter := after[name]
+		d := OffsetDiff{
+			Segment: name,
+			Before:  b.Offset,
+			After:   a.Offset,
+			Bytes:   a.Offset - b.Offset,
+			Lines:   a.LinesProcessed - b.LinesProcessed,
+		}
+		if a.Line != 0 || b.Line != 0 {
+			d.Lines = a.Line - b.Line
+		}
+
+		switch {
+		case !hadBefore:
+			d.Change = OffsetAdded
+		case !hasAfter:
+			d.Change = OffsetRemoved
+		case a.Offset < b.Offset || a.Offset == b.Offset && a.Line < b.Line:
+			d.Change = OffsetRegressed
+		case a.Offset > b.Offset || a.Line > b.Line:
+			d.Change = OffsetAdvanced
+		default:
+			d.Change = OffsetUnchanged
+		}
+		d.Replaced = a.Fingerprint != "" && b.Fingerprint != "" && a.Fingerprint != b.Fingerprint
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
@@ -0,0 +1,72 @@
+package processor
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestDiffOffsets verifies advanced, added, removed, unchanged and
+// regressed segments are classified, including a regression read back
+// from Export documents
+func TestDiffOffsets(t *testing.T) {
+	snapshot := func(offsets map[string][2]int64) map[string]OffsetData {
+		t.Helper()
+		om, err := NewOffsetManager(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, o := range offsets {
+			if err := om.CommitOffset(name, o[0], o[1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var buf bytes.Buffer
+		if err := om.Export(&buf); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ReadOffsetExport(&buf)
+		if err != nil {
+			t.Fatalf("ReadOffsetExport: %v", err)
+		}
+		return data
+	}
+
+	before := snapshot(map[string][2]int64{
+		"app.log.1": {100, 10},
+		"app.log.2": {500, 50},
+		"app.log.3": {300, 30},
+		"app.log.4": {200, 20},
+	})
+	after := snapshot(map[string][2]int64{
+		"app.log.1": {400, 40},
+		"app.log.2": {120, 12},
+		"app.log.3": {300, 30},
+		"app.log.5": {80, 8},
+	})
+
+	want := []OffsetDiff{
+		{Segment: "app.log.1", Change: OffsetAdvanced, Before: 100, After: 400, Bytes: 300, Lines: 30},
+		{Segment: "app.log.2", Change: OffsetRegressed, Before: 500, After: 120, Bytes: -380, Lines: -38},
+		{Segment: "app.log.3", Change: OffsetUnchanged, Before: 300, After: 300},
+		{Segment: "app.log.4", Change: OffsetRemoved, Before: 200, Bytes: -200, Lines: -20},
+		{Segment: "app.log.5", Change: OffsetAdded, After: 80, Bytes: 80, Lines: 8},
+	}
+	got := DiffOffsets(before, after)
+	if len(got) != len(want) {
+		t.Fatalf("got %d diffs, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("diff %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// A line checkpoint moving back at the same offset is a regression too
+	lines := DiffOffsets(
+		map[string]OffsetData{"s": {Segment: "s", Line: 9}},
+		map[string]OffsetData{"s": {Segment: "s", Line: 4, Fingerprint: "b"}},
+	)
+	if lines[0].Change != OffsetRegressed || lines[0].Lines != -5 || lines[0].Replaced {
+		t.Errorf("line regression diff = %+v", lines[0])
+	}
+}
@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+)
+
+// onceSegmentRetries is ProcessOnce's MaxSegmentRetries when unset, so
+// a segment that keeps failing is given up on and reported in the
+// RunReport rather than retried until the caller gives up
+const onceSegmentRetries = 3
+
+// ProcessDir processes the rotated segments of pattern in dir that are
+// present when it's called, waits for them to complete and returns the
+// run's report. Offsets are kept in a temporary directory removed on
+// return, so nothing is written next to the logs and every call starts
+// from the beginning; use ProcessOnce with an OffsetsDir to resume.
+// Records fn fails are counted in the report's Errors rather than
+// returned, unless fn marks the error with Fatal, which ends the run.
+func ProcessDir(ctx context.Context, dir, pattern string, fn ProcessFunc) (RunReport, error) {
+	return ProcessOnce(ctx, Config{LogsDir: dir, LogPattern: pattern}, fn)
+}
+
+// ProcessOnce is ProcessDir for a full Config. An empty OffsetsDir means
+// a temporary one, WorkerCount defaults to 1, MaxSegmentRetries to 3
+// (segments still failing are listed in the report's SegmentsFailed),
+// and Follow is not allowed. Segments that appear after the call are
+// left for the next.
+func ProcessOnce(ctx context.Context, cfg Config, fn ProcessFunc) (RunReport, error) {
+	if cfg.Follow {
+		return RunReport{}, errors.New("ProcessOnce cannot follow the active file")
+	}
+	if cfg.WorkerCount == 0 {
+		cfg.WorkerCount = 1
+	}
+	if cfg.MaxSegmentRetries == 0 {
+		cfg.MaxSegmentRetries = onceSegmentRetries
+	}
+	if cfg.ScanInterval == 0 {
+		cfg.ScanInterval = time.Hour // Only the initial scan matters
+	}
+	if cfg.OffsetsDir == "" {
+		dir, err := os.MkdirTemp("", "log-processor-offsets-")
+		if err != nil {
+			return RunReport{}, err
+		}
+		defer os.RemoveAll(dir)
+		cfg.OffsetsDir = dir
+	}
+
+	p, err := NewProcessor(cfg, fn)
+	if err != nil {
+		return RunReport{}, err
+	}
+	run := p.snapshotRun()
+	if err := p.Start(ctx); err != nil {
+		return RunReport{}, err
+	}
+
+	g := p.group
+	err = p.waitDrained(ctx)
+	if stopErr := p.Stop(); err == nil {
+		err = stopErr
+	}
+	if err == nil {
+		err = g.Wait() // A Fatal error from the process func
+	}
+	return p.buildReport(run, errors.Is(err, ErrStopTimeout)), err
+}
+
+// waitDrained blocks until no segment is pending or processing. Unlike
+// WaitForIdle it doesn't wait for another scan, so segments found by
+// Start's are enough. Stopping on its own (MaxRecords) isn't an error.
+func (p *Processor) waitDrained(ctx context.Context) error {
+	stopped := p.Done()
+	for {
+		idle, _, changed := p.segmentMgr.idleState()
+		if idle {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-stopped:
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return nil
+		}
+	}
+}
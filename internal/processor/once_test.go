@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestProcessDir verifies a synchronous run over two segments reports
+// their totals and leaves no offsets behind
+func TestProcessDir(t *testing.T) {
+	dir := t.TempDir()
+	writeSegment(t, dir, "app.log.20260101-000000",
+		`{"level":"INFO","message":"a"}`,
+		`{"level":"INFO","message":"b"}`,
+	)
+	writeSegment(t, dir, "app.log.20260101-010000",
+		`{"level":"INFO","message":"c"}`,
+		`{"level":"ERROR","message":"fail"}`,
+		`{"level":"INFO","message":"d"}`,
+	)
+
+	var calls atomic.Int64
+	fn := func(r *LogRecord) error {
+		calls.Add(1)
+		if r.Entry.Message == "fail" {
+			return errors.New("rejected")
+		}
+		return nil
+	}
+
+	for run := 1; run <= 2; run++ {
+		report, err := ProcessDir(context.Background(), dir, "app.log", fn)
+		if err != nil {
+			t.Fatalf("run %d: ProcessDir: %v", run, err)
+		}
+		if report.Processed != 4 || report.Errors != 1 {
+			t.Fatalf("run %d: processed %d, errors %d; want 4, 1", run, report.Processed, report.Errors)
+		}
+		if report.SegmentsTotal != 2 || report.SegmentsComplete != 2 || len(report.Segments) != 2 {
+			t.Fatalf("run %d: segments %d total, %d complete, %d reported; want 2 each",
+				run, report.SegmentsTotal, report.SegmentsComplete, len(report.Segments))
+		}
+		for _, seg := range report.Segments {
+			if !seg.Complete || seg.OffsetAfter == 0 {
+				t.Fatalf("run %d: segment %+v not processed to the end", run, seg)
+			}
+		}
+	}
+	// Without stored offsets the second run read everything again
+	if got := calls.Load(); got != 10 {
+		t.Fatalf("process func called %d times, want 10", got)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("dir has %d entries after the runs, want just the 2 segments", len(entries))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := ProcessDir(ctx, dir, "app.log", fn); !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled ProcessDir = %v, want context.Canceled", err)
+	}
+}
+
+// TestProcessOnceFailingSegment verifies a segment that never opens
+// doesn't hold the run up forever: it is given up on after the default
+// retries and reported, and the other segments are still processed
+func TestProcessOnceFailingSegment(t *testing.T) {
+	src := &brokenSource{MemorySource: NewMemorySource(), bad: "app.log.1"}
+	src.Put("app.log.1", []byte(`{"message":"a"}`+"\n"))
+	src.Put("app.log.2", []byte(`{"message":"b"}`+"\n"))
+
+	cfg := Config{
+		LogPattern: "app.log",
+		Source:     src,
+		RetryDelay: 10 * time.Millisecond,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	report, err := ProcessOnce(ctx, cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("ProcessOnce: %v", err)
+	}
+	if report.Processed != 1 || report.SegmentsComplete != 1 {
+		t.Errorf("processed %d records, %d segments complete; want 1 each", report.Processed, report.SegmentsComplete)
+	}
+	if len(report.SegmentsFailed) != 1 || report.SegmentsFailed[0] != "app.log.1" {
+		t.Errorf("report lists failed segments %v, want [app.log.1]", report.SegmentsFailed)
+	}
+	if want := int64(onceSegmentRetries + 1); report.Errors != want {
+		t.Errorf("errors = %d, want one per attempt (%d)", report.Errors, want)
+	}
+}
+
+// brokenSource serves a MemorySource whose bad segment never opens
+type brokenSource struct {
+	*MemorySource
+	bad string
+}
+
+func (s *brokenSource) Open(name string, offset int64) (io.ReadCloser, error) {
+	if name == s.bad {
+		return nil, errors.New("permission denied")
+	}
+	return s.MemorySource.Open(name, offset)
+}
@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// fileLimiter bounds the files the processor holds open at once
+// (MaxOpenFiles) and counts them
+type fileLimiter struct {
+	slots chan struct{} // One per open file; nil without a limit
+	open  atomic.Int64
+}
+
+// newFileLimiter returns a limiter allowing max open files, or any
+// number if max is 0
+func newFileLimiter(max int) *fileLimiter {
+	l := &fileLimiter{}
+	if max > 0 {
+		l.slots = make(chan struct{}, max)
+	}
+	return l
+}
+
+// acquire waits for a slot for one more open file, returning ctx.Err()
+// if ctx ends first. Every successful acquire must be released.
+func (l *fileLimiter) acquire(ctx context.Context) error {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	l.open.Add(1)
+	return nil
+}
+
+// release frees the slot of a file that has been closed
+func (l *fileLimiter) release() {
+	l.open.Add(-1)
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// heldFiles returns how many files cfg keeps open for a whole run, so
+// MaxOpenFiles must exceed it to leave room for reading segments: the
+// followed active file and each worker's WorkerOutput
+func (c *Config) heldFiles() int {
+	var held int
+	if c.Follow {
+		held++
+	}
+	if c.WorkerOutput != nil {
+		held += c.WorkerCount
+		if c.Follow {
+			held++ // The follower's worker has an output too
+		}
+	}
+	return held
+}
+
+// OpenFiles returns how many segment, active and WorkerOutput files
+// the processor holds open (see MaxOpenFiles)
+func (p *Processor) OpenFiles() int64 {
+	return p.files.open.Load()
+}
@@ -0,0 +1,157 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSource serves a MemorySource, tracking how many segments are
+// open at once
+type countingSource struct {
+	*MemorySource
+	open, peak atomic.Int64
+}
+
+func (s *countingSource) Open(name string, offset int64) (io.ReadCloser, error) {
+	rc, err := s.MemorySource.Open(name, offset)
+	if err != nil {
+		return nil, err
+	}
+	n := s.open.Add(1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	return &countedReader{ReadCloser: rc, open: &s.open}, nil
+}
+
+// countedReader decrements its source's open count when closed
+type countedReader struct {
+	io.ReadCloser
+	open *atomic.Int64
+	once sync.Once
+}
+
+func (r *countedReader) Close() error {
+	r.once.Do(func() { r.open.Add(-1) })
+	return r.ReadCloser.Close()
+}
+
+// TestMaxOpenFiles verifies workers never hold more files open than
+// MaxOpenFiles allows, counting their outputs, and still process every
+// segment
+func TestMaxOpenFiles(t *testing.T) {
+	const workers, segments = 6, 24
+	src := &countingSource{MemorySource: NewMemorySource()}
+	for i := range segments {
+		src.Put(fmt.Sprintf("app.log.%02d", i), []byte(`{"message":"a"}`+"\n"+`{"message":"b"}`+"\n"))
+	}
+
+	cfg := newTestConfig(t, workers)
+	cfg.Source = src
+	cfg.WorkerOutput = func(int) (io.Writer, error) { return io.Discard, nil }
+	cfg.MaxOpenFiles = workers + 2 // Room for two segments
+
+	bad := cfg
+	bad.MaxOpenFiles = workers
+	if err := bad.Validate(); err == nil {
+		t.Fatal("expected MaxOpenFiles within the worker outputs to be rejected")
+	}
+
+	var peakOpen atomic.Int64
+	var proc *Processor
+	proc, err := NewProcessor(cfg, func(*LogRecord) error {
+		if n := proc.OpenFiles(); n > peakOpen.Load() {
+			peakOpen.Store(n)
+		}
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 5*time.Second, func() bool {
+		_, _, stats := proc.Stats()
+		return stats[3] == segments
+	})
+	if peak := src.peak.Load(); peak > 2 {
+		t.Errorf("%d segments open at once, want at most 2", peak)
+	}
+	if peak := peakOpen.Load(); peak > int64(cfg.MaxOpenFiles) {
+		t.Errorf("OpenFiles reached %d, over the limit of %d", peak, cfg.MaxOpenFiles)
+	}
+	if processed, _, _ := proc.Stats(); processed != 2*segments {
+		t.Errorf("processed %d records, want %d", processed, 2*segments)
+	}
+
+	proc.Stop()
+	if n := proc.OpenFiles(); n != 0 {
+		t.Errorf("OpenFiles after Stop = %d, want 0", n)
+	}
+}
+
+// TestMaxOpenFilesReload verifies a worker retired by Reload gives back
+// its output's MaxOpenFiles slot, so scaling down and up again doesn't
+// run out of slots and hang
+func TestMaxOpenFilesReload(t *testing.T) {
+	cfg := newTestConfig(t, 2)
+	cfg.WorkerOutput = func(int) (io.Writer, error) { return io.Discard, nil }
+	cfg.MaxOpenFiles = 3 // Both outputs and one segment
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	reload := func(workers int) {
+		t.Helper()
+		next := cfg
+		next.WorkerCount = workers
+		done := make(chan error, 1)
+		go func() {
+			_, err := proc.Reload(next)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("Reload to %d workers: %v", workers, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Reload to %d workers hung waiting for an open file slot", workers)
+		}
+	}
+	for range 3 {
+		reload(1)
+		waitFor(t, 2*time.Second, func() bool { return proc.OpenFiles() == 1 })
+		reload(2)
+	}
+
+	segment := "app.log.20260101-000000"
+	writeSegment(t, cfg.LogsDir, segment, `{"message":"a"}`)
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, stats := proc.Stats()
+		return stats[3] == 1
+	})
+
+	proc.Stop()
+	if n := proc.OpenFiles(); n != 0 {
+		t.Errorf("OpenFiles after Stop = %d, want 0", n)
+	}
+}
@@ -0,0 +1,190 @@
+package processor
+
+import (
+	"bufio"
+	"container/list"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"log-processor/internal/logger"
+)
+
+const (
+	// defaultMaxOpenPartitions bounds a PartitionWriter's open files
+	defaultMaxOpenPartitions = 64
+
+	// unknownPartition receives records without the partition field
+	unknownPartition = "_unknown"
+)
+
+// PartitionWriter writes records as NDJSON to one file per value of a
+// field, e.g. out/payment-service.ndjson when partitioning by service.
+// Files are created on first use and appended to, so a rerun resumed
+// from committed offsets continues them. To bound open files with
+// high-cardinality fields, the least recently written file is closed
+// once maxOpen are open, and reopened if its value comes up again.
+//
+// Write is safe for concurrent use and can be passed to NewProcessor as
+// the ProcessFunc. Output is buffered per file: call Close after
+// stopping the processor. Records buffered when the process crashes are
+// lost even though their offsets may have been committed.
+type PartitionWriter struct {
+	dir     string
+	field   string
+	maxOpen int
+
+	mu     sync.Mutex
+	open   map[string]*list.Element // Partition name to its LRU element
+	lru    *list.List               // Of *partitionFile, most recent first
+	closed bool
+}
+
+// partitionFile is an open partition
+type partitionFile struct {
+	name string
+	file *os.File
+	buf  *bufio.Writer
+}
+
+// NewPartitionWriter creates a writer partitioning records into dir by
+// field (a known field or Extra key), keeping at most maxOpen files
+// open (64 if zero)
+func NewPartitionWriter(dir, field string, maxOpen int) (*PartitionWriter, error) {
+	if field == "" {
+		return nil, errors.New("partition field is required")
+	}
+	if maxOpen < 0 {
+		return nil, errors.New("max open partitions must not be negative")
+	}
+	if maxOpen == 0 {
+		maxOpen = defaultMaxOpenPartitions
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &PartitionWriter{
+		dir:     dir,
+		field:   field,
+		maxOpen: maxOpen,
+		open:    make(map[string]*list.Element),
+		lru:     list.New(),
+	}, nil
+}
+
+// Write appends a record to the file of its field value. Records
+// without the field go to _unknown.ndjson.
+func (w *PartitionWriter) Write(record *LogRecord) error {
+	data, err := logger.Marshal(record.Entry)
+	if err != nil {
+		return err
+	}
+	name := unknownPartition
+	if v, ok := record.Entry.Field(w.field); ok {
+		name = partitionName(fmt.Sprint(v))
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errors.New("partition writer closed")
+	}
+	f, err := w.partition(name)
+	if err != nil {
+		return err
+	}
+	f.buf.Write(data)
+	return f.buf.WriteByte('\n')
+}
+
+// partition returns the open file for a partition, opening it (and
+// closing the least recently used one if at the limit) as needed
+func (w *PartitionWriter) partition(name string) (*partitionFile, error) {
+	if e, ok := w.open[name]; ok {
+		w.lru.MoveToFront(e)
+		return e.Value.(*partitionFile), nil
+	}
+
+	if w.lru.Len() >= w.maxOpen {
+		oldest := w.lru.Back()
+		w.lru.Remove(oldest)
+		f := oldest.Value.(*partitionFile)
+		delete(w.open, f.name)
+		if err := f.close(); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(filepath.Join(w.dir, name+".ndjson"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	f := &partitionFile{name: name, file: file, buf: bufio.NewWriter(file)}
+	w.open[name] = w.lru.PushFront(f)
+	return f, nil
+}
+
+// close flushes and closes a partition file
+func (f *partitionFile) close() error {
+	err := f.buf.Flush()
+	if cerr := f.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("partition %s: %w", f.name, err)
+	}
+	return nil
+}
+
+// Flush writes out the buffered records of every open file
+func (w *PartitionWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []error
+	for e := w.lru.Front(); e != nil; e = e.Next() {
+		f := e.Value.(*partitionFile)
+		if err := f.buf.Flush(); err != nil {
+			errs = append(errs, fmt.Errorf("partition %s: %w", f.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes and closes every open file. Later writes fail.
+func (w *PartitionWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var errs []error
+	for e := w.lru.Front(); e != nil; e = e.Next() {
+		if err := e.Value.(*partitionFile).close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	w.open = make(map[string]*list.Element)
+	w.lru.Init()
+	w.closed = true
+	return errors.Join(errs...)
+}
+
+// partitionName makes a field value safe to use as a file name
+func partitionName(value string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		}
+		return '_'
+	}, value)
+
+	if strings.Trim(name, ".") == "" {
+		return unknownPartition // Empty, "." or ".."
+	}
+	return name
+}
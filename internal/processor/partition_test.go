@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"log-processor/internal/logger"
+)
+
+// TestPartitionWriter verifies records land in the file of their
+// service, including when files are closed to stay under the limit
+func TestPartitionWriter(t *testing.T) {
+	cfg := newTestConfig(t, 2)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"service":"payment-service","message":"p1"}`,
+		`{"service":"auth-service","message":"a1"}`,
+		`{"service":"order-service","message":"o1"}`,
+		`{"service":"payment-service","message":"p2"}`,
+	)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000001",
+		`{"service":"auth-service","message":"a2"}`,
+		`{"message":"none"}`,
+		`{"service":"../etc","message":"escape"}`,
+	)
+
+	out := filepath.Join(t.TempDir(), "out")
+	pw, err := NewPartitionWriter(out, "service", 2)
+	if err != nil {
+		t.Fatalf("NewPartitionWriter: %v", err)
+	}
+
+	proc, err := NewProcessor(cfg, pw.Write)
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := proc.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle: %v", err)
+	}
+	proc.Stop()
+	if err := pw.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	want := map[string][]string{
+		"payment-service.ndjson": {"p1", "p2"},
+		"auth-service.ndjson":    {"a1", "a2"},
+		"order-service.ndjson":   {"o1"},
+		"_unknown.ndjson":        {"none"},
+		".._etc.ndjson":          {"escape"},
+	}
+	entries, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d partition files, want %d", len(entries), len(want))
+	}
+	for name, messages := range want {
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		var got []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			entry, err := logger.ParseJSON([]byte(line))
+			if err != nil {
+				t.Fatalf("%s: parse %q: %v", name, line, err)
+			}
+			got = append(got, entry.Message)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(messages, ",") {
+			t.Errorf("%s holds %v, want %v", name, got, messages)
+		}
+	}
+
+	if err := pw.Write(&LogRecord{}); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
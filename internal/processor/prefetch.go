@@ -0,0 +1,23 @@
+package processor
+
+import (
+	"io"
+	"os"
+)
+
+// prefetchWindow is how far past the resume offset Prefetch asks the
+// kernel to start reading at once
+const prefetchWindow = 32 << 20
+
+// prefetch advises sequential access to the file behind rc, if any,
+// from offset. Compressed segments are advised from the start, since
+// offset counts decompressed bytes.
+func prefetch(rc io.ReadCloser, offset int64) error {
+	switch f := rc.(type) {
+	case *os.File:
+		return adviseSequential(f, offset)
+	case *decompressReadCloser:
+		return adviseSequential(f.file, 0)
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build linux
+
+package processor
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// adviseSequential declares f will be read sequentially from offset
+// (POSIX_FADV_SEQUENTIAL, doubling the kernel's readahead) and starts
+// reading its next prefetchWindow bytes (POSIX_FADV_WILLNEED)
+func adviseSequential(f *os.File, offset int64) error {
+	fd := int(f.Fd())
+	if err := unix.Fadvise(fd, offset, 0, unix.FADV_SEQUENTIAL); err != nil {
+		return os.NewSyscallError("fadvise", err)
+	}
+	if err := unix.Fadvise(fd, offset, prefetchWindow, unix.FADV_WILLNEED); err != nil {
+		return os.NewSyscallError("fadvise", err)
+	}
+	return nil
+}
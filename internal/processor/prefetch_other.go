@@ -0,0 +1,10 @@
+//go:build !linux
+
+package processor
+
+import "os"
+
+// adviseSequential is a no-op without posix_fadvise
+func adviseSequential(f *os.File, offset int64) error {
+	return nil
+}
@@ -2,9 +2,25 @@ package processor
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"math/rand"
+	"path/filepath"
+	"runtime/debug"
+	"slices"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"log-processor/internal/logger"
+	"log-processor/internal/rotation"
+	"log-processor/internal/schema"
 )
 
 // Config holds the processor configuration
@@ -14,24 +30,608 @@ type Config struct {
 	OffsetsDir   string
 	WorkerCount  int
 	ScanInterval time.Duration
+
+	// ScanJitter randomizes each scan within [ScanInterval-ScanJitter,
+	// ScanInterval+ScanJitter] so many instances don't scan in lockstep.
+	// Values larger than ScanInterval are capped to it.
+	ScanJitter time.Duration
+
+	// RecordDelimiter is the byte separating records ('\n' if 0). With
+	// '\n', a CR before it is trimmed too, so CRLF input needs nothing
+	// more. Since 0 means the default, NUL-delimited streams set
+	// NULDelimited instead.
+	RecordDelimiter byte
+	NULDelimited    bool
+
+	// UseMmap memory-maps rotated segments from the default file source
+	// and scans the mapped bytes for records, saving the read syscalls
+	// and the copy into a read buffer. Each record's bytes are still
+	// copied out of the mapping once, since records (their Raw bytes
+	// included) outlive the segment's reader. This mainly helps large
+	// segments already in the page cache. Rotated segments must no
+	// longer grow; the followed active file, compressed segments,
+	// streams and the file of a NewExactFileSource (which may still be
+	// written) are always read with buffered reads, as are segments
+	// that fail to map.
+	UseMmap bool
+
+	// Prefetch advises the kernel that each segment opened from a file
+	// will be read sequentially from its resume offset, so it reads
+	// ahead more aggressively and starts loading the next prefetchWindow
+	// bytes into the page cache before the reader needs them. This only
+	// helps disk-bound processing of cold files. It is a no-op on
+	// platforms without posix_fadvise and for streams and mapped
+	// segments.
+	Prefetch bool
+
+	// FieldMap maps JSON field names of other logging libraries to
+	// LogEntry's, e.g. logger.ZapFieldMap or logger.LogrusFieldMap, so
+	// their records aren't left with every field in Extra. Raw is
+	// unchanged.
+	FieldMap map[string]string
+
+	// ResyncCorrupt skips corrupt regions of JSON segments (see
+	// ReaderOptions.Resync) instead of delivering each garbage line: a
+	// region is dead-lettered as one record with a *CorruptRegionError,
+	// counted by CorruptRegions and as an error, and never reaches the
+	// process func. Valid records after it are processed as usual.
+	ResyncCorrupt bool
+
+	// SanitizeUTF8 replaces invalid UTF-8 in each record with U+FFFD
+	// before it is parsed, or drops it with DropInvalidUTF8, so stray
+	// binary bytes don't fail the parse or reach the process func (see
+	// ReaderOptions.SanitizeUTF8). Sanitized records are counted by
+	// SanitizedRecords.
+	SanitizeUTF8    bool
+	DropInvalidUTF8 bool
+
+	// InputFormat is how records are encoded: logger.JSON (the
+	// default), logger.Text, logger.Syslog, logger.Logfmt, Wrapped for
+	// one JSON document per segment, or Auto to detect it per segment
+	InputFormat logger.Format
+
+	// WrappedPath locates the array of records in a Wrapped document
+	// (see ReaderOptions.WrappedPath)
+	WrappedPath string
+
+	// StreamKey, if set, maps a segment name to a stream, e.g. the
+	// tenant in "tenant-a.app.log.20260101-000000" (see
+	// PrefixStreamKey). The key is set on each record as
+	// LogRecord.StreamKey; RouteStreams dispatches on it.
+	StreamKey func(segmentName string) string
+
+	// Source supplies segments. Defaults to a FileSource over
+	// LogsDir/LogPattern.
+	Source SegmentSource
+
+	// VerifyOffsetIdentity records a fingerprint of each segment's head
+	// with its offset and checks it before resuming, so an offset is
+	// only applied to the file it was committed for. A different file
+	// under the same name (e.g. from another logs directory sharing
+	// OffsetsDir) is then processed from the start rather than resumed
+	// from, or skipped as complete at, the stale offset.
+	VerifyOffsetIdentity bool
+
+	// ExactFile processes this one file as the only segment instead of
+	// the rotated files of LogsDir/LogPattern (see NewExactFileSource).
+	// Its offset is still persisted, so a rerun resumes where the last
+	// one stopped.
+	ExactFile string
+
+	// Rotation is the naming scheme of rotated segments (zero value is
+	// the generator's default "<pattern>.20060102-150405")
+	Rotation rotation.Scheme
+
+	// MaxCompleteSegments bounds how many completed segments stay in
+	// memory (0 = unbounded). Evicted segments remain complete in the
+	// offset store and are excluded from segment stats.
+	MaxCompleteSegments int
+
+	// IgnoreEmptySegments skips zero-byte segments (e.g. from an
+	// immediate rotation) so they aren't tracked or counted
+	IgnoreEmptySegments bool
+
+	// PanicOnCallbackPanic disables panic recovery around the process
+	// func, so a panicking callback crashes the processor (fail-fast).
+	// By default panics are converted to *PanicError and counted.
+	PanicOnCallbackPanic bool
+
+	// ProcessFuncCtx, if set, is used instead of the ProcessFunc passed
+	// to NewProcessor. Its context is cancelled when the processor
+	// stops, so long-running callbacks can abort promptly.
+	ProcessFuncCtx ProcessFuncCtx
+
+	// MeasureLatency times every call of the process func (not the
+	// pipeline around it) into a histogram reported by Latency. It is
+	// off by default to avoid the clock reads.
+	MeasureLatency bool
+
+	// WorkerOutput, if set, opens a writer for each worker when the
+	// processor starts. A ProcessFuncCtx writes to it through the
+	// buffered WorkerContext.Out (see WorkerFromContext), which is
+	// flushed before every offset commit and closed, if an io.Closer,
+	// once the workers stop.
+	WorkerOutput func(workerID int) (io.Writer, error)
+
+	// WorkerBufferSize is the size of each WorkerOutput buffer
+	// (64 KiB if zero)
+	WorkerBufferSize int
+
+	// MaxOpenFiles limits the files held open at once: segments being
+	// read, the followed active file and WorkerOutput writers, which are
+	// counted whether or not they are files. Workers wait for a file to
+	// be closed rather than exceed it. It must exceed the outputs and
+	// followed file, which stay open all run (0 = no limit).
+	MaxOpenFiles int
+
+	// Redact maps field names (known fields or Extra keys) to functions
+	// that mask their values before anything else sees the record:
+	// validation, Transform, the process func, and DeadLetter. Raw is
+	// re-encoded from the redacted entry.
+	Redact map[string]RedactFunc
+
+	// ValidateEntries treats parsed records that fail
+	// logger.LogEntry.Validate (e.g. no level or a bad timestamp) as
+	// errors: they are counted and dead-lettered instead of processed
+	ValidateEntries bool
+
+	// SchemaPath, if set, names a JSON Schema file (see package schema
+	// for the keywords supported) that each parsed record must satisfy.
+	// The record's Raw bytes are validated for JSON input, otherwise
+	// the re-marshaled entry. Violations are counted by
+	// SchemaViolations and dead-lettered as a *SchemaError, or with
+	// HaltOnSchemaViolation stop the processor at the violating record,
+	// which is left unprocessed for the next run.
+	SchemaPath            string
+	HaltOnSchemaViolation bool
+
+	// Transform, if set, runs on each record before the process func,
+	// e.g. to enrich it with looked-up fields in Entry.Extra. A record
+	// whose transform fails is counted as an error and dead-lettered;
+	// one whose transform returns ErrDrop is skipped and counted by
+	// Filtered.
+	Transform Transform
+
+	// Transforms are further stages run in order after Transform, so
+	// enrichment, redaction and filtering can be composed from reusable
+	// pieces. Each sees the record as the previous stage left it, and
+	// the first to fail or drop the record ends the pipeline.
+	Transforms []Transform
+
+	// DeadLetter, if set, receives records that failed irrecoverably
+	// along with the reason (e.g. a *PanicError from the callback, or an
+	// error it marked with Permanent)
+	DeadLetter DeadLetterFunc
+
+	// RetryDelay is how long a segment waits before it is retried after
+	// the process func returned a Transient error or reading it failed
+	// (default 1s)
+	RetryDelay time.Duration
+
+	// Tracer, if set, receives a span for each run (log-processor.run,
+	// from Start to Stop) and, as its children, one for each attempt at
+	// a segment (log-processor.segment) with the segment's name, size,
+	// lines processed and record errors. Failed attempts record their
+	// error. ProcessFuncCtx's context carries the segment's span. See
+	// Tracer for adapting OpenTelemetry.
+	Tracer Tracer
+
+	// MaxSegmentRetries bounds how many times a failing segment is
+	// retried in one run. A segment that fails again after that is
+	// given up on until the next run and reported by FailedSegments,
+	// rather than retried indefinitely (0 = unlimited).
+	MaxSegmentRetries int
+
+	// SegmentDeadline bounds how long a worker keeps one segment (0 = no
+	// limit). Once it has passed, the worker commits its progress and
+	// hands the segment back behind the other pending ones, to resume
+	// from its offset later, so one huge segment or a slow process func
+	// can't keep the rest waiting. It is checked between records.
+	// Streams, which can't resume, run to the end.
+	SegmentDeadline time.Duration
+
+	// Prioritize, if set, orders the pending segments each time a worker
+	// looks for one to claim, replacing the default order (by name, with
+	// segments yielded at SegmentDeadline last). Workers claim from the
+	// returned slice in order; segments left out are not claimed on that
+	// pass. It is called from several workers at once and must not
+	// modify the segments.
+	Prioritize PrioritizeFunc
+
+	// OnCommitError, if set, is called each time an offset commit fails
+	// (e.g. OffsetsDir became read-only or the disk is full). Failures
+	// are also logged and counted by CommitErrors.
+	OnCommitError CommitErrorFunc
+
+	// OpenRetries is how many more times opening a segment is tried
+	// before the attempt fails, waiting OpenRetryDelay (default 50ms)
+	// and then twice as long each time, to ride out e.g. a writer
+	// briefly locking the file while rotating it (0 = no retries).
+	// Segments that no longer exist are not retried.
+	OpenRetries    int
+	OpenRetryDelay time.Duration
+
+	// OnOpenError, if set, is called when opening a segment has failed
+	// for good, retries included. The failure is also logged and
+	// counted as an error, and the segment is retried after RetryDelay.
+	OnOpenError OpenErrorFunc
+
+	// OnSegmentComplete, if set, is called by the worker that finishes a
+	// segment, after its final offset commit and before it is marked
+	// complete (so WaitForIdle returns only once it has run). It is not
+	// called for the file being followed, which is never completed.
+	OnSegmentComplete SegmentCompleteFunc
+
+	// RunReports, if positive, writes a RunReport of each run (Start to
+	// Stop) to OffsetsDir, keeping this many of the most recent
+	RunReports int
+
+	// MaxCommitFailures stops the processor after this many consecutive
+	// failed offset commits, rather than processing on without durable
+	// progress (0 = never stop)
+	MaxCommitFailures int
+
+	// CheckMonotonic counts records whose timestamp is earlier than the
+	// previous record's in the same segment. Processing is unaffected.
+	CheckMonotonic bool
+
+	// OnOutOfOrder, if set, is called for each monotonicity violation
+	OnOutOfOrder OutOfOrderFunc
+
+	// Follow also tails the active file (LogsDir/LogPattern) as it is
+	// written, handing it off as a completed segment when it rotates.
+	// Requires the default file source.
+	Follow bool
+
+	// DeleteAfterComplete removes each segment, and then its offset
+	// file, once it is fully processed and DeleteGrace has passed. A
+	// segment requeued during the grace period is not deleted. This is
+	// destructive and requires a source implementing SegmentRemover.
+	DeleteAfterComplete bool
+
+	// DeleteGrace is how long a completed segment is kept before
+	// DeleteAfterComplete removes it
+	DeleteGrace time.Duration
+
+	// CommitTarget enables adaptive offset commits: the number of
+	// records between commits follows the observed throughput so a
+	// commit happens about once per CommitTarget, within
+	// [MinCommitRecords, MaxCommitRecords] (defaults 1 and 10000). When
+	// zero, offsets are committed every 100 records.
+	CommitTarget     time.Duration
+	MinCommitRecords int
+	MaxCommitRecords int
+
+	// AutoSelectParser benchmarks the registered JSON backends on a
+	// small sample of the input at Start and selects the fastest for
+	// decoding. The choice is process-wide (see logger.SetUnmarshaler)
+	// and reported by SelectedParser.
+	AutoSelectParser bool
+
+	// ParserBackend names the registered JSON backend (see
+	// logger.Backends) to decode with from Start, process-wide like
+	// AutoSelectParser. Empty keeps the current one.
+	ParserBackend string
+
+	// Logger receives the processor's operational events (scans,
+	// claims, completions, errors, shutdown). Defaults to discarding.
+	Logger *slog.Logger
+
+	// TimeFrom and TimeTo restrict processing to records timestamped
+	// within [TimeFrom, TimeTo]; a zero bound is open. Other records,
+	// including those without a parseable timestamp, are skipped but
+	// still committed past. Segments rotated before TimeFrom (per their
+	// name) are not tracked at all.
+	TimeFrom time.Time
+	TimeTo   time.Time
+
+	// ExcludeSegments lists segment names or glob patterns (matched
+	// against the base name) that are never tracked or processed, e.g.
+	// to quarantine a corrupt file without deleting it. If
+	// IncludeSegments is set, only segments matching one of its
+	// patterns are tracked. Exclusion wins over inclusion.
+	ExcludeSegments []string
+	IncludeSegments []string
+
+	// ResumeFromLatest skips the backlog present on the first run: if
+	// OffsetsDir holds no offsets, every existing segment (and with
+	// Follow, the active file's complete records) is committed as
+	// processed at Start, so only data written afterwards is. It is
+	// applied once; later runs resume normally, backlog included.
+	ResumeFromLatest bool
+
+	// SuppressDuplicates skips segment records already delivered earlier
+	// in this run, e.g. when a segment is requeued and re-read from an
+	// older offset. Delivered records are remembered in a Bloom filter
+	// sized for ExpectedRecords (default 1,000,000, about 3.6 MB) with a
+	// one-in-a-million false-positive rate; a false positive silently
+	// drops a record that was never delivered, and the rate rises once
+	// a run delivers more than ExpectedRecords. Records are identified
+	// by segment and byte offset, so the followed active file is not
+	// covered. Nothing is remembered across restarts.
+	SuppressDuplicates bool
+	ExpectedRecords    int
+
+	// MaxRecords stops the processor after this many records (0 = no
+	// limit). Offsets are committed just past the last admitted record,
+	// so the next run continues with the following one. Done is closed
+	// once the limit is reached.
+	MaxRecords int64
+
+	// StopTimeout bounds how long Stop waits for workers, e.g. one stuck
+	// in a callback that ignores cancellation (0 = wait indefinitely)
+	StopTimeout time.Duration
+
+	// BuildIndex writes a sidecar index (<segment>.index.json in
+	// OffsetsDir) for each segment processed from the start, mapping
+	// level and service to record offsets, so Filter can read matching
+	// records directly. An index is ignored once the segment changes.
+	// The index also maps every LineIndexStride-th line (default 1000)
+	// to its offset, for OpenAtLine.
+	BuildIndex      bool
+	LineIndexStride int64
+
+	// FollowInterval is how often a caught-up follower polls the active
+	// file for new data or rotation (default 100ms). On Linux the
+	// follower instead waits for inotify to report a change to the
+	// file, polling only every 5s in case one is missed, and falls back
+	// to FollowInterval if inotify is unavailable.
+	FollowInterval time.Duration
+}
+
+// Validate checks the configuration, reporting every problem found
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, msg string) {
+		if !ok {
+			errs = append(errs, errors.New(msg))
+		}
+	}
+
+	check(c.WorkerCount >= 1, "WorkerCount must be at least 1")
+	check(c.ScanInterval > 0, "ScanInterval must be positive")
+	check(c.ScanJitter >= 0, "ScanJitter must not be negative")
+	check(c.OffsetsDir != "", "OffsetsDir is required")
+	check(c.ExactFile == "" || c.Source == nil, "ExactFile and Source are mutually exclusive")
+	if c.Source == nil && c.ExactFile == "" {
+		check(c.LogsDir != "", "LogsDir is required")
+		check(c.LogPattern != "", "LogPattern is required")
+	}
+	if c.InputFormat != "" && c.InputFormat != Wrapped && c.InputFormat != Auto {
+		_, err := logger.ParseFormat(string(c.InputFormat))
+		check(err == nil, "InputFormat must be json, text, syslog, logfmt, wrapped or auto")
+	}
+	check(c.WrappedPath == "" || c.InputFormat == Wrapped || c.InputFormat == Auto,
+		"WrappedPath requires the wrapped or auto InputFormat")
+	check(c.InputFormat != Wrapped || !c.Follow, "Follow cannot tail a wrapped document")
+	check(c.RecordDelimiter == 0 || !c.NULDelimited, "RecordDelimiter and NULDelimited are mutually exclusive")
+	if err := c.Rotation.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	check(c.MaxCompleteSegments >= 0, "MaxCompleteSegments must not be negative")
+	check(!c.Follow || (c.Source == nil && c.ExactFile == ""), "Follow requires the default file source")
+	check(c.FollowInterval >= 0, "FollowInterval must not be negative")
+	check(c.DeleteGrace >= 0, "DeleteGrace must not be negative")
+	check(c.StopTimeout >= 0, "StopTimeout must not be negative")
+	check(c.RunReports >= 0, "RunReports must not be negative")
+	check(c.WorkerBufferSize >= 0, "WorkerBufferSize must not be negative")
+	check(c.MaxOpenFiles >= 0, "MaxOpenFiles must not be negative")
+	check(c.MaxOpenFiles == 0 || c.MaxOpenFiles > c.heldFiles(),
+		"MaxOpenFiles must exceed the WorkerOutput writers and followed file held open all run")
+	check(c.RetryDelay >= 0, "RetryDelay must not be negative")
+	check(c.MaxSegmentRetries >= 0, "MaxSegmentRetries must not be negative")
+	check(c.OpenRetries >= 0, "OpenRetries must not be negative")
+	check(c.SegmentDeadline >= 0, "SegmentDeadline must not be negative")
+	check(c.OpenRetryDelay >= 0, "OpenRetryDelay must not be negative")
+	check(c.MaxCommitFailures >= 0, "MaxCommitFailures must not be negative")
+	check(c.MaxRecords >= 0, "MaxRecords must not be negative")
+	check(c.ExpectedRecords >= 0, "ExpectedRecords must not be negative")
+	check(c.LineIndexStride >= 0, "LineIndexStride must not be negative")
+	check(c.TimeFrom.IsZero() || c.TimeTo.IsZero() || !c.TimeTo.Before(c.TimeFrom),
+		"TimeTo must not be before TimeFrom")
+	for _, patterns := range [][]string{c.ExcludeSegments, c.IncludeSegments} {
+		for _, pattern := range patterns {
+			_, err := filepath.Match(pattern, "")
+			check(err == nil, fmt.Sprintf("invalid segment pattern %q", pattern))
+		}
+	}
+	check(c.CommitTarget >= 0, "CommitTarget must not be negative")
+	check(c.MinCommitRecords >= 0, "MinCommitRecords must not be negative")
+	check(c.MaxCommitRecords >= 0, "MaxCommitRecords must not be negative")
+	check(c.MaxCommitRecords == 0 || c.MaxCommitRecords >= c.MinCommitRecords,
+		"MaxCommitRecords must not be less than MinCommitRecords")
+	for _, stage := range c.Transforms {
+		check(stage != nil, "Transforms must not contain nil stages")
+	}
+	if c.ParserBackend != "" {
+		b, ok := logger.BackendByName(c.ParserBackend)
+		check(ok, "ParserBackend must name a registered JSON backend")
+		check(!ok || logger.Supported(b), "ParserBackend is not supported on this platform")
+		check(!c.AutoSelectParser, "ParserBackend and AutoSelectParser are mutually exclusive")
+	}
+	if c.DeleteAfterComplete && c.Source != nil {
+		_, ok := c.Source.(SegmentRemover)
+		check(ok, "DeleteAfterComplete requires a source that can remove segments")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid processor config: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// followInterval returns the effective follow poll interval
+func (c Config) followInterval() time.Duration {
+	if c.FollowInterval <= 0 {
+		return 100 * time.Millisecond
+	}
+	return c.FollowInterval
+}
+
+// retryDelay returns the effective delay before a transient retry
+func (c Config) retryDelay() time.Duration {
+	if c.RetryDelay <= 0 {
+		return time.Second
+	}
+	return c.RetryDelay
+}
+
+// readerOptions builds the reader options implied by the config
+func (c Config) readerOptions() ReaderOptions {
+	opts := DefaultReaderOptions()
+	opts.Format = c.InputFormat
+	opts.Resync = c.ResyncCorrupt
+	opts.SanitizeUTF8 = c.SanitizeUTF8 || c.DropInvalidUTF8
+	opts.DropInvalidUTF8 = c.DropInvalidUTF8
+	opts.FieldMap = c.FieldMap
+	opts.WrappedPath = c.WrappedPath
+	opts.Delimiter = c.delimiter()
+	return opts
+}
+
+// delimiter returns the byte separating records
+func (c Config) delimiter() byte {
+	switch {
+	case c.NULDelimited:
+		return 0
+	case c.RecordDelimiter != 0:
+		return c.RecordDelimiter
+	}
+	return '\n'
+}
+
+// segmentReaderOptions builds the reader options for records delivered
+// from a segment, tagged with its stream key
+func (c Config) segmentReaderOptions(segment string) ReaderOptions {
+	opts := c.readerOptions()
+	if c.StreamKey != nil {
+		opts.StreamKey = c.StreamKey(segment)
+	}
+	return opts
+}
+
+// ParseSegmentTime returns the rotation time encoded in a segment name
+func (c Config) ParseSegmentTime(name string) (time.Time, error) {
+	return c.Rotation.Parse(c.LogPattern, name)
 }
 
 // ProcessFunc is the callback function for processing each log record
 type ProcessFunc func(*LogRecord) error
 
+// ProcessFuncCtx is a ProcessFunc that observes processor shutdown
+type ProcessFuncCtx func(ctx context.Context, record *LogRecord) error
+
+// Transform modifies a record in place before it is processed
+type Transform func(*LogRecord) error
+
+// CommitErrorFunc receives a failed offset commit
+type CommitErrorFunc func(segment string, err error)
+
+// OpenErrorFunc receives a segment that could not be opened and the
+// number of attempts made
+type OpenErrorFunc func(segment string, attempts int, err error)
+
+// SegmentCompletion describes a segment a worker has finished, for
+// notifying downstream systems (e.g. that it is safe to archive)
+type SegmentCompletion struct {
+	Segment string
+	Path    string
+	Worker  int
+
+	// Ordinal is the segment's ordinal and LastOrdinal that of the last
+	// record delivered from it this run (see LogRecord), so a consumer
+	// merging segments knows when it has all of one
+	Ordinal     int64
+	LastOrdinal int64
+
+	// Records and Errors count the records processed successfully and
+	// those that failed in the pass that completed the segment; for a
+	// resumed segment, records from earlier runs are not included.
+	Records int64
+	Errors  int64
+
+	// Offset is the final byte offset committed
+	Offset int64
+
+	// Start and End are the earliest and latest record timestamps seen
+	// in the pass, zero if no record had an RFC 3339 timestamp
+	Start time.Time
+	End   time.Time
+
+	// Err is set if the final offset commit failed
+	Err error
+}
+
+// PrioritizeFunc returns pending segments in the order to claim them
+type PrioritizeFunc func(pending []*Segment) []*Segment
+
+// SegmentCompleteFunc receives each completed segment
+type SegmentCompleteFunc func(SegmentCompletion)
+
+// DeadLetterFunc receives a record that could not be processed
+type DeadLetterFunc func(record *LogRecord, err error)
+
+// OutOfOrderFunc reports a record timestamped before its predecessor.
+// offset is the byte offset just after the offending record.
+type OutOfOrderFunc func(segment string, prev, curr time.Time, offset int64)
+
 // Processor orchestrates log file processing
 type Processor struct {
 	cfg         Config
 	processFunc ProcessFunc
+	log         *slog.Logger
 
+	source     SegmentSource
 	offsetMgr  *OffsetManager
 	segmentMgr *SegmentManager
 
-	workers  []*worker
-	workerWg sync.WaitGroup
+	workers   []*worker
+	started   []*worker // Workers of the current run, with the follower's and retired ones
+	workersMu sync.Mutex
+	group     *errgroup.Group // Runs the workers of the current run; the first error cancels ctx
+
+	nextWorkerID int
+	live         atomic.Pointer[Config] // cfg with the settings applied by Reload
+	rescan       chan struct{}          // Reschedules the next scan after a Reload
+
+	processed  atomic.Int64
+	errors     atomic.Int64
+	outOfOrder atomic.Int64
+	admitted   atomic.Int64 // Records admitted against MaxRecords
+	duplicates atomic.Int64
+	filtered   atomic.Int64 // Records dropped by a transform
+	transient  atomic.Int64 // Process func errors marked Transient
+	permanent  atomic.Int64 // Process func errors marked Permanent
+
+	schemaViolations atomic.Int64
+	commitErrors     atomic.Int64
+	corruptRegions   atomic.Int64
+	sanitized        atomic.Int64 // Records with invalid UTF-8 removed
+	commitStreak     atomic.Int64 // Consecutive failed commits
+
+	delivered *bloomFilter   // Records delivered this run, for SuppressDuplicates
+	schema    *schema.Schema // Compiled from SchemaPath
+
+	commitInterval atomic.Int64 // Most recent adaptive commit threshold
+
+	run *runState // For the run report, with RunReports
+
+	latency *latencyHistogram // With MeasureLatency
+
+	tracer  Tracer // Config.Tracer, or a no-op
+	runSpan Span   // Span of the current run
+
+	selectedParser string // Backend chosen by AutoSelectParser
+
+	stats statsHub // StatsChannel subscribers
 
-	processed atomic.Int64
-	errors    atomic.Int64
+	files *fileLimiter // Open files, bounded by MaxOpenFiles
+
+	barrier      barrier    // Pauses record delivery for a Checkpoint
+	checkpointMu sync.Mutex // Serializes Checkpoints
+
+	deletions   map[string]*time.Timer // Pending DeleteAfterComplete timers
+	deletionsMu sync.Mutex
 
 	ctx     context.Context
 	cancel  context.CancelFunc
@@ -42,33 +642,100 @@ type Processor struct {
 type worker struct {
 	id        int
 	processor *Processor
+
+	ctx context.Context // Processor context carrying wc, set by start
+	wc  *WorkerContext
+
+	retire chan struct{} // Closed when a Reload removes the worker
 }
 
 // NewProcessor creates a new log processor
 func NewProcessor(cfg Config, processFunc ProcessFunc) (*Processor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if processFunc == nil && cfg.ProcessFuncCtx == nil {
+		return nil, errors.New("a process func is required")
+	}
+
 	// Create offset manager
 	offsetMgr, err := NewOffsetManager(cfg.OffsetsDir)
 	if err != nil {
 		return nil, err
 	}
 
+	source := cfg.Source
+	switch {
+	case source != nil:
+	case cfg.ExactFile != "":
+		source = NewExactFileSource(cfg.ExactFile)
+	default:
+		source = NewSchemeFileSource(cfg.LogsDir, cfg.LogPattern, cfg.Rotation)
+	}
+
+	if cfg.VerifyOffsetIdentity {
+		offsetMgr.SetFingerprint(sourceFingerprint(source))
+	}
+
 	// Create segment manager
-	segmentMgr := NewSegmentManager(cfg.LogsDir, cfg.LogPattern, offsetMgr)
+	segmentMgr := NewSegmentManager(source, offsetMgr)
+	segmentMgr.SetMaxComplete(cfg.MaxCompleteSegments)
+	segmentMgr.SetIgnoreEmpty(cfg.IgnoreEmptySegments)
+	segmentMgr.SetMaxRetries(cfg.MaxSegmentRetries)
+	segmentMgr.SetPrioritize(cfg.Prioritize)
+	segmentMgr.SetDelimiter(cfg.delimiter())
 
 	p := &Processor{
 		cfg:         cfg,
 		processFunc: processFunc,
+		source:      source,
 		offsetMgr:   offsetMgr,
 		segmentMgr:  segmentMgr,
+		log:         cfg.Logger,
+		rescan:      make(chan struct{}, 1),
+		files:       newFileLimiter(cfg.MaxOpenFiles),
+	}
+	p.live.Store(&cfg)
+	if p.log == nil {
+		p.log = slog.New(slog.DiscardHandler)
+	}
+	if fs, ok := source.(*FileSource); ok && fs.log == nil {
+		fs.log = p.log
+	}
+
+	if cfg.SchemaPath != "" {
+		if p.schema, err = schema.Load(cfg.SchemaPath); err != nil {
+			return nil, fmt.Errorf("load schema: %w", err)
+		}
+	}
+
+	if cfg.SuppressDuplicates {
+		n := cfg.ExpectedRecords
+		if n == 0 {
+			n = defaultExpectedRecords
+		}
+		p.delivered = newBloomFilter(n, duplicateFalsePositiveRate)
+	}
+
+	if cfg.MeasureLatency {
+		p.latency = &latencyHistogram{}
+	}
+
+	p.tracer = cfg.Tracer
+	if p.tracer == nil {
+		p.tracer = noopTracer{}
+	}
+
+	if cfg.DeleteAfterComplete {
+		p.deletions = make(map[string]*time.Timer)
+		segmentMgr.SetOnComplete(p.scheduleDelete)
 	}
 
 	// Create workers
 	p.workers = make([]*worker, cfg.WorkerCount)
-	for i := 0; i < cfg.WorkerCount; i++ {
-		p.workers[i] = &worker{
-			id:        i,
-			processor: p,
-		}
+	for i := range p.workers {
+		p.workers[i] = p.newWorker()
 	}
 
 	return p, nil
@@ -79,41 +746,288 @@ func (p *Processor) Start(ctx context.Context) error {
 	if p.running.Swap(true) {
 		return nil // Already running
 	}
+	p.workersMu.Lock()
+	defer p.workersMu.Unlock()
+
+	var groupCtx context.Context
+	p.group, groupCtx = errgroup.WithContext(p.startRunSpan(ctx))
+	p.ctx, p.cancel = context.WithCancel(groupCtx)
+	p.beginRun()
+
+	if p.cfg.AutoSelectParser {
+		p.selectParser()
+	} else if b, ok := logger.BackendByName(p.cfg.ParserBackend); ok {
+		logger.SetUnmarshaler(b)
+	}
 
-	p.ctx, p.cancel = context.WithCancel(ctx)
+	// The follower must be able to hide a just-rotated file from the
+	// scanner before the first scan
+	var f *follower
+	switch {
+	case p.cfg.Follow:
+		f = newFollower(p)
+		if err := f.reclaim(); err != nil {
+			p.log.Error("recovering the active file's offset failed", "error", err)
+		}
+		p.segmentMgr.SetSkip(func(info SegmentInfo) bool {
+			return f.owns(info) || p.cfg.skipSegment(info)
+		})
+	case !p.cfg.TimeFrom.IsZero() || len(p.cfg.ExcludeSegments) > 0 || len(p.cfg.IncludeSegments) > 0:
+		p.segmentMgr.SetSkip(p.cfg.skipSegment)
+	}
+
+	p.started = slices.Clone(p.workers)
+	if f != nil {
+		p.started = append(p.started, f.w)
+	}
+	// abort undoes the start so far, leaving Start free to be retried
+	abort := func(err error) error {
+		p.closeOutputs()
+		p.cancel()
+		p.endRunSpan(false)
+		p.running.Store(false)
+		return err
+	}
+
+	for _, w := range p.started {
+		if err := w.start(); err != nil {
+			return abort(err)
+		}
+	}
+
+	if err := p.seedLatest(); err != nil {
+		p.log.Error("seeding offsets from latest failed", "error", err)
+		return abort(err)
+	}
 
 	// Initial scan
 	if err := p.segmentMgr.Scan(); err != nil {
-		return err
+		p.log.Error("initial scan failed", "error", err)
+		return abort(err)
 	}
 
 	// Start workers
 	for _, w := range p.workers {
-		p.workerWg.Add(1)
-		go w.run()
+		p.group.Go(w.run)
+	}
+
+	if f != nil {
+		p.group.Go(f.run)
 	}
 
 	// Start scanner goroutine
 	go p.scanLoop()
 
+	total, pending, _, _ := p.segmentMgr.GetStats()
+	p.log.Info("processor started",
+		"workers", len(p.workers), "follow", p.cfg.Follow,
+		"segments", total, "pending", pending)
 	return nil
 }
 
-// Stop gracefully stops the processor
-func (p *Processor) Stop() {
+// Stop gracefully stops the processor, waiting for workers to finish.
+// With a StopTimeout it gives up after that long and returns
+// ErrStopTimeout, leaving stuck workers running in the background.
+func (p *Processor) Stop() error {
 	if !p.running.Swap(false) {
-		return // Not running
+		return nil // Not running
 	}
-
 	if p.cancel != nil {
 		p.cancel()
 	}
+	// A Reload in progress finishes adding workers before they are
+	// waited on; cancelling first stops it waiting for an open file slot
+	p.workersMu.Lock()
+	p.workersMu.Unlock()
+	p.cancelDeletes()
 
 	// Wait for workers to finish
-	p.workerWg.Wait()
+	if p.cfg.StopTimeout <= 0 {
+		p.group.Wait()
+		p.closeOutputs()
+		p.endRun(false)
+		p.endRunSpan(false)
+		p.closeStats()
+		p.log.Info("processor stopped")
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.group.Wait()
+		close(done)
+	}()
+
+	timer := time.NewTimer(p.cfg.StopTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		p.closeOutputs()
+		p.endRun(false)
+		p.endRunSpan(false)
+		p.closeStats()
+		p.log.Info("processor stopped")
+		return nil
+	case <-timer.C:
+		p.log.Warn("stop timed out with workers still running", "timeout", p.cfg.StopTimeout)
+		p.endRun(true)
+		p.endRunSpan(true)
+		p.closeStats()
+		return ErrStopTimeout
+	}
 }
 
-// Stats returns processing statistics
+// Run starts the processor and blocks until it stops: when ctx is
+// cancelled, MaxRecords is reached, or a ProcessFunc returns an error
+// marked with Fatal, which cancels the other workers and is returned.
+// Other record errors are counted as with Start and don't end the run.
+func (p *Processor) Run(ctx context.Context) error {
+	if err := p.Start(ctx); err != nil {
+		return err
+	}
+	<-p.Done()
+
+	g := p.group
+	if err := p.Stop(); err != nil {
+		return err
+	}
+	return g.Wait()
+}
+
+// Done is closed when the processor stops on its own (MaxRecords was
+// reached, or on a Fatal error) or its context is cancelled. It is nil
+// before Start.
+func (p *Processor) Done() <-chan struct{} {
+	if p.ctx == nil {
+		return nil
+	}
+	return p.ctx.Done()
+}
+
+// openSegment opens a segment for processing at offset, mapping it into
+// memory with UseMmap when possible
+func (p *Processor) openSegment(seg *Segment, offset int64) (io.ReadCloser, error) {
+	if seg.Stream {
+		return p.openStream(seg, offset)
+	}
+	if fs, ok := p.source.(*FileSource); ok && p.cfg.UseMmap && !fs.exact && !isCompressed(seg.Name) {
+		m, err := openMapped(seg.Path, offset)
+		if err == nil {
+			return m, nil
+		}
+		p.log.Debug("segment not mapped; using buffered reads", "segment", seg.Name, "error", err)
+	}
+	rc, err := p.source.Open(seg.Name, offset)
+	if err == nil && p.cfg.Prefetch && !seg.Stream {
+		if err := prefetch(rc, offset); err != nil {
+			p.log.Debug("segment prefetch not advised", "segment", seg.Name, "error", err)
+		}
+	}
+	return rc, err
+}
+
+// openStream opens a stream segment, giving up if the processor stops
+// first: opening a named pipe blocks until it has a writer. A stream
+// opened after that is closed unread.
+func (p *Processor) openStream(seg *Segment, offset int64) (io.ReadCloser, error) {
+	type opened struct {
+		rc  io.ReadCloser
+		err error
+	}
+	result := make(chan opened, 1)
+	go func() {
+		rc, err := p.source.Open(seg.Name, offset)
+		result <- opened{rc, err}
+	}()
+
+	select {
+	case r := <-result:
+		return r.rc, r.err
+	case <-p.ctx.Done():
+		go func() {
+			if r := <-result; r.err == nil {
+				r.rc.Close()
+			}
+		}()
+		return nil, p.ctx.Err()
+	}
+}
+
+// openSegmentRetrying opens a segment, retrying failures other than
+// its absence per OpenRetries with exponential backoff. The final
+// failure is logged and passed to OnOpenError.
+func (p *Processor) openSegmentRetrying(seg *Segment, offset int64) (io.ReadCloser, error) {
+	delay := p.cfg.OpenRetryDelay
+	if delay <= 0 {
+		delay = 50 * time.Millisecond
+	}
+
+	attempts := 0
+	for {
+		attempts++
+		rc, err := p.openSegment(seg, offset)
+		if err == nil {
+			if attempts > 1 {
+				p.log.Info("segment opened after retrying", "segment", seg.Name, "attempts", attempts)
+			}
+			return rc, nil
+		}
+		if p.ctx.Err() != nil {
+			return nil, err // Stopping
+		}
+
+		retry := attempts <= p.cfg.OpenRetries && !errors.Is(err, fs.ErrNotExist)
+		if retry {
+			p.log.Debug("open segment failed; retrying", "segment", seg.Name, "attempt", attempts, "delay", delay, "error", err)
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+				delay *= 2
+				continue
+			case <-p.ctx.Done():
+				timer.Stop()
+			}
+		}
+
+		p.log.Error("open segment failed", "segment", seg.Name, "attempts", attempts, "error", err)
+		if p.cfg.OnOpenError != nil {
+			p.cfg.OnOpenError(seg.Name, attempts, err)
+		}
+		return nil, err
+	}
+}
+
+// observe widens the completion's time range to include a record
+func (c *SegmentCompletion) observe(record *LogRecord) {
+	if record.ParseErr != nil || record.Entry.Timestamp == "" {
+		return
+	}
+	ts, err := time.Parse(time.RFC3339Nano, record.Entry.Timestamp)
+	if err != nil {
+		return
+	}
+	if c.Start.IsZero() || ts.Before(c.Start) {
+		c.Start = ts
+	}
+	if ts.After(c.End) {
+		c.End = ts
+	}
+}
+
+// closeOutputs closes the workers' WorkerOutput writers once they have
+// stopped
+func (p *Processor) closeOutputs() {
+	for _, w := range p.started {
+		if err := w.closeOutput(); err != nil {
+			p.log.Error("worker output not closed cleanly", "worker", w.id, "error", err)
+		}
+		w.wc = nil
+	}
+}
+
+// Stats returns processing statistics. Segments given up on (see
+// FailedSegments) count toward the total only.
 func (p *Processor) Stats() (processed, errors int64, segmentStats [4]int) {
 	processed = p.processed.Load()
 	errors = p.errors.Load()
@@ -124,29 +1038,241 @@ func (p *Processor) Stats() (processed, errors int64, segmentStats [4]int) {
 	return
 }
 
+// Each invokes fn once for every record in every segment currently
+// available, in segment name order, starting from offset zero. It is a
+// read-only scan: committed offsets are ignored and nothing is persisted,
+// so it can run alongside or after normal processing. Iteration stops at
+// the first error returned by fn or when ctx is cancelled.
+func (p *Processor) Each(ctx context.Context, fn ProcessFunc) error {
+	infos, err := p.source.List()
+	if err != nil {
+		return err
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	for _, info := range infos {
+		if err := p.eachInSegment(ctx, info, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// eachInSegment invokes fn for every record of one segment
+func (p *Processor) eachInSegment(ctx context.Context, info SegmentInfo, fn ProcessFunc) error {
+	rc, err := p.source.Open(info.Name, 0)
+	if err != nil {
+		return err
+	}
+	reader := NewLogReaderFrom(rc, info.Path, 0, p.cfg.segmentReaderOptions(info.Name))
+	defer reader.Close()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+}
+
+// SegmentStats returns per-segment counts of parsed and malformed lines
+// for segments read by workers during this run. Segments evicted by
+// MaxCompleteSegments are no longer reported.
+func (p *Processor) SegmentStats() map[string]SegmentStats {
+	return p.segmentMgr.SegmentStats()
+}
+
+// CommitInterval returns the number of records between offset commits
+// currently in effect. It varies with throughput when CommitTarget is
+// set; otherwise it is the fixed default.
+func (p *Processor) CommitInterval() int64 {
+	if n := p.commitInterval.Load(); n > 0 {
+		return n
+	}
+	return p.newCommitPacer().threshold
+}
+
+// OutOfOrder returns how many records were timestamped earlier than
+// their predecessor (only tracked when CheckMonotonic is set)
+func (p *Processor) OutOfOrder() int64 {
+	return p.outOfOrder.Load()
+}
+
+// ErrorCounts returns how many process func errors were marked
+// Transient and Permanent. Both are included in the errors reported by
+// Stats, along with unclassified errors; a transient error is counted
+// each time the record fails.
+func (p *Processor) ErrorCounts() (transient, permanent int64) {
+	return p.transient.Load(), p.permanent.Load()
+}
+
+// Latency returns the process func latency percentiles, all zero
+// unless MeasureLatency is set
+func (p *Processor) Latency() LatencyStats {
+	if p.latency == nil {
+		return LatencyStats{}
+	}
+	return p.latency.stats()
+}
+
+// CorruptRegions returns how many corrupt regions ResyncCorrupt skipped
+func (p *Processor) CorruptRegions() int64 {
+	return p.corruptRegions.Load()
+}
+
+// SanitizedRecords returns how many records SanitizeUTF8 removed
+// invalid UTF-8 from
+func (p *Processor) SanitizedRecords() int64 {
+	return p.sanitized.Load()
+}
+
+// FailedSegments returns the segments whose retries ran out this run
+// (see MaxSegmentRetries)
+func (p *Processor) FailedSegments() []string {
+	return p.segmentMgr.FailedSegments()
+}
+
+// CommitErrors returns how many offset commits failed
+func (p *Processor) CommitErrors() int64 {
+	return p.commitErrors.Load()
+}
+
+// SchemaViolations returns how many records failed validation against
+// SchemaPath. They are also included in the errors reported by Stats.
+func (p *Processor) SchemaViolations() int64 {
+	return p.schemaViolations.Load()
+}
+
+// Duplicates returns how many records SuppressDuplicates skipped as
+// already delivered
+func (p *Processor) Duplicates() int64 {
+	return p.duplicates.Load()
+}
+
+// Filtered returns how many records a transform dropped with ErrDrop.
+// They are neither processed nor errors.
+func (p *Processor) Filtered() int64 {
+	return p.filtered.Load()
+}
+
+// WaitForIdle blocks until every segment found by a scan started after
+// the call is complete (none pending or processing), e.g. to process
+// the current backlog and then act on it. Unlike Stop, the processor
+// keeps running. The followed active file is not a segment and never
+// holds it up. It returns ctx.Err() if ctx ends first, and ErrStopped
+// if the processor isn't running or stops.
+func (p *Processor) WaitForIdle(ctx context.Context) error {
+	if !p.running.Load() {
+		return ErrStopped
+	}
+	stopped := p.Done()
+
+	_, start, _ := p.segmentMgr.idleState()
+	for {
+		idle, scans, changed := p.segmentMgr.idleState()
+		if idle && scans > start {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-stopped:
+			return ErrStopped
+		}
+	}
+}
+
+// Assignments returns the segment each worker is currently processing.
+// Every worker ID is present; idle workers map to an empty string.
+func (p *Processor) Assignments() map[int]string {
+	held := p.segmentMgr.Assignments()
+
+	p.workersMu.Lock()
+	defer p.workersMu.Unlock()
+	result := make(map[int]string, len(p.workers))
+	for _, w := range p.workers {
+		result[w.id] = held[w.id]
+	}
+	return result
+}
+
 // scanLoop periodically scans for new segments
 func (p *Processor) scanLoop() {
-	ticker := time.NewTicker(p.cfg.ScanInterval)
-	defer ticker.Stop()
+	timer := time.NewTimer(p.nextScanInterval())
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-p.ctx.Done():
 			return
-		case <-ticker.C:
-			_ = p.segmentMgr.Scan()
+		case <-timer.C:
+			p.scan()
+			p.publishStats()
+			timer.Reset(p.nextScanInterval())
+		case <-p.rescan:
+			timer.Reset(p.nextScanInterval())
 		}
 	}
 }
 
-// run is the main loop for a worker
-func (w *worker) run() {
-	defer w.processor.workerWg.Done()
+// scan looks for new segments, logging what it finds
+func (p *Processor) scan() {
+	before, _, _, _ := p.segmentMgr.GetStats()
+	if err := p.segmentMgr.Scan(); err != nil {
+		p.log.Warn("scan failed", "error", err)
+		return
+	}
+	if after, _, _, _ := p.segmentMgr.GetStats(); after > before {
+		p.log.Debug("scan found segments", "new", after-before, "tracked", after)
+	}
+}
 
+// nextScanInterval returns the delay before the next scan
+func (p *Processor) nextScanInterval() time.Duration {
+	cfg := p.live.Load()
+	return jitterInterval(cfg.ScanInterval, cfg.ScanJitter, rand.Int63n)
+}
+
+// jitterInterval picks a delay uniformly in [interval-jitter, interval+jitter]
+func jitterInterval(interval, jitter time.Duration, int63n func(int64) int64) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	if jitter > interval {
+		jitter = interval
+	}
+	return interval - jitter + time.Duration(int63n(int64(2*jitter)+1))
+}
+
+// run is the main loop for a worker
+func (w *worker) run() error {
 	for {
 		select {
 		case <-w.processor.ctx.Done():
-			return
+			return nil
+		case <-w.retire:
+			// Free the output's MaxOpenFiles slot for the workers still
+			// running, rather than holding it until Stop
+			if err := w.closeOutput(); err != nil {
+				w.processor.log.Error("worker output not closed cleanly", "worker", w.id, "error", err)
+			}
+			w.processor.log.Debug("worker retired", "worker", w.id)
+			return nil
 		default:
 			// Get pending segments
 			segments := w.processor.segmentMgr.GetPendingSegments()
@@ -159,7 +1285,10 @@ func (w *worker) run() {
 			// Try to claim a segment
 			for _, seg := range segments {
 				if w.processor.segmentMgr.ClaimSegment(seg.Name, w.id) {
-					w.processSegment(seg)
+					w.processor.log.Debug("segment claimed", "segment", seg.Name, "worker", w.id)
+					if err := w.processSegment(seg); err != nil {
+						return err
+					}
 					break
 				}
 			}
@@ -167,54 +1296,540 @@ func (w *worker) run() {
 	}
 }
 
-// processSegment processes a single segment
-func (w *worker) processSegment(seg *Segment) {
-	// Get starting offset
-	startOffset, _ := w.processor.offsetMgr.GetOffset(seg.Name)
+// fail releases a segment after a failed attempt, to be retried after
+// RetryDelay unless its retries have run out
+func (w *worker) fail(seg *Segment) {
+	if w.processor.segmentMgr.FailSegment(seg.Name, time.Now().Add(w.processor.cfg.retryDelay())) {
+		w.processor.log.Error("segment failed; retries exhausted",
+			"segment", seg.Name, "retries", w.processor.cfg.MaxSegmentRetries)
+	}
+}
 
-	// Create reader
-	reader, err := NewLogReader(seg.Path, startOffset)
+// processSegment processes a single segment, returning only a Fatal
+// error from the process func
+func (w *worker) processSegment(seg *Segment) error {
+	var linesProcessed int64
+	trace := w.startSegmentSpan(seg)
+	defer func() {
+		trace.lines = linesProcessed
+		w.endSegmentSpan(trace)
+	}()
+
+	// Get the resume point; streams always start from the beginning
+	var startOffset, startLine, epoch int64
+	if !seg.Stream {
+		epoch = w.processor.offsetMgr.GetEpoch(seg.Name)
+	}
+	switch {
+	case seg.Stream:
+	case seg.Resume == ResumeSkipLines:
+		startLine = w.processor.offsetMgr.GetLine(seg.Name)
+	default:
+		startOffset, _ = w.processor.offsetMgr.GetOffset(seg.Name)
+	}
+
+	// Create reader, once MaxOpenFiles allows another open file
+	if err := w.processor.files.acquire(w.processor.ctx); err != nil {
+		w.processor.segmentMgr.ReleaseSegment(seg.Name)
+		return nil // Stopping
+	}
+	defer w.processor.files.release()
+	rc, err := w.processor.openSegmentRetrying(seg, startOffset)
+	if err != nil && w.processor.ctx.Err() != nil {
+		w.processor.segmentMgr.ReleaseSegment(seg.Name)
+		return nil // Stopping
+	}
 	if err != nil {
 		w.processor.errors.Add(1)
-		w.processor.segmentMgr.ReleaseSegment(seg.Name)
-		return
+		trace.err = err
+		w.fail(seg)
+		return nil
 	}
+	if seg.Stream {
+		// A read waiting on the stream's writer only returns once the
+		// stream is closed, so close it as soon as the processor stops
+		stop := context.AfterFunc(w.processor.ctx, func() { rc.Close() })
+		defer stop()
+	}
+	reader := NewLogReaderFrom(rc, seg.Path, startOffset, w.processor.cfg.segmentReaderOptions(seg.Name))
 	defer reader.Close()
 
-	var linesProcessed int64
+	if err := reader.SkipLines(startLine); err != nil && err != io.EOF {
+		w.processor.log.Error("resume segment failed", "segment", seg.Name, "line", startLine, "error", err)
+		w.processor.errors.Add(1)
+		trace.err = err
+		w.fail(seg)
+		return nil
+	}
+
+	// commit checkpoints progress. Line-skip segments record the line
+	// reached, with the byte offset only marking completion.
+	commitAt := func(offset, line, lines int64, done bool) error {
+		err := w.flushOutput()
+		if err != nil {
+			if seg.Stream {
+				w.processor.log.Warn("worker output not flushed", "segment", seg.Name, "error", err)
+			} else {
+				w.processor.committed(seg.Name, err)
+			}
+			return err
+		}
+		switch {
+		case seg.Stream:
+		case seg.Resume == ResumeSkipLines:
+			var marker int64
+			if done {
+				marker = seg.Size
+			}
+			err = w.processor.offsetMgr.CommitLine(seg.Name, marker, lines, line)
+		default:
+			err = w.processor.offsetMgr.CommitOffset(seg.Name, offset, lines)
+		}
+		if !seg.Stream {
+			w.processor.committed(seg.Name, err)
+		}
+		return err
+	}
+	commit := func(lines int64, done bool) error {
+		return commitAt(reader.Offset(), reader.LineNumber(), lines, done)
+	}
+
+	var lastTimestamp time.Time
+
+	var completion *SegmentCompletion
+	if w.processor.cfg.OnSegmentComplete != nil {
+		completion = &SegmentCompletion{Segment: seg.Name, Path: seg.Path, Worker: w.id}
+	}
+
+	// Only a pass over the whole segment can produce a complete index
+	var index *segmentIndex
+	if w.processor.cfg.BuildIndex && !seg.Stream && startOffset == 0 && startLine == 0 {
+		index = newSegmentIndex(seg.Name, w.processor.cfg.LineIndexStride)
+	}
+
+	pacer := w.processor.newCommitPacer()
 
-	// Process each record
+	var deadline <-chan time.Time
+	if d := w.processor.cfg.SegmentDeadline; d > 0 && !seg.Stream {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	// Process each record, letting Checkpoints through in between.
+	// Streams stay out of them, since a read may block indefinitely.
+	if !seg.Stream {
+		w.processor.barrier.mu.RLock()
+		defer w.processor.barrier.mu.RUnlock()
+	}
 	for {
+		if !seg.Stream {
+			w.processor.barrier.pass(func() error { return commit(linesProcessed, false) })
+		}
+
 		select {
 		case <-w.processor.ctx.Done():
 			// Save progress before exiting
-			_ = w.processor.offsetMgr.CommitOffset(seg.Name, reader.Offset(), linesProcessed)
+			commit(linesProcessed, false)
 			w.processor.segmentMgr.ReleaseSegment(seg.Name)
-			return
+			w.processor.log.Debug("segment released on shutdown", "segment", seg.Name, "offset", reader.Offset())
+			return nil
+		case <-deadline:
+			// Give the other pending segments a turn
+			commit(linesProcessed, false)
+			w.processor.segmentMgr.YieldSegment(seg.Name)
+			w.processor.log.Debug("segment deadline passed; segment yielded",
+				"segment", seg.Name, "worker", w.id, "offset", reader.Offset())
+			return nil
 		default:
 		}
 
+		start, consumed := reader.Offset(), reader.LineNumber()
 		record, err := reader.Read()
+		if err == io.EOF {
+			break // Complete
+		}
+		if err != nil && w.processor.ctx.Err() != nil {
+			// The stream was closed for shutdown
+			commit(linesProcessed, false)
+			w.processor.segmentMgr.ReleaseSegment(seg.Name)
+			return nil
+		}
 		if err != nil {
-			// EOF or error - mark complete
-			break
+			// Keep what was read and retry the rest later, rather than
+			// marking the segment complete and dropping it
+			commit(linesProcessed, false)
+			w.processor.errors.Add(1)
+			trace.err = err
+			w.fail(seg)
+			w.processor.log.Warn("read failed; segment released",
+				"segment", seg.Name, "offset", reader.Offset(), "retry_in", w.processor.cfg.retryDelay(), "error", err)
+			return nil
+		}
+
+		seg.recordParse(record)
+		if completion != nil {
+			completion.observe(record)
+		}
+		if index != nil {
+			index.add(record, start, consumed)
+		}
+
+		if !w.processor.cfg.inWindow(record) || w.processor.duplicate(seg.Name, record) {
+			if pacer.record() {
+				commit(linesProcessed, false)
+			}
+			continue
+		}
+
+		if !w.processor.admit() {
+			// Leave this record for the next run
+			commitAt(start, consumed, linesProcessed, false)
+			w.processor.segmentMgr.ReleaseSegment(seg.Name)
+			return nil
+		}
+
+		if w.processor.cfg.CheckMonotonic {
+			w.checkMonotonic(seg.Name, record, &lastTimestamp)
 		}
 
 		// Process the record
-		if err := w.processor.processFunc(record); err != nil {
-			w.processor.errors.Add(1)
-		} else {
-			w.processor.processed.Add(1)
+		seg.delivered++
+		record.Segment, record.SegmentOrdinal, record.Ordinal = seg.Name, seg.Ordinal, seg.delivered
+		record.Epoch = epoch
+		result, err := w.deliver(seg.Name, record, !seg.Stream)
+		if err != nil {
+			trace.errors++
+			if completion != nil {
+				completion.Errors++
+			}
+		}
+		switch result {
+		case delivered:
 			linesProcessed++
+			if w.processor.delivered != nil {
+				w.processor.delivered.add(recordKey(seg.Name, record))
+			}
+		case halted:
+			commitAt(start, consumed, linesProcessed, false)
+			w.processor.segmentMgr.ReleaseSegment(seg.Name)
+			trace.err = err
+			w.processor.halt(seg.Name, record, err)
+			return nil
+		case fatal:
+			// Leave the record for the next run and stop
+			commitAt(start, consumed, linesProcessed, false)
+			w.processor.segmentMgr.ReleaseSegment(seg.Name)
+			trace.err = err
+			return err
+		case retried:
+			// Resume from this record once the retry delay has passed
+			commitAt(start, consumed, linesProcessed, false)
+			seg.delivered-- // Delivered again on the retry
+			trace.err = err
+			w.fail(seg)
+			w.processor.log.Warn("transient error; segment deferred",
+				"segment", seg.Name, "line", record.LineNumber, "retry_in", w.processor.cfg.retryDelay(), "error", err)
+			return nil
 		}
 
-		// Commit offset periodically (every 100 records)
-		if linesProcessed%100 == 0 {
-			_ = w.processor.offsetMgr.CommitOffset(seg.Name, reader.Offset(), linesProcessed)
+		// Commit offset when the pacer says so: about once per
+		// CommitTarget, within [MinCommitRecords, MaxCommitRecords]
+		// records, or every 100 records without a target
+		if pacer.record() {
+			commit(linesProcessed, false)
 		}
 	}
 
 	// Final offset commit
-	_ = w.processor.offsetMgr.CommitOffset(seg.Name, reader.Offset(), linesProcessed)
+	commitErr := commit(linesProcessed, true)
+	trace.err = commitErr
+
+	if index != nil {
+		fingerprint, err := w.processor.fingerprint(seg.Name, reader.Offset())
+		if err == nil {
+			err = w.processor.saveIndex(index, fingerprint)
+		}
+		if err != nil {
+			w.processor.log.Warn("index not saved", "segment", seg.Name, "error", err)
+		}
+	}
+	if completion != nil {
+		completion.Records = linesProcessed
+		completion.Ordinal = seg.Ordinal
+		completion.LastOrdinal = seg.delivered
+		completion.Offset = reader.Offset()
+		completion.Err = commitErr
+		w.processor.cfg.OnSegmentComplete(*completion)
+	}
 	w.processor.segmentMgr.MarkComplete(seg.Name)
+	w.processor.log.Info("segment complete",
+		"segment", seg.Name, "worker", w.id, "records", linesProcessed, "offset", reader.Offset())
+	return nil
+}
+
+// commitOffset commits a segment's offset, reporting the result
+func (p *Processor) commitOffset(segment string, offset, lines int64) error {
+	err := p.offsetMgr.CommitOffset(segment, offset, lines)
+	p.committed(segment, err)
+	return err
+}
+
+// committed records the result of an offset commit. A failure is
+// logged, counted and passed to OnCommitError, and MaxCommitFailures
+// in a row stop the processor.
+func (p *Processor) committed(segment string, err error) {
+	defer p.publishStats()
+	if err == nil {
+		p.commitStreak.Store(0)
+		return
+	}
+
+	p.commitErrors.Add(1)
+	p.log.Warn("offset commit failed", "segment", segment, "error", err)
+	if p.cfg.OnCommitError != nil {
+		p.cfg.OnCommitError(segment, err)
+	}
+
+	streak := p.commitStreak.Add(1)
+	if limit := int64(p.cfg.MaxCommitFailures); limit > 0 && streak == limit {
+		p.log.Error("offset commits keep failing; stopping", "failures", streak, "error", err)
+		p.cancel()
+	}
+}
+
+// unadmit returns a record's MaxRecords slot, for a record that will be
+// read again
+func (p *Processor) unadmit() {
+	if p.cfg.MaxRecords > 0 {
+		p.admitted.Add(-1)
+	}
+}
+
+// admit counts a record against MaxRecords, reporting whether it may
+// be processed. Reaching the limit stops the processor.
+func (p *Processor) admit() bool {
+	if p.cfg.MaxRecords <= 0 {
+		return true
+	}
+
+	n := p.admitted.Add(1)
+	if n == p.cfg.MaxRecords {
+		p.log.Info("record limit reached; stopping", "limit", p.cfg.MaxRecords)
+		p.cancel()
+	}
+	return n <= p.cfg.MaxRecords
+}
+
+// transform runs Transform and then each of Transforms on a record,
+// stopping at the first error (including ErrDrop)
+func (p *Processor) transform(record *LogRecord) error {
+	if p.cfg.Transform != nil {
+		if err := p.cfg.Transform(record); err != nil {
+			return err
+		}
+	}
+	for _, stage := range p.cfg.Transforms {
+		if err := stage(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// duplicate reports whether a record was already delivered this run,
+// counting it if so (only with SuppressDuplicates)
+func (p *Processor) duplicate(segment string, record *LogRecord) bool {
+	if p.delivered == nil || !p.delivered.contains(recordKey(segment, record)) {
+		return false
+	}
+	p.duplicates.Add(1)
+	return true
+}
+
+// haltsOn reports whether a record's error stops the processor, leaving
+// the record for the next run (HaltOnSchemaViolation)
+func (p *Processor) haltsOn(err error) bool {
+	var schemaErr *SchemaError
+	return p.cfg.HaltOnSchemaViolation && errors.As(err, &schemaErr)
+}
+
+// halt stops the processor at a record that haltsOn, once its offset
+// has been committed before the record
+func (p *Processor) halt(segment string, record *LogRecord, err error) {
+	p.unadmit()
+	p.log.Error("schema violation; stopping",
+		"segment", segment, "line", record.LineNumber, "error", err)
+	p.cancel()
+}
+
+// validateSchema checks a record against the compiled schema
+func (p *Processor) validateSchema(record *LogRecord) error {
+	raw := trimDelimiter(record.Raw, p.cfg.delimiter())
+	if (p.cfg.InputFormat != "" && p.cfg.InputFormat != logger.JSON) || raw == nil {
+		var err error
+		if raw, err = logger.Marshal(record.Entry); err != nil {
+			return &SchemaError{Err: err}
+		}
+	}
+
+	if err := p.schema.ValidateJSON(raw); err != nil {
+		return &SchemaError{Err: err}
+	}
+	return nil
+}
+
+// checkMonotonic flags a record whose timestamp precedes the previous
+// record's. Records without a parseable timestamp are not checked.
+func (w *worker) checkMonotonic(segment string, record *LogRecord, last *time.Time) {
+	ts, err := time.Parse(time.RFC3339Nano, record.Entry.Timestamp)
+	if err != nil {
+		return
+	}
+
+	if !last.IsZero() && ts.Before(*last) {
+		w.processor.outOfOrder.Add(1)
+		if hook := w.processor.cfg.OnOutOfOrder; hook != nil {
+			hook(segment, *last, ts, record.Offset)
+		}
+	}
+	*last = ts
+}
+
+// outcome is what became of a record handed to deliver
+type outcome int
+
+const (
+	delivered outcome = iota // Processed
+	passed                   // Dropped, or failed and moved past
+	halted                   // Stops the processor (haltsOn): commit before it, then halt
+	fatal                    // Stops the processor with a Fatal error
+	retried                  // Failed transiently: deliver it again after RetryDelay
+)
+
+// deliver processes a record of segment and counts the result. A
+// failure is returned along with what the caller should do about it;
+// transient ones are only retried when retry is set (not for streams,
+// which can't be read again). Records to be read again by a later run
+// or retry give back their MaxRecords slot, except halted ones, which
+// halt does.
+func (w *worker) deliver(segment string, record *LogRecord, retry bool) (outcome, error) {
+	p := w.processor
+	err := w.process(record)
+	switch {
+	case err == nil:
+		p.processed.Add(1)
+		return delivered, nil
+	case errors.Is(err, ErrDrop):
+		return passed, nil
+	}
+
+	p.errors.Add(1)
+	switch {
+	case p.haltsOn(err):
+		return halted, err
+	case IsFatal(err):
+		p.unadmit()
+		p.log.Error("fatal error; stopping",
+			"segment", segment, "line", record.LineNumber, "worker", w.id, "error", err)
+		return fatal, err
+	case retry && IsTransient(err):
+		p.unadmit()
+		return retried, err
+	}
+	return passed, err
+}
+
+// process invokes the process func, recovering panics unless configured
+// to fail fast. Recovered panics are dead-lettered when a handler is set.
+func (w *worker) process(record *LogRecord) (err error) {
+	p := w.processor
+	if !p.cfg.PanicOnCallbackPanic {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError{Value: r, Stack: debug.Stack()}
+				p.log.Error("process func panicked", "line", record.LineNumber, "panic", r)
+				if p.cfg.DeadLetter != nil {
+					p.cfg.DeadLetter(record, err)
+				}
+			}
+		}()
+	}
+
+	if len(p.cfg.Redact) > 0 {
+		redact(record, p.cfg.Redact)
+	}
+
+	if record.Sanitized {
+		p.sanitized.Add(1)
+	}
+
+	var corrupt *CorruptRegionError
+	if errors.As(record.ParseErr, &corrupt) {
+		p.corruptRegions.Add(1)
+		p.log.Warn("skipped corrupt region", "line", record.LineNumber, "lines", corrupt.Lines, "bytes", corrupt.Bytes)
+		if p.cfg.DeadLetter != nil {
+			p.cfg.DeadLetter(record, record.ParseErr)
+		}
+		return record.ParseErr
+	}
+
+	if p.cfg.ValidateEntries && record.ParseErr == nil {
+		if err := record.Entry.Validate(); err != nil {
+			if p.cfg.DeadLetter != nil {
+				p.cfg.DeadLetter(record, err)
+			}
+			return err
+		}
+	}
+
+	if p.schema != nil && record.ParseErr == nil {
+		if err := p.validateSchema(record); err != nil {
+			p.schemaViolations.Add(1)
+			if p.cfg.DeadLetter != nil && !p.cfg.HaltOnSchemaViolation {
+				p.cfg.DeadLetter(record, err)
+			}
+			return err
+		}
+	}
+
+	if err := p.transform(record); err != nil {
+		if errors.Is(err, ErrDrop) {
+			p.filtered.Add(1)
+			return ErrDrop
+		}
+		if p.cfg.DeadLetter != nil {
+			p.cfg.DeadLetter(record, err)
+		}
+		return err
+	}
+
+	var started time.Time
+	if p.latency != nil {
+		started = time.Now()
+	}
+	if p.cfg.ProcessFuncCtx != nil {
+		ctx := w.ctx
+		if ctx == nil {
+			ctx = p.ctx
+		}
+		err = p.cfg.ProcessFuncCtx(ctx, record)
+	} else {
+		err = p.processFunc(record)
+	}
+	if p.latency != nil {
+		p.latency.record(time.Since(started))
+	}
+
+	switch {
+	case err == nil:
+	case IsTransient(err):
+		p.transient.Add(1)
+	case IsPermanent(err):
+		p.permanent.Add(1)
+		if p.cfg.DeadLetter != nil {
+			p.cfg.DeadLetter(record, err)
+		}
+	}
+	return err
 }
@@ -0,0 +1,1308 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// writeSegment creates a segment file containing the given lines
+func writeSegment(t *testing.T, dir, name string, lines ...string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+	content := ""
+	if len(lines) > 0 {
+		content = strings.Join(lines, "\n") + "\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write segment: %v", err)
+	}
+	return path
+}
+
+// newTestConfig returns a config rooted in fresh temp directories
+func newTestConfig(t *testing.T, workers int) Config {
+	t.Helper()
+
+	return Config{
+		LogsDir:      t.TempDir(),
+		LogPattern:   "app.log",
+		OffsetsDir:   t.TempDir(),
+		WorkerCount:  workers,
+		ScanInterval: 50 * time.Millisecond,
+	}
+}
+
+// waitFor polls cond until it returns true or the timeout expires
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before timeout")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestAssignments verifies a held segment is reported until it completes
+func TestAssignments(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", `{"level":"INFO","message":"one"}`)
+
+	release := make(chan struct{})
+	proc, err := NewProcessor(cfg, func(*LogRecord) error {
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+
+	if got := proc.Assignments(); len(got) != 1 || got[0] != "" {
+		t.Fatalf("expected idle worker before start, got %v", got)
+	}
+
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		return proc.Assignments()[0] == "app.log.20260101-000000"
+	})
+
+	close(release)
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	if got := proc.Assignments()[0]; got != "" {
+		t.Fatalf("expected assignment cleared after completion, got %q", got)
+	}
+}
+
+// TestJitterInterval verifies jittered scan delays stay within bounds
+func TestJitterInterval(t *testing.T) {
+	const (
+		interval = time.Second
+		jitter   = 200 * time.Millisecond
+	)
+
+	if got := jitterInterval(interval, 0, nil); got != interval {
+		t.Fatalf("zero jitter: got %v, want %v", got, interval)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	var below, above int
+	for i := 0; i < 10000; i++ {
+		d := jitterInterval(interval, jitter, rng.Int63n)
+		if d < interval-jitter || d > interval+jitter {
+			t.Fatalf("interval %v outside [%v, %v]", d, interval-jitter, interval+jitter)
+		}
+		if d < interval {
+			below++
+		} else if d > interval {
+			above++
+		}
+	}
+
+	// Uniform draws should land on both sides of the base interval
+	if below < 4000 || above < 4000 {
+		t.Fatalf("skewed distribution: %d below, %d above", below, above)
+	}
+
+	// Jitter larger than the interval never produces a negative delay
+	for i := 0; i < 1000; i++ {
+		if d := jitterInterval(interval, 5*interval, rng.Int63n); d < 0 || d > 2*interval {
+			t.Fatalf("capped jitter produced %v", d)
+		}
+	}
+}
+
+// TestProcessFuncPanicRecovered verifies a panicking callback is counted
+// and dead-lettered without stopping the worker
+func TestProcessFuncPanicRecovered(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	writeSegment(t, cfg.LogsDir, "app.log.1",
+		`{"message":"ok-1"}`,
+		`{"message":"boom"}`,
+		`{"message":"ok-2"}`,
+	)
+
+	var mu sync.Mutex
+	var dead []*LogRecord
+	var deadErr error
+	cfg.DeadLetter = func(r *LogRecord, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		dead = append(dead, r)
+		deadErr = err
+	}
+
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		if r.Entry.Message == "boom" {
+			panic("bad record")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	processed, errs, _ := proc.Stats()
+	if processed != 2 || errs != 1 {
+		t.Fatalf("processed=%d errors=%d, want 2 and 1", processed, errs)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dead) != 1 || dead[0].Entry.Message != "boom" {
+		t.Fatalf("expected the panicking record to be dead-lettered, got %d", len(dead))
+	}
+	var panicErr *PanicError
+	if !errors.As(deadErr, &panicErr) || panicErr.Value != "bad record" || len(panicErr.Stack) == 0 {
+		t.Fatalf("unexpected dead-letter error: %v", deadErr)
+	}
+}
+
+// TestCheckMonotonic verifies a single out-of-order record is flagged once
+func TestCheckMonotonic(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	writeSegment(t, cfg.LogsDir, "app.log.1",
+		`{"timestamp":"2026-01-01T10:00:00Z","message":"a"}`,
+		`{"timestamp":"2026-01-01T10:00:02Z","message":"b"}`,
+		`{"timestamp":"2026-01-01T10:00:01Z","message":"late"}`,
+		`{"timestamp":"2026-01-01T10:00:03Z","message":"c"}`,
+		`{"message":"no timestamp"}`,
+	)
+
+	type violation struct {
+		prev, curr time.Time
+		offset     int64
+	}
+	var mu sync.Mutex
+	var seen []violation
+
+	cfg.CheckMonotonic = true
+	cfg.OnOutOfOrder = func(segment string, prev, curr time.Time, offset int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		seen = append(seen, violation{prev, curr, offset})
+	}
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	if got := proc.OutOfOrder(); got != 1 {
+		t.Fatalf("OutOfOrder() = %d, want 1", got)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 1 {
+		t.Fatalf("hook called %d times, want 1", len(seen))
+	}
+	wantPrev := time.Date(2026, 1, 1, 10, 0, 2, 0, time.UTC)
+	wantCurr := time.Date(2026, 1, 1, 10, 0, 1, 0, time.UTC)
+	if !seen[0].prev.Equal(wantPrev) || !seen[0].curr.Equal(wantCurr) || seen[0].offset == 0 {
+		t.Fatalf("unexpected violation: %+v", seen[0])
+	}
+}
+
+// TestEachIgnoresOffsets verifies Each visits every record regardless of
+// committed offsets and leaves them untouched
+func TestEachIgnoresOffsets(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	writeSegment(t, cfg.LogsDir, "app.log.1", `{"message":"a"}`, `{"message":"b"}`)
+	writeSegment(t, cfg.LogsDir, "app.log.2", `{"message":"c"}`)
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+
+	// Pretend app.log.1 was fully processed by a previous run
+	if err := proc.offsetMgr.CommitOffset("app.log.1", 32, 2); err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []string
+	err = proc.Each(context.Background(), func(r *LogRecord) error {
+		messages = append(messages, r.Entry.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Each: %v", err)
+	}
+
+	if got := strings.Join(messages, ","); got != "a,b,c" {
+		t.Fatalf("visited %q, want a,b,c", got)
+	}
+	if offset, _ := proc.offsetMgr.GetOffset("app.log.1"); offset != 32 {
+		t.Fatalf("offset changed to %d", offset)
+	}
+	if offset, _ := proc.offsetMgr.GetOffset("app.log.2"); offset != 0 {
+		t.Fatalf("offset written for app.log.2: %d", offset)
+	}
+}
+
+// TestProcessFuncCtxCancelled verifies a context-aware callback sees
+// shutdown and lets Stop return promptly
+func TestProcessFuncCtxCancelled(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", `{"message":"slow"}`)
+
+	started := make(chan struct{})
+	cfg.ProcessFuncCtx = func(ctx context.Context, r *LogRecord) error {
+		close(started)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Minute):
+			return nil
+		}
+	}
+
+	proc, err := NewProcessor(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("callback never invoked")
+	}
+
+	begin := time.Now()
+	proc.Stop()
+	if elapsed := time.Since(begin); elapsed > time.Second {
+		t.Fatalf("Stop took %v", elapsed)
+	}
+
+	if _, errs, _ := proc.Stats(); errs != 1 {
+		t.Fatalf("errors = %d, want 1 for the cancelled record", errs)
+	}
+}
+
+// TestConfigValidate verifies each misconfiguration is reported and
+// that all problems are aggregated into one error
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   []string
+	}{
+		{name: "valid", modify: func(*Config) {}},
+		{name: "no workers", modify: func(c *Config) { c.WorkerCount = 0 }, want: []string{"WorkerCount"}},
+		{name: "zero scan interval", modify: func(c *Config) { c.ScanInterval = 0 }, want: []string{"ScanInterval"}},
+		{name: "no logs dir", modify: func(c *Config) { c.LogsDir = "" }, want: []string{"LogsDir"}},
+		{name: "no offsets dir", modify: func(c *Config) { c.OffsetsDir = "" }, want: []string{"OffsetsDir"}},
+		{name: "no pattern", modify: func(c *Config) { c.LogPattern = "" }, want: []string{"LogPattern"}},
+		{name: "two delimiters", modify: func(c *Config) {
+			c.RecordDelimiter = ';'
+			c.NULDelimited = true
+		}, want: []string{"RecordDelimiter"}},
+		{name: "bad rotation", modify: func(c *Config) { c.Rotation.Template = "{base}" }, want: []string{"{date}"}},
+		{name: "follow custom source", modify: func(c *Config) {
+			c.Source = NewMemorySource()
+			c.Follow = true
+		}, want: []string{"Follow"}},
+		{name: "memory source needs no dir", modify: func(c *Config) {
+			c.Source = NewMemorySource()
+			c.LogsDir, c.LogPattern = "", ""
+		}},
+		{name: "aggregated", modify: func(c *Config) {
+			c.WorkerCount = -1
+			c.ScanInterval = -time.Second
+			c.OffsetsDir = ""
+		}, want: []string{"WorkerCount", "ScanInterval", "OffsetsDir"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, 1)
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+
+			if _, err := NewProcessor(cfg, func(*LogRecord) error { return nil }); err == nil {
+				t.Fatal("NewProcessor accepted an invalid config")
+			}
+		})
+	}
+}
+
+// TestStopTimeout verifies Stop gives up on a worker stuck in a
+// callback that ignores cancellation
+func TestStopTimeout(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.StopTimeout = 100 * time.Millisecond
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", `{"message":"stuck"}`)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error {
+		close(started)
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("callback never invoked")
+	}
+
+	begin := time.Now()
+	err = proc.Stop()
+	elapsed := time.Since(begin)
+
+	if !errors.Is(err, ErrStopTimeout) {
+		t.Fatalf("Stop() = %v, want ErrStopTimeout", err)
+	}
+	if elapsed < cfg.StopTimeout || elapsed > time.Second {
+		t.Fatalf("Stop returned after %v, want about %v", elapsed, cfg.StopTimeout)
+	}
+}
+
+// TestMaxRecords verifies the processor stops at the record limit
+// across workers, and a second run continues where the first stopped
+func TestMaxRecords(t *testing.T) {
+	cfg := newTestConfig(t, 4)
+	cfg.MaxRecords = 25
+
+	var total int
+	for i := 0; i < 4; i++ {
+		var lines []string
+		for j := 0; j < 20; j++ {
+			lines = append(lines, fmt.Sprintf(`{"message":"seg%d line%d"}`, i, j))
+		}
+		writeSegment(t, cfg.LogsDir, fmt.Sprintf("app.log.20260101-00000%d", i), lines...)
+		total += len(lines)
+	}
+
+	run := func(cfg Config) map[string]bool {
+		var mu sync.Mutex
+		seen := make(map[string]bool)
+		proc, err := NewProcessor(cfg, func(record *LogRecord) error {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[record.Entry.Message] = true
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("NewProcessor: %v", err)
+		}
+		if err := proc.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+
+		select {
+		case <-proc.Done():
+		case <-time.After(5 * time.Second):
+			t.Fatal("processor did not stop at the limit")
+		}
+		if err := proc.Stop(); err != nil {
+			t.Fatalf("Stop: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		return seen
+	}
+
+	first := run(cfg)
+	if len(first) != int(cfg.MaxRecords) {
+		t.Fatalf("first run processed %d records, want %d", len(first), cfg.MaxRecords)
+	}
+
+	cfg.MaxRecords = int64(total - len(first))
+	second := run(cfg)
+	for msg := range second {
+		if first[msg] {
+			t.Errorf("%q processed again after restart", msg)
+		}
+	}
+	if got := len(first) + len(second); got != total {
+		t.Fatalf("processed %d distinct records over two runs, want %d", got, total)
+	}
+}
+
+// TestErrorClassification verifies transient errors defer the segment
+// and retry the record, while permanent ones are dead-lettered
+func TestErrorClassification(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.RetryDelay = 50 * time.Millisecond
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"message":"ok"}`,
+		`{"message":"rejected"}`,
+		`{"message":"flaky"}`,
+		`{"message":"unclassified"}`,
+		`{"message":"done"}`,
+	)
+
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	var deadLettered []string
+	cfg.DeadLetter = func(record *LogRecord, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		deadLettered = append(deadLettered, record.Entry.Message)
+	}
+
+	proc, err := NewProcessor(cfg, func(record *LogRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+		calls[record.Entry.Message]++
+		switch record.Entry.Message {
+		case "rejected":
+			return Permanent(errors.New("schema mismatch"))
+		case "flaky":
+			if calls["flaky"] <= 2 {
+				return Transient(errors.New("downstream unavailable"))
+			}
+		case "unclassified":
+			return errors.New("boom")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	processed, errs, _ := proc.Stats()
+	transient, permanent := proc.ErrorCounts()
+	if transient != 2 || permanent != 1 {
+		t.Errorf("ErrorCounts() = %d, %d; want 2, 1", transient, permanent)
+	}
+	if processed != 3 || errs != 4 {
+		t.Errorf("Stats() processed=%d errors=%d; want 3 and 4", processed, errs)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls["flaky"] != 3 {
+		t.Errorf("flaky record attempted %d times, want 3", calls["flaky"])
+	}
+	for _, msg := range []string{"ok", "rejected", "unclassified", "done"} {
+		if calls[msg] != 1 {
+			t.Errorf("%q processed %d times, want once", msg, calls[msg])
+		}
+	}
+	if len(deadLettered) != 1 || deadLettered[0] != "rejected" {
+		t.Errorf("dead-lettered %q, want only the permanent failure", deadLettered)
+	}
+}
+
+// TestValidateEntries verifies semantically invalid records are counted
+// as errors and dead-lettered
+func TestValidateEntries(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.ValidateEntries = true
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"timestamp":"2026-01-01T00:00:00Z","level":"INFO","service":"svc","message":"ok"}`,
+		`{"timestamp":"2026-01-01T00:00:01Z","service":"svc","message":"no level"}`,
+	)
+
+	var mu sync.Mutex
+	var dead []string
+	cfg.DeadLetter = func(r *LogRecord, err error) {
+		mu.Lock()
+		dead = append(dead, r.Entry.Message)
+		mu.Unlock()
+	}
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	processed, errs, _ := proc.Stats()
+	if processed != 1 || errs != 1 {
+		t.Fatalf("processed=%d errors=%d, want 1 and 1", processed, errs)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dead) != 1 || dead[0] != "no level" {
+		t.Fatalf("dead letters = %q", dead)
+	}
+}
+
+// TestOperationalLogging verifies key lifecycle events reach the
+// configured slog logger
+func TestOperationalLogging(t *testing.T) {
+	var buf syncBuffer
+	cfg := newTestConfig(t, 1)
+	cfg.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", `{"message":"a"}`)
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { panic("boom") })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+	proc.Stop()
+
+	events := make(map[string]map[string]any)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("bad log line %q: %v", line, err)
+		}
+		events[event["msg"].(string)] = event
+	}
+
+	for msg, level := range map[string]string{
+		"processor started":     "INFO",
+		"segment claimed":       "DEBUG",
+		"process func panicked": "ERROR",
+		"segment complete":      "INFO",
+		"processor stopped":     "INFO",
+	} {
+		event, ok := events[msg]
+		if !ok {
+			t.Errorf("event %q not logged", msg)
+			continue
+		}
+		if event["level"] != level {
+			t.Errorf("event %q logged at %v, want %s", msg, event["level"], level)
+		}
+	}
+	if seg := events["segment complete"]["segment"]; seg != "app.log.20260101-000000" {
+		t.Errorf("segment complete has segment=%v", seg)
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent writers
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// TestWaitForIdle verifies WaitForIdle returns once the backlog,
+// including segments added later, is processed, without stopping
+func TestWaitForIdle(t *testing.T) {
+	cfg := newTestConfig(t, 2)
+	for i := 0; i < 3; i++ {
+		writeSegment(t, cfg.LogsDir, fmt.Sprintf("app.log.20260101-00000%d", i), `{"message":"a"}`, `{"message":"b"}`)
+	}
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := proc.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle: %v", err)
+	}
+	if processed, _, segStats := proc.Stats(); processed != 6 || segStats[3] != 3 {
+		t.Fatalf("processed=%d complete=%d after idle, want 6 and 3", processed, segStats[3])
+	}
+
+	// Still running: a new segment is picked up by the next wait
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000003", `{"message":"c"}`)
+	if err := proc.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle: %v", err)
+	}
+	if processed, _, _ := proc.Stats(); processed != 7 {
+		t.Fatalf("processed=%d after second idle, want 7", processed)
+	}
+
+	proc.Stop()
+	if err := proc.WaitForIdle(ctx); err != ErrStopped {
+		t.Fatalf("WaitForIdle after Stop = %v, want ErrStopped", err)
+	}
+}
+
+// TestOnSegmentComplete verifies the completion payload for a known
+// segment
+func TestOnSegmentComplete(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	path := writeSegment(t, cfg.LogsDir, "app.log.20260101-090000",
+		`{"timestamp":"2026-01-01T09:00:05Z","message":"b"}`,
+		`{"timestamp":"2026-01-01T09:00:01Z","message":"a"}`,
+		`{"message":"untimed"}`,
+		`{"timestamp":"2026-01-01T09:59:59Z","message":"fail"}`)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	completions := make(chan SegmentCompletion, 1)
+	cfg.OnSegmentComplete = func(c SegmentCompletion) { completions <- c }
+
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		if r.Entry.Message == "fail" {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	var got SegmentCompletion
+	select {
+	case got = <-completions:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnSegmentComplete not called")
+	}
+
+	want := SegmentCompletion{
+		Segment: "app.log.20260101-090000",
+		Path:    path,
+		Records: 3,
+		Errors:  1,
+		Offset:  info.Size(),
+		Start:   time.Date(2026, 1, 1, 9, 0, 1, 0, time.UTC),
+		End:     time.Date(2026, 1, 1, 9, 59, 59, 0, time.UTC),
+	}
+	if got.Segment != want.Segment || got.Path != want.Path || got.Worker != 0 ||
+		got.Records != want.Records || got.Errors != want.Errors || got.Offset != want.Offset ||
+		!got.Start.Equal(want.Start) || !got.End.Equal(want.End) || got.Err != nil {
+		t.Fatalf("completion = %+v, want %+v", got, want)
+	}
+}
+
+// failingSource serves a MemorySource whose first Open of each segment
+// fails with an I/O error after failAfter bytes
+type failingSource struct {
+	*MemorySource
+	failAfter int64
+	failed    sync.Map
+}
+
+func (s *failingSource) Open(name string, offset int64) (io.ReadCloser, error) {
+	rc, err := s.MemorySource.Open(name, offset)
+	if err != nil {
+		return nil, err
+	}
+	if _, done := s.failed.LoadOrStore(name, true); done {
+		return rc, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(io.LimitReader(rc, s.failAfter), iotest.ErrReader(errors.New("disk error"))), rc}, nil
+}
+
+// TestReadErrorReleasesSegment verifies a read error mid-segment
+// releases the segment for a retry instead of marking it complete
+func TestReadErrorReleasesSegment(t *testing.T) {
+	src := &failingSource{MemorySource: NewMemorySource(), failAfter: 20}
+	src.Put("app.log.1", []byte(`{"message":"a"}`+"\n"+`{"message":"b"}`+"\n"+`{"message":"c"}`+"\n"))
+
+	cfg := newTestConfig(t, 1)
+	cfg.Source = src
+	cfg.RetryDelay = 300 * time.Millisecond
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[r.Entry.Message]++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, errs, _ := proc.Stats()
+		return errs == 1
+	})
+	if processed, _, segStats := proc.Stats(); processed != 1 || segStats[3] != 0 || segStats[1] != 1 {
+		t.Fatalf("after read error: processed=%d segments=%v, want 1 processed and the segment pending", processed, segStats)
+	}
+
+	// The retry resumes after the last record read
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	for _, msg := range []string{"a", "b", "c"} {
+		if seen[msg] != 1 {
+			t.Errorf("record %q processed %d times, want 1", msg, seen[msg])
+		}
+	}
+}
+
+// flakySource serves a MemorySource whose Opens fail until fails have
+// failed
+type flakySource struct {
+	*MemorySource
+	fails atomic.Int32
+}
+
+func (s *flakySource) Open(name string, offset int64) (io.ReadCloser, error) {
+	if s.fails.Add(-1) >= 0 {
+		return nil, errors.New("device not ready")
+	}
+	return s.MemorySource.Open(name, offset)
+}
+
+// unlistableSource fails to list segments a given number of times
+type unlistableSource struct {
+	*MemorySource
+	fails atomic.Int32
+}
+
+func (s *unlistableSource) List() ([]SegmentInfo, error) {
+	if s.fails.Add(-1) >= 0 {
+		return nil, errors.New("source unreachable")
+	}
+	return s.MemorySource.List()
+}
+
+// closeCounter is a WorkerOutput writer counting its closes
+type closeCounter struct {
+	io.Writer
+	closes *atomic.Int32
+}
+
+func (c closeCounter) Close() error {
+	c.closes.Add(1)
+	return nil
+}
+
+// TestStartScanFailure verifies a Start whose initial scan fails releases
+// what it acquired, so Start can be called again
+func TestStartScanFailure(t *testing.T) {
+	src := &unlistableSource{MemorySource: NewMemorySource()}
+	src.fails.Store(1)
+	src.Put("app.log.20260101-000000", []byte(`{"message":"a"}`+"\n"))
+
+	var closes atomic.Int32
+	cfg := newTestConfig(t, 1)
+	cfg.Source = src
+	cfg.MaxOpenFiles = 2 // The worker's output and one segment
+	cfg.WorkerOutput = func(int) (io.Writer, error) {
+		return closeCounter{io.Discard, &closes}, nil
+	}
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err == nil {
+		t.Fatal("expected Start to fail on the failed scan")
+	}
+	if n := closes.Load(); n != 1 {
+		t.Fatalf("worker output closed %d times after the failed Start, want once", n)
+	}
+
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("retried Start: %v", err)
+	}
+	defer proc.Stop()
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+}
+
+// TestMaxSegmentRetries verifies a failing segment is retried within
+// its budget, and given up on and reported once the budget is spent
+func TestMaxSegmentRetries(t *testing.T) {
+	run := func(fails int32) *Processor {
+		t.Helper()
+
+		src := &flakySource{MemorySource: NewMemorySource()}
+		src.fails.Store(fails)
+		src.Put("app.log.1", []byte(`{"message":"a"}`+"\n"+`{"message":"b"}`+"\n"))
+
+		cfg := newTestConfig(t, 1)
+		cfg.Source = src
+		cfg.RetryDelay = 20 * time.Millisecond
+		cfg.MaxSegmentRetries = 2
+		cfg.RunReports = 1
+
+		proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+		if err != nil {
+			t.Fatalf("NewProcessor: %v", err)
+		}
+		if err := proc.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		t.Cleanup(func() { proc.Stop() })
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := proc.WaitForIdle(ctx); err != nil {
+			t.Fatalf("WaitForIdle: %v", err)
+		}
+		return proc
+	}
+
+	// Fails twice, then succeeds on the last retry
+	proc := run(2)
+	if processed, errs, segStats := proc.Stats(); processed != 2 || errs != 2 || segStats[3] != 1 {
+		t.Errorf("processed=%d errors=%d segments=%v, want 2 processed, 2 errors and the segment complete",
+			processed, errs, segStats)
+	}
+	if failed := proc.FailedSegments(); len(failed) != 0 {
+		t.Errorf("FailedSegments = %v, want none", failed)
+	}
+
+	// Fails the first attempt and both retries
+	proc = run(3)
+	if processed, errs, segStats := proc.Stats(); processed != 0 || errs != 3 || segStats != [4]int{1, 0, 0, 0} {
+		t.Errorf("processed=%d errors=%d segments=%v, want the segment failed after 3 attempts",
+			processed, errs, segStats)
+	}
+	if failed := proc.FailedSegments(); len(failed) != 1 || failed[0] != "app.log.1" {
+		t.Errorf("FailedSegments = %v, want [app.log.1]", failed)
+	}
+
+	proc.Stop()
+	reports, _ := filepath.Glob(filepath.Join(proc.cfg.OffsetsDir, "run-*"+reportSuffix))
+	if len(reports) != 1 {
+		t.Fatalf("found %d run reports, want 1", len(reports))
+	}
+	data, _ := os.ReadFile(reports[0])
+	var report RunReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if len(report.SegmentsFailed) != 1 || report.SegmentsFailed[0] != "app.log.1" {
+		t.Errorf("report lists failed segments %v, want [app.log.1]", report.SegmentsFailed)
+	}
+}
+
+// TestOpenRetries verifies a segment that fails to open is retried in
+// place with backoff, and that a failure outlasting the retries reaches
+// OnOpenError
+func TestOpenRetries(t *testing.T) {
+	type failure struct {
+		segment  string
+		attempts int
+	}
+	run := func(fails int32, retries int) (*Processor, []failure) {
+		t.Helper()
+
+		src := &flakySource{MemorySource: NewMemorySource()}
+		src.fails.Store(fails)
+		src.Put("app.log.1", []byte(`{"message":"a"}`+"\n"+`{"message":"b"}`+"\n"))
+
+		var mu sync.Mutex
+		var failures []failure
+		cfg := newTestConfig(t, 1)
+		cfg.Source = src
+		cfg.OpenRetries = retries
+		cfg.OpenRetryDelay = 5 * time.Millisecond
+		cfg.MaxSegmentRetries = 1
+		cfg.RetryDelay = 10 * time.Millisecond
+		cfg.OnOpenError = func(segment string, attempts int, err error) {
+			mu.Lock()
+			failures = append(failures, failure{segment, attempts})
+			mu.Unlock()
+		}
+
+		proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+		if err != nil {
+			t.Fatalf("NewProcessor: %v", err)
+		}
+		if err := proc.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		t.Cleanup(func() { proc.Stop() })
+
+		waitFor(t, 2*time.Second, func() bool {
+			_, _, segStats := proc.Stats()
+			return segStats[3] == 1 || len(proc.FailedSegments()) == 1
+		})
+		mu.Lock()
+		defer mu.Unlock()
+		return proc, append([]failure(nil), failures...)
+	}
+
+	proc, failures := run(2, 3)
+	if processed, errs, _ := proc.Stats(); processed != 2 || errs != 0 {
+		t.Fatalf("processed %d, errors %d; want 2, 0 after opening on the third attempt", processed, errs)
+	}
+	if len(failures) != 0 {
+		t.Fatalf("OnOpenError called for %+v, want no calls", failures)
+	}
+
+	// Two attempts per try, and the segment is tried twice in all
+	proc, failures = run(10, 1)
+	if processed, errs, _ := proc.Stats(); processed != 0 || errs != 2 {
+		t.Fatalf("processed %d, errors %d; want 0, 2", processed, errs)
+	}
+	want := []failure{{"app.log.1", 2}, {"app.log.1", 2}}
+	if !reflect.DeepEqual(failures, want) {
+		t.Fatalf("OnOpenError calls = %+v, want %+v", failures, want)
+	}
+}
+
+// TestRecordOrdinals verifies records of segments processed in
+// parallel carry ordinals that restore each segment's order
+func TestRecordOrdinals(t *testing.T) {
+	cfg := newTestConfig(t, 3)
+	names := []string{"app.log.20260101-000000", "app.log.20260101-000001", "app.log.20260101-000002"}
+	for i, name := range names {
+		var lines []string
+		for j := 0; j < 50*(i+1); j++ {
+			lines = append(lines, fmt.Sprintf(`{"message":"%d"}`, j))
+		}
+		writeSegment(t, cfg.LogsDir, name, lines...)
+	}
+
+	var mu sync.Mutex
+	last := make(map[string]*LogRecord)
+	completions := make(map[string]SegmentCompletion)
+	cfg.OnSegmentComplete = func(c SegmentCompletion) {
+		mu.Lock()
+		defer mu.Unlock()
+		completions[c.Segment] = c
+	}
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+		prev := last[r.Segment]
+		switch {
+		case prev == nil && r.Ordinal != 1:
+			t.Errorf("%s starts at ordinal %d", r.Segment, r.Ordinal)
+		case prev != nil && (r.Ordinal != prev.Ordinal+1 || r.Offset <= prev.Offset):
+			t.Errorf("%s: ordinal %d at offset %d follows %d at %d", r.Segment, r.Ordinal, r.Offset, prev.Ordinal, prev.Offset)
+		case prev != nil && r.SegmentOrdinal != prev.SegmentOrdinal:
+			t.Errorf("%s: segment ordinal changed from %d to %d", r.Segment, prev.SegmentOrdinal, r.SegmentOrdinal)
+		}
+		if msg := fmt.Sprint(r.Ordinal - 1); r.Entry.Message != msg {
+			t.Errorf("%s: ordinal %d delivered message %q", r.Segment, r.Ordinal, r.Entry.Message)
+		}
+		copied := *r
+		last[r.Segment] = &copied
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := proc.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, name := range names {
+		r, c := last[name], completions[name]
+		if r == nil {
+			t.Fatalf("no records from %s", name)
+		}
+		if want := int64(i + 1); r.SegmentOrdinal != want || c.Ordinal != want {
+			t.Errorf("%s has segment ordinal %d (completion %d), want %d", name, r.SegmentOrdinal, c.Ordinal, want)
+		}
+		if want := int64(50 * (i + 1)); r.Ordinal != want || c.LastOrdinal != want {
+			t.Errorf("%s ends at ordinal %d (completion %d), want %d", name, r.Ordinal, c.LastOrdinal, want)
+		}
+	}
+}
+
+// TestSegmentDeadline verifies a slow segment is yielded at its
+// deadline so later segments aren't starved, and that it then resumes
+// where it left off
+func TestSegmentDeadline(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.SegmentDeadline = 50 * time.Millisecond
+
+	const slowRecords = 40
+	var slow []string
+	for i := 0; i < slowRecords; i++ {
+		slow = append(slow, fmt.Sprintf(`{"level":"INFO","message":"slow-%d"}`, i))
+	}
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", slow...)
+	for i := 1; i <= 3; i++ {
+		writeSegment(t, cfg.LogsDir, fmt.Sprintf("app.log.20260101-0%d0000", i), fmt.Sprintf(`{"level":"INFO","message":"fast-%d"}`, i))
+	}
+
+	var mu sync.Mutex
+	var seen []string
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		if strings.HasPrefix(r.Entry.Message, "slow") {
+			time.Sleep(10 * time.Millisecond)
+		}
+		mu.Lock()
+		seen = append(seen, r.Entry.Message)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := proc.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != slowRecords+3 {
+		t.Fatalf("processed %d records, want %d", len(seen), slowRecords+3)
+	}
+	// Without the deadline the fast segments would only follow the
+	// whole slow one
+	if seen[len(seen)-1] != fmt.Sprintf("slow-%d", slowRecords-1) {
+		t.Fatalf("fast segments were starved: %q", seen)
+	}
+	next := 0
+	for _, msg := range seen {
+		if strings.HasPrefix(msg, "slow") {
+			if want := fmt.Sprintf("slow-%d", next); msg != want {
+				t.Fatalf("got %s, want %s: slow segment didn't resume where it left off", msg, want)
+			}
+			next++
+		}
+	}
+}
+
+// TestPrioritize verifies a single worker claims segments in the order
+// the prioritizer returns rather than by name
+func TestPrioritize(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	priority := map[string]int{
+		"app.log.20260101-020000": 0,
+		"app.log.20260101-000000": 1,
+		"app.log.20260101-010000": 2,
+	}
+	for name := range priority {
+		writeSegment(t, cfg.LogsDir, name, `{"level":"INFO","message":"`+name+`"}`)
+	}
+
+	var calls atomic.Int64
+	cfg.Prioritize = func(pending []*Segment) []*Segment {
+		calls.Add(1)
+		ordered := append([]*Segment(nil), pending...)
+		sort.Slice(ordered, func(i, j int) bool {
+			return priority[ordered[i].Name] < priority[ordered[j].Name]
+		})
+		return ordered
+	}
+
+	var mu sync.Mutex
+	var seen []string
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		seen = append(seen, r.Entry.Message)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := proc.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"app.log.20260101-020000", "app.log.20260101-000000", "app.log.20260101-010000"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Fatalf("claimed %q, want %q", seen, want)
+	}
+	if calls.Load() == 0 {
+		t.Fatal("prioritizer never called")
+	}
+}
+
+// TestRunFatalError verifies a Fatal error from the process func stops
+// every worker and is returned by Run, leaving its record for the next
+// run, while other record errors are only counted
+func TestRunFatalError(t *testing.T) {
+	cfg := newTestConfig(t, 2)
+	const name = "app.log.20260101-000000"
+	writeSegment(t, cfg.LogsDir, name,
+		`{"message":"ok"}`,
+		`{"message":"unclassified"}`,
+		`{"message":"gone"}`,
+		`{"message":"after"}`,
+	)
+
+	errGone := errors.New("downstream gone")
+	proc, err := NewProcessor(cfg, func(record *LogRecord) error {
+		switch record.Entry.Message {
+		case "unclassified":
+			return errors.New("boom")
+		case "gone":
+			return Fatal(errGone)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = proc.Run(ctx)
+	if !errors.Is(err, errGone) || !IsFatal(err) {
+		t.Fatalf("Run = %v, want the fatal error", err)
+	}
+	if ctx.Err() != nil {
+		t.Fatal("Run returned only once its context ended")
+	}
+	if processed, errs, _ := proc.Stats(); processed != 1 || errs != 2 {
+		t.Errorf("Stats() processed=%d errors=%d; want 1 and 2", processed, errs)
+	}
+
+	// The next run resumes at the record that failed
+	var resumed []string
+	if _, err := ProcessOnce(ctx, cfg, func(record *LogRecord) error {
+		resumed = append(resumed, record.Entry.Message)
+		return nil
+	}); err != nil {
+		t.Fatalf("ProcessOnce: %v", err)
+	}
+	if want := []string{"gone", "after"}; !reflect.DeepEqual(resumed, want) {
+		t.Errorf("next run delivered %q, want %q", resumed, want)
+	}
+
+	// A clean shutdown isn't an error
+	proc, err = NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	cancelled, stop := context.WithCancel(context.Background())
+	stop()
+	if err := proc.Run(cancelled); err != nil {
+		t.Errorf("Run after cancel = %v, want nil", err)
+	}
+}
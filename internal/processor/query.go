@@ -0,0 +1,297 @@
+package processor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"log-processor/internal/logger"
+)
+
+// Expr is a parsed query expression over entry fields
+type Expr interface {
+	Match(e logger.LogEntry) bool
+}
+
+// ParseFilter parses a query expression such as
+//
+//	level=ERROR AND service=payment-service OR duration_ms>500
+//
+// into a Filter. A condition is a field (a known field or Extra key),
+// an operator and a value, which may be double-quoted. The operators
+// are = and != (exact), ~ (contains) and <, <=, >, >= (numeric when
+// both sides are numbers, otherwise by string, which orders RFC 3339
+// timestamps of one zone). A missing field satisfies only !=. Levels
+// are normalized, so level=warn matches WARNING. AND binds tighter
+// than OR, and parentheses group.
+//
+// Equalities on level and service joined by AND at the top level also
+// set the Filter's Level and Service, so an index can narrow the scan.
+func ParseFilter(s string) (Filter, error) {
+	p := &queryParser{}
+	if err := p.lex(s); err != nil {
+		return Filter{}, err
+	}
+	if len(p.tokens) == 0 {
+		return Filter{}, nil
+	}
+
+	expr, err := p.parseOr()
+	if err != nil {
+		return Filter{}, err
+	}
+	if p.pos < len(p.tokens) {
+		return Filter{}, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+
+	f := Filter{Where: expr}
+	conds := []Expr{expr}
+	if and, ok := expr.(andExpr); ok {
+		conds = and
+	}
+	for _, e := range conds {
+		c, ok := e.(*condExpr)
+		if !ok || c.op != "=" {
+			continue
+		}
+		switch c.field {
+		case "level":
+			f.Level = logger.LogLevel(c.value)
+		case "service":
+			f.Service = c.value
+		}
+	}
+	return f, nil
+}
+
+// andExpr matches when all of its terms do
+type andExpr []Expr
+
+func (a andExpr) Match(e logger.LogEntry) bool {
+	for _, x := range a {
+		if !x.Match(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// orExpr matches when any of its terms does
+type orExpr []Expr
+
+func (o orExpr) Match(e logger.LogEntry) bool {
+	for _, x := range o {
+		if x.Match(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// condExpr compares one field with a value
+type condExpr struct {
+	field string
+	op    string
+	value string
+	num   float64
+	isNum bool
+}
+
+func (c *condExpr) Match(e logger.LogEntry) bool {
+	v, ok := e.Field(c.field)
+	if !ok {
+		return c.op == "!="
+	}
+	s := fmt.Sprint(v)
+
+	switch c.op {
+	case "=":
+		return s == c.value
+	case "!=":
+		return s != c.value
+	case "~":
+		return strings.Contains(s, c.value)
+	}
+
+	var cmp int
+	if n, ok := queryNumber(v); ok && c.isNum {
+		switch {
+		case n < c.num:
+			cmp = -1
+		case n > c.num:
+			cmp = 1
+		}
+	} else {
+		cmp = strings.Compare(s, c.value)
+	}
+
+	switch c.op {
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default: // >=
+		return cmp >= 0
+	}
+}
+
+// queryNumber converts a field value to a number if it is one
+func queryNumber(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// queryToken is a lexed token of a query expression
+type queryToken struct {
+	kind byte // 'w'ord, 's'tring, 'o'perator, '(' or ')'
+	text string
+}
+
+// queryParser is a recursive descent parser over lexed tokens
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+// lex splits a query expression into tokens
+func (p *queryParser) lex(s string) error {
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')':
+			p.tokens = append(p.tokens, queryToken{kind: c, text: string(c)})
+			i++
+		case strings.IndexByte("=!~<>", c) >= 0:
+			op := string(c)
+			if i+1 < len(s) && s[i+1] == '=' && c != '=' && c != '~' {
+				op += "="
+			}
+			if op == "!" {
+				return fmt.Errorf("expected != at %d", i)
+			}
+			p.tokens = append(p.tokens, queryToken{kind: 'o', text: op})
+			i += len(op)
+		case c == '"':
+			value, err := strconv.QuotedPrefix(s[i:])
+			if err != nil {
+				return fmt.Errorf("unterminated string at %d", i)
+			}
+			text, _ := strconv.Unquote(value)
+			p.tokens = append(p.tokens, queryToken{kind: 's', text: text})
+			i += len(value)
+		default:
+			j := i
+			for j < len(s) && !unicode.IsSpace(rune(s[j])) && strings.IndexByte("()=!~<>\"", s[j]) < 0 {
+				j++
+			}
+			p.tokens = append(p.tokens, queryToken{kind: 'w', text: s[i:j]})
+			i = j
+		}
+	}
+	return nil
+}
+
+// peekKeyword reports whether the next token is the keyword kw
+func (p *queryParser) peekKeyword(kw string) bool {
+	return p.pos < len(p.tokens) && p.tokens[p.pos].kind == 'w' &&
+		strings.EqualFold(p.tokens[p.pos].text, kw)
+}
+
+// parseOr parses terms joined by OR
+func (p *queryParser) parseOr() (Expr, error) {
+	var terms orExpr
+	for {
+		term, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		if !p.peekKeyword("OR") {
+			break
+		}
+		p.pos++
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+// parseAnd parses conditions joined by AND
+func (p *queryParser) parseAnd() (Expr, error) {
+	var terms andExpr
+	for {
+		term, err := p.parseCond()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+		if !p.peekKeyword("AND") {
+			break
+		}
+		p.pos++
+	}
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return terms, nil
+}
+
+// parseCond parses a parenthesized expression or field op value
+func (p *queryParser) parseCond() (Expr, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, fmt.Errorf("expected a condition at end of expression")
+	}
+
+	if p.tokens[p.pos].kind == '(' {
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].kind != ')' {
+			return nil, fmt.Errorf("missing )")
+		}
+		p.pos++
+		return expr, nil
+	}
+
+	if p.pos+3 > len(p.tokens) {
+		return nil, fmt.Errorf("incomplete condition at %q", p.tokens[p.pos].text)
+	}
+	field, op, value := p.tokens[p.pos], p.tokens[p.pos+1], p.tokens[p.pos+2]
+	if field.kind != 'w' {
+		return nil, fmt.Errorf("expected a field name, got %q", field.text)
+	}
+	if op.kind != 'o' {
+		return nil, fmt.Errorf("expected an operator after %s, got %q", field.text, op.text)
+	}
+	if value.kind != 'w' && value.kind != 's' {
+		return nil, fmt.Errorf("expected a value after %s%s, got %q", field.text, op.text, value.text)
+	}
+	p.pos += 3
+
+	c := &condExpr{field: field.text, op: op.text, value: value.text}
+	if c.field == "level" && (c.op == "=" || c.op == "!=") {
+		c.value = string(logger.NormalizeLevel(c.value))
+	}
+	if n, err := strconv.ParseFloat(c.value, 64); err == nil {
+		c.num, c.isNum = n, true
+	}
+	return c, nil
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"testing"
+
+	"log-processor/internal/logger"
+)
+
+// TestParseFilter verifies the expression grammar and its matching
+func TestParseFilter(t *testing.T) {
+	payment := logger.LogEntry{
+		Level: logger.ERROR, Service: "payment-service", Message: "card declined",
+		Duration: 750, Extra: map[string]any{"region": "eu", "attempt": float64(3)},
+	}
+	auth := logger.LogEntry{
+		Level: logger.WARNING, Service: "auth-service", Message: "slow login",
+		Timestamp: "2026-01-01T12:00:00Z",
+	}
+
+	tests := []struct {
+		expr          string
+		payment, auth bool
+	}{
+		{"", true, true},
+		{"level=ERROR AND service=payment-service", true, false},
+		{"level=error", true, false},
+		{"level=warn", false, true},
+		{"level!=ERROR", false, true},
+		{"service=auth-service OR duration_ms>500", true, true},
+		{"level=ERROR AND service=auth-service OR level=WARNING", false, true},
+		{"level=ERROR AND (service=auth-service OR level=WARNING)", false, false},
+		{`message~"declined"`, true, false},
+		{`message="slow login"`, false, true},
+		{"duration_ms>=750 and attempt<4", true, false},
+		{"region=eu", true, false},
+		{"region!=eu", false, true},
+		{"timestamp<2026-01-02T00:00:00Z", false, true},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.expr)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): %v", tt.expr, err)
+			continue
+		}
+		if got := f.Match(payment); got != tt.payment {
+			t.Errorf("%q matches payment entry = %v, want %v", tt.expr, got, tt.payment)
+		}
+		if got := f.Match(auth); got != tt.auth {
+			t.Errorf("%q matches auth entry = %v, want %v", tt.expr, got, tt.auth)
+		}
+	}
+
+	// Top-level equalities narrow index lookups
+	f, _ := ParseFilter("level=error AND service=payment-service AND duration_ms>1")
+	if f.Level != logger.ERROR || f.Service != "payment-service" {
+		t.Errorf("hoisted level=%q service=%q", f.Level, f.Service)
+	}
+	f, _ = ParseFilter("level=ERROR OR service=payment-service")
+	if f.Level != "" || f.Service != "" {
+		t.Errorf("OR expression hoisted level=%q service=%q", f.Level, f.Service)
+	}
+
+	for _, bad := range []string{
+		"level", "level=", "=ERROR", "level ERROR", "level=ERROR AND",
+		"(level=ERROR", "level=ERROR)", `message="open`, "level!ERROR",
+	} {
+		if _, err := ParseFilter(bad); err == nil {
+			t.Errorf("ParseFilter(%q) succeeded", bad)
+		}
+	}
+}
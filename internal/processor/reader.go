@@ -2,88 +2,508 @@ package processor
 
 import (
 	"bufio"
+	"bytes"
 	"io"
 	"os"
+	"unicode/utf8"
 
 	"log-processor/internal/logger"
-
-	json "github.com/goccy/go-json"
 )
 
+// ReaderOptions controls how a LogReader splits and parses records
+type ReaderOptions struct {
+	// Delimiter separates records. With '\n', a trailing '\r' is also
+	// trimmed so CRLF files parse cleanly.
+	Delimiter byte
+
+	// HoldPartial keeps an unterminated trailing record buffered at EOF
+	// instead of returning it, for files that are still being written.
+	// The offset only advances once the record's delimiter arrives.
+	HoldPartial bool
+
+	// Format is the encoding of each record (logger.JSON if empty), or
+	// Auto to detect it from the first line
+	Format logger.Format
+
+	// FieldMap renames JSON and logfmt fields as each record is parsed
+	// (see logger.LogEntry.MapFields)
+	FieldMap map[string]string
+
+	// StreamKey is copied to every record read (see Config.StreamKey)
+	StreamKey string
+
+	// Resync collapses a run of unparseable JSON lines into a single
+	// record whose ParseErr is a *CorruptRegionError, resuming at the
+	// next line that parses or at a '{' within a line from which the
+	// rest of the line parses (a valid record glued to garbage, e.g.
+	// when corruption swallowed a delimiter). Ignored for other formats.
+	Resync bool
+
+	// WrappedPath is the dot-separated keys leading to the records of a
+	// Wrapped document, e.g. "logs" for {"logs":[...]}. Empty means the
+	// document itself is the record or array of records.
+	WrappedPath string
+
+	// SanitizeUTF8 replaces each run of invalid UTF-8 in a record with
+	// U+FFFD before it is parsed, or with nothing if DropInvalidUTF8 is
+	// also set. Raw holds the sanitized bytes and Sanitized is set.
+	SanitizeUTF8    bool
+	DropInvalidUTF8 bool
+}
+
+// DefaultReaderOptions returns the options used by NewLogReader
+func DefaultReaderOptions() ReaderOptions {
+	return ReaderOptions{
+		Delimiter: '\n',
+	}
+}
+
 // LogReader reads log entries from a segment with offset tracking
 type LogReader struct {
-	file       *os.File
+	file       io.ReadCloser
 	reader     *bufio.Reader
 	segment    string
-	offset     int64 // Current byte offset
-	lineNumber int64 // Current line number
+	opts       ReaderOptions
+	partial    []byte // Unterminated bytes held back by HoldPartial
+	offset     int64  // Current byte offset
+	lineStart  int64  // Offset of the last line read
+	lineNumber int64  // Current line number
+	term       []byte // Delimiter (and CR) trimmed from the last line read
+
+	lines *LineIndex // Optional sparse index for SeekToLine
+
+	mapped *mappedFile // Set when reading a memory-mapped segment
+
+	wrapped wrappedState // Position within a Wrapped document
+	auto    bool         // Format was detected (see Auto)
+
+	// A record found by resync, returned by the next Read along with
+	// the position after it
+	pending       *LogRecord
+	pendingOffset int64
+	pendingLine   int64
+
+	// A record returned by Peek, returned again by the next Read along
+	// with the position after it
+	peeked       *LogRecord
+	peekedOffset int64
+	peekedLine   int64
 }
 
 // NewLogReader creates a reader for a segment, starting from the given offset
 func NewLogReader(segmentPath string, startOffset int64) (*LogReader, error) {
-	file, err := os.Open(segmentPath)
+	return NewLogReaderWithOptions(segmentPath, startOffset, DefaultReaderOptions())
+}
+
+// NewLogReaderWithOptions creates a reader for a segment using the given
+// options. A non-regular file such as a named pipe cannot seek, so it is
+// read from the start and its offset counts the bytes read.
+func NewLogReaderWithOptions(segmentPath string, startOffset int64, opts ReaderOptions) (*LogReader, error) {
+	file, err := openSegment(segmentPath, startOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	if info, err := file.Stat(); err == nil && isStream(info) {
+		startOffset = 0
+	}
+
+	return NewLogReaderFrom(file, segmentPath, startOffset, opts), nil
+}
+
+// openSegment opens a segment file positioned at offset, skipping the
+// seek for streams
+func openSegment(path string, offset int64) (*os.File, error) {
+	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
+	if offset <= 0 {
+		return file, nil
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if isStream(info) {
+		return file, nil
+	}
 
 	// Seek to start offset
-	if startOffset > 0 {
-		if _, err := file.Seek(startOffset, io.SeekStart); err != nil {
-			file.Close()
-			return nil, err
-		}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
 	}
+	return file, nil
+}
+
+// isStream reports whether a file is a non-regular file (a named pipe,
+// socket, or device) that must be read sequentially
+func isStream(info os.FileInfo) bool {
+	return !info.Mode().IsRegular()
+}
 
+// NewLogReaderFrom wraps an already-positioned stream, such as one
+// returned by SegmentSource.Open, starting at startOffset
+func NewLogReaderFrom(rc io.ReadCloser, segment string, startOffset int64, opts ReaderOptions) *LogReader {
+	mapped, _ := rc.(*mappedFile)
+	wrapped := wrappedStart
+	if startOffset > 0 {
+		wrapped = wrappedNext // Offsets are only committed after a record
+	}
 	return &LogReader{
-		file:       file,
-		reader:     bufio.NewReader(file),
-		segment:    segmentPath,
+		file:       rc,
+		reader:     bufio.NewReader(rc),
+		mapped:     mapped,
+		segment:    segment,
+		opts:       opts,
 		offset:     startOffset,
 		lineNumber: 0,
-	}, nil
+		wrapped:    wrapped,
+	}
 }
 
 // ReadEntry reads the next log entry and returns it with position info
 type LogRecord struct {
 	Entry      logger.LogEntry
-	Offset     int64 // Byte offset AFTER this entry
-	LineNumber int64 // Line number of this entry
-	Raw        []byte
+	Offset     int64  // Byte offset AFTER this entry
+	LineNumber int64  // Line number of this entry
+	Raw        []byte // Record bytes as read, including the delimiter
+	ParseErr   error  // Set when Raw is not a valid log entry
+	StreamKey  string // Config.StreamKey of the segment, if set
+	Sanitized  bool   // Invalid UTF-8 was removed (see ReaderOptions.SanitizeUTF8)
+
+	// Set by the processor's workers so consumers of segments processed
+	// in parallel can restore each file's order: the segment name, its
+	// ordinal (segments are numbered from 1 in name order as the run
+	// discovers them; 0 for the followed active file), and the record's
+	// ordinal among those delivered from the segment this run, from 1
+	// with no gaps. Across runs, Offset orders a segment's records.
+	Segment        string
+	SegmentOrdinal int64
+	Ordinal        int64
+
+	// Epoch is the segment's offset epoch when its delivery began: 0
+	// until the segment is first replayed with ForceCommitOffset, then
+	// one more per replay (see OffsetData.Epoch)
+	Epoch int64
 }
 
 // Read reads the next log entry from the segment
 func (lr *LogReader) Read() (*LogRecord, error) {
-	line, err := lr.reader.ReadBytes('\n')
+	if record := lr.peeked; record != nil {
+		lr.unpeek()
+		return record, nil
+	}
+	if record := lr.pending; record != nil {
+		lr.pending = nil
+		lr.offset, lr.lineNumber = lr.pendingOffset, lr.pendingLine
+		return record, nil
+	}
+	if lr.opts.Format == Auto && !lr.detect() {
+		return nil, io.EOF
+	}
+	if lr.opts.Format == Wrapped {
+		return lr.readWrapped()
+	}
+
+	start := lr.offset
+	line, err := lr.readLine()
+	if err != nil {
+		return nil, err
+	}
+
+	record := lr.parse(line, lr.term)
+	if record.ParseErr != nil && lr.opts.Resync && lr.isJSON() {
+		return lr.resync(start, record), nil
+	}
+	return record, nil
+}
+
+// Peek returns the next record without consuming it: the next Read
+// returns the same record, and Offset and LineNumber don't move until
+// then. Peeking again before reading returns the same record. Errors
+// are returned as Read would, without anything being held.
+func (lr *LogReader) Peek() (*LogRecord, error) {
+	if lr.peeked != nil {
+		return lr.peeked, nil
+	}
+
+	offset, lineNumber := lr.offset, lr.lineNumber
+	record, err := lr.Read()
 	if err != nil {
-		if err == io.EOF && len(line) == 0 {
-			return nil, io.EOF
+		return nil, err
+	}
+	lr.peeked = record
+	lr.peekedOffset, lr.peekedLine = lr.offset, lr.lineNumber
+	lr.offset, lr.lineNumber = offset, lineNumber
+	return record, nil
+}
+
+// unpeek consumes a peeked record, moving to the position after it
+func (lr *LogReader) unpeek() {
+	if lr.peeked != nil {
+		lr.peeked = nil
+		lr.offset, lr.lineNumber = lr.peekedOffset, lr.peekedLine
+	}
+}
+
+// parse builds the record for a line ending at the current position,
+// parsing it without the delimiter bytes term that followed it. The raw
+// line is returned even if parsing fails.
+func (lr *LogReader) parse(line, term []byte) *LogRecord {
+	line, sanitized := lr.sanitize(line)
+	record := &LogRecord{
+		Sanitized:  sanitized,
+		Offset:     lr.offset,
+		LineNumber: lr.lineNumber,
+		Raw:        withDelimiter(line, term),
+		StreamKey:  lr.opts.StreamKey,
+	}
+
+	record.Entry, record.ParseErr = lr.parseAs(line, lr.opts.Format)
+	if record.ParseErr != nil && lr.auto {
+		// A mixed file: try the format this line looks like
+		if f := DetectFormat(line); f != lr.opts.Format && f != Wrapped {
+			if entry, err := lr.parseAs(line, f); err == nil {
+				record.Entry, record.ParseErr = entry, nil
+			}
+		}
+	}
+	if record.ParseErr != nil {
+		record.Entry = logger.LogEntry{}
+	}
+	return record
+}
+
+// sanitize replaces or drops invalid UTF-8 with SanitizeUTF8,
+// reporting whether there was any
+func (lr *LogReader) sanitize(line []byte) ([]byte, bool) {
+	if !lr.opts.SanitizeUTF8 || utf8.Valid(line) {
+		return line, false
+	}
+	replacement := []byte(string(utf8.RuneError))
+	if lr.opts.DropInvalidUTF8 {
+		replacement = nil
+	}
+	return bytes.ToValidUTF8(line, replacement), true
+}
+
+// parseAs parses a line in the given format, renaming fields per
+// FieldMap for the formats with arbitrary keys
+func (lr *LogReader) parseAs(line []byte, format logger.Format) (logger.LogEntry, error) {
+	var entry logger.LogEntry
+	var err error
+	switch format {
+	case "", logger.JSON, Wrapped:
+		err = logger.Unmarshal(line, &entry)
+	default:
+		entry, err = logger.Parse(line, format)
+	}
+	if err == nil && len(lr.opts.FieldMap) > 0 && format != logger.Text && format != logger.Syslog {
+		entry.MapFields(lr.opts.FieldMap)
+	}
+	return entry, err
+}
+
+// isJSON reports whether records are JSON encoded
+func (lr *LogReader) isJSON() bool {
+	return lr.opts.Format == "" || lr.opts.Format == logger.JSON || lr.opts.Format == Wrapped
+}
+
+const (
+	// maxCorruptRaw caps the bytes of a corrupt region kept in Raw
+	maxCorruptRaw = 64 << 10
+
+	// maxResyncCandidates caps the '{' positions tried per line
+	maxResyncCandidates = 64
+)
+
+// resync reads past the corrupt region starting with bad (a record that
+// began at offset start) and returns the region as one record. The
+// first valid record after it is held for the next Read, and until then
+// the reader's position is the end of the region, so a commit made in
+// between doesn't skip the recovered record.
+func (lr *LogReader) resync(start int64, bad *LogRecord) *LogRecord {
+	region := &CorruptRegionError{Lines: 1}
+	record := &LogRecord{LineNumber: bad.LineNumber, StreamKey: lr.opts.StreamKey, ParseErr: region}
+	raw := bad.Raw[:len(bad.Raw)-len(lr.term)]
+
+	// The bad line itself may end in a valid record, unless sanitizing
+	// it moved the bytes from where they are in the segment
+	line, appended := raw, true
+	lineStart, lineNo := lr.lineStart, lr.lineNumber-1
+	shifted := bad.Sanitized
+	for {
+		if i := validSuffix(line); i > 0 && !shifted {
+			lr.hold(lr.parse(line[i:], lr.term))
+			if appended {
+				raw = raw[:len(raw)-len(line)+i]
+			}
+			lr.offset, lr.lineNumber = lineStart+int64(i), lineNo
+			break
+		}
+
+		next, err := lr.readLine()
+		if err != nil {
+			break // The region runs to the end of the input (for now)
+		}
+		lineStart, lineNo = lr.lineStart, lr.lineNumber-1
+		if candidate := lr.parse(next, lr.term); candidate.ParseErr == nil {
+			lr.hold(candidate)
+			lr.offset, lr.lineNumber = lineStart, lineNo
+			break
 		}
-		if err != io.EOF {
-			return nil, err
+
+		region.Lines++
+		line, appended, shifted = next, len(raw) < maxCorruptRaw, false
+		if appended {
+			raw = append(append(raw, '\n'), next...)
 		}
 	}
 
-	// Update position
-	lr.offset += int64(len(line))
-	lr.lineNumber++
+	region.Bytes = lr.offset - start
+	record.Offset = lr.offset
+	record.Raw, record.Sanitized = lr.sanitize(raw[:min(len(raw), maxCorruptRaw)])
+	record.Sanitized = record.Sanitized || bad.Sanitized
+	return record
+}
+
+// hold keeps a record for the next Read, which also restores the
+// position after it
+func (lr *LogReader) hold(record *LogRecord) {
+	lr.pending = record
+	lr.pendingOffset, lr.pendingLine = lr.offset, lr.lineNumber
+}
 
-	// Parse JSON log entry
+// validSuffix returns the position of a '{' after the start of line
+// from which the rest of the line is a valid JSON log entry, or 0
+func validSuffix(line []byte) int {
 	var entry logger.LogEntry
-	if err := json.Unmarshal(line, &entry); err != nil {
-		// Return raw line even if parsing fails
-		return &LogRecord{
-			Offset:     lr.offset,
-			LineNumber: lr.lineNumber,
-			Raw:        line,
-		}, nil
+	pos := 0
+	for tries := 0; tries < maxResyncCandidates; tries++ {
+		i := bytes.IndexByte(line[pos+1:], '{')
+		if i < 0 {
+			return 0
+		}
+		pos += i + 1
+		if logger.Unmarshal(line[pos:], &entry) == nil {
+			return pos
+		}
 	}
+	return 0
+}
 
-	return &LogRecord{
-		Entry:      entry,
-		Offset:     lr.offset,
-		LineNumber: lr.lineNumber,
-		Raw:        line,
-	}, nil
+// SkipLines discards input until n lines have been consumed in total,
+// without parsing them. It is used to resume segments checkpointed by
+// line number rather than byte offset. A peeked record counts as
+// consumed. In a Wrapped document, n counts records.
+func (lr *LogReader) SkipLines(n int64) error {
+	lr.unpeek()
+	if lr.opts.Format == Auto {
+		lr.detect()
+	}
+	if lr.opts.Format == Wrapped {
+		for lr.lineNumber < n {
+			if _, err := lr.readWrapped(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for lr.lineNumber < n {
+		line, err := lr.readBytes()
+		lr.offset += int64(len(line))
+		if err != nil {
+			if len(line) > 0 {
+				lr.lineNumber++
+			}
+			return err
+		}
+		lr.lineNumber++
+	}
+	return nil
+}
+
+// utf8BOM is the byte order mark some Windows tools write at the start
+// of UTF-8 files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// readLine returns the next non-blank line without its delimiter.
+// Blank and whitespace-only lines are consumed (advancing the offset
+// and line number) but never returned. A BOM at the start of the input
+// is stripped from the first line; the offset still counts it.
+func (lr *LogReader) readLine() ([]byte, error) {
+	for {
+		line, err := lr.readBytes()
+		if len(lr.partial) > 0 {
+			line = append(lr.partial, line...)
+			lr.partial = nil
+		}
+		if err != nil {
+			if err == io.EOF && len(line) == 0 {
+				return nil, io.EOF
+			}
+			if err != io.EOF || lr.opts.HoldPartial {
+				lr.partial = line
+				return nil, err
+			}
+		}
+
+		// Update position
+		lr.lineStart = lr.offset
+		lr.offset += int64(len(line))
+		lr.lineNumber++
+
+		trimmed := trimDelimiter(line, lr.opts.Delimiter)
+		line, lr.term = trimmed, line[len(trimmed):]
+		if lr.lineStart == 0 {
+			line = bytes.TrimPrefix(line, utf8BOM)
+		}
+		if len(bytes.TrimSpace(line)) > 0 {
+			return line, nil
+		}
+	}
+}
+
+// readBytes reads through the next delimiter, straight from the mapping
+// of a memory-mapped segment
+func (lr *LogReader) readBytes() ([]byte, error) {
+	if lr.mapped != nil {
+		return lr.mapped.next(lr.opts.Delimiter)
+	}
+	return lr.reader.ReadBytes(lr.opts.Delimiter)
+}
+
+// trimDelimiter strips the record delimiter (and CR for CRLF input)
+func trimDelimiter(line []byte, delimiter byte) []byte {
+	line = bytes.TrimSuffix(line, []byte{delimiter})
+	if delimiter == '\n' {
+		line = bytes.TrimSuffix(line, []byte{'\r'})
+	}
+	return line
+}
+
+// withDelimiter returns line followed by the delimiter bytes term,
+// reusing line's buffer when they already follow it there
+func withDelimiter(line, term []byte) []byte {
+	n := len(line)
+	if len(term) == 0 {
+		return line
+	}
+	if cap(line)-n >= len(term) && bytes.Equal(line[n:n+len(term)], term) {
+		return line[:n+len(term)]
+	}
+	return append(line[:n:n], term...)
+}
+
+// ReleasePartial stops holding back unterminated records, so the next
+// Read returns any buffered trailing bytes (e.g. once a followed file
+// has been rotated and can no longer grow)
+func (lr *LogReader) ReleasePartial() {
+	lr.opts.HoldPartial = false
 }
 
 // Offset returns the current byte offset
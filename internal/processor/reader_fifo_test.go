@@ -0,0 +1,208 @@
+//go:build unix
+
+package processor
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// makeFIFO creates a named pipe, skipping the test where unsupported
+func makeFIFO(t *testing.T, path string) {
+	t.Helper()
+	if err := syscall.Mkfifo(path, 0644); err != nil {
+		t.Skipf("mkfifo: %v", err)
+	}
+}
+
+// writeFIFO opens a named pipe for writing and writes lines to it
+func writeFIFO(t *testing.T, path string, lines ...string) {
+	t.Helper()
+	go func() {
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			t.Errorf("open fifo for writing: %v", err)
+			return
+		}
+		defer f.Close()
+		for _, line := range lines {
+			if _, err := f.WriteString(line + "\n"); err != nil {
+				t.Errorf("write fifo: %v", err)
+				return
+			}
+		}
+	}()
+}
+
+// TestReaderFIFO verifies a named pipe is read sequentially, ignoring
+// the requested offset instead of failing to seek
+func TestReaderFIFO(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.pipe")
+	makeFIFO(t, path)
+
+	lines := []string{`{"message":"one"}`, `{"message":"two"}`}
+	writeFIFO(t, path, lines...)
+
+	reader, err := NewLogReader(path, 1000)
+	if err != nil {
+		t.Fatalf("NewLogReader: %v", err)
+	}
+	defer reader.Close()
+
+	for _, want := range []string{"one", "two"} {
+		record, err := reader.Read()
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if record.Entry.Message != want {
+			t.Fatalf("got %q, want %q", record.Entry.Message, want)
+		}
+	}
+	if _, err := reader.Read(); err != io.EOF {
+		t.Fatalf("expected EOF once the writer closes, got %v", err)
+	}
+	if want := int64(len(lines[0]) + len(lines[1]) + 2); reader.Offset() != want {
+		t.Fatalf("offset = %d, want bytes read %d", reader.Offset(), want)
+	}
+}
+
+// TestProcessorFIFOSegment verifies a named pipe segment is processed as
+// a stream, without persisting an offset
+func TestProcessorFIFOSegment(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	path := filepath.Join(cfg.LogsDir, "app.log.20260101-000000")
+	makeFIFO(t, path)
+
+	var mu sync.Mutex
+	var seen []string
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		seen = append(seen, r.Entry.Message)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	writeFIFO(t, path, `{"message":"a"}`, `{"message":"b"}`)
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != 2 || seen[0] != "a" || seen[1] != "b" {
+		t.Fatalf("processed %q", seen)
+	}
+	if offsets := proc.offsetMgr.GetAllOffsets(); len(offsets) != 0 {
+		t.Fatalf("stream offsets persisted: %+v", offsets)
+	}
+}
+
+// TestCheckpointStreamSegment verifies a checkpoint isn't held up by a
+// worker blocked reading a stream whose writer has gone quiet
+func TestCheckpointStreamSegment(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	path := filepath.Join(cfg.LogsDir, "app.log.20260101-000000")
+	makeFIFO(t, path)
+
+	var processed sync.WaitGroup
+	processed.Add(1)
+	proc, err := NewProcessor(cfg, func(*LogRecord) error {
+		processed.Done()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	// Write one record and keep the pipe open, so the next read blocks
+	writer, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open fifo for writing: %v", err)
+	}
+	defer writer.Close()
+	if _, err := writer.WriteString(`{"message":"a"}` + "\n"); err != nil {
+		t.Fatal(err)
+	}
+	processed.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if _, err := proc.CheckpointContext(ctx); err != nil {
+		t.Fatalf("Checkpoint: %v", err)
+	}
+}
+
+// TestProcessorFIFOStop verifies Stop isn't held up by a worker waiting
+// for a named pipe's writer to appear, or for it to write more
+func TestProcessorFIFOStop(t *testing.T) {
+	for _, writer := range []bool{false, true} {
+		t.Run(map[bool]string{false: "no writer", true: "idle writer"}[writer], func(t *testing.T) {
+			cfg := newTestConfig(t, 1)
+			path := filepath.Join(cfg.LogsDir, "app.log.20260101-000000")
+			makeFIFO(t, path)
+
+			var processed sync.WaitGroup
+			proc, err := NewProcessor(cfg, func(*LogRecord) error {
+				processed.Done()
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("NewProcessor: %v", err)
+			}
+			if err := proc.Start(context.Background()); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+
+			if writer {
+				processed.Add(1)
+				w, err := os.OpenFile(path, os.O_WRONLY, 0)
+				if err != nil {
+					t.Fatalf("open fifo for writing: %v", err)
+				}
+				defer w.Close()
+				if _, err := w.WriteString(`{"message":"a"}` + "\n"); err != nil {
+					t.Fatal(err)
+				}
+				processed.Wait()
+			} else {
+				waitFor(t, 2*time.Second, func() bool {
+					_, _, stats := proc.Stats()
+					return stats[2] == 1
+				})
+			}
+
+			stopped := make(chan error, 1)
+			go func() { stopped <- proc.Stop() }()
+			select {
+			case err := <-stopped:
+				if err != nil {
+					t.Fatalf("Stop: %v", err)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("Stop hung on the named pipe")
+			}
+			if _, errs, _ := proc.Stats(); errs != 0 {
+				t.Errorf("stopping counted %d errors, want none", errs)
+			}
+		})
+	}
+}
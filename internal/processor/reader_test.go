@@ -0,0 +1,633 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"log-processor/internal/logger"
+)
+
+// TestReaderDelimiters verifies records split and resume for each delimiter
+func TestReaderDelimiters(t *testing.T) {
+	records := []string{
+		`{"level":"INFO","message":"one"}`,
+		`{"level":"WARNING","message":"two"}`,
+		`{"level":"ERROR","message":"three"}`,
+	}
+
+	tests := []struct {
+		name      string
+		separator string
+		cfg       Config
+	}{
+		{name: "LF", separator: "\n"},
+		{name: "CRLF", separator: "\r\n"},
+		{name: "NUL", separator: "\x00", cfg: Config{NULDelimited: true}},
+		{name: "semicolon", separator: ";", cfg: Config{RecordDelimiter: ';'}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var content string
+			for _, r := range records {
+				content += r + tt.separator
+			}
+			path := filepath.Join(t.TempDir(), "app.log.1")
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			opts := tt.cfg.readerOptions()
+
+			// Read the first two records, then "restart" from the offset
+			reader, err := NewLogReaderWithOptions(path, 0, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 2; i++ {
+				record, err := reader.Read()
+				if err != nil {
+					t.Fatalf("read %d: %v", i, err)
+				}
+				if want := records[i] + tt.separator; string(record.Raw) != want {
+					t.Fatalf("record %d: got %q, want %q", i, record.Raw, want)
+				}
+			}
+			resumeAt := reader.Offset()
+			reader.Close()
+
+			wantOffset := int64(len(records[0]) + len(records[1]) + 2*len(tt.separator))
+			if resumeAt != wantOffset {
+				t.Fatalf("offset after two records: got %d, want %d", resumeAt, wantOffset)
+			}
+
+			reader, err = NewLogReaderWithOptions(path, resumeAt, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+
+			record, err := reader.Read()
+			if err != nil {
+				t.Fatalf("read after resume: %v", err)
+			}
+			if record.Entry.Message != "three" {
+				t.Fatalf("resumed at wrong record: %q", record.Raw)
+			}
+			if record.Offset != int64(len(content)) {
+				t.Fatalf("final offset: got %d, want %d", record.Offset, len(content))
+			}
+			if _, err := reader.Read(); err != io.EOF {
+				t.Fatalf("expected EOF, got %v", err)
+			}
+		})
+	}
+}
+
+// TestReaderSkipsBlankLines verifies whitespace-only lines are consumed silently
+func TestReaderSkipsBlankLines(t *testing.T) {
+	content := "\n   \n\t\r\n" + `{"message":"only"}` + "\n\n  \n"
+	path := filepath.Join(t.TempDir(), "app.log.1")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := NewLogReader(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	record, err := reader.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if record.Entry.Message != "only" || record.LineNumber != 4 {
+		t.Fatalf("unexpected record: line %d %q", record.LineNumber, record.Raw)
+	}
+
+	if _, err := reader.Read(); err != io.EOF {
+		t.Fatalf("expected EOF after trailing blanks, got %v", err)
+	}
+	if reader.Offset() != int64(len(content)) {
+		t.Fatalf("offset: got %d, want %d", reader.Offset(), len(content))
+	}
+
+	// A file of only blank lines yields no records at all
+	blank := filepath.Join(t.TempDir(), "app.log.2")
+	if err := os.WriteFile(blank, []byte("\n\n   \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reader2, err := NewLogReader(blank, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader2.Close()
+	if _, err := reader2.Read(); err != io.EOF {
+		t.Fatalf("expected EOF for blank file, got %v", err)
+	}
+}
+
+// TestReaderBOM verifies a leading BOM is stripped, before blank lines
+// or glued to the first record, and still counted in offsets
+func TestReaderBOM(t *testing.T) {
+	first, second := `{"level":"INFO","message":"first"}`, `{"level":"INFO","message":"second"}`
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "record", content: "\xEF\xBB\xBF" + first + "\n" + second + "\n"},
+		{name: "blank lines", content: "\xEF\xBB\xBF\r\n\n  \n" + first + "\n" + second + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "app.log.1")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			reader, err := NewLogReader(path, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+
+			record, err := reader.Read()
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if record.ParseErr != nil || record.Entry.Message != "first" {
+				t.Fatalf("first record: %q, %v", record.Raw, record.ParseErr)
+			}
+			if want := int64(strings.Index(tt.content, first) + len(first) + 1); record.Offset != want {
+				t.Fatalf("offset after first record = %d, want %d (BOM included)", record.Offset, want)
+			}
+
+			// Resuming there reads on without touching the BOM again
+			resumed, err := NewLogReader(path, record.Offset)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resumed.Close()
+			next, err := resumed.Read()
+			if err != nil || next.Entry.Message != "second" {
+				t.Fatalf("resumed Read = %v, %v; want second", next, err)
+			}
+		})
+	}
+}
+
+// TestReaderSyslogFormat verifies records are parsed in the configured format
+func TestReaderSyslogFormat(t *testing.T) {
+	content := `<11>1 2026-01-02T10:00:00Z host payment-service - - [log@32473 request_id="req-1"] charge failed` + "\n" +
+		`{"message":"not syslog"}` + "\n"
+	path := filepath.Join(t.TempDir(), "app.log.1")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultReaderOptions()
+	opts.Format = logger.Syslog
+	reader, err := NewLogReaderWithOptions(path, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	record, err := reader.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	want := logger.LogEntry{
+		Timestamp: "2026-01-02T10:00:00Z",
+		Level:     logger.ERROR,
+		Service:   "payment-service",
+		Message:   "charge failed",
+		RequestID: "req-1",
+	}
+	if record.ParseErr != nil || !reflect.DeepEqual(record.Entry, want) {
+		t.Fatalf("Read() = %+v (%v), want %+v", record.Entry, record.ParseErr, want)
+	}
+
+	if record, err := reader.Read(); err != nil || record.ParseErr == nil {
+		t.Fatalf("JSON line parsed as syslog: %+v, %v", record, err)
+	}
+}
+
+// TestReaderFieldMap verifies zap records are mapped onto LogEntry as
+// they are read
+func TestReaderFieldMap(t *testing.T) {
+	content := `{"level":"error","ts":1767268800,"logger":"payment-service","msg":"charge failed"}` + "\n"
+	path := filepath.Join(t.TempDir(), "app.log.1")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultReaderOptions()
+	opts.FieldMap = logger.ZapFieldMap
+	reader, err := NewLogReaderWithOptions(path, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	record, err := reader.Read()
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	want := logger.LogEntry{
+		Timestamp: "2026-01-01T12:00:00Z",
+		Level:     logger.ERROR,
+		Service:   "payment-service",
+		Message:   "charge failed",
+	}
+	if record.ParseErr != nil || !reflect.DeepEqual(record.Entry, want) {
+		t.Fatalf("Read() = %+v (%v), want %+v", record.Entry, record.ParseErr, want)
+	}
+}
+
+// TestReaderResync verifies a corrupt middle region, including a valid
+// record glued to garbage, is skipped as one record and that reading
+// resumes correctly from the offset reported for it
+func TestReaderResync(t *testing.T) {
+	glued := `more garbage{"message":"b"}`
+	content := `{"message":"a"}` + "\n" +
+		"garbage \x00\xff\n" +
+		`{"message": "trunc` + "\n" +
+		glued + "\n" +
+		`{"message":"c"}` + "\n" +
+		"trailing garbage\n"
+	path := filepath.Join(t.TempDir(), "app.log.20260101-000000")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := DefaultReaderOptions()
+	opts.Resync = true
+	reader, err := NewLogReaderWithOptions(path, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	var got []string
+	var regions []*CorruptRegionError
+	var regionEnd int64
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if corrupt, ok := record.ParseErr.(*CorruptRegionError); ok {
+			regions = append(regions, corrupt)
+			if regionEnd == 0 {
+				regionEnd = record.Offset
+				if reader.Offset() != record.Offset {
+					t.Errorf("reader offset %d after region, want %d", reader.Offset(), record.Offset)
+				}
+			}
+			continue
+		}
+		if record.ParseErr != nil {
+			t.Fatalf("unexpected parse error %v", record.ParseErr)
+		}
+		got = append(got, record.Entry.Message)
+	}
+
+	if !reflect.DeepEqual(got, []string{"a", "b", "c"}) {
+		t.Fatalf("recovered %q, want a, b and c", got)
+	}
+	wantEnd := int64(strings.Index(content, `{"message":"b"}`))
+	if len(regions) != 2 || regions[0].Lines != 3 || regionEnd != wantEnd ||
+		regions[0].Bytes != wantEnd-int64(len(`{"message":"a"}`)+1) {
+		t.Fatalf("regions %+v ending at %d, want two, the first of 3 lines ending at %d", regions, regionEnd, wantEnd)
+	}
+
+	// Resuming from the region's end picks up the glued record
+	resumed, err := NewLogReaderWithOptions(path, regionEnd, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Close()
+	if record, err := resumed.Read(); err != nil || record.Entry.Message != "b" {
+		t.Fatalf("resumed read = %+v, %v; want b", record, err)
+	}
+}
+
+// TestReaderPeek verifies Peek returns the record the next Read does
+// without moving the reader, including around resynced regions
+func TestReaderPeek(t *testing.T) {
+	content := `{"message":"a"}` + "\n\n" +
+		"garbage\n" +
+		`garbage{"message":"b"}` + "\n" +
+		`{"message":"c"}`
+	path := filepath.Join(t.TempDir(), "app.log.20260101-000000")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := DefaultReaderOptions()
+	opts.Resync = true
+
+	type step struct {
+		raw          string
+		offset, line int64
+	}
+	readAllSteps := func(peek bool) []step {
+		reader, err := NewLogReaderWithOptions(path, 0, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+
+		var steps []step
+		for {
+			var peeked *LogRecord
+			if peek {
+				offset, line := reader.Offset(), reader.LineNumber()
+				peeked, err = reader.Peek()
+				if again, _ := reader.Peek(); again != peeked {
+					t.Fatal("second Peek returned a different record")
+				}
+				if reader.Offset() != offset || reader.LineNumber() != line {
+					t.Fatalf("Peek moved the reader from %d:%d to %d:%d", offset, line, reader.Offset(), reader.LineNumber())
+				}
+				if err == io.EOF {
+					if _, err := reader.Read(); err != io.EOF {
+						t.Fatalf("Read after peeking EOF = %v", err)
+					}
+					return steps
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			record, err := reader.Read()
+			if err == io.EOF {
+				return steps
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if peek && record != peeked {
+				t.Fatalf("Read returned %q, not the peeked %q", record.Raw, peeked.Raw)
+			}
+			steps = append(steps, step{string(record.Raw), reader.Offset(), reader.LineNumber()})
+		}
+	}
+
+	plain, peeking := readAllSteps(false), readAllSteps(true)
+	if len(plain) != 4 {
+		t.Fatalf("read %d records, want a, the corrupt region, b and c", len(plain))
+	}
+	if !reflect.DeepEqual(peeking, plain) {
+		t.Fatalf("with Peek read %+v, want %+v", peeking, plain)
+	}
+
+	// Skipping lines consumes a peeked record
+	reader, err := NewLogReaderWithOptions(path, 0, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if _, err := reader.Peek(); err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.SkipLines(4); err != nil {
+		t.Fatal(err)
+	}
+	if record, err := reader.Read(); err != nil || record.Entry.Message != "c" {
+		t.Fatalf("Read after SkipLines = %+v, %v; want c", record, err)
+	}
+}
+
+// TestResyncCorrupt verifies the processor dead-letters a corrupt
+// region once and processes the valid records around it
+func TestResyncCorrupt(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.ResyncCorrupt = true
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"message":"before"}`, "\x00\x00\x00", `{"mess`, `age":"x"}garbage`, `{"message":"after"}`)
+
+	var mu sync.Mutex
+	var dead []error
+	var processed []string
+	cfg.DeadLetter = func(r *LogRecord, err error) {
+		mu.Lock()
+		dead = append(dead, err)
+		mu.Unlock()
+	}
+
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		processed = append(processed, r.Entry.Message)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(processed, []string{"before", "after"}) {
+		t.Fatalf("processed %q, want before and after", processed)
+	}
+	var corrupt *CorruptRegionError
+	if len(dead) != 1 || !errors.As(dead[0], &corrupt) || corrupt.Lines != 3 {
+		t.Fatalf("dead letters %v, want one 3-line region", dead)
+	}
+	if proc.CorruptRegions() != 1 {
+		t.Fatalf("CorruptRegions = %d, want 1", proc.CorruptRegions())
+	}
+}
+
+// panickyUnmarshaler panics on lines containing "boom", standing in for
+// a decoder bug tripped by malformed input
+type panickyUnmarshaler struct{}
+
+func (panickyUnmarshaler) Unmarshal(data []byte, v any) error {
+	if strings.Contains(string(data), "boom") {
+		panic("index out of range")
+	}
+	return logger.GoJSON.Unmarshal(data, v)
+}
+
+// TestReaderSanitizeUTF8 verifies invalid UTF-8 is replaced, or
+// dropped, before parsing, leaving offsets on the original bytes
+func TestReaderSanitizeUTF8(t *testing.T) {
+	content := "{\"level\":\"INFO\",\"message\":\"bad \xff\xfe byte\"}\n\xc3\n{\"message\":\"after\"}\n"
+	path := filepath.Join(t.TempDir(), "app.log.1")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		drop    bool
+		message string
+		junk    string
+	}{
+		{name: "replace", message: "bad \uFFFD byte", junk: "\uFFFD"},
+		{name: "drop", drop: true, message: "bad  byte", junk: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultReaderOptions()
+			opts.SanitizeUTF8 = true
+			opts.DropInvalidUTF8 = tt.drop
+			reader, err := NewLogReaderWithOptions(path, 0, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+
+			var records []*LogRecord
+			for {
+				record, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Read: %v", err)
+				}
+				records = append(records, record)
+			}
+			if len(records) != 3 {
+				t.Fatalf("read %d records, want 3", len(records))
+			}
+
+			if r := records[0]; r.ParseErr != nil || !r.Sanitized || r.Entry.Message != tt.message {
+				t.Errorf("first record = %q, %v, sanitized %v; want message %q", r.Entry.Message, r.ParseErr, r.Sanitized, tt.message)
+			}
+			if r := records[1]; r.ParseErr == nil || !r.Sanitized || string(r.Raw) != tt.junk+"\n" {
+				t.Errorf("junk record = %q, %v; want a parse error on %q", r.Raw, r.ParseErr, tt.junk+"\n")
+			}
+			if r := records[2]; r.Sanitized || r.Entry.Message != "after" || r.Offset != int64(len(content)) {
+				t.Errorf("last record = %q at %d, sanitized %v", r.Entry.Message, r.Offset, r.Sanitized)
+			}
+		})
+	}
+}
+
+// TestSanitizeUTF8 verifies a processor delivers sanitized records,
+// dead-letters junk that still doesn't parse and counts both
+func TestSanitizeUTF8(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.SanitizeUTF8 = true
+	cfg.ResyncCorrupt = true
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		"{\"message\":\"bad \xff byte\"}", "\xde\xad\xbe\xef", `{"message":"after"}`)
+
+	var mu sync.Mutex
+	var dead [][]byte
+	var processed []string
+	cfg.DeadLetter = func(r *LogRecord, err error) {
+		mu.Lock()
+		dead = append(dead, r.Raw)
+		mu.Unlock()
+	}
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		processed = append(processed, r.Entry.Message)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(processed, []string{"bad \uFFFD byte", "after"}) {
+		t.Fatalf("processed %q", processed)
+	}
+	if len(dead) != 1 || !utf8.Valid(dead[0]) {
+		t.Fatalf("dead letters %q, want one sanitized region", dead)
+	}
+	if n := proc.SanitizedRecords(); n != 2 {
+		t.Fatalf("SanitizedRecords = %d, want 2", n)
+	}
+}
+
+// TestDecoderPanicIsParseError verifies a panicking decoder turns the
+// line into a parse error instead of crashing the worker
+func TestDecoderPanicIsParseError(t *testing.T) {
+	logger.SetUnmarshaler(panickyUnmarshaler{})
+	defer logger.SetBackend(logger.GoJSON)
+
+	cfg := newTestConfig(t, 1)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"message":"one"}`, `{"message":"boom"}`, `{"message":"three"}`)
+
+	var mu sync.Mutex
+	var got []string
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+		var panicErr *logger.DecodePanicError
+		switch {
+		case r.ParseErr == nil:
+			got = append(got, r.Entry.Message)
+		case errors.As(r.ParseErr, &panicErr):
+			got = append(got, "parse error: "+string(r.Raw))
+		default:
+			t.Errorf("unexpected parse error %v", r.ParseErr)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := proc.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"one", "parse error: {\"message\":\"boom\"}\n", "three"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered %q, want %q", got, want)
+	}
+	if stats := proc.SegmentStats()["app.log.20260101-000000"]; stats.ParseFailed != 1 || stats.Parsed != 2 {
+		t.Errorf("segment stats %+v, want 1 failed and 2 parsed", stats)
+	}
+}
@@ -0,0 +1,92 @@
+package processor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"log-processor/internal/logger"
+)
+
+// RedactFunc replaces a field's value. The result must suit the field:
+// a string for known string fields, an integer for duration_ms; known
+// fields given an unsuitable value are cleared instead.
+type RedactFunc func(value any) any
+
+// RedactHash replaces a value with the hex SHA-256 of its string form,
+// keeping values correlatable without revealing them
+func RedactHash(value any) any {
+	sum := sha256.Sum256([]byte(fmt.Sprint(value)))
+	return hex.EncodeToString(sum[:])
+}
+
+// RedactMask replaces a value with "****"
+func RedactMask(value any) any {
+	return "****"
+}
+
+// RedactConstant returns a RedactFunc replacing every value with v
+func RedactConstant(v any) RedactFunc {
+	return func(any) any { return v }
+}
+
+// RedactMaskMiddle returns a RedactFunc keeping the first and last keep
+// characters and masking the rest. Values too short to keep anything
+// are masked entirely.
+func RedactMaskMiddle(keep int) RedactFunc {
+	return func(value any) any {
+		r := []rune(fmt.Sprint(value))
+		if len(r) <= 2*keep {
+			return strings.Repeat("*", len(r))
+		}
+		return string(r[:keep]) + strings.Repeat("*", len(r)-2*keep) + string(r[len(r)-keep:])
+	}
+}
+
+// RedactTruncate returns a RedactFunc keeping at most n characters
+func RedactTruncate(n int) RedactFunc {
+	return func(value any) any {
+		r := []rune(fmt.Sprint(value))
+		if len(r) <= n {
+			return string(r)
+		}
+		return string(r[:n])
+	}
+}
+
+// redact applies the configured redactors to a record in place and
+// re-encodes Raw, so the original values never reach the callback or
+// dead-letter handler. Unparseable records can't be redacted field by
+// field, so their Raw bytes are dropped.
+func redact(record *LogRecord, redactors map[string]RedactFunc) {
+	if record.ParseErr != nil {
+		record.Raw = nil
+		return
+	}
+
+	// Apply in a stable order in case redactors have side effects
+	names := make([]string, 0, len(redactors))
+	for name := range redactors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	e := &record.Entry
+	for _, name := range names {
+		value, ok := e.Field(name)
+		if !ok {
+			continue
+		}
+		if err := e.SetField(name, redactors[name](value)); err != nil {
+			e.DeleteField(name)
+		}
+	}
+
+	if raw, err := logger.Marshal(*e); err == nil {
+		record.Raw = raw
+	} else {
+		record.Raw = nil
+	}
+}
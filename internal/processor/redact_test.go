@@ -0,0 +1,74 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRedact verifies hashed and masked values replace the originals
+// everywhere downstream, including Raw and dead letters
+func TestRedact(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"level":"INFO","service":"svc","message":"card 4111111111111111 charged","user_id":"user-1234","email":"a@example.com"}`,
+		`{"level":"ERROR","service":"svc","message":"secret failure","user_id":"user-1234"}`,
+	)
+	cfg.Redact = map[string]RedactFunc{
+		"user_id":     RedactHash,
+		"message":     RedactMaskMiddle(4),
+		"email":       RedactMask,
+		"duration_ms": RedactMask, // Unsuitable value clears the field
+	}
+
+	var mu sync.Mutex
+	var seen []string
+	record := func(r *LogRecord) {
+		mu.Lock()
+		seen = append(seen, string(r.Raw), r.Entry.FormatJSON(), r.Entry.Message, r.Entry.UserID)
+		mu.Unlock()
+	}
+	cfg.DeadLetter = func(r *LogRecord, err error) { record(r) }
+
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		record(r)
+		if r.Entry.Level == "ERROR" {
+			return errors.New("rejected")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	downstream := strings.Join(seen, "\n")
+	for _, secret := range []string{"user-1234", "4111111111111111", "a@example.com", "secret"} {
+		if strings.Contains(downstream, secret) {
+			t.Fatalf("original value %q leaked downstream:\n%s", secret, downstream)
+		}
+	}
+
+	sum := sha256.Sum256([]byte("user-1234"))
+	if !strings.Contains(downstream, hex.EncodeToString(sum[:])) {
+		t.Fatal("hashed user_id not found downstream")
+	}
+	if !strings.Contains(downstream, "card*********************rged") || !strings.Contains(downstream, `"email":"****"`) {
+		t.Fatalf("masked values not found downstream:\n%s", downstream)
+	}
+}
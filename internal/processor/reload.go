@@ -0,0 +1,108 @@
+package processor
+
+import (
+	"reflect"
+	"slices"
+)
+
+// reloadable are the Config fields Reload applies to a running
+// processor; changes to any other field need a restart
+var reloadable = map[string]bool{
+	"WorkerCount":  true,
+	"ScanInterval": true,
+	"ScanJitter":   true,
+}
+
+// Reload applies cfg to the processor, which may be running: workers
+// are added or retired to reach WorkerCount, and a changed ScanInterval
+// or ScanJitter reschedules the next scan. Retired workers finish the
+// segment they hold first. Other fields that differ from the current
+// configuration are left as they are and returned by name, so callers
+// can report them. Fields holding funcs or interfaces can't be compared
+// and are never reported.
+func (p *Processor) Reload(cfg Config) (ignored []string, err error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	current := p.live.Load()
+	ignored = changedFields(*current, cfg)
+
+	next := *current
+	next.WorkerCount = cfg.WorkerCount
+	next.ScanInterval = cfg.ScanInterval
+	next.ScanJitter = cfg.ScanJitter
+	p.live.Store(&next)
+
+	if err := p.scaleWorkers(cfg.WorkerCount); err != nil {
+		return ignored, err
+	}
+	if next.ScanInterval != current.ScanInterval || next.ScanJitter != current.ScanJitter {
+		select {
+		case p.rescan <- struct{}{}:
+		default: // One is already pending
+		}
+	}
+
+	p.log.Info("configuration reloaded",
+		"workers", next.WorkerCount, "scan_interval", next.ScanInterval,
+		"scan_jitter", next.ScanJitter, "ignored", ignored)
+	return ignored, nil
+}
+
+// changedFields returns the names of the fields other than reloadable
+// ones that differ between a and b
+func changedFields(a, b Config) []string {
+	var changed []string
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	for i := 0; i < va.NumField(); i++ {
+		field := va.Type().Field(i)
+		if reloadable[field.Name] {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.Func, reflect.Interface, reflect.Chan:
+			continue
+		}
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changed = append(changed, field.Name)
+		}
+	}
+	return changed
+}
+
+// scaleWorkers adds or retires workers until there are n. While the
+// processor is running, added workers are started at once.
+func (p *Processor) scaleWorkers(n int) error {
+	p.workersMu.Lock()
+	defer p.workersMu.Unlock()
+
+	running := p.running.Load()
+	for len(p.workers) < n {
+		w := p.newWorker()
+		if running {
+			if err := w.start(); err != nil {
+				return err
+			}
+			p.started = append(p.started, w)
+			p.group.Go(w.run)
+		}
+		p.workers = append(p.workers, w)
+	}
+
+	// Newest first; those of a running processor stay in started so
+	// their outputs are closed on Stop
+	for len(p.workers) > n {
+		w := p.workers[len(p.workers)-1]
+		p.workers = slices.Delete(p.workers, len(p.workers)-1, len(p.workers))
+		close(w.retire)
+	}
+	return nil
+}
+
+// newWorker creates a worker with the next unused ID
+func (p *Processor) newWorker() *worker {
+	w := &worker{id: p.nextWorkerID, processor: p, retire: make(chan struct{})}
+	p.nextWorkerID++
+	return w
+}
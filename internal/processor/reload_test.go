@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestReloadWorkerCount verifies a running processor scales its workers
+// to a reloaded WorkerCount and keeps processing, and that changes that
+// need a restart are reported as ignored
+func TestReloadWorkerCount(t *testing.T) {
+	cfg := newTestConfig(t, 2)
+	var processed atomic.Int64
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		processed.Add(1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	segment := 0
+	writeAndWait := func() {
+		t.Helper()
+		segment++
+		writeSegment(t, cfg.LogsDir, fmt.Sprintf("app.log.20260101-%02d0000", segment), `{"level":"INFO","message":"m"}`)
+		waitFor(t, 2*time.Second, func() bool {
+			_, _, stats := proc.Stats()
+			return processed.Load() == int64(segment) && stats[3] == segment
+		})
+	}
+
+	writeAndWait()
+
+	cfg.WorkerCount = 4
+	cfg.ScanInterval = 20 * time.Millisecond
+	ignored, err := proc.Reload(cfg)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(ignored) != 0 {
+		t.Fatalf("ignored %q, want nothing", ignored)
+	}
+	if got := proc.Assignments(); !reflect.DeepEqual(got, map[int]string{0: "", 1: "", 2: "", 3: ""}) {
+		t.Fatalf("assignments after scaling up = %v", got)
+	}
+	writeAndWait()
+
+	cfg.WorkerCount = 1
+	cfg.LogPattern = "other.log"
+	ignored, err = proc.Reload(cfg)
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if !reflect.DeepEqual(ignored, []string{"LogPattern"}) {
+		t.Fatalf("ignored %q, want [LogPattern]", ignored)
+	}
+	if got := proc.Assignments(); !reflect.DeepEqual(got, map[int]string{0: ""}) {
+		t.Fatalf("assignments after scaling down = %v", got)
+	}
+	writeAndWait()
+
+	cfg.WorkerCount = 0
+	if _, err := proc.Reload(cfg); err == nil {
+		t.Fatal("expected an invalid configuration to be rejected")
+	}
+}
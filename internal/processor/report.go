@@ -0,0 +1,172 @@
+package processor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	json "github.com/goccy/go-json"
+)
+
+// reportSuffix ends the names of run report files in OffsetsDir
+const reportSuffix = ".report.json"
+
+// RunReport records what one run of the processor did, from Start to
+// Stop. Reports are written to OffsetsDir as run-<run ID>.report.json
+// when Config.RunReports is set; run IDs begin with the start time.
+type RunReport struct {
+	RunID string    `json:"run_id"`
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+
+	// Counts for this run only
+	Processed        int64 `json:"processed"`
+	Errors           int64 `json:"errors"`
+	CommitErrors     int64 `json:"commit_errors"`
+	SchemaViolations int64 `json:"schema_violations"`
+	CorruptRegions   int64 `json:"corrupt_regions"`
+
+	// Segment counts by state at the end of the run
+	SegmentsTotal    int `json:"segments_total"`
+	SegmentsPending  int `json:"segments_pending"`
+	SegmentsComplete int `json:"segments_complete"`
+
+	// SegmentsFailed lists the segments whose retries ran out (see
+	// Config.MaxSegmentRetries)
+	SegmentsFailed []string `json:"segments_failed,omitempty"`
+
+	// Segments lists the segments whose offsets the run committed
+	Segments []ReportSegment `json:"segments"`
+
+	// TimedOut is set if Stop gave up waiting for workers, so the
+	// offsets may have moved on after the report was written
+	TimedOut bool `json:"timed_out,omitempty"`
+}
+
+// ReportSegment is a segment's progress during a run
+type ReportSegment struct {
+	Segment      string `json:"segment"`
+	OffsetBefore int64  `json:"offset_before"`
+	OffsetAfter  int64  `json:"offset_after"`
+	Lines        int64  `json:"lines_processed"`
+	Complete     bool   `json:"complete"`
+}
+
+// runState is the snapshot taken at Start that a report is measured from
+type runState struct {
+	id      string
+	start   time.Time
+	offsets map[string]OffsetData
+	counts  [5]int64
+}
+
+// runCounts returns the counters a report takes the difference of
+func (p *Processor) runCounts() [5]int64 {
+	return [5]int64{
+		p.processed.Load(), p.errors.Load(), p.commitErrors.Load(),
+		p.schemaViolations.Load(), p.corruptRegions.Load(),
+	}
+}
+
+// beginRun snapshots offsets and counters for this run's report
+func (p *Processor) beginRun() {
+	if p.cfg.RunReports <= 0 {
+		return
+	}
+	p.run = p.snapshotRun()
+}
+
+// snapshotRun captures the state a report is measured from
+func (p *Processor) snapshotRun() *runState {
+	id := make([]byte, 4)
+	rand.Read(id)
+	now := time.Now().UTC()
+	return &runState{
+		id:      now.Format("20060102T150405.000Z") + "-" + hex.EncodeToString(id),
+		start:   now,
+		offsets: p.offsetMgr.GetAllOffsets(),
+		counts:  p.runCounts(),
+	}
+}
+
+// endRun writes the report of the run and prunes old ones. Failures are
+// logged rather than failing Stop.
+func (p *Processor) endRun(timedOut bool) {
+	if p.run == nil {
+		return
+	}
+	report := p.buildReport(p.run, timedOut)
+	p.run = nil
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err == nil {
+		name := filepath.Join(p.cfg.OffsetsDir, "run-"+report.RunID+reportSuffix)
+		err = writeFileAtomic(name, data, 0644)
+	}
+	if err != nil {
+		p.log.Warn("run report not written", "run", report.RunID, "error", err)
+		return
+	}
+	p.pruneReports()
+}
+
+// buildReport compares the offsets and counters now with those at Start
+func (p *Processor) buildReport(run *runState, timedOut bool) RunReport {
+	counts := p.runCounts()
+	total, pending, _, complete := p.segmentMgr.GetStats()
+	report := RunReport{
+		RunID:            run.id,
+		Start:            run.start,
+		End:              time.Now().UTC(),
+		Processed:        counts[0] - run.counts[0],
+		Errors:           counts[1] - run.counts[1],
+		CommitErrors:     counts[2] - run.counts[2],
+		SchemaViolations: counts[3] - run.counts[3],
+		CorruptRegions:   counts[4] - run.counts[4],
+		SegmentsTotal:    total,
+		SegmentsPending:  pending,
+		SegmentsComplete: complete,
+		SegmentsFailed:   p.segmentMgr.FailedSegments(),
+		Segments:         []ReportSegment{},
+		TimedOut:         timedOut,
+	}
+
+	for name, after := range p.offsetMgr.GetAllOffsets() {
+		before, seen := run.offsets[name]
+		if seen && before.LastUpdated.Equal(after.LastUpdated) {
+			continue // Not committed this run
+		}
+		entry := ReportSegment{
+			Segment:      name,
+			OffsetBefore: before.Offset,
+			OffsetAfter:  after.Offset,
+			Lines:        after.LinesProcessed,
+		}
+		if seg := p.segmentMgr.GetSegment(name); seg != nil {
+			entry.Complete = seg.State == SegmentComplete
+		}
+		report.Segments = append(report.Segments, entry)
+	}
+	sort.Slice(report.Segments, func(i, j int) bool {
+		return report.Segments[i].Segment < report.Segments[j].Segment
+	})
+	return report
+}
+
+// pruneReports deletes the oldest reports beyond RunReports
+func (p *Processor) pruneReports() {
+	files, err := globDir(p.cfg.OffsetsDir, "run-*"+reportSuffix)
+	if err != nil {
+		return
+	}
+	sort.Strings(files) // Run IDs begin with the start time
+	for len(files) > p.cfg.RunReports {
+		if err := os.Remove(files[0]); err != nil && !os.IsNotExist(err) {
+			p.log.Warn("old run report not removed", "path", files[0], "error", err)
+		}
+		files = files[1:]
+	}
+}
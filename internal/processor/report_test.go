@@ -0,0 +1,97 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	json "github.com/goccy/go-json"
+)
+
+// TestRunReport verifies each run writes a report of the segments it
+// committed, and that old reports are pruned
+func TestRunReport(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.RunReports = 2
+	path := writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", `{"message":"a"}`, `{"message":"b"}`)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000001", `{"message":"c"}`)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := func() {
+		t.Helper()
+		proc, err := NewProcessor(cfg, func(r *LogRecord) error { return nil })
+		if err != nil {
+			t.Fatalf("NewProcessor: %v", err)
+		}
+		if err := proc.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := proc.WaitForIdle(ctx); err != nil {
+			t.Fatalf("WaitForIdle: %v", err)
+		}
+		if err := proc.Stop(); err != nil {
+			t.Fatalf("Stop: %v", err)
+		}
+	}
+	reports := func() []RunReport {
+		t.Helper()
+		files, err := filepath.Glob(filepath.Join(cfg.OffsetsDir, "run-*.report.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []RunReport
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var r RunReport
+			if err := json.Unmarshal(data, &r); err != nil {
+				t.Fatalf("%s: %v", file, err)
+			}
+			out = append(out, r)
+		}
+		return out
+	}
+
+	run()
+	got := reports()
+	if len(got) != 1 {
+		t.Fatalf("%d reports after one run, want 1", len(got))
+	}
+	r := got[0]
+	if r.RunID == "" || r.Processed != 3 || r.Errors != 0 || r.SegmentsComplete != 2 || r.End.Before(r.Start) {
+		t.Fatalf("report = %+v", r)
+	}
+	want := []ReportSegment{
+		{Segment: "app.log.20260101-000000", OffsetAfter: info.Size(), Lines: 2, Complete: true},
+		{Segment: "app.log.20260101-000001", OffsetAfter: 16, Lines: 1, Complete: true},
+	}
+	if len(r.Segments) != len(want) || r.Segments[0] != want[0] || r.Segments[1] != want[1] {
+		t.Fatalf("segments = %+v, want %+v", r.Segments, want)
+	}
+
+	// A run with nothing to do touches no segments; only two reports
+	// are kept
+	time.Sleep(time.Millisecond)
+	run()
+	time.Sleep(time.Millisecond)
+	run()
+	got = reports()
+	if len(got) != 2 {
+		t.Fatalf("%d reports after three runs, want 2", len(got))
+	}
+	if last := got[1]; len(last.Segments) != 0 || last.Processed != 0 {
+		t.Fatalf("idle run report = %+v", last)
+	}
+	if got[0].RunID == r.RunID {
+		t.Fatal("oldest report not pruned")
+	}
+}
@@ -0,0 +1,135 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeSchema writes a schema file and returns its path
+func writeSchema(t *testing.T, schema string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const testSchema = `{
+	"type": "object",
+	"required": ["timestamp", "level", "request_id"],
+	"properties": {
+		"timestamp": {"type": "string", "format": "date-time"},
+		"level": {"enum": ["DEBUG", "INFO", "WARNING", "ERROR", "FATAL"]}
+	}
+}`
+
+// TestSchemaViolations verifies records failing the schema are counted
+// and dead-lettered with a *SchemaError
+func TestSchemaViolations(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.SchemaPath = writeSchema(t, testSchema)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"timestamp":"2026-01-01T00:00:00Z","level":"INFO","message":"ok","request_id":"r1"}`,
+		`{"timestamp":"2026-01-01T00:00:01Z","level":"INFO","message":"no request id"}`,
+		`{"timestamp":"2026-01-01T00:00:02Z","level":"TRACE","message":"bad level","request_id":"r3"}`,
+	)
+
+	var mu sync.Mutex
+	var dead []string
+	cfg.DeadLetter = func(r *LogRecord, err error) {
+		var schemaErr *SchemaError
+		if !errors.As(err, &schemaErr) {
+			t.Errorf("dead letter error = %v, want a *SchemaError", err)
+		}
+		mu.Lock()
+		dead = append(dead, r.Entry.Message)
+		mu.Unlock()
+	}
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	processed, errs, _ := proc.Stats()
+	if processed != 1 || errs != 2 || proc.SchemaViolations() != 2 {
+		t.Fatalf("processed=%d errors=%d violations=%d, want 1, 2 and 2", processed, errs, proc.SchemaViolations())
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dead) != 2 || dead[0] != "no request id" || dead[1] != "bad level" {
+		t.Fatalf("dead letters = %q", dead)
+	}
+}
+
+// TestHaltOnSchemaViolation verifies the processor stops at the first
+// violation and leaves that record for the next run
+func TestHaltOnSchemaViolation(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.SchemaPath = writeSchema(t, testSchema)
+	cfg.HaltOnSchemaViolation = true
+	good := `{"timestamp":"2026-01-01T00:00:00Z","level":"INFO","message":"ok","request_id":"r1"}`
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		good,
+		`{"timestamp":"2026-01-01T00:00:01Z","level":"INFO","message":"no request id"}`,
+		good,
+	)
+
+	deadLettered := false
+	cfg.DeadLetter = func(*LogRecord, error) { deadLettered = true }
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	select {
+	case <-proc.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("processor did not halt")
+	}
+	proc.Stop()
+
+	processed, _, _ := proc.Stats()
+	if processed != 1 || proc.SchemaViolations() != 1 || deadLettered {
+		t.Fatalf("processed=%d violations=%d dead-lettered=%v, want 1, 1 and false",
+			processed, proc.SchemaViolations(), deadLettered)
+	}
+
+	offsets, err := NewOffsetManager(cfg.OffsetsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset, _ := offsets.GetOffset("app.log.20260101-000000"); offset != int64(len(good)+1) {
+		t.Fatalf("committed offset = %d, want %d (before the violation)", offset, len(good)+1)
+	}
+}
+
+// TestSchemaPathInvalid verifies a broken schema fails NewProcessor
+func TestSchemaPathInvalid(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.SchemaPath = writeSchema(t, `{"type": "strnig"}`)
+
+	if _, err := NewProcessor(cfg, func(*LogRecord) error { return nil }); err == nil {
+		t.Fatal("expected an error for an unknown type")
+	}
+}
@@ -1,11 +1,15 @@
 package processor
 
 import (
+	"bytes"
+	"fmt"
+	"io"
 	"os"
-	"path/filepath"
 	"sort"
-	"strings"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // SegmentState represents the processing state of a segment
@@ -15,100 +19,285 @@ const (
 	SegmentPending    SegmentState = iota // Ready for processing
 	SegmentProcessing                     // Being processed by a worker
 	SegmentComplete                       // Fully processed
+	SegmentFailed                         // Given up on after too many failed attempts
 )
 
 // Segment represents a log file segment
 type Segment struct {
-	Name     string       // Segment filename (e.g., "app.log.20260101-231106")
-	Path     string       // Full path to segment file
-	Size     int64        // File size in bytes
-	State    SegmentState // Current processing state
-	WorkerID int          // Assigned worker ID (-1 if unassigned)
+	Name     string         // Segment filename (e.g., "app.log.20260101-231106")
+	Path     string         // Source location (full path for files)
+	Size     int64          // File size in bytes
+	State    SegmentState   // Current processing state
+	WorkerID int            // Assigned worker ID (-1 if unassigned)
+	Stream   bool           // Read sequentially without persisted offsets
+	Resume   ResumeStrategy // How processing resumes after a restart
+	Ordinal  int64          // Discovery order this run, from 1
+
+	// RecordCount is the number of records in the segment as of the
+	// last SegmentManager.CountRecords, or 0 if it hasn't been counted
+	RecordCount int64
+
+	completeSeq uint64    // Completion order, for retention eviction
+	yieldSeq    uint64    // Order of the last YieldSegment, 0 if never yielded
+	retryAt     time.Time // Not handed out again before this time
+	failures    int       // Failed attempts this run
+	delivered   int64     // Records handed to the process func this run
+
+	parsed      atomic.Int64 // Lines parsed as log entries this run
+	parseFailed atomic.Int64 // Lines that failed to parse this run
+}
+
+// SegmentStats reports how well a segment's lines parsed
+type SegmentStats struct {
+	Parsed      int64
+	ParseFailed int64
+}
+
+// FailureRate returns the fraction of lines that failed to parse
+func (s SegmentStats) FailureRate() float64 {
+	total := s.Parsed + s.ParseFailed
+	if total == 0 {
+		return 0
+	}
+	return float64(s.ParseFailed) / float64(total)
+}
+
+// recordParse counts a record read from the segment
+func (seg *Segment) recordParse(record *LogRecord) {
+	if record.ParseErr != nil {
+		seg.parseFailed.Add(1)
+	} else {
+		seg.parsed.Add(1)
+	}
 }
 
 // SegmentManager manages log file segments
 type SegmentManager struct {
-	logsDir   string
-	pattern   string // Base log file pattern (e.g., "app.log")
-	segments  map[string]*Segment
-	offsetMgr *OffsetManager
-	mu        sync.RWMutex
+	source      SegmentSource
+	segments    map[string]*Segment
+	offsetMgr   *OffsetManager
+	maxComplete int  // Completed segments kept in memory (0 = unbounded)
+	maxRetries  int  // Retries of a failing segment (0 = unlimited)
+	ignoreEmpty bool // Skip zero-byte segments entirely
+	skip        func(SegmentInfo) bool
+	prioritize  func([]*Segment) []*Segment
+	onComplete  func(name string)
+	completions uint64        // Completion counter for eviction order
+	yields      uint64        // YieldSegment counter for requeue order
+	ordinals    int64         // Segments discovered so far
+	scans       uint64        // Successful scans so far
+	changed     chan struct{} // Closed (and replaced) on scans and completions
+	counts      map[string]recordCount
+	delimiter   byte // Record delimiter CountRecords splits on
+	mu          sync.RWMutex
+}
+
+// recordCount is a cached CountRecords result
+type recordCount struct {
+	fingerprint string // Size and head hash of the segment counted
+	records     int64
 }
 
-// NewSegmentManager creates a new segment manager
-func NewSegmentManager(logsDir, pattern string, offsetMgr *OffsetManager) *SegmentManager {
+// NewSegmentManager creates a new segment manager over a source
+func NewSegmentManager(source SegmentSource, offsetMgr *OffsetManager) *SegmentManager {
 	return &SegmentManager{
-		logsDir:   logsDir,
-		pattern:   pattern,
+		source:    source,
 		segments:  make(map[string]*Segment),
 		offsetMgr: offsetMgr,
+		counts:    make(map[string]recordCount),
+		delimiter: '\n',
 	}
 }
 
-// Scan discovers all available segments in the logs directory
-func (sm *SegmentManager) Scan() error {
+// SetMaxComplete bounds how many completed segments are kept in memory.
+// Older completions are evicted first; the offset store still records
+// them as complete, so they are not rediscovered as pending.
+func (sm *SegmentManager) SetMaxComplete(n int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.maxComplete = n
+	sm.evictCompleteLocked()
+	sm.notifyLocked()
+}
+
+// SetMaxRetries bounds how many times FailSegment lets a segment be
+// retried before marking it failed (0 = unlimited)
+func (sm *SegmentManager) SetMaxRetries(n int) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	// Find all rotated log files (pattern.TIMESTAMP format)
-	files, err := filepath.Glob(filepath.Join(sm.logsDir, sm.pattern+".*"))
+	sm.maxRetries = n
+}
+
+// notifyLocked wakes everyone waiting in idleState's channel. Callers
+// must hold sm.mu.
+func (sm *SegmentManager) notifyLocked() {
+	if sm.changed != nil {
+		close(sm.changed)
+		sm.changed = nil
+	}
+}
+
+// idleState reports whether no segment is pending or processing, how
+// many scans have completed, and a channel closed at the next scan or
+// completion
+func (sm *SegmentManager) idleState() (idle bool, scans uint64, changed <-chan struct{}) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	idle = true
+	for _, seg := range sm.segments {
+		if seg.State == SegmentPending || seg.State == SegmentProcessing {
+			idle = false
+			break
+		}
+	}
+
+	if sm.changed == nil {
+		sm.changed = make(chan struct{})
+	}
+	return idle, sm.scans, sm.changed
+}
+
+// SetIgnoreEmpty controls whether zero-byte segments are tracked. An
+// ignored segment is picked up by a later scan once it has data.
+func (sm *SegmentManager) SetIgnoreEmpty(ignore bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.ignoreEmpty = ignore
+}
+
+// SetDelimiter sets the record delimiter CountRecords splits segments
+// on ('\n' by default), which should match the reader's
+func (sm *SegmentManager) SetDelimiter(delim byte) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.delimiter = delim
+}
+
+// SetSkip installs a filter for segments that must not be tracked yet,
+// such as a rotated file whose contents are still being consumed
+func (sm *SegmentManager) SetSkip(skip func(SegmentInfo) bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.skip = skip
+}
+
+// SetPrioritize installs a function that reorders the segments
+// GetPendingSegments returns. It is called without the manager locked.
+func (sm *SegmentManager) SetPrioritize(fn func([]*Segment) []*Segment) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.prioritize = fn
+}
+
+// SetOnComplete installs a callback invoked with the name of each
+// segment that is marked complete or discovered already complete. It
+// runs with the manager locked, so it must not call back into it.
+func (sm *SegmentManager) SetOnComplete(fn func(name string)) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.onComplete = fn
+}
+
+// Scan discovers all segments currently available from the source
+func (sm *SegmentManager) Scan() error {
+	list := sm.source.List
+	if cl, ok := sm.source.(cachedLister); ok {
+		list = cl.listCached
+	}
+
+	infos, err := list()
 	if err != nil {
 		return err
 	}
 
-	for _, path := range files {
-		// Skip offset files
-		if strings.HasSuffix(path, ".offset.json") || strings.HasSuffix(path, ".tmp") {
-			continue
-		}
+	// New segments are numbered in name order
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
 
-		name := filepath.Base(path)
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.notifyLocked()
+	sm.scans++
 
+	for _, info := range infos {
 		// Skip if already tracked
-		if _, exists := sm.segments[name]; exists {
+		if _, exists := sm.segments[info.Name]; exists {
 			continue
 		}
 
-		info, err := os.Stat(path)
-		if err != nil {
+		if sm.ignoreEmpty && info.Size == 0 && !info.Stream {
+			continue
+		}
+
+		if sm.skip != nil && sm.skip(info) {
 			continue
 		}
 
 		// Determine state based on offset
 		state := SegmentPending
-		if sm.offsetMgr.IsComplete(name, info.Size()) {
+		if sm.offsetMgr.IsComplete(info.Name, info.Size) {
+			if sm.onComplete != nil {
+				sm.onComplete(info.Name)
+			}
+
+			// With bounded retention, finished segments (including
+			// evicted ones) are never tracked again
+			if sm.maxComplete > 0 {
+				continue
+			}
 			state = SegmentComplete
 		}
 
-		sm.segments[name] = &Segment{
-			Name:     name,
-			Path:     path,
-			Size:     info.Size(),
+		sm.ordinals++
+		sm.segments[info.Name] = &Segment{
+			Name:     info.Name,
+			Path:     info.Path,
+			Size:     info.Size,
 			State:    state,
 			WorkerID: -1,
+			Stream:   info.Stream,
+			Resume:   info.Resume,
+			Ordinal:  sm.ordinals,
 		}
 	}
 
 	return nil
 }
 
-// GetPendingSegments returns segments ready for processing
+// GetPendingSegments returns segments ready for processing, in the
+// order set by SetPrioritize if any. Segments deferred by DeferSegment
+// are left out until their retry time.
 func (sm *SegmentManager) GetPendingSegments() []*Segment {
 	sm.mu.RLock()
-	defer sm.mu.RUnlock()
-
+	now := time.Now()
 	var pending []*Segment
 	for _, seg := range sm.segments {
-		if seg.State == SegmentPending {
+		if seg.State == SegmentPending && !now.Before(seg.retryAt) {
 			pending = append(pending, seg)
 		}
 	}
 
-	// Sort by name (chronological order)
+	// Sort by name (chronological order), with yielded segments after
+	// the rest in the order they were yielded
 	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].yieldSeq != pending[j].yieldSeq {
+			return pending[i].yieldSeq < pending[j].yieldSeq
+		}
 		return pending[i].Name < pending[j].Name
 	})
 
+	prioritize := sm.prioritize
+	sm.mu.RUnlock()
+	if prioritize != nil && len(pending) > 0 {
+		pending = prioritize(pending)
+	}
 	return pending
 }
 
@@ -118,7 +307,7 @@ func (sm *SegmentManager) ClaimSegment(segmentName string, workerID int) bool {
 	defer sm.mu.Unlock()
 
 	seg, exists := sm.segments[segmentName]
-	if !exists || seg.State != SegmentPending {
+	if !exists || seg.State != SegmentPending || time.Now().Before(seg.retryAt) {
 		return false
 	}
 
@@ -135,6 +324,40 @@ func (sm *SegmentManager) MarkComplete(segmentName string) {
 	if seg, exists := sm.segments[segmentName]; exists {
 		seg.State = SegmentComplete
 		seg.WorkerID = -1
+		sm.completions++
+		seg.completeSeq = sm.completions
+
+		if sm.onComplete != nil && !seg.Stream {
+			sm.onComplete(segmentName)
+		}
+	}
+
+	sm.evictCompleteLocked()
+	sm.notifyLocked()
+}
+
+// evictCompleteLocked drops the oldest completed segments beyond the
+// retention limit. Callers must hold sm.mu.
+func (sm *SegmentManager) evictCompleteLocked() {
+	if sm.maxComplete <= 0 {
+		return
+	}
+
+	var complete []*Segment
+	for _, seg := range sm.segments {
+		if seg.State == SegmentComplete {
+			complete = append(complete, seg)
+		}
+	}
+	if len(complete) <= sm.maxComplete {
+		return
+	}
+
+	sort.Slice(complete, func(i, j int) bool {
+		return complete[i].completeSeq < complete[j].completeSeq
+	})
+	for _, seg := range complete[:len(complete)-sm.maxComplete] {
+		delete(sm.segments, seg.Name)
 	}
 }
 
@@ -149,6 +372,89 @@ func (sm *SegmentManager) ReleaseSegment(segmentName string) {
 	}
 }
 
+// YieldSegment releases a segment that isn't finished back to pending,
+// queued behind the other pending segments (see Config.SegmentDeadline)
+func (sm *SegmentManager) YieldSegment(segmentName string) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if seg, exists := sm.segments[segmentName]; exists {
+		sm.yields++
+		seg.State = SegmentPending
+		seg.WorkerID = -1
+		seg.yieldSeq = sm.yields
+	}
+}
+
+// DeferSegment releases a segment back to pending, but keeps it from
+// being handed out again until the given time (e.g. after a transient
+// failure)
+func (sm *SegmentManager) DeferSegment(segmentName string, until time.Time) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	if seg, exists := sm.segments[segmentName]; exists {
+		seg.State = SegmentPending
+		seg.WorkerID = -1
+		seg.retryAt = until
+	}
+}
+
+// FailSegment records a failed attempt at a segment and defers it like
+// DeferSegment. Once its retries are used up it is marked failed
+// instead, and is not handed out again this run; FailSegment then
+// reports true.
+func (sm *SegmentManager) FailSegment(segmentName string, until time.Time) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	seg, exists := sm.segments[segmentName]
+	if !exists {
+		return false
+	}
+	seg.WorkerID = -1
+	seg.failures++
+	if sm.maxRetries > 0 && seg.failures > sm.maxRetries {
+		seg.State = SegmentFailed
+		sm.notifyLocked()
+		return true
+	}
+	seg.State = SegmentPending
+	seg.retryAt = until
+	return false
+}
+
+// FailedSegments returns the names of segments given up on by
+// FailSegment, in order
+func (sm *SegmentManager) FailedSegments() []string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	var names []string
+	for name, seg := range sm.segments {
+		if seg.State == SegmentFailed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RemoveComplete stops tracking a segment if it is still complete and
+// reports whether it did. Untracked (e.g. evicted) segments count as
+// complete; a segment that was requeued in the meantime is kept.
+func (sm *SegmentManager) RemoveComplete(segmentName string) bool {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	seg, exists := sm.segments[segmentName]
+	if exists && seg.State != SegmentComplete {
+		return false
+	}
+	delete(sm.segments, segmentName)
+	return true
+}
+
 // GetSegment returns a segment by name
 func (sm *SegmentManager) GetSegment(name string) *Segment {
 	sm.mu.RLock()
@@ -157,6 +463,39 @@ func (sm *SegmentManager) GetSegment(name string) *Segment {
 	return sm.segments[name]
 }
 
+// Assignments returns the segment currently held by each busy worker
+func (sm *SegmentManager) Assignments() map[int]string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	result := make(map[int]string)
+	for _, seg := range sm.segments {
+		if seg.State == SegmentProcessing && seg.WorkerID >= 0 {
+			result[seg.WorkerID] = seg.Name
+		}
+	}
+	return result
+}
+
+// SegmentStats returns parse statistics for each tracked segment that
+// has been read during this run
+func (sm *SegmentManager) SegmentStats() map[string]SegmentStats {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	result := make(map[string]SegmentStats)
+	for name, seg := range sm.segments {
+		stats := SegmentStats{
+			Parsed:      seg.parsed.Load(),
+			ParseFailed: seg.parseFailed.Load(),
+		}
+		if stats.Parsed+stats.ParseFailed > 0 {
+			result[name] = stats
+		}
+	}
+	return result
+}
+
 // GetStats returns segment statistics
 func (sm *SegmentManager) GetStats() (total, pending, processing, complete int) {
 	sm.mu.RLock()
@@ -175,3 +514,125 @@ func (sm *SegmentManager) GetStats() (total, pending, processing, complete int)
 	}
 	return
 }
+
+// CountRecords returns the number of records in a segment, split and
+// skipped as the reader does: on the delimiter set with SetDelimiter,
+// not counting blank or whitespace-only lines, and counting a final
+// unterminated record. The count is cached against the segment's size
+// and a hash of its head, so it is only redone once the segment has
+// changed. The segment's RecordCount is updated if it is tracked.
+func (sm *SegmentManager) CountRecords(name string) (int64, error) {
+	fingerprint, err := sm.countFingerprint(name)
+	if err != nil {
+		return 0, err
+	}
+
+	sm.mu.RLock()
+	cached, ok := sm.counts[name]
+	delim := sm.delimiter
+	sm.mu.RUnlock()
+
+	records := cached.records
+	if !ok || cached.fingerprint != fingerprint {
+		if records, err = sm.countLines(name, delim); err != nil {
+			return 0, err
+		}
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.counts[name] = recordCount{fingerprint: fingerprint, records: records}
+	if seg, ok := sm.segments[name]; ok {
+		seg.RecordCount = records
+	}
+	return records, nil
+}
+
+// countFingerprint identifies a segment's content by its current size
+// and a hash of its head
+func (sm *SegmentManager) countFingerprint(name string) (string, error) {
+	info, ok, err := sm.statSegment(name)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("%s: %w", name, os.ErrNotExist)
+	}
+	if info.Stream {
+		return "", fmt.Errorf("%s: streams can't be counted without consuming them", name)
+	}
+	head, err := sourceFingerprint(sm.source)(name, fingerprintBytes)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatInt(info.Size, 10) + "/" + head, nil
+}
+
+// statSegment describes the named segment, asking the source for it
+// alone when it can and listing the source otherwise
+func (sm *SegmentManager) statSegment(name string) (SegmentInfo, bool, error) {
+	if s, ok := sm.source.(segmentStater); ok {
+		info, ok := s.statSegment(name)
+		return info, ok, nil
+	}
+
+	infos, err := sm.source.List()
+	if err != nil {
+		return SegmentInfo{}, false, err
+	}
+	for _, info := range infos {
+		if info.Name == name {
+			return info, true, nil
+		}
+	}
+	return SegmentInfo{}, false, nil
+}
+
+// countLines counts a segment's records with the reader's line
+// splitting, without parsing them
+func (sm *SegmentManager) countLines(name string, delim byte) (int64, error) {
+	rc, err := sm.source.Open(name, 0)
+	if err != nil {
+		return 0, err
+	}
+	reader := NewLogReaderFrom(rc, name, 0, ReaderOptions{Delimiter: delim})
+	defer reader.Close()
+
+	var records int64
+	for {
+		if _, err := reader.readLine(); err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return 0, err
+		}
+		records++
+	}
+}
+
+// countBufferSize is the read size CountSegments scans segments with
+const countBufferSize = 1 << 20
+
+// countDelimited counts the records in r ending with delim, plus a
+// final record without one, and the bytes read
+func countDelimited(r io.Reader, delim byte) (records, n int64, err error) {
+	buf := make([]byte, countBufferSize)
+	last := delim
+	for {
+		m, err := r.Read(buf)
+		if m > 0 {
+			records += int64(bytes.Count(buf[:m], []byte{delim}))
+			last = buf[m-1]
+			n += int64(m)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if last != delim {
+		records++
+	}
+	return records, n, nil
+}
@@ -0,0 +1,253 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSegmentRetentionBounded verifies completed segments are evicted
+// while rotation continues, without being rediscovered as pending
+func TestSegmentRetentionBounded(t *testing.T) {
+	offsetMgr, err := NewOffsetManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := NewMemorySource()
+	sm := NewSegmentManager(src, offsetMgr)
+	sm.SetMaxComplete(3)
+
+	data := []byte(`{"message":"x"}` + "\n")
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("app.log.%04d", i)
+		src.Put(name, data)
+
+		if err := sm.Scan(); err != nil {
+			t.Fatalf("Scan: %v", err)
+		}
+
+		pending := sm.GetPendingSegments()
+		if len(pending) != 1 || pending[0].Name != name {
+			t.Fatalf("iteration %d: expected only %s pending, got %d segments", i, name, len(pending))
+		}
+
+		if !sm.ClaimSegment(name, 0) {
+			t.Fatalf("failed to claim %s", name)
+		}
+		if err := offsetMgr.CommitOffset(name, int64(len(data)), 1); err != nil {
+			t.Fatal(err)
+		}
+		sm.MarkComplete(name)
+
+		if total, _, _, _ := sm.GetStats(); total > 3 {
+			t.Fatalf("iteration %d: %d segments tracked, want <= 3", i, total)
+		}
+	}
+
+	// The most recent completions are the ones retained
+	if sm.GetSegment("app.log.0019") == nil || sm.GetSegment("app.log.0000") != nil {
+		t.Fatal("expected oldest completions to be evicted first")
+	}
+}
+
+// TestSegmentIgnoreEmpty verifies zero-byte segments are skipped until they have data
+func TestSegmentIgnoreEmpty(t *testing.T) {
+	offsetMgr, err := NewOffsetManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := NewMemorySource()
+	src.Put("app.log.1", nil)
+	src.Put("app.log.2", []byte(`{"message":"x"}`+"\n"))
+
+	sm := NewSegmentManager(src, offsetMgr)
+	if err := sm.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if total, _, _, _ := sm.GetStats(); total != 2 {
+		t.Fatalf("default: %d segments tracked, want 2", total)
+	}
+
+	sm = NewSegmentManager(src, offsetMgr)
+	sm.SetIgnoreEmpty(true)
+	if err := sm.Scan(); err != nil {
+		t.Fatal(err)
+	}
+	if total, _, _, _ := sm.GetStats(); total != 1 || sm.GetSegment("app.log.1") != nil {
+		t.Fatalf("ignore empty: %d segments tracked, want only app.log.2", total)
+	}
+}
+
+// TestSegmentParseStats verifies per-segment parse failure rates
+func TestSegmentParseStats(t *testing.T) {
+	cfg := newTestConfig(t, 2)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000",
+		`{"message":"a"}`, `not json`, `{"message":"b"}`, `{"message":`,
+	)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000001",
+		`{"message":"c"}`, `{"message":"d"}`,
+	)
+
+	var parseErrs atomic.Int64
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		if r.ParseErr != nil {
+			parseErrs.Add(1)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 2
+	})
+
+	stats := proc.SegmentStats()
+	bad := stats["app.log.20260101-000000"]
+	if bad.Parsed != 2 || bad.ParseFailed != 2 || bad.FailureRate() != 0.5 {
+		t.Fatalf("mixed segment stats = %+v (rate %v)", bad, bad.FailureRate())
+	}
+	good := stats["app.log.20260101-000001"]
+	if good.Parsed != 2 || good.ParseFailed != 0 || good.FailureRate() != 0 {
+		t.Fatalf("clean segment stats = %+v", good)
+	}
+	if parseErrs.Load() != 2 {
+		t.Fatalf("callback saw %d parse errors, want 2", parseErrs.Load())
+	}
+}
+
+// readCountingSource counts the bytes read from its segments
+type readCountingSource struct {
+	SegmentSource
+	read atomic.Int64
+}
+
+func (s *readCountingSource) Open(name string, offset int64) (io.ReadCloser, error) {
+	rc, err := s.SegmentSource.Open(name, offset)
+	if err != nil {
+		return nil, err
+	}
+	return &countingReadCloser{ReadCloser: rc, n: &s.read}, nil
+}
+
+type countingReadCloser struct {
+	io.ReadCloser
+	n *atomic.Int64
+}
+
+func (c *countingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// TestCountRecords verifies records are counted, the count is reused
+// while the segment is unchanged and redone once it grows
+func TestCountRecords(t *testing.T) {
+	offsetMgr, err := NewOffsetManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := NewMemorySource()
+	src := &readCountingSource{SegmentSource: mem}
+	sm := NewSegmentManager(src, offsetMgr)
+
+	const name = "app.log.20260101-000000"
+	var data []byte
+	for i := 0; i < 999; i++ {
+		data = append(data, fmt.Sprintf(`{"message":"record %d"}`+"\n", i)...)
+	}
+	data = append(data, `{"message":"unterminated"}`...)
+	mem.Put(name, data)
+	if err := sm.Scan(); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := sm.CountRecords(name)
+	if err != nil || n != 1000 {
+		t.Fatalf("CountRecords = %d, %v; want 1000", n, err)
+	}
+	if got := sm.GetSegment(name).RecordCount; got != 1000 {
+		t.Fatalf("RecordCount = %d, want 1000", got)
+	}
+
+	// Unchanged: only the head is read to check the fingerprint
+	src.read.Store(0)
+	if n, err := sm.CountRecords(name); err != nil || n != 1000 {
+		t.Fatalf("second CountRecords = %d, %v; want 1000", n, err)
+	}
+	if read := src.read.Load(); read > fingerprintBytes {
+		t.Fatalf("second CountRecords read %d bytes, want the cached count reused", read)
+	}
+
+	mem.Put(name, append(data, "\n{\"message\":\"appended\"}\n"...))
+	if n, err := sm.CountRecords(name); err != nil || n != 1001 {
+		t.Fatalf("CountRecords after growth = %d, %v; want 1001", n, err)
+	}
+
+	if _, err := sm.CountRecords("missing"); err == nil {
+		t.Fatal("expected an error counting a missing segment")
+	}
+}
+
+// listlessSource fails any listing, so a caller must resolve segments
+// directly
+type listlessSource struct{ *MemorySource }
+
+func (listlessSource) List() ([]SegmentInfo, error) {
+	return nil, errors.New("listing the whole source")
+}
+
+// TestCountRecordsAsRead verifies records are counted as the reader
+// splits them, with a custom delimiter and blank lines skipped, and
+// without listing the source
+func TestCountRecordsAsRead(t *testing.T) {
+	offsetMgr, err := NewOffsetManager(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := NewMemorySource()
+	sm := NewSegmentManager(listlessSource{mem}, offsetMgr)
+	sm.SetDelimiter(';')
+
+	const name = "app.log.20260101-000000"
+	data := "\xEF\xBB\xBF" + `{"message":"a\nb"};;  ;` + "\n;" + `{"message":"c"};{"message":"d"}`
+	mem.Put(name, []byte(data))
+
+	var read []string
+	reader := NewLogReaderFrom(io.NopCloser(strings.NewReader(data)), name, 0, ReaderOptions{Delimiter: ';'})
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		read = append(read, record.Entry.Message)
+	}
+	if len(read) != 3 {
+		t.Fatalf("reader returned %q, want 3 records", read)
+	}
+
+	if n, err := sm.CountRecords(name); err != nil || n != int64(len(read)) {
+		t.Fatalf("CountRecords = %d, %v; want the %d records read", n, err, len(read))
+	}
+	if _, err := sm.CountRecords("app.log.20260102-000000"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("counting a missing segment: %v, want ErrNotExist", err)
+	}
+}
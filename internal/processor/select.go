@@ -0,0 +1,31 @@
+package processor
+
+import "path/filepath"
+
+// skipSegment reports whether Scan must leave a segment untracked: it
+// was rotated before TimeFrom, or ExcludeSegments and IncludeSegments
+// rule it out
+func (c Config) skipSegment(info SegmentInfo) bool {
+	return c.beforeWindow(info) || !c.selectsSegment(info.Name)
+}
+
+// selectsSegment reports whether a segment passes ExcludeSegments and
+// IncludeSegments, matched against its base name
+func (c Config) selectsSegment(name string) bool {
+	base := filepath.Base(name)
+	if matchesAny(c.ExcludeSegments, base) {
+		return false
+	}
+	return len(c.IncludeSegments) == 0 || matchesAny(c.IncludeSegments, base)
+}
+
+// matchesAny reports whether name matches one of the glob patterns.
+// Patterns are checked by Config.Validate.
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package processor
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSegmentSelection verifies excluded segments are never tracked or
+// processed, and that IncludeSegments limits tracking to its matches
+func TestSegmentSelection(t *testing.T) {
+	segments := []string{
+		"app.log.20260101-000000",
+		"app.log.20260101-010000",
+		"app.log.20260102-000000",
+		"app.log.20260102-010000",
+	}
+
+	tests := []struct {
+		name    string
+		exclude []string
+		include []string
+		want    []string
+	}{
+		{
+			name:    "exclude",
+			exclude: []string{"app.log.20260101-010000", "*20260102-01*"},
+			want:    []string{"app.log.20260101-000000", "app.log.20260102-000000"},
+		},
+		{
+			name:    "include",
+			include: []string{"app.log.20260102-*"},
+			want:    []string{"app.log.20260102-000000", "app.log.20260102-010000"},
+		},
+		{
+			name:    "exclude wins",
+			exclude: []string{"app.log.20260102-000000"},
+			include: []string{"app.log.20260102-*"},
+			want:    []string{"app.log.20260102-010000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, 2)
+			cfg.ExcludeSegments = tt.exclude
+			cfg.IncludeSegments = tt.include
+			for _, name := range segments {
+				writeSegment(t, cfg.LogsDir, name, `{"level":"INFO","message":"`+name+`"}`)
+			}
+
+			var mu sync.Mutex
+			var seen []string
+			proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+				mu.Lock()
+				seen = append(seen, r.Entry.Message)
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("NewProcessor: %v", err)
+			}
+			if err := proc.Start(context.Background()); err != nil {
+				t.Fatalf("Start: %v", err)
+			}
+			defer proc.Stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			if err := proc.WaitForIdle(ctx); err != nil {
+				t.Fatalf("WaitForIdle: %v", err)
+			}
+
+			_, _, segStats := proc.Stats()
+			if segStats[0] != len(tt.want) || segStats[3] != len(tt.want) {
+				t.Fatalf("tracked %d, complete %d; want %d of each", segStats[0], segStats[3], len(tt.want))
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			sort.Strings(seen)
+			if len(seen) != len(tt.want) {
+				t.Fatalf("processed %q, want %q", seen, tt.want)
+			}
+			for i := range seen {
+				if seen[i] != tt.want[i] {
+					t.Fatalf("processed %q, want %q", seen, tt.want)
+				}
+			}
+		})
+	}
+
+	cfg := newTestConfig(t, 1)
+	cfg.ExcludeSegments = []string{"app.log.["}
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected a malformed pattern to fail validation")
+	}
+}
@@ -0,0 +1,452 @@
+package processor
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"log-processor/internal/rotation"
+)
+
+// SegmentInfo describes a segment as reported by a SegmentSource
+type SegmentInfo struct {
+	Name string // Segment name, unique within the source
+	Path string // Source-specific location (file path, URL, key)
+	Size int64  // Current size in bytes
+
+	// Stream marks a segment without stable offsets, such as a named
+	// pipe. It is read sequentially from the start and its offset is
+	// never persisted.
+	Stream bool
+
+	// Resume is how processing of the segment resumes after a restart
+	Resume ResumeStrategy
+}
+
+// ResumeStrategy selects how a partially processed segment is resumed
+type ResumeStrategy int
+
+const (
+	// ResumeSeek reopens the segment at the committed byte offset
+	ResumeSeek ResumeStrategy = iota
+
+	// ResumeSkipLines reopens the segment from the start and skips the
+	// committed number of lines, for segments such as gzip files whose
+	// byte offsets can't be seeked to
+	ResumeSkipLines
+)
+
+// SegmentSource lists and opens log segments. The processor only talks
+// to segments through this interface, so segments can live on local
+// disk, in object storage, or in memory.
+type SegmentSource interface {
+	// List returns all segments currently available
+	List() ([]SegmentInfo, error)
+
+	// Open returns a reader positioned at offset within the named segment
+	Open(name string, offset int64) (io.ReadCloser, error)
+}
+
+// SegmentRemover is implemented by sources that can delete segments,
+// as required by Config.DeleteAfterComplete
+type SegmentRemover interface {
+	// Remove deletes the named segment. Removing a segment that no
+	// longer exists is not an error.
+	Remove(name string) error
+}
+
+// dirMtimeSlack is how much older than a listing the directory's mtime
+// must be before it is trusted to reveal later changes. Filesystems
+// with coarse timestamps can otherwise hide a file created in the same
+// tick as the listing.
+const dirMtimeSlack = 2 * time.Second
+
+// FileSource serves rotated segments (pattern.*) from a local directory
+type FileSource struct {
+	dir     string
+	pattern string
+	glob    string // Matches rotated names of pattern
+
+	mu     sync.Mutex    // Guards the listCached state below
+	cache  []SegmentInfo // Segments from the last cached listing
+	dirMod time.Time     // Directory mtime at the last cached listing
+	listed time.Time     // When the directory was last read
+
+	log      *slog.Logger // Set by the processor; nil logs nothing
+	brokenMu sync.Mutex
+	broken   map[string]bool // Symlinks already warned about as broken
+
+	exact bool // Serves one named file, which may still be growing
+}
+
+// cachedLister is implemented by sources that can list cheaply from a
+// cache at the cost of possibly stale sizes for segments listed before.
+// The segment manager only uses listings to discover new segments, so
+// its scans accept that.
+type cachedLister interface {
+	listCached() ([]SegmentInfo, error)
+}
+
+// segmentStater is implemented by sources that can describe one segment
+// without listing them all
+type segmentStater interface {
+	statSegment(name string) (SegmentInfo, bool)
+}
+
+// NewFileSource creates a source for rotated files of pattern in dir
+func NewFileSource(dir, pattern string) *FileSource {
+	return NewSchemeFileSource(dir, pattern, rotation.Default())
+}
+
+// NewSchemeFileSource creates a source for files of pattern rotated
+// under a custom naming scheme
+func NewSchemeFileSource(dir, pattern string, scheme rotation.Scheme) *FileSource {
+	return &FileSource{
+		dir:     dir,
+		pattern: pattern,
+		glob:    scheme.Glob(pattern),
+	}
+}
+
+// NewExactFileSource creates a source whose only segment is the file at
+// path, named by its base name, for processing one specific file such
+// as an active log with no rotated siblings
+func NewExactFileSource(path string) *FileSource {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	// Match the name literally. Metacharacters are bracketed, which
+	// works without backslash escapes (unsupported on Windows, where a
+	// backslash can't occur in a name anyway).
+	var glob strings.Builder
+	for _, r := range name {
+		switch r {
+		case '*', '?', '[':
+			glob.WriteString("[" + string(r) + "]")
+		case '\\':
+			glob.WriteString(`\\`)
+		default:
+			glob.WriteRune(r)
+		}
+	}
+
+	return &FileSource{
+		dir:     filepath.Clean(dir),
+		pattern: name,
+		glob:    glob.String(),
+		exact:   true,
+	}
+}
+
+// List discovers rotated log files (pattern.TIMESTAMP format by default)
+func (fs *FileSource) List() ([]SegmentInfo, error) {
+	files, err := globDir(fs.dir, fs.glob)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []SegmentInfo
+	for _, path := range files {
+		if info, ok := fs.stat(filepath.Base(path)); ok {
+			infos = append(infos, info)
+		}
+	}
+
+	return fs.dedupe(infos), nil
+}
+
+// listCached is List for periodic scans of large backlogs. While the
+// directory's mtime is unchanged its entries are not re-read, and
+// segments already listed are not re-stat'd unless they were empty or
+// streams, so their sizes may be stale if they grew in place.
+func (fs *FileSource) listCached() ([]SegmentInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	dirInfo, err := os.Stat(fs.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// Creating, removing or renaming a file updates the directory's
+	// mtime, so an unchanged one means the same set of names
+	if fs.cache != nil && dirInfo.ModTime().Equal(fs.dirMod) && fs.listed.Sub(fs.dirMod) > dirMtimeSlack {
+		infos := make([]SegmentInfo, 0, len(fs.cache))
+		for _, info := range fs.cache {
+			if info.Size == 0 || info.Stream {
+				var ok bool
+				if info, ok = fs.stat(info.Name); !ok {
+					continue
+				}
+			}
+			infos = append(infos, info)
+		}
+		fs.cache = infos
+		return infos, nil
+	}
+
+	fs.listed = time.Now()
+	files, err := globDir(fs.dir, fs.glob)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]SegmentInfo, len(fs.cache))
+	for _, info := range fs.cache {
+		known[info.Name] = info
+	}
+
+	var infos []SegmentInfo
+	for _, path := range files {
+		name := filepath.Base(path)
+		info, ok := known[name]
+		if !ok || info.Size == 0 || info.Stream {
+			if info, ok = fs.stat(name); !ok {
+				continue
+			}
+		}
+		infos = append(infos, info)
+	}
+
+	infos = fs.dedupe(infos)
+	fs.cache = infos
+	fs.dirMod = dirInfo.ModTime()
+	return infos, nil
+}
+
+// stat describes a rotated file, reporting false for files that are not
+// segments (offset, index and temp files, directories) or are gone.
+// Symlinks are followed; broken ones, including loops, are skipped with
+// a warning.
+func (fs *FileSource) stat(name string) (SegmentInfo, bool) {
+	if strings.HasSuffix(name, ".offset.json") || strings.HasSuffix(name, ".index.json") ||
+		strings.HasSuffix(name, ".tmp") {
+		return SegmentInfo{}, false
+	}
+
+	path := filepath.Join(fs.dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		if link, lerr := os.Lstat(path); lerr == nil && link.Mode()&os.ModeSymlink != 0 {
+			fs.warnBroken(name, err)
+		}
+		return SegmentInfo{}, false
+	}
+	if info.IsDir() {
+		return SegmentInfo{}, false
+	}
+
+	return SegmentInfo{
+		Name:   name,
+		Path:   path,
+		Size:   info.Size(),
+		Stream: isStream(info),
+		Resume: resumeStrategy(name),
+	}, true
+}
+
+// statSegment describes the named segment as List would, without
+// listing the directory. Unlike List it doesn't drop a symlink to the
+// file of another segment.
+func (fs *FileSource) statSegment(name string) (SegmentInfo, bool) {
+	if matched, err := filepath.Match(fs.glob, name); err != nil || !matched || filepath.Base(name) != name {
+		return SegmentInfo{}, false
+	}
+	return fs.stat(name)
+}
+
+// warnBroken logs a symlink that can't be resolved, once per name
+func (fs *FileSource) warnBroken(name string, err error) {
+	fs.brokenMu.Lock()
+	defer fs.brokenMu.Unlock()
+	if fs.broken[name] || fs.log == nil {
+		return
+	}
+	if fs.broken == nil {
+		fs.broken = make(map[string]bool)
+	}
+	fs.broken[name] = true
+	fs.log.Warn("broken symlink skipped", "segment", name, "error", err)
+}
+
+// dedupe drops segments that are symlinks to the file of another one,
+// so a file isn't processed once per name. The file's own name is kept,
+// or if it isn't listed, the first symlink's in infos' order.
+func (fs *FileSource) dedupe(infos []SegmentInfo) []SegmentInfo {
+	links := make([]bool, len(infos))
+	linked := false
+	for i, info := range infos {
+		if link, err := os.Lstat(info.Path); err == nil && link.Mode()&os.ModeSymlink != 0 {
+			links[i], linked = true, true
+		}
+	}
+	if !linked {
+		return infos
+	}
+
+	// Key each segment by the file it resolves to; the file's own name
+	// claims it first, then the first symlink to it
+	targets := make([]string, len(infos))
+	owner := make(map[string]int, len(infos))
+	for _, wantLinks := range []bool{false, true} {
+		for i, info := range infos {
+			if links[i] != wantLinks {
+				continue
+			}
+			target, err := filepath.EvalSymlinks(info.Path)
+			if err != nil {
+				continue // Kept as it is
+			}
+			targets[i] = target
+			if _, ok := owner[target]; !ok {
+				owner[target] = i
+			}
+		}
+	}
+
+	kept := make([]SegmentInfo, 0, len(infos))
+	for i, info := range infos {
+		if targets[i] != "" && owner[targets[i]] != i {
+			if fs.log != nil {
+				fs.log.Debug("symlinked segment skipped as a duplicate", "segment", info.Name, "of", infos[owner[targets[i]]].Name)
+			}
+			continue
+		}
+		kept = append(kept, info)
+	}
+	return kept
+}
+
+// Open opens the named segment file and seeks to offset. Streams such
+// as named pipes cannot seek and are always read from the start; note
+// that opening a named pipe blocks until it has a writer. Compressed
+// segments (".gz", ".zst" or another registered Codec's extension) are
+// decompressed, with offset counting decompressed bytes.
+func (fs *FileSource) Open(name string, offset int64) (io.ReadCloser, error) {
+	if c := codecFor(name); c != nil {
+		return openCompressed(filepath.Join(fs.dir, name), c, offset)
+	}
+
+	file, err := openSegment(filepath.Join(fs.dir, name), offset)
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// resumeStrategy picks how a file segment is resumed from its name
+func resumeStrategy(name string) ResumeStrategy {
+	if isCompressed(name) {
+		return ResumeSkipLines
+	}
+	return ResumeSeek
+}
+
+// Remove deletes the named segment file
+func (fs *FileSource) Remove(name string) error {
+	err := os.Remove(filepath.Join(fs.dir, name))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// MemorySource is an in-memory SegmentSource, mainly for tests
+type MemorySource struct {
+	segments map[string][]byte
+	mu       sync.RWMutex
+}
+
+// NewMemorySource creates an empty in-memory source
+func NewMemorySource() *MemorySource {
+	return &MemorySource{
+		segments: make(map[string][]byte),
+	}
+}
+
+// Put sets the contents of a segment, replacing any existing data
+func (ms *MemorySource) Put(name string, data []byte) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.segments[name] = append([]byte(nil), data...)
+}
+
+// Append adds data to the end of a segment, creating it if needed
+func (ms *MemorySource) Append(name string, data []byte) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.segments[name] = append(ms.segments[name], data...)
+}
+
+// List returns all segments sorted by name
+func (ms *MemorySource) List() ([]SegmentInfo, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	infos := make([]SegmentInfo, 0, len(ms.segments))
+	for name, data := range ms.segments {
+		infos = append(infos, SegmentInfo{
+			Name: name,
+			Path: name,
+			Size: int64(len(data)),
+		})
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	return infos, nil
+}
+
+// statSegment describes the named segment as List would
+func (ms *MemorySource) statSegment(name string) (SegmentInfo, bool) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	data, ok := ms.segments[name]
+	if !ok {
+		return SegmentInfo{}, false
+	}
+	return SegmentInfo{Name: name, Path: name, Size: int64(len(data))}, true
+}
+
+// Open returns a reader over a snapshot of the segment from offset
+func (ms *MemorySource) Open(name string, offset int64) (io.ReadCloser, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	data, ok := ms.segments[name]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	if offset < 0 || offset > int64(len(data)) {
+		return nil, errors.New("offset out of range")
+	}
+
+	return io.NopCloser(bytes.NewReader(data[offset:])), nil
+}
+
+// Remove deletes the named segment
+func (ms *MemorySource) Remove(name string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.segments, name)
+	return nil
+}
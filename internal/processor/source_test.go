@@ -0,0 +1,412 @@
+package processor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"log-processor/internal/rotation"
+)
+
+// TestFileSourceList verifies only rotated segments are listed
+func TestFileSourceList(t *testing.T) {
+	dir := t.TempDir()
+	writeSegment(t, dir, "app.log", `{"message":"active"}`)
+	writeSegment(t, dir, "app.log.20260101-000000", `{"message":"rotated"}`)
+	writeSegment(t, dir, "app.log.20260101-000000.offset.json", `{}`)
+	writeSegment(t, dir, "other.log.20260101-000000", `{"message":"other"}`)
+
+	infos, err := NewFileSource(dir, "app.log").List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(infos) != 1 || infos[0].Name != "app.log.20260101-000000" {
+		t.Fatalf("unexpected segments: %+v", infos)
+	}
+}
+
+// TestFileSourceSymlinks verifies symlinked segments are followed,
+// a symlink to a listed segment isn't listed again, and broken or
+// looping symlinks are skipped with one warning each
+func TestFileSourceSymlinks(t *testing.T) {
+	dir, outside := t.TempDir(), t.TempDir()
+	writeSegment(t, dir, "app.log.20260101-010000", `{"message":"real"}`)
+	writeSegment(t, outside, "app.log.20260101-000000", `{"message":"elsewhere"}`)
+	symlink := func(target, name string) {
+		t.Helper()
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+	symlink(filepath.Join(outside, "app.log.20260101-000000"), "app.log.20260101-000000")
+	symlink("app.log.20260101-010000", "app.log.20260101-020000")                         // Duplicate
+	symlink(filepath.Join(outside, "app.log.20260101-000000"), "app.log.20260101-030000") // Duplicate
+	symlink(filepath.Join(outside, "missing"), "app.log.20260101-040000")                 // Broken
+	symlink("app.log.20260101-050000", "app.log.20260101-050000")                         // Loop
+	symlink(outside, "app.log.20260101-060000")                                           // Directory
+
+	var logs bytes.Buffer
+	src := NewFileSource(dir, "app.log")
+	src.log = slog.New(slog.NewTextHandler(&logs, nil))
+
+	want := []string{"app.log.20260101-000000", "app.log.20260101-010000"}
+	for _, list := range []func() ([]SegmentInfo, error){src.List, src.listCached, src.List} {
+		infos, err := list()
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.Name)
+		}
+		if !slices.Equal(names, want) {
+			t.Fatalf("listed %q, want %q", names, want)
+		}
+	}
+
+	for _, name := range []string{"app.log.20260101-040000", "app.log.20260101-050000"} {
+		if n := strings.Count(logs.String(), "segment="+name); n != 1 {
+			t.Errorf("%s warned about %d times, want once", name, n)
+		}
+	}
+
+	// Each file is processed once
+	cfg := newTestConfig(t, 2)
+	cfg.LogsDir = dir
+	var mu sync.Mutex
+	var messages []string
+	if _, err := ProcessOnce(context.Background(), cfg, func(record *LogRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+		messages = append(messages, record.Entry.Message)
+		return nil
+	}); err != nil {
+		t.Fatalf("ProcessOnce: %v", err)
+	}
+	slices.Sort(messages)
+	if want := []string{"elsewhere", "real"}; !slices.Equal(messages, want) {
+		t.Errorf("processed %q, want %q", messages, want)
+	}
+}
+
+// TestMemorySourceOpen verifies reads start at the requested offset
+func TestMemorySourceOpen(t *testing.T) {
+	src := NewMemorySource()
+	src.Put("seg", []byte("line1\nline2\n"))
+
+	rc, err := src.Open("seg", 6)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer rc.Close()
+
+	data, _ := io.ReadAll(rc)
+	if string(data) != "line2\n" {
+		t.Fatalf("got %q", data)
+	}
+
+	if _, err := src.Open("missing", 0); err == nil {
+		t.Fatal("expected error opening missing segment")
+	}
+}
+
+// TestProcessorMemorySource processes segments without touching the logs dir
+func TestProcessorMemorySource(t *testing.T) {
+	src := NewMemorySource()
+	src.Put("app.log.1", []byte(`{"message":"a"}`+"\n"+`{"message":"b"}`+"\n"))
+	src.Put("app.log.2", []byte(`{"message":"c"}`+"\n"))
+
+	cfg := newTestConfig(t, 2)
+	cfg.Source = src
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		seen[r.Entry.Message] = true
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 2
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, msg := range []string{"a", "b", "c"} {
+		if !seen[msg] {
+			t.Errorf("record %q not processed", msg)
+		}
+	}
+}
+
+// TestFileSourceScheme verifies segments named by a custom scheme are discovered
+func TestFileSourceScheme(t *testing.T) {
+	dir := t.TempDir()
+	writeSegment(t, dir, "app.log", `{"message":"active"}`)
+	writeSegment(t, dir, "app-20260102-123045.log", `{"message":"rotated"}`)
+
+	cfg := Config{LogPattern: "app.log", Rotation: rotation.Scheme{Template: "app-{date}.log"}}
+	infos, err := NewSchemeFileSource(dir, cfg.LogPattern, cfg.Rotation).List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(infos) != 1 || infos[0].Name != "app-20260102-123045.log" {
+		t.Fatalf("unexpected segments: %+v", infos)
+	}
+
+	ts, err := cfg.ParseSegmentTime(infos[0].Name)
+	if err != nil {
+		t.Fatalf("ParseSegmentTime: %v", err)
+	}
+	if want := time.Date(2026, 1, 2, 12, 30, 45, 0, time.UTC); !ts.Equal(want) {
+		t.Fatalf("ParseSegmentTime() = %v, want %v", ts, want)
+	}
+}
+
+// ageDir backdates a directory's mtime so cached listings trust it
+func ageDir(t testing.TB, dir string) {
+	t.Helper()
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(dir, old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFileSourceListCached verifies cached listings skip unchanged
+// segments but still see new, removed and previously empty ones
+func TestFileSourceListCached(t *testing.T) {
+	dir := t.TempDir()
+	seg := writeSegment(t, dir, "app.log.20260101-000000", `{"message":"one"}`)
+	empty := writeSegment(t, dir, "app.log.20260101-000001")
+	ageDir(t, dir)
+
+	src := NewFileSource(dir, "app.log")
+	first, err := src.listCached()
+	if err != nil {
+		t.Fatalf("listCached: %v", err)
+	}
+	if len(first) != 2 || first[1].Size != 0 {
+		t.Fatalf("unexpected segments: %+v", first)
+	}
+
+	// Growth in place leaves the directory untouched; only the empty
+	// segment is re-stat'd
+	for _, path := range []string{seg, empty} {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.WriteString(`{"message":"more"}` + "\n")
+		f.Close()
+	}
+	ageDir(t, dir)
+
+	cached, err := src.listCached()
+	if err != nil {
+		t.Fatalf("listCached: %v", err)
+	}
+	if cached[0].Size != first[0].Size {
+		t.Errorf("unchanged segment re-stat'd: size %d, want cached %d", cached[0].Size, first[0].Size)
+	}
+	if cached[1].Size == 0 {
+		t.Error("empty segment not re-stat'd")
+	}
+
+	// List stays exact
+	if exact, _ := src.List(); exact[0].Size == first[0].Size {
+		t.Error("List returned a cached size")
+	}
+
+	// New and removed files change the directory
+	writeSegment(t, dir, "app.log.20260101-000002", `{"message":"new"}`)
+	os.Remove(empty)
+	infos, err := src.listCached()
+	if err != nil {
+		t.Fatalf("listCached: %v", err)
+	}
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name)
+	}
+	if got := strings.Join(names, ","); got != "app.log.20260101-000000,app.log.20260101-000002" {
+		t.Fatalf("listed %s after changes", got)
+	}
+}
+
+// BenchmarkFileSourceScan compares repeated listings of a large static
+// backlog with and without the scan cache
+func BenchmarkFileSourceScan(b *testing.B) {
+	dir := b.TempDir()
+	for i := 0; i < 50000; i++ {
+		name := fmt.Sprintf("app.log.20260101-%06d", i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}\n"), 0644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	ageDir(b, dir)
+
+	b.Run("List", func(b *testing.B) {
+		src := NewFileSource(dir, "app.log")
+		for i := 0; i < b.N; i++ {
+			if _, err := src.List(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("listCached", func(b *testing.B) {
+		src := NewFileSource(dir, "app.log")
+		if _, err := src.listCached(); err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := src.listCached(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// TestGzipResumeFromLineCheckpoint verifies a gzip segment resumes by
+// skipping committed lines, and is not reprocessed once complete
+func TestGzipResumeFromLineCheckpoint(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	name := "app.log.20260101-000000.gz"
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	for _, msg := range []string{"one", "two", "", "three", "four"} {
+		if msg == "" {
+			zw.Write([]byte("\n")) // Blank lines count toward the checkpoint
+			continue
+		}
+		fmt.Fprintf(zw, `{"message":%q}`+"\n", msg)
+	}
+	zw.Close()
+	if err := os.WriteFile(filepath.Join(cfg.LogsDir, name), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := NewFileSource(cfg.LogsDir, cfg.LogPattern).List()
+	if err != nil || len(infos) != 1 || infos[0].Resume != ResumeSkipLines {
+		t.Fatalf("List() = %+v, %v; want one line-skip segment", infos, err)
+	}
+
+	// A previous run got through "two" and the blank line
+	offsetMgr, err := NewOffsetManager(cfg.OffsetsDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := offsetMgr.CommitLine(name, 0, 2, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func() []string {
+		var mu sync.Mutex
+		var seen []string
+		proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+			mu.Lock()
+			seen = append(seen, r.Entry.Message)
+			mu.Unlock()
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("NewProcessor: %v", err)
+		}
+		if err := proc.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		waitFor(t, 2*time.Second, func() bool {
+			_, _, segStats := proc.Stats()
+			return segStats[3] == 1
+		})
+		proc.Stop()
+
+		mu.Lock()
+		defer mu.Unlock()
+		return seen
+	}
+
+	if got := run(); strings.Join(got, ",") != "three,four" {
+		t.Fatalf("resumed run processed %q, want three,four", got)
+	}
+	if got := run(); len(got) != 0 {
+		t.Fatalf("completed segment reprocessed: %q", got)
+	}
+}
+
+// TestExactFile verifies a single named file is processed as the only
+// segment, and that a rerun resumes from its committed offset
+func TestExactFile(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	path := writeSegment(t, cfg.LogsDir, "app[1].log", `{"message":"one"}`, `{"message":"two"}`, `{"message":"three"}`)
+	writeSegment(t, cfg.LogsDir, "app[1].log.20260101-000000", `{"message":"rotated"}`)
+	writeSegment(t, cfg.LogsDir, "app1.log", `{"message":"other"}`)
+	cfg.LogsDir, cfg.LogPattern = "", ""
+	cfg.ExactFile = path
+
+	run := func(limit int64) []string {
+		t.Helper()
+		cfg.MaxRecords = limit
+
+		var mu sync.Mutex
+		var seen []string
+		proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+			mu.Lock()
+			defer mu.Unlock()
+			seen = append(seen, r.Entry.Message)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("NewProcessor: %v", err)
+		}
+		if err := proc.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		select {
+		case <-proc.Done():
+		case <-ctx.Done():
+			t.Fatal("processor did not stop")
+		default:
+			if err := proc.WaitForIdle(ctx); err != nil && err != ErrStopped {
+				t.Fatalf("WaitForIdle: %v", err)
+			}
+		}
+		proc.Stop()
+
+		mu.Lock()
+		defer mu.Unlock()
+		return seen
+	}
+
+	if got := strings.Join(run(2), ","); got != "one,two" {
+		t.Fatalf("first run processed %s, want one,two", got)
+	}
+	if got := strings.Join(run(0), ","); got != "three" {
+		t.Fatalf("rerun processed %s, want three", got)
+	}
+}
@@ -0,0 +1,104 @@
+package processor
+
+import (
+	"sync"
+	"time"
+)
+
+// StatsSnapshot is the processor's progress at one moment, as sent by
+// StatsChannel
+type StatsSnapshot struct {
+	Time       time.Time
+	Processed  int64
+	Errors     int64
+	Segments   int // Tracked segments, as counted by Stats
+	Pending    int
+	Processing int
+	Complete   int
+	Failed     int   // Segments given up on (see FailedSegments)
+	OpenFiles  int64 // Files held open (see OpenFiles)
+}
+
+// statsHub fans snapshots out to StatsChannel subscribers
+type statsHub struct {
+	mu   sync.Mutex
+	subs []chan StatsSnapshot
+}
+
+// StatsChannel subscribes to stats updates: a snapshot after every scan
+// tick and offset commit, and a last one when Stop returns, after which
+// the channel is closed. Sends never block the processor; a subscriber
+// that falls behind only sees the latest snapshot, so Processed never
+// goes backwards between snapshots received. Subscribe before Start
+// (or again after Stop) to follow the next run.
+func (p *Processor) StatsChannel() <-chan StatsSnapshot {
+	ch := make(chan StatsSnapshot, 1)
+
+	p.stats.mu.Lock()
+	defer p.stats.mu.Unlock()
+	p.stats.subs = append(p.stats.subs, ch)
+	return ch
+}
+
+// publishStats sends a snapshot to every subscriber. Snapshots are
+// taken under the hub's lock, so subscribers receive them in order.
+func (p *Processor) publishStats() {
+	p.stats.mu.Lock()
+	defer p.stats.mu.Unlock()
+
+	if len(p.stats.subs) == 0 {
+		return
+	}
+	snapshot := p.statsSnapshot()
+	for _, ch := range p.stats.subs {
+		offerLatest(ch, snapshot)
+	}
+}
+
+// closeStats sends a final snapshot and closes every subscription
+func (p *Processor) closeStats() {
+	p.stats.mu.Lock()
+	defer p.stats.mu.Unlock()
+
+	if len(p.stats.subs) == 0 {
+		return
+	}
+	snapshot := p.statsSnapshot()
+	for _, ch := range p.stats.subs {
+		offerLatest(ch, snapshot)
+		close(ch)
+	}
+	p.stats.subs = nil
+}
+
+// statsSnapshot captures the current stats
+func (p *Processor) statsSnapshot() StatsSnapshot {
+	processed, errors, segs := p.Stats()
+	return StatsSnapshot{
+		Time:       time.Now(),
+		Processed:  processed,
+		Errors:     errors,
+		Segments:   segs[0],
+		Pending:    segs[1],
+		Processing: segs[2],
+		Complete:   segs[3],
+		Failed:     len(p.segmentMgr.FailedSegments()),
+		OpenFiles:  p.OpenFiles(),
+	}
+}
+
+// offerLatest puts s in ch without blocking, replacing a snapshot the
+// subscriber hasn't taken yet
+func offerLatest(ch chan StatsSnapshot, s StatsSnapshot) {
+	for {
+		select {
+		case ch <- s:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestStatsChannel verifies subscribers receive snapshots with
+// processed counts that never go backwards, and that a subscriber that
+// never reads neither stalls processing nor misses the final snapshot
+func TestStatsChannel(t *testing.T) {
+	cfg := newTestConfig(t, 2)
+	for i := 0; i < 3; i++ {
+		var lines []string
+		for j := 0; j < 250; j++ {
+			lines = append(lines, fmt.Sprintf(`{"message":"%d-%d"}`, i, j))
+		}
+		writeSegment(t, cfg.LogsDir, fmt.Sprintf("app.log.20260101-00000%d", i), lines...)
+	}
+
+	proc, err := NewProcessor(cfg, func(*LogRecord) error { return nil })
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	fast := proc.StatsChannel()
+	slow := proc.StatsChannel()
+
+	var received []StatsSnapshot
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for s := range fast {
+			received = append(received, s)
+		}
+	}()
+
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := proc.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle: %v", err)
+	}
+	if err := proc.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	select {
+	case <-collected:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stats channel not closed by Stop")
+	}
+	if len(received) < 2 {
+		t.Fatalf("received %d snapshots, want several", len(received))
+	}
+	for i := 1; i < len(received); i++ {
+		if received[i].Processed < received[i-1].Processed {
+			t.Fatalf("snapshot %d processed %d after %d", i, received[i].Processed, received[i-1].Processed)
+		}
+	}
+	last := received[len(received)-1]
+	if last.Processed != 750 || last.Complete != 3 || last.Segments != 3 {
+		t.Fatalf("final snapshot %+v, want 750 processed and 3 of 3 segments complete", last)
+	}
+
+	// The slow subscriber kept only the latest snapshot
+	var slowReceived []StatsSnapshot
+	for s := range slow {
+		slowReceived = append(slowReceived, s)
+	}
+	if len(slowReceived) != 1 || slowReceived[0].Processed != 750 {
+		t.Fatalf("slow subscriber received %+v, want just the final snapshot", slowReceived)
+	}
+}
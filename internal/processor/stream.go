@@ -0,0 +1,35 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PrefixStreamKey returns a Config.StreamKey that takes the stream from
+// the part of a segment name before ".<pattern>", e.g. "tenant-a" for
+// "tenant-a.app.log.20260101-000000" with pattern "app.log". Names
+// without a prefix map to the empty key.
+func PrefixStreamKey(pattern string) func(segmentName string) string {
+	return func(segmentName string) string {
+		prefix, _, ok := strings.Cut(segmentName, "."+pattern)
+		if !ok {
+			return ""
+		}
+		return prefix
+	}
+}
+
+// RouteStreams returns a ProcessFunc that hands each record to the
+// route for its StreamKey, e.g. a per-tenant sink. Records of other
+// streams go to fallback, or fail with a Permanent error if it is nil.
+func RouteStreams(routes map[string]ProcessFunc, fallback ProcessFunc) ProcessFunc {
+	return func(record *LogRecord) error {
+		if fn, ok := routes[record.StreamKey]; ok {
+			return fn(record)
+		}
+		if fallback != nil {
+			return fallback(record)
+		}
+		return Permanent(fmt.Errorf("no route for stream %q", record.StreamKey))
+	}
+}
@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestRouteStreams verifies records from two tenants' segments are
+// tagged with their stream key and routed to that tenant's func
+func TestRouteStreams(t *testing.T) {
+	source := NewMemorySource()
+	source.Put("tenant-a.app.log.20260101-000000", []byte(`{"message":"a1"}`+"\n"+`{"message":"a2"}`+"\n"))
+	source.Put("tenant-b.app.log.20260101-000000", []byte(`{"message":"b1"}`+"\n"))
+	source.Put("app.log.20260101-000000", []byte(`{"message":"none"}`+"\n"))
+
+	cfg := newTestConfig(t, 2)
+	cfg.Source = source
+	cfg.StreamKey = PrefixStreamKey("app.log")
+
+	var mu sync.Mutex
+	got := make(map[string][]string)
+	route := func(stream string) ProcessFunc {
+		return func(r *LogRecord) error {
+			mu.Lock()
+			defer mu.Unlock()
+			got[stream] = append(got[stream], r.StreamKey+":"+r.Entry.Message)
+			return nil
+		}
+	}
+
+	proc, err := NewProcessor(cfg, RouteStreams(map[string]ProcessFunc{
+		"tenant-a": route("a"),
+		"tenant-b": route("b"),
+	}, nil))
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 3
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if a := got["a"]; len(a) != 2 || a[0] != "tenant-a:a1" || a[1] != "tenant-a:a2" {
+		t.Errorf("tenant-a received %q", a)
+	}
+	if b := got["b"]; len(b) != 1 || b[0] != "tenant-b:b1" {
+		t.Errorf("tenant-b received %q", b)
+	}
+
+	// The unprefixed segment has no route and no fallback
+	if _, permanent := proc.ErrorCounts(); permanent != 1 {
+		t.Errorf("permanent errors = %d, want 1", permanent)
+	}
+}
@@ -0,0 +1,120 @@
+package processor
+
+import (
+	"context"
+)
+
+// Tracer starts spans for distributed tracing. It mirrors the shape of
+// OpenTelemetry's trace.Tracer without depending on it; adapting one is
+// a few lines:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string, attrs ...processor.Attribute) (context.Context, processor.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name, trace.WithAttributes(toKeyValues(attrs)...))
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	// Start begins a span as a child of any span in ctx, returning a
+	// context carrying the new span
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// Span is a started span
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	RecordError(err error)
+	End()
+}
+
+// Attribute is a span attribute. Values are strings, int64s or bools.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Names of the processor's spans
+const (
+	runSpanName     = "log-processor.run"
+	segmentSpanName = "log-processor.segment"
+)
+
+// noopTracer is the default Tracer
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+// noopSpan is the span of noopTracer
+type noopSpan struct{}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+func (noopSpan) RecordError(error)          {}
+func (noopSpan) End()                       {}
+
+// startRunSpan starts the span covering a run, from Start to Stop, and
+// returns ctx carrying it so segment spans become its children
+func (p *Processor) startRunSpan(ctx context.Context) context.Context {
+	ctx, p.runSpan = p.tracer.Start(ctx, runSpanName,
+		Attribute{"workers", int64(len(p.workers))},
+		Attribute{"follow", p.cfg.Follow},
+	)
+	return ctx
+}
+
+// endRunSpan ends the run's span with its totals
+func (p *Processor) endRunSpan(timedOut bool) {
+	if p.runSpan == nil {
+		return
+	}
+	total, _, _, complete := p.segmentMgr.GetStats()
+	p.runSpan.SetAttributes(
+		Attribute{"records.processed", p.processed.Load()},
+		Attribute{"records.errors", p.errors.Load()},
+		Attribute{"segments.total", int64(total)},
+		Attribute{"segments.complete", int64(complete)},
+		Attribute{"segments.failed", int64(len(p.segmentMgr.FailedSegments()))},
+		Attribute{"timed_out", timedOut},
+	)
+	p.runSpan.End()
+	p.runSpan = nil
+}
+
+// segmentTrace is the span of one attempt at a segment
+type segmentTrace struct {
+	span   Span
+	parent context.Context // Worker context to restore
+	lines  int64
+	errors int64
+	err    error
+}
+
+// startSegmentSpan starts a segment's span, making it the parent of
+// spans the process func starts from its context until the trace ends
+func (w *worker) startSegmentSpan(seg *Segment) *segmentTrace {
+	t := &segmentTrace{parent: w.ctx}
+	ctx := w.ctx
+	if ctx == nil {
+		ctx = w.processor.ctx
+	}
+	w.ctx, t.span = w.processor.tracer.Start(ctx, segmentSpanName,
+		Attribute{"segment.name", seg.Name},
+		Attribute{"segment.size", seg.Size},
+		Attribute{"worker", int64(w.id)},
+	)
+	return t
+}
+
+// endSegmentSpan ends a segment's span with what the attempt did
+func (w *worker) endSegmentSpan(t *segmentTrace) {
+	w.ctx = t.parent
+	t.span.SetAttributes(
+		Attribute{"segment.lines", t.lines},
+		Attribute{"segment.errors", t.errors},
+	)
+	if t.err != nil {
+		t.span.RecordError(t.err)
+	}
+	t.span.End()
+}
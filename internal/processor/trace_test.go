@@ -0,0 +1,151 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mockSpan records what was done to a span
+type mockSpan struct {
+	tracer *mockTracer
+	name   string
+	parent *mockSpan
+	attrs  map[string]any
+	errs   []error
+	ended  bool
+}
+
+func (s *mockSpan) SetAttributes(attrs ...Attribute) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *mockSpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+func (s *mockSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+}
+
+type mockSpanKey struct{}
+
+// mockTracer collects spans, linking each to the span in its context
+type mockTracer struct {
+	mu    sync.Mutex
+	spans []*mockSpan
+}
+
+func (t *mockTracer) Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span) {
+	parent, _ := ctx.Value(mockSpanKey{}).(*mockSpan)
+	span := &mockSpan{tracer: t, name: name, parent: parent, attrs: make(map[string]any)}
+	for _, a := range attrs {
+		span.attrs[a.Key] = a.Value
+	}
+
+	t.mu.Lock()
+	t.spans = append(t.spans, span)
+	t.mu.Unlock()
+	return context.WithValue(ctx, mockSpanKey{}, span), span
+}
+
+// TestTracer verifies a run span and a child span per segment with the
+// segment's attributes
+func TestTracer(t *testing.T) {
+	cfg := newTestConfig(t, 2)
+	tracer := &mockTracer{}
+	cfg.Tracer = tracer
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", `{"message":"a"}`, `{"message":"b"}`)
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000001", `{"message":"c"}`, `{"message":"bad"}`, `{"message":"d"}`)
+
+	var mu sync.Mutex
+	inSegmentSpan := 0
+	cfg.ProcessFuncCtx = func(ctx context.Context, r *LogRecord) error {
+		if span, _ := ctx.Value(mockSpanKey{}).(*mockSpan); span != nil && span.name == segmentSpanName {
+			mu.Lock()
+			inSegmentSpan++
+			mu.Unlock()
+		}
+		if r.Entry.Message == "bad" {
+			return errors.New("rejected")
+		}
+		return nil
+	}
+	proc, err := NewProcessor(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := proc.WaitForIdle(ctx); err != nil {
+		t.Fatalf("WaitForIdle: %v", err)
+	}
+	proc.Stop()
+
+	tracer.mu.Lock()
+	defer tracer.mu.Unlock()
+
+	var run *mockSpan
+	segments := make(map[string]*mockSpan)
+	for _, span := range tracer.spans {
+		if !span.ended {
+			t.Errorf("span %s not ended", span.name)
+		}
+		switch span.name {
+		case runSpanName:
+			run = span
+		case segmentSpanName:
+			segments[span.attrs["segment.name"].(string)] = span
+		default:
+			t.Errorf("unexpected span %s", span.name)
+		}
+	}
+	if run == nil || run.parent != nil {
+		t.Fatalf("missing root run span")
+	}
+	if run.attrs["records.processed"] != int64(4) || run.attrs["segments.complete"] != int64(2) {
+		t.Errorf("run span attributes %v", run.attrs)
+	}
+
+	want := map[string][3]int64{ // size, lines, errors
+		"app.log.20260101-000000": {32, 2, 0},
+		"app.log.20260101-000001": {50, 2, 1},
+	}
+	if len(segments) != len(want) {
+		t.Fatalf("got %d segment spans, want %d", len(segments), len(want))
+	}
+	for name, w := range want {
+		span := segments[name]
+		if span == nil {
+			t.Errorf("no span for %s", name)
+			continue
+		}
+		if span.parent != run {
+			t.Errorf("span for %s is not a child of the run span", name)
+		}
+		got := [3]int64{span.attrs["segment.size"].(int64), span.attrs["segment.lines"].(int64), span.attrs["segment.errors"].(int64)}
+		if got != w {
+			t.Errorf("span for %s has size, lines, errors %v, want %v", name, got, w)
+		}
+		if len(span.errs) != 0 {
+			t.Errorf("span for %s recorded errors %v", name, span.errs)
+		}
+	}
+
+	if inSegmentSpan != 5 {
+		t.Errorf("process func saw the segment span for %d records, want 5", inSegmentSpan)
+	}
+}
@@ -0,0 +1,95 @@
+package processor
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// ValidationReport summarizes a ValidateSegments scan
+type ValidationReport struct {
+	Segments  int   `json:"segments"`
+	Records   int64 `json:"records"`
+	Malformed int64 `json:"malformed"`
+
+	// Malformed records by cause
+	ParseErrors      int64 `json:"parse_errors"`
+	InvalidEntries   int64 `json:"invalid_entries"`
+	SchemaViolations int64 `json:"schema_violations"`
+
+	// BySegment counts the malformed records of each segment with any
+	BySegment map[string]int64 `json:"by_segment"`
+
+	// Samples lists the first malformed records found, in segment order
+	Samples []MalformedRecord `json:"samples"`
+}
+
+// MalformedRecord is a record that failed validation
+type MalformedRecord struct {
+	Segment string `json:"segment"`
+	Line    int64  `json:"line"`
+	Reason  string `json:"reason"`
+}
+
+// ValidateSegments checks every record of every segment currently
+// available without processing anything: like Each it reads from
+// offset zero and persists nothing. A record is malformed if it does
+// not parse, fails logger.LogEntry.Validate when ValidateEntries is
+// set, or violates SchemaPath. Up to maxSamples malformed records are
+// listed in the report.
+func (p *Processor) ValidateSegments(ctx context.Context, maxSamples int) (ValidationReport, error) {
+	report := ValidationReport{
+		BySegment: make(map[string]int64),
+		Samples:   []MalformedRecord{},
+	}
+
+	infos, err := p.source.List()
+	if err != nil {
+		return report, err
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	for _, info := range infos {
+		report.Segments++
+		err := p.eachInSegment(ctx, info, func(record *LogRecord) error {
+			report.Records++
+
+			var cause *int64
+			err := record.ParseErr
+			switch {
+			case err != nil:
+				cause = &report.ParseErrors
+			case p.cfg.ValidateEntries:
+				if err = record.Entry.Validate(); err != nil {
+					cause = &report.InvalidEntries
+				}
+			}
+			if err == nil && p.schema != nil {
+				if err = p.validateSchema(record); err != nil {
+					cause = &report.SchemaViolations
+				}
+			}
+			if err == nil {
+				return nil
+			}
+
+			*cause++
+			report.Malformed++
+			report.BySegment[info.Name]++
+			if len(report.Samples) < maxSamples {
+				report.Samples = append(report.Samples, MalformedRecord{
+					Segment: info.Name,
+					Line:    record.LineNumber,
+					Reason:  strings.ReplaceAll(err.Error(), "\n", "; "),
+				})
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
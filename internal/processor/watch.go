@@ -0,0 +1,22 @@
+package processor
+
+import "time"
+
+// watchedFollowInterval is how often a follower woken by a change
+// watcher still polls, in case a notification is missed (e.g. on
+// network filesystems)
+const watchedFollowInterval = 5 * time.Second
+
+// changeWatcher signals when a file may have changed: written to,
+// renamed away, removed or created. Signals are coalesced; a follower
+// re-checks the file after each one.
+type changeWatcher interface {
+	// Changes receives a value after one or more changes
+	Changes() <-chan struct{}
+
+	// Failed reports whether the watcher stopped delivering changes
+	Failed() bool
+
+	// Close releases the watcher
+	Close() error
+}
@@ -0,0 +1,111 @@
+//go:build linux
+
+package processor
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"syscall"
+)
+
+// inotifyWatcher watches the directory of a file with inotify, which
+// reports changes to the file's contents (IN_MODIFY) as well as its
+// rotation (IN_MOVED_FROM, IN_DELETE) and re-creation (IN_CREATE,
+// IN_MOVED_TO) under one watch that survives the file being replaced
+type inotifyWatcher struct {
+	file    *os.File
+	name    string // Base name of the watched file
+	changes chan struct{}
+	failed  atomic.Bool
+	closed  atomic.Bool
+}
+
+// watchFile starts watching path for changes
+func watchFile(path string) (changeWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	const mask = syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE | syscall.IN_CREATE |
+		syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+	if _, err := syscall.InotifyAddWatch(fd, filepath.Dir(path), mask); err != nil {
+		syscall.Close(fd)
+		return nil, os.NewSyscallError("inotify_add_watch", err)
+	}
+
+	// The descriptor is non-blocking, so reads wait in the runtime
+	// poller and Close interrupts them
+	w := &inotifyWatcher{
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		name:    filepath.Base(path),
+		changes: make(chan struct{}, 1),
+	}
+	go w.run()
+	return w, nil
+}
+
+// run reads events until the watcher is closed, signalling those that
+// concern the watched file
+func (w *inotifyWatcher) run() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !w.closed.Load() {
+				w.failed.Store(true)
+				w.signal() // Wake the follower to fall back to polling
+			}
+			return
+		}
+
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			mask := binary.NativeEndian.Uint32(buf[off+4:])
+			length := int(binary.NativeEndian.Uint32(buf[off+12:]))
+			name := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+length]
+			off += syscall.SizeofInotifyEvent + length
+
+			// Names are NUL-padded; a queue overflow may have hidden
+			// events for the file
+			if trimNUL(name) == w.name || mask&syscall.IN_Q_OVERFLOW != 0 {
+				w.signal()
+			}
+		}
+	}
+}
+
+// trimNUL returns b up to its first NUL byte as a string
+func trimNUL(b []byte) string {
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// signal records a change without blocking
+func (w *inotifyWatcher) signal() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+	}
+}
+
+// Changes implements changeWatcher
+func (w *inotifyWatcher) Changes() <-chan struct{} {
+	return w.changes
+}
+
+// Failed implements changeWatcher
+func (w *inotifyWatcher) Failed() bool {
+	return w.failed.Load()
+}
+
+// Close implements changeWatcher
+func (w *inotifyWatcher) Close() error {
+	w.closed.Store(true)
+	return w.file.Close()
+}
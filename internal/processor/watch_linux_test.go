@@ -0,0 +1,55 @@
+//go:build linux
+
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestFollowInotify verifies appended records and a rotation are picked
+// up promptly even though polling alone would take a minute
+func TestFollowInotify(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.Follow = true
+	cfg.FollowInterval = time.Minute
+	cfg.ScanInterval = 10 * time.Millisecond
+
+	active := filepath.Join(cfg.LogsDir, "app.log")
+	appendLines(t, active, "a1")
+
+	var total atomic.Int64
+	proc, err := NewProcessor(cfg, func(*LogRecord) error {
+		total.Add(1)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	seen := func(n int64) func() bool {
+		return func() bool { return total.Load() >= n }
+	}
+	waitFor(t, time.Second, seen(1))
+
+	// Let the follower catch up and block waiting for a change
+	time.Sleep(50 * time.Millisecond)
+	appendLines(t, active, "a2")
+	waitFor(t, time.Second, seen(2))
+
+	// Rotation is noticed and the new active file followed
+	time.Sleep(50 * time.Millisecond)
+	if err := os.Rename(active, active+".20260101-000000"); err != nil {
+		t.Fatal(err)
+	}
+	appendLines(t, active, "b1")
+	waitFor(t, time.Second, seen(3))
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package processor
+
+import "errors"
+
+// watchFile is only implemented with inotify on Linux; elsewhere the
+// follower polls
+func watchFile(path string) (changeWatcher, error) {
+	return nil, errors.New("file change notifications are not supported on this platform")
+}
@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"time"
+)
+
+// windowed reports whether a time window is configured
+func (c Config) windowed() bool {
+	return !c.TimeFrom.IsZero() || !c.TimeTo.IsZero()
+}
+
+// inWindow reports whether a record is timestamped within [TimeFrom,
+// TimeTo]. With a window set, records without a parseable timestamp
+// fall outside it.
+func (c Config) inWindow(record *LogRecord) bool {
+	if !c.windowed() {
+		return true
+	}
+	if record.ParseErr != nil {
+		return false
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, record.Entry.Timestamp)
+	if err != nil {
+		return false
+	}
+	return (c.TimeFrom.IsZero() || !ts.Before(c.TimeFrom)) &&
+		(c.TimeTo.IsZero() || !ts.After(c.TimeTo))
+}
+
+// beforeWindow reports whether a segment was rotated before TimeFrom,
+// so all of its records are too old. Rotated names carry local time
+// (see the generator); segments whose name has no parseable time are
+// never skipped.
+func (c Config) beforeWindow(info SegmentInfo) bool {
+	if c.TimeFrom.IsZero() {
+		return false
+	}
+
+	rotated, err := c.Rotation.ParseInLocation(c.LogPattern, info.Name, time.Local)
+	if err != nil {
+		return false
+	}
+	return rotated.Before(c.TimeFrom)
+}
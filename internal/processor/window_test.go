@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestTimeWindow verifies records outside [TimeFrom, TimeTo] are
+// skipped and segments rotated before TimeFrom are never tracked
+func TestTimeWindow(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.TimeFrom = time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)
+	cfg.TimeTo = time.Date(2026, 1, 2, 11, 0, 0, 0, time.UTC)
+
+	old := time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local)
+	recent := time.Date(2026, 1, 3, 0, 0, 0, 0, time.Local)
+	writeSegment(t, cfg.LogsDir, cfg.Rotation.Name(cfg.LogPattern, old),
+		`{"timestamp":"2026-01-02T10:30:00Z","message":"old segment"}`)
+	writeSegment(t, cfg.LogsDir, cfg.Rotation.Name(cfg.LogPattern, recent),
+		`{"timestamp":"2026-01-02T09:59:59Z","message":"too early"}`,
+		`{"timestamp":"2026-01-02T10:00:00Z","message":"from"}`,
+		`{"timestamp":"2026-01-02T12:30:00+02:00","message":"inside"}`,
+		`{"message":"no timestamp"}`,
+		`not json`,
+		`{"timestamp":"2026-01-02T11:00:00Z","message":"to"}`,
+		`{"timestamp":"2026-01-02T11:00:01Z","message":"too late"}`,
+	)
+
+	var mu sync.Mutex
+	var got []string
+	proc, err := NewProcessor(cfg, func(record *LogRecord) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, record.Entry.Message)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, stats := proc.Stats()
+		return stats[3] == 1
+	})
+
+	_, _, stats := proc.Stats()
+	if stats[0] != 1 {
+		t.Errorf("tracking %d segments, want only the one rotated after TimeFrom", stats[0])
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"from", "inside", "to"}
+	if len(got) != len(want) {
+		t.Fatalf("processed %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("processed %q, want %q", got, want)
+		}
+	}
+}
+
+// TestConfigValidateTimeWindow verifies an inverted window is rejected
+func TestConfigValidateTimeWindow(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.TimeFrom = time.Date(2026, 1, 2, 11, 0, 0, 0, time.UTC)
+	cfg.TimeTo = cfg.TimeFrom.Add(-time.Second)
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("Validate accepted TimeTo before TimeFrom")
+	}
+
+	cfg.TimeTo = cfg.TimeFrom
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate rejected an instant window: %v", err)
+	}
+}
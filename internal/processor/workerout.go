@@ -0,0 +1,125 @@
+package processor
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+)
+
+// defaultWorkerBufferSize is the WorkerOutput buffer size by default
+const defaultWorkerBufferSize = 64 << 10
+
+// WorkerContext is the state of the worker running a ProcessFuncCtx,
+// available from its context through WorkerFromContext
+type WorkerContext struct {
+	ID int
+
+	// Out buffers writes to the worker's Config.WorkerOutput (nil
+	// without one). It is flushed before each offset commit, so output
+	// reaches the writer before the records that produced it are
+	// recorded as processed: on segment completion, when a segment is
+	// released (shutdown, a record limit or a transient error), and at
+	// periodic commits.
+	Out *bufio.Writer
+
+	dst io.Writer
+}
+
+// workerContextKey is the context key of a *WorkerContext
+type workerContextKey struct{}
+
+// WorkerFromContext returns the WorkerContext of the worker calling a
+// ProcessFuncCtx, or nil for other contexts
+func WorkerFromContext(ctx context.Context) *WorkerContext {
+	wc, _ := ctx.Value(workerContextKey{}).(*WorkerContext)
+	return wc
+}
+
+// start gives the worker its context and, with WorkerOutput, opens its
+// buffered output
+func (w *worker) start() error {
+	p := w.processor
+	wc := &WorkerContext{ID: w.id}
+	if p.cfg.WorkerOutput != nil {
+		if err := p.files.acquire(p.ctx); err != nil {
+			return err
+		}
+		dst, err := p.cfg.WorkerOutput(w.id)
+		if err != nil {
+			p.files.release()
+			return fmt.Errorf("open output of worker %d: %w", w.id, err)
+		}
+		size := p.cfg.WorkerBufferSize
+		if size <= 0 {
+			size = defaultWorkerBufferSize
+		}
+		wc.dst, wc.Out = dst, bufio.NewWriterSize(dst, size)
+	}
+
+	w.wc = wc
+	w.ctx = context.WithValue(p.ctx, workerContextKey{}, wc)
+	return nil
+}
+
+// flushOutput writes out the worker's buffered output
+func (w *worker) flushOutput() error {
+	if w.wc == nil || w.wc.Out == nil {
+		return nil
+	}
+	if err := w.wc.Out.Flush(); err != nil {
+		return fmt.Errorf("flush output of worker %d: %w", w.id, err)
+	}
+	return nil
+}
+
+// closeOutput flushes the worker's output and closes it if it is an
+// io.Closer
+func (w *worker) closeOutput() error {
+	err := w.flushOutput()
+	if w.wc != nil && w.wc.dst != nil {
+		if c, ok := w.wc.dst.(io.Closer); ok {
+			if cerr := c.Close(); err == nil {
+				err = cerr
+			}
+		}
+		w.wc.dst = nil
+		w.processor.files.release()
+	}
+	return err
+}
+
+// commitOffset flushes the worker's output, then commits a segment's
+// offset. If the flush fails the offset is left as it was, so the
+// records are processed again, and the failure counts as a failed
+// commit.
+func (w *worker) commitOffset(segment string, offset, lines int64) error {
+	if err := w.flushOutput(); err != nil {
+		w.processor.committed(segment, err)
+		return err
+	}
+	return w.processor.commitOffset(segment, offset, lines)
+}
+
+// resetOffset flushes the worker's output, then moves a segment's
+// offset back to the start, as a forced commit
+func (w *worker) resetOffset(segment string) {
+	p := w.processor
+	if err := w.flushOutput(); err != nil {
+		p.committed(segment, err)
+		return
+	}
+	p.committed(segment, p.offsetMgr.ForceCommitOffset(segment, 0, 0))
+}
+
+// clearOffset flushes the worker's output, then forgets a segment's
+// offset, so it starts afresh from the start without counting as a
+// rewind (see LogRecord.Epoch)
+func (w *worker) clearOffset(segment string) {
+	p := w.processor
+	if err := w.flushOutput(); err != nil {
+		p.committed(segment, err)
+		return
+	}
+	p.committed(segment, p.offsetMgr.DeleteOffset(segment))
+}
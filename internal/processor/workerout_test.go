@@ -0,0 +1,104 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingWriter records each Write call and whether it was closed
+type countingWriter struct {
+	mu     sync.Mutex
+	writes []string
+	closed int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes = append(c.writes, string(p))
+	return len(p), nil
+}
+
+func (c *countingWriter) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed++
+	return nil
+}
+
+// TestWorkerOutputFlushedPerSegment verifies buffered worker output is
+// written once per completed segment and closed on Stop
+func TestWorkerOutputFlushedPerSegment(t *testing.T) {
+	cfg := newTestConfig(t, 2)
+	for i := 0; i < 3; i++ {
+		writeSegment(t, cfg.LogsDir, fmt.Sprintf("app.log.20260101-00000%d", i),
+			fmt.Sprintf(`{"message":"s%d-a"}`, i), fmt.Sprintf(`{"message":"s%d-b"}`, i))
+	}
+
+	var mu sync.Mutex
+	outputs := make(map[int]*countingWriter)
+	cfg.WorkerOutput = func(id int) (io.Writer, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		outputs[id] = &countingWriter{}
+		return outputs[id], nil
+	}
+	cfg.ProcessFuncCtx = func(ctx context.Context, r *LogRecord) error {
+		wc := WorkerFromContext(ctx)
+		if wc == nil {
+			return fmt.Errorf("no worker context")
+		}
+		_, err := fmt.Fprintf(wc.Out, "%d %s\n", wc.ID, r.Entry.Message)
+		return err
+	}
+
+	proc, err := NewProcessor(cfg, nil)
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 3
+	})
+	if err := proc.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	if len(outputs) != 2 {
+		t.Fatalf("opened %d outputs, want 2", len(outputs))
+	}
+	var writes int
+	var lines []string
+	for id, out := range outputs {
+		if out.closed != 1 {
+			t.Errorf("worker %d output closed %d times, want 1", id, out.closed)
+		}
+		writes += len(out.writes)
+		for _, w := range out.writes {
+			for _, line := range strings.Split(strings.TrimSuffix(w, "\n"), "\n") {
+				if !strings.HasPrefix(line, fmt.Sprintf("%d ", id)) {
+					t.Errorf("worker %d output has %q", id, line)
+				}
+				lines = append(lines, line[strings.IndexByte(line, ' ')+1:])
+			}
+		}
+	}
+	if writes != 3 {
+		t.Errorf("%d writes, want one per segment (3)", writes)
+	}
+
+	sort.Strings(lines)
+	want := "s0-a s0-b s1-a s1-b s2-a s2-b"
+	if got := strings.Join(lines, " "); got != want {
+		t.Errorf("output lines = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,254 @@
+package processor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	json "github.com/goccy/go-json"
+
+	"log-processor/internal/logger"
+)
+
+// Wrapped is the InputFormat of files holding one JSON document rather
+// than a record per line: either a single record, or an object wrapping
+// an array of records such as {"logs":[{...},{...}]} (see
+// Config.WrappedPath). Newlines and indentation don't matter, so
+// pretty-printed exports read the same as compact ones.
+const Wrapped logger.Format = "wrapped"
+
+// wrappedState is how far a LogReader has got through a wrapped document
+type wrappedState int
+
+const (
+	wrappedStart wrappedState = iota // Before the document
+	wrappedFirst                     // Inside the array, before any element
+	wrappedNext                      // Inside the array, after an element
+	wrappedDone                      // Past the last record
+)
+
+// readWrapped returns the next record of a wrapped document. Records
+// are scanned one value at a time, so the document is never held in
+// memory whole. Offset is the byte position after each record, from
+// which a new reader resumes inside the array, and LineNumber counts
+// records rather than lines.
+func (lr *LogReader) readWrapped() (*LogRecord, error) {
+	var b byte
+	var err error
+	switch lr.wrapped {
+	case wrappedStart:
+		if b, err = lr.findWrapped(); err != nil {
+			return nil, err
+		}
+		switch b {
+		case '[':
+			lr.wrapped = wrappedFirst
+			return lr.readWrapped()
+		case '{':
+			lr.wrapped = wrappedDone
+			return lr.wrappedRecord(b)
+		}
+		return nil, fmt.Errorf("wrapped %s: records must be an object or an array, found %q", lr.segment, b)
+
+	case wrappedFirst:
+		if b, err = lr.skipSpace(); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if b == ']' {
+			lr.wrapped = wrappedDone
+			return nil, io.EOF
+		}
+		lr.wrapped = wrappedNext
+		return lr.wrappedRecord(b)
+
+	case wrappedNext:
+		if b, err = lr.skipSpace(); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		switch b {
+		case ']':
+			lr.wrapped = wrappedDone
+			return nil, io.EOF
+		case ',':
+			if b, err = lr.skipSpace(); err != nil {
+				return nil, unexpectedEOF(err)
+			}
+			return lr.wrappedRecord(b)
+		}
+		return nil, fmt.Errorf("wrapped %s: expected ',' or ']' at offset %d, found %q", lr.segment, lr.offset-1, b)
+	}
+	return nil, io.EOF
+}
+
+// wrappedRecord reads the value starting with first as a record
+func (lr *LogReader) wrappedRecord(first byte) (*LogRecord, error) {
+	raw, err := lr.scanValue(first)
+	if err != nil {
+		return nil, err
+	}
+	lr.lineNumber++
+	return lr.parse(raw, nil), nil
+}
+
+// findWrapped walks the keys of ReaderOptions.WrappedPath from the
+// start of the document, after any BOM, returning the first byte of
+// the value found
+func (lr *LogReader) findWrapped() (byte, error) {
+	if lr.offset == 0 {
+		if head, _ := lr.reader.Peek(len(utf8BOM)); bytes.Equal(head, utf8BOM) {
+			n, _ := lr.reader.Discard(len(utf8BOM))
+			lr.offset += int64(n)
+		}
+	}
+	b, err := lr.skipSpace()
+	if err != nil || lr.opts.WrappedPath == "" {
+		return b, err
+	}
+
+	for _, key := range strings.Split(lr.opts.WrappedPath, ".") {
+		if b != '{' {
+			return 0, fmt.Errorf("wrapped %s: no %q in %q, found %q", lr.segment, key, lr.opts.WrappedPath, b)
+		}
+		if b, err = lr.findKey(key); err != nil {
+			return 0, unexpectedEOF(err)
+		}
+	}
+	return b, nil
+}
+
+// findKey reads the members of an object until key, returning the first
+// byte of its value. Other members' values are skipped.
+func (lr *LogReader) findKey(key string) (byte, error) {
+	for {
+		b, err := lr.skipSpace()
+		if err != nil {
+			return 0, err
+		}
+		if b == '}' {
+			return 0, fmt.Errorf("wrapped %s: no %q in %q", lr.segment, key, lr.opts.WrappedPath)
+		}
+		if b != '"' {
+			return 0, fmt.Errorf("wrapped %s: expected a key at offset %d, found %q", lr.segment, lr.offset-1, b)
+		}
+		quoted, err := lr.scanValue(b)
+		if err != nil {
+			return 0, err
+		}
+		var name string
+		if err := json.Unmarshal(quoted, &name); err != nil {
+			return 0, fmt.Errorf("wrapped %s: %w", lr.segment, err)
+		}
+
+		if b, err = lr.skipSpace(); err != nil {
+			return 0, err
+		}
+		if b != ':' {
+			return 0, fmt.Errorf("wrapped %s: expected ':' at offset %d, found %q", lr.segment, lr.offset-1, b)
+		}
+		if b, err = lr.skipSpace(); err != nil {
+			return 0, err
+		}
+		if name == key {
+			return b, nil
+		}
+		if _, err := lr.scanValue(b); err != nil {
+			return 0, err
+		}
+
+		if b, err = lr.skipSpace(); err != nil {
+			return 0, err
+		}
+		if b == '}' {
+			return 0, fmt.Errorf("wrapped %s: no %q in %q", lr.segment, key, lr.opts.WrappedPath)
+		}
+		if b != ',' {
+			return 0, fmt.Errorf("wrapped %s: expected ',' or '}' at offset %d, found %q", lr.segment, lr.offset-1, b)
+		}
+	}
+}
+
+// scanValue reads the rest of the JSON value starting with first and
+// returns all of it. It only finds where the value ends; parsing is
+// left to the caller.
+func (lr *LogReader) scanValue(first byte) ([]byte, error) {
+	value := []byte{first}
+	depth, inString, escaped := 0, first == '"', false
+	switch first {
+	case '{', '[':
+		depth = 1
+	case '"':
+	default:
+		// A scalar runs to the next delimiter
+		for {
+			next, err := lr.reader.Peek(1)
+			if err == io.EOF {
+				return value, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			if bytes.IndexByte([]byte(",]} \t\r\n"), next[0]) >= 0 {
+				return value, nil
+			}
+			b, _ := lr.readByte()
+			value = append(value, b)
+		}
+	}
+
+	for depth > 0 || inString {
+		b, err := lr.readByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		value = append(value, b)
+
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			escaped = b == '\\'
+			inString = b != '"'
+		case b == '"':
+			inString = true
+		case b == '{' || b == '[':
+			depth++
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+	return value, nil
+}
+
+// skipSpace returns the next byte that isn't JSON whitespace
+func (lr *LogReader) skipSpace() (byte, error) {
+	for {
+		b, err := lr.readByte()
+		if err != nil {
+			return 0, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return b, nil
+		}
+	}
+}
+
+// readByte reads one byte, advancing the offset
+func (lr *LogReader) readByte() (byte, error) {
+	b, err := lr.reader.ReadByte()
+	if err == nil {
+		lr.offset++
+	}
+	return b, err
+}
+
+// unexpectedEOF turns io.EOF inside a value into io.ErrUnexpectedEOF, so
+// a truncated document isn't mistaken for a finished one
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,152 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestProcessorWrapped verifies both records of a pretty-printed
+// {"logs":[...]} document are processed
+func TestProcessorWrapped(t *testing.T) {
+	cfg := newTestConfig(t, 1)
+	cfg.InputFormat = Wrapped
+	cfg.WrappedPath = "logs"
+	writeSegment(t, cfg.LogsDir, "app.log.20260101-000000", `{
+  "exporter": {"name": "batch", "tags": ["a]", "{b"]},
+  "logs": [
+    {
+      "level": "INFO",
+      "message": "first\nline",
+      "request_id": "req-1"
+    },
+    {
+      "level": "ERROR",
+      "message": "second \"quoted\"",
+      "nested": {
+        "deep": [1, {"x": "}"}]
+      }
+    }
+  ]
+}`)
+
+	var mu sync.Mutex
+	var messages []string
+	proc, err := NewProcessor(cfg, func(r *LogRecord) error {
+		mu.Lock()
+		messages = append(messages, r.Entry.Message)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("NewProcessor: %v", err)
+	}
+	if err := proc.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer proc.Stop()
+
+	waitFor(t, 2*time.Second, func() bool {
+		_, _, segStats := proc.Stats()
+		return segStats[3] == 1
+	})
+
+	processed, errs, _ := proc.Stats()
+	if processed != 2 || errs != 0 {
+		t.Fatalf("processed %d, errors %d; want 2, 0", processed, errs)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []string{"first\nline", `second "quoted"`}; !reflect.DeepEqual(messages, want) {
+		t.Fatalf("messages = %q, want %q", messages, want)
+	}
+
+	cfg.Follow = true
+	if err := cfg.Validate(); err == nil {
+		t.Fatal("expected Follow with a wrapped document to fail validation")
+	}
+}
+
+// TestReaderWrapped covers the shapes of wrapped documents and resuming
+// from a record's offset
+func TestReaderWrapped(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	readAll := func(path, wrappedPath string, offset int64) ([]string, []int64, error) {
+		opts := DefaultReaderOptions()
+		opts.Format = Wrapped
+		opts.WrappedPath = wrappedPath
+		reader, err := NewLogReaderWithOptions(path, offset, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+
+		var messages []string
+		var offsets []int64
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				return messages, offsets, nil
+			}
+			if err != nil {
+				return messages, offsets, err
+			}
+			if record.ParseErr != nil {
+				t.Fatalf("record %d: %v", record.LineNumber, record.ParseErr)
+			}
+			messages = append(messages, record.Entry.Message)
+			offsets = append(offsets, record.Offset)
+		}
+	}
+
+	nested := write("nested.json", `{"meta": 1, "data": {"items": [
+		{"message": "a"}, {"message": "b"}, {"message": "c"}
+	]}}`)
+	messages, offsets, err := readAll(nested, "data.items", 0)
+	if err != nil || !reflect.DeepEqual(messages, []string{"a", "b", "c"}) {
+		t.Fatalf("nested = %q, %v; want a, b, c", messages, err)
+	}
+	resumed, _, err := readAll(nested, "data.items", offsets[0])
+	if err != nil || !reflect.DeepEqual(resumed, []string{"b", "c"}) {
+		t.Fatalf("resumed after a = %q, %v; want b, c", resumed, err)
+	}
+	if rest, _, err := readAll(nested, "data.items", offsets[2]); err != nil || len(rest) != 0 {
+		t.Fatalf("resumed after c = %q, %v; want nothing", rest, err)
+	}
+
+	single := write("single.json", "{\n  \"level\": \"INFO\",\n  \"message\": \"only\"\n}\n")
+	if messages, _, err := readAll(single, "", 0); err != nil || !reflect.DeepEqual(messages, []string{"only"}) {
+		t.Fatalf("single = %q, %v; want only", messages, err)
+	}
+
+	array := write("array.json", `[{"message": "x"}, {"message": "y"}]`)
+	if messages, _, err := readAll(array, "", 0); err != nil || !reflect.DeepEqual(messages, []string{"x", "y"}) {
+		t.Fatalf("array = %q, %v; want x, y", messages, err)
+	}
+
+	if _, _, err := readAll(array, "logs", 0); err == nil {
+		t.Fatal("expected an error for a path into an array")
+	}
+	if _, _, err := readAll(nested, "logs", 0); err == nil {
+		t.Fatal("expected an error for a missing key")
+	}
+
+	truncated := write("truncated.json", `{"logs": [{"message": "x"}, {"message": "y`)
+	messages, _, err = readAll(truncated, "logs", 0)
+	if !errors.Is(err, io.ErrUnexpectedEOF) || !reflect.DeepEqual(messages, []string{"x"}) {
+		t.Fatalf("truncated = %q, %v; want x then io.ErrUnexpectedEOF", messages, err)
+	}
+}
@@ -0,0 +1,143 @@
+package rotation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTemplate names rotated files "<base>.<date>"
+	DefaultTemplate = "{base}.{date}"
+
+	// DefaultLayout is the time layout substituted for {date}
+	DefaultLayout = "20060102-150405"
+)
+
+// Scheme describes how rotated log files are named. Template may use
+// {base} (the active file's name) and must contain {date}, which is
+// replaced by the rotation time formatted with Layout. The zero value
+// is the default "<base>.20060102-150405" scheme.
+type Scheme struct {
+	Template string
+	Layout   string
+}
+
+// Default returns the default naming scheme
+func Default() Scheme {
+	return Scheme{Template: DefaultTemplate, Layout: DefaultLayout}
+}
+
+// template returns the effective template
+func (s Scheme) template() string {
+	if s.Template == "" {
+		return DefaultTemplate
+	}
+	return s.Template
+}
+
+// layout returns the effective time layout
+func (s Scheme) layout() string {
+	if s.Layout == "" {
+		return DefaultLayout
+	}
+	return s.Layout
+}
+
+// Validate checks that the scheme can produce and parse names
+func (s Scheme) Validate() error {
+	tmpl := s.template()
+	if strings.Count(tmpl, "{date}") != 1 {
+		return errors.New("rotation template must contain {date} exactly once")
+	}
+	if strings.ContainsAny(strings.NewReplacer("{base}", "", "{date}", "").Replace(tmpl), `/\`) {
+		return errors.New("rotation template must not contain path separators")
+	}
+	if time.Unix(0, 0).UTC().Format(s.layout()) == s.layout() {
+		return fmt.Errorf("rotation layout %q contains no time fields", s.layout())
+	}
+	return nil
+}
+
+// Warnings reports properties of the scheme that may cause problems:
+// names that don't sort chronologically, or that repeat for rotations
+// less than a second apart (a later rotation would overwrite an
+// earlier file).
+func (s Scheme) Warnings() []string {
+	var warnings []string
+
+	// Probe times that increase in each field, including carries
+	base := time.Date(2026, 9, 30, 9, 59, 59, 0, time.UTC)
+	probes := []time.Time{
+		base,
+		base.Add(time.Second),
+		base.Add(time.Minute),
+		base.Add(time.Hour),
+		base.AddDate(0, 0, 1),
+		base.AddDate(0, 1, 0),
+		base.AddDate(0, 3, 5),
+		base.AddDate(1, 0, 0),
+	}
+	names := make([]string, len(probes))
+	for i, t := range probes {
+		names[i] = s.Name("app.log", t)
+	}
+	if !sort.StringsAreSorted(names) {
+		warnings = append(warnings, "rotated names do not sort chronologically; use a layout ordered year-month-day-hour-minute-second with zero padding")
+	}
+
+	start := time.Date(2026, 9, 30, 10, 0, 0, 0, time.UTC)
+	if s.Name("app.log", start) == s.Name("app.log", start.Add(time.Second)) {
+		warnings = append(warnings, "rotated names are not unique per second; rapid rotations will overwrite each other")
+	}
+
+	return warnings
+}
+
+// Name returns the rotated file name for base at time t
+func (s Scheme) Name(base string, t time.Time) string {
+	return strings.NewReplacer(
+		"{base}", base,
+		"{date}", t.Format(s.layout()),
+	).Replace(s.template())
+}
+
+// Glob returns a filepath.Match pattern matching rotated names of base
+func (s Scheme) Glob(base string) string {
+	return strings.NewReplacer(
+		"{base}", base,
+		"{date}", "*",
+	).Replace(s.template())
+}
+
+// Parse extracts the rotation time from a rotated file name of base.
+// Layouts without a zone are interpreted as UTC.
+func (s Scheme) Parse(base, name string) (time.Time, error) {
+	return s.ParseInLocation(base, name, time.UTC)
+}
+
+// ParseInLocation is like Parse but interprets layouts without a zone
+// in loc, e.g. time.Local for names generated from time.Now()
+func (s Scheme) ParseInLocation(base, name string, loc *time.Location) (time.Time, error) {
+	parts := strings.SplitN(s.template(), "{date}", 2)
+	if len(parts) != 2 {
+		return time.Time{}, errors.New("rotation template must contain {date}")
+	}
+
+	quote := func(p string) string {
+		return strings.ReplaceAll(regexp.QuoteMeta(p), regexp.QuoteMeta("{base}"), regexp.QuoteMeta(base))
+	}
+	re, err := regexp.Compile("^" + quote(parts[0]) + "(.+)" + quote(parts[1]) + "$")
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	m := re.FindStringSubmatch(name)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("%q does not match rotation template %q", name, s.template())
+	}
+	return time.ParseInLocation(s.layout(), m[1], loc)
+}
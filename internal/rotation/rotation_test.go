@@ -0,0 +1,61 @@
+package rotation
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSchemeRoundTrip verifies names round-trip through Parse for several schemes
+func TestSchemeRoundTrip(t *testing.T) {
+	at := time.Date(2026, 1, 2, 12, 30, 45, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		scheme Scheme
+		want   string
+	}{
+		{name: "default", scheme: Scheme{}, want: "app.log.20260102-123045"},
+		{name: "date in middle", scheme: Scheme{Template: "app-{date}.log"}, want: "app-20260102-123045.log"},
+		{name: "custom layout", scheme: Scheme{Template: "{base}-{date}", Layout: "2006-01-02T15.04.05"}, want: "app.log-2026-01-02T12.30.45"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.scheme.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if w := tt.scheme.Warnings(); len(w) != 0 {
+				t.Fatalf("unexpected warnings: %v", w)
+			}
+
+			name := tt.scheme.Name("app.log", at)
+			if name != tt.want {
+				t.Fatalf("Name() = %q, want %q", name, tt.want)
+			}
+
+			parsed, err := tt.scheme.Parse("app.log", name)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !parsed.Equal(at) {
+				t.Fatalf("Parse() = %v, want %v", parsed, at)
+			}
+		})
+	}
+}
+
+// TestSchemeWarnings verifies unsortable and non-unique layouts are flagged
+func TestSchemeWarnings(t *testing.T) {
+	if w := (Scheme{Layout: "02-01-2006_150405"}).Warnings(); len(w) == 0 {
+		t.Fatal("expected a sortability warning for day-first layout")
+	}
+	if w := (Scheme{Layout: "20060102-1504"}).Warnings(); len(w) == 0 {
+		t.Fatal("expected a uniqueness warning for minute resolution")
+	}
+	if err := (Scheme{Template: "{base}.log"}).Validate(); err == nil {
+		t.Fatal("expected error for template without {date}")
+	}
+	if _, err := (Scheme{}).Parse("app.log", "other.log.20260102-123045"); err == nil {
+		t.Fatal("expected error parsing a name for another base")
+	}
+}
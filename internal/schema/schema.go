@@ -0,0 +1,324 @@
+// Package schema validates JSON documents against a JSON Schema. It
+// implements the subset of the specification that is useful for log
+// records: type, enum, const, required, properties,
+// additionalProperties, items, minLength, maxLength, pattern, minimum,
+// maximum and the "date-time" format. Other keywords (such as $schema,
+// title and description) are accepted and ignored.
+package schema
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	json "github.com/goccy/go-json"
+)
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	types      []string
+	enum       []any
+	constant   *any
+	required   []string
+	properties map[string]*Schema
+	additional *Schema // Applies to properties not listed; nil allows any
+	items      *Schema
+	minLength  *int
+	maxLength  *int
+	pattern    *regexp.Regexp
+	minimum    *float64
+	maximum    *float64
+	format     string
+	never      bool // The false schema: nothing validates
+}
+
+// rawSchema is the JSON form of a schema
+type rawSchema struct {
+	Type                 json.RawMessage            `json:"type"`
+	Enum                 []any                      `json:"enum"`
+	Const                json.RawMessage            `json:"const"`
+	Required             []string                   `json:"required"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+	Pattern              *string                    `json:"pattern"`
+	Minimum              *float64                   `json:"minimum"`
+	Maximum              *float64                   `json:"maximum"`
+	Format               string                     `json:"format"`
+}
+
+// validTypes are the JSON Schema type names
+var validTypes = map[string]bool{
+	"string": true, "number": true, "integer": true, "boolean": true,
+	"object": true, "array": true, "null": true,
+}
+
+// Load reads and compiles a schema file
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := Compile(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Compile parses a schema document
+func Compile(data []byte) (*Schema, error) {
+	return compile(data, "")
+}
+
+// compile parses the schema at a JSON pointer location
+func compile(data []byte, at string) (*Schema, error) {
+	switch strings.TrimSpace(string(data)) {
+	case "true":
+		return &Schema{}, nil
+	case "false":
+		return &Schema{never: true}, nil
+	}
+
+	var raw rawSchema
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("schema%s: %w", at, err)
+	}
+
+	s := &Schema{
+		enum:      raw.Enum,
+		required:  raw.Required,
+		minLength: raw.MinLength,
+		maxLength: raw.MaxLength,
+		minimum:   raw.Minimum,
+		maximum:   raw.Maximum,
+		format:    raw.Format,
+	}
+
+	if len(raw.Type) > 0 {
+		var one string
+		if err := json.Unmarshal(raw.Type, &one); err == nil {
+			s.types = []string{one}
+		} else if err := json.Unmarshal(raw.Type, &s.types); err != nil {
+			return nil, fmt.Errorf("schema%s: type must be a string or an array of strings", at)
+		}
+		for _, t := range s.types {
+			if !validTypes[t] {
+				return nil, fmt.Errorf("schema%s: unknown type %q", at, t)
+			}
+		}
+	}
+
+	if len(raw.Const) > 0 {
+		var c any
+		if err := json.Unmarshal(raw.Const, &c); err != nil {
+			return nil, fmt.Errorf("schema%s: const: %w", at, err)
+		}
+		s.constant = &c
+	}
+
+	if raw.Pattern != nil {
+		re, err := regexp.Compile(*raw.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("schema%s: pattern: %w", at, err)
+		}
+		s.pattern = re
+	}
+
+	if len(raw.Properties) > 0 {
+		s.properties = make(map[string]*Schema, len(raw.Properties))
+		for name, sub := range raw.Properties {
+			compiled, err := compile(sub, at+"/properties/"+name)
+			if err != nil {
+				return nil, err
+			}
+			s.properties[name] = compiled
+		}
+	}
+
+	var err error
+	if len(raw.AdditionalProperties) > 0 {
+		if s.additional, err = compile(raw.AdditionalProperties, at+"/additionalProperties"); err != nil {
+			return nil, err
+		}
+	}
+	if len(raw.Items) > 0 {
+		if s.items, err = compile(raw.Items, at+"/items"); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// ValidateJSON decodes a document and validates it
+func (s *Schema) ValidateJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return s.Validate(v)
+}
+
+// Validate checks a decoded JSON value (as produced by unmarshaling
+// into an any), reporting every violation found
+func (s *Schema) Validate(v any) error {
+	var errs []error
+	s.validate(v, "", &errs)
+	return errors.Join(errs...)
+}
+
+// validate appends the violations of v at path to errs
+func (s *Schema) validate(v any, path string, errs *[]error) {
+	fail := func(format string, args ...any) {
+		at := path
+		if at == "" {
+			at = "/"
+		}
+		*errs = append(*errs, fmt.Errorf("%s: %s", at, fmt.Sprintf(format, args...)))
+	}
+
+	if s.never {
+		fail("not allowed")
+		return
+	}
+
+	if len(s.types) > 0 && !matchesType(v, s.types) {
+		fail("must be %s, not %s", strings.Join(s.types, " or "), typeOf(v))
+		return
+	}
+
+	if len(s.enum) > 0 && !contains(s.enum, v) {
+		fail("must be one of %s", formatValues(s.enum))
+	}
+	if s.constant != nil && !reflect.DeepEqual(*s.constant, v) {
+		fail("must be %s", formatValues([]any{*s.constant}))
+	}
+
+	switch v := v.(type) {
+	case string:
+		s.validateString(v, fail)
+	case float64:
+		if s.minimum != nil && v < *s.minimum {
+			fail("must be at least %v", *s.minimum)
+		}
+		if s.maximum != nil && v > *s.maximum {
+			fail("must be at most %v", *s.maximum)
+		}
+	case map[string]any:
+		s.validateObject(v, path, errs, fail)
+	case []any:
+		if s.items != nil {
+			for i, item := range v {
+				s.items.validate(item, fmt.Sprintf("%s/%d", path, i), errs)
+			}
+		}
+	}
+}
+
+// validateString applies the string keywords
+func (s *Schema) validateString(v string, fail func(string, ...any)) {
+	n := utf8.RuneCountInString(v)
+	if s.minLength != nil && n < *s.minLength {
+		fail("must be at least %d characters", *s.minLength)
+	}
+	if s.maxLength != nil && n > *s.maxLength {
+		fail("must be at most %d characters", *s.maxLength)
+	}
+	if s.pattern != nil && !s.pattern.MatchString(v) {
+		fail("must match %q", s.pattern.String())
+	}
+	if s.format == "date-time" {
+		if _, err := time.Parse(time.RFC3339Nano, v); err != nil {
+			fail("must be an RFC 3339 date-time")
+		}
+	}
+}
+
+// validateObject applies the object keywords
+func (s *Schema) validateObject(v map[string]any, path string, errs *[]error, fail func(string, ...any)) {
+	for _, name := range s.required {
+		if _, ok := v[name]; !ok {
+			fail("missing required property %q", name)
+		}
+	}
+
+	names := make([]string, 0, len(v))
+	for name := range v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sub, ok := s.properties[name]
+		if !ok {
+			sub = s.additional
+		}
+		if sub != nil {
+			sub.validate(v[name], path+"/"+name, errs)
+		}
+	}
+}
+
+// matchesType reports whether v is one of the named JSON types
+func matchesType(v any, types []string) bool {
+	actual := typeOf(v)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf names the JSON type of a decoded value
+func typeOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// contains reports whether values holds v
+func contains(values []any, v any) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatValues renders values as JSON for messages
+func formatValues(values []any) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		data, _ := json.Marshal(v)
+		parts[i] = string(data)
+	}
+	return strings.Join(parts, ", ")
+}
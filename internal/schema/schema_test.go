@@ -0,0 +1,87 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestValidate covers each supported keyword
+func TestValidate(t *testing.T) {
+	s, err := Compile([]byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"required": ["level", "message"],
+		"properties": {
+			"level": {"enum": ["INFO", "ERROR"]},
+			"message": {"type": "string", "minLength": 1, "maxLength": 10},
+			"timestamp": {"type": "string", "format": "date-time"},
+			"request_id": {"type": "string", "pattern": "^req-"},
+			"duration_ms": {"type": "integer", "minimum": 0, "maximum": 1000},
+			"version": {"const": 2},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"user_id": {"type": ["string", "null"]}
+		},
+		"additionalProperties": false
+	}`))
+	if err != nil {
+		t.Fatalf("Compile: %v", err)
+	}
+
+	tests := []struct {
+		doc  string
+		want string // Substring of the error; empty if valid
+	}{
+		{`{"level":"INFO","message":"ok"}`, ""},
+		{`{"level":"INFO","message":"ok","timestamp":"2026-01-01T00:00:00.5Z","request_id":"req-1",` +
+			`"duration_ms":5,"version":2,"tags":["a"],"user_id":null}`, ""},
+		{`{"message":"ok"}`, `missing required property "level"`},
+		{`{"level":"WARN","message":"ok"}`, `/level: must be one of "INFO", "ERROR"`},
+		{`{"level":"INFO","message":""}`, "/message: must be at least 1 characters"},
+		{`{"level":"INFO","message":"far too long"}`, "/message: must be at most 10"},
+		{`{"level":"INFO","message":"ok","timestamp":"yesterday"}`, "/timestamp: must be an RFC 3339"},
+		{`{"level":"INFO","message":"ok","request_id":"r-1"}`, "/request_id: must match"},
+		{`{"level":"INFO","message":"ok","duration_ms":1.5}`, "/duration_ms: must be integer, not number"},
+		{`{"level":"INFO","message":"ok","duration_ms":-1}`, "/duration_ms: must be at least 0"},
+		{`{"level":"INFO","message":"ok","version":3}`, "/version: must be 2"},
+		{`{"level":"INFO","message":"ok","tags":["a",1]}`, "/tags/1: must be string"},
+		{`{"level":"INFO","message":"ok","extra":true}`, "/extra: not allowed"},
+		{`[]`, "/: must be object, not array"},
+	}
+
+	for _, tt := range tests {
+		err := s.ValidateJSON([]byte(tt.doc))
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", tt.doc, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: error %v, want %q", tt.doc, err, tt.want)
+		}
+	}
+}
+
+// TestValidateReportsAll verifies every violation is reported
+func TestValidateReportsAll(t *testing.T) {
+	s, err := Compile([]byte(`{"required": ["a", "b"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = s.ValidateJSON([]byte(`{}`))
+	if err == nil || !strings.Contains(err.Error(), `"a"`) || !strings.Contains(err.Error(), `"b"`) {
+		t.Fatalf("error = %v, want both properties", err)
+	}
+}
+
+// TestCompileErrors verifies malformed schemas are rejected
+func TestCompileErrors(t *testing.T) {
+	for _, doc := range []string{
+		`{"type": "strnig"}`,
+		`{"type": 1}`,
+		`{"properties": {"a": {"pattern": "("}}}`,
+		`not json`,
+	} {
+		if _, err := Compile([]byte(doc)); err == nil {
+			t.Errorf("Compile(%s) succeeded", doc)
+		}
+	}
+}